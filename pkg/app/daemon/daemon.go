@@ -3,13 +3,15 @@ package daemon
 import (
 	"encoding/json"
 	"fmt"
-	"log"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strconv"
+	"strings"
 
 	"github.com/fsmiamoto/git-todo-parser/todo"
 	"github.com/jesseduffield/lazygit/pkg/commands/models"
+	"github.com/jesseduffield/lazygit/pkg/commands/oscommands"
 	"github.com/jesseduffield/lazygit/pkg/common"
 	"github.com/jesseduffield/lazygit/pkg/utils"
 	"github.com/samber/lo"
@@ -39,6 +41,10 @@ const (
 	DaemonKindInsertBreak
 	DaemonKindChangeTodoActions
 	DaemonKindMoveFixupCommitDown
+	DaemonKindEditCommitWithCheck
+	DaemonKindReorderTodos
+	DaemonKindEditorForRewords
+	DaemonKindExecOnPaths
 )
 
 const (
@@ -46,6 +52,10 @@ const (
 
 	// Contains json-encoded arguments to the daemon
 	DaemonInstructionEnvKey string = "LAZYGIT_DAEMON_INSTRUCTION"
+
+	// Contains a json-encoded map from commit sha to the committer date that
+	// the commit should keep when it is rebuilt by the rebase
+	DaemonCommitterDatesEnvKey string = "LAZYGIT_DAEMON_COMMITTER_DATES"
 )
 
 func getInstruction() Instruction {
@@ -59,6 +69,10 @@ func getInstruction() Instruction {
 		DaemonKindMoveTodoUp:          deserializeInstruction[*MoveTodoUpInstruction],
 		DaemonKindMoveTodoDown:        deserializeInstruction[*MoveTodoDownInstruction],
 		DaemonKindInsertBreak:         deserializeInstruction[*InsertBreakInstruction],
+		DaemonKindEditCommitWithCheck: deserializeInstruction[*EditCommitWithCheckInstruction],
+		DaemonKindReorderTodos:        deserializeInstruction[*ReorderTodosInstruction],
+		DaemonKindEditorForRewords:    deserializeInstruction[*EditorForRewordsInstruction],
+		DaemonKindExecOnPaths:         deserializeInstruction[*ExecOnPathsInstruction],
 	}
 
 	return mapping[getDaemonKind()](jsonData)
@@ -71,8 +85,11 @@ func Handle(common *common.Common) {
 
 	instruction := getInstruction()
 
-	if err := instruction.run(common); err != nil {
-		log.Fatal(err)
+	if err := instruction.run(common, getEditorInput()); err != nil {
+		// git shows this to the parent lazygit as part of its error output,
+		// so we leave out the timestamp that log.Fatal would add
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
 
 	os.Exit(0)
@@ -91,6 +108,11 @@ func getDaemonKind() DaemonKind {
 	return DaemonKind(intValue)
 }
 
+// Returns the character that git uses to mark comment lines in the todo
+// file. This needs to match what git used when it wrote the file, otherwise
+// we'd parse comments as todos (or commit messages starting with '#' as
+// comments). Git uses '#' for the todo file when core.commentChar is 'auto',
+// which is what the fallback gives us.
 func getCommentChar() byte {
 	cmd := exec.Command("git", "config", "--get", "--null", "core.commentChar")
 	if output, err := cmd.Output(); err == nil && len(output) == 2 {
@@ -107,7 +129,47 @@ type Instruction interface {
 	SerializedInstructions() string
 
 	// runs the instruction
-	run(common *common.Common) error
+	run(common *common.Common, input editorInput) error
+}
+
+// What an instruction works on: the file that git wants edited, and the
+// settings that aren't specific to any one instruction
+type editorInput struct {
+	path           string
+	committerDates map[string]string
+}
+
+// In daemon mode, git passes the file as our argument, and the parent lazygit
+// passes the settings in env vars
+func getEditorInput() editorInput {
+	path := ""
+	if len(os.Args) > 1 {
+		path = os.Args[1]
+	}
+
+	return editorInput{
+		path:           path,
+		committerDates: getCommitterDates(),
+	}
+}
+
+// EditTodoFile carries out the instruction on the todo file of a rebase that
+// is stopped (e.g. at a break before its first todo), for when lazygit can't
+// be run as git's sequence editor (see git.rebase.useDaemon). todoPath must be
+// the rebase's git-rebase-todo file. Messages that the instruction would give
+// to git when it asks for them are ignored; see SuppliesMessages.
+func EditTodoFile(common *common.Common, instruction Instruction, todoPath string, committerDates map[string]string) error {
+	return instruction.run(common, editorInput{
+		path:           todoPath,
+		committerDates: committerDates,
+	})
+}
+
+// SuppliesMessages tells whether the instruction gives git the messages of
+// commits that it rewords, which only works when lazygit is git's editor
+func SuppliesMessages(instruction Instruction) bool {
+	changeTodoActions, ok := instruction.(*ChangeTodoActionsInstruction)
+	return ok && lo.SomeBy(changeTodoActions.Changes, func(c ChangeTodoAction) bool { return c.NewMessage != "" })
 }
 
 func serializeInstruction[T any](instruction T) string {
@@ -137,6 +199,29 @@ func ToEnvVars(instruction Instruction) []string {
 	}
 }
 
+// Unlike the instruction, the committer dates aren't specific to any one kind
+// of rebase, so they're passed separately and applied after the instruction
+// has edited the todo file
+func CommitterDatesToEnvVars(committerDates map[string]string) []string {
+	return []string{
+		fmt.Sprintf("%s=%s", DaemonCommitterDatesEnvKey, serializeInstruction(committerDates)),
+	}
+}
+
+func getCommitterDates() map[string]string {
+	jsonData := os.Getenv(DaemonCommitterDatesEnvKey)
+	if jsonData == "" {
+		return nil
+	}
+
+	var committerDates map[string]string
+	if err := json.Unmarshal([]byte(jsonData), &committerDates); err != nil {
+		panic(err)
+	}
+
+	return committerDates
+}
+
 type ExitImmediatelyInstruction struct{}
 
 func (self *ExitImmediatelyInstruction) Kind() DaemonKind {
@@ -147,7 +232,7 @@ func (self *ExitImmediatelyInstruction) SerializedInstructions() string {
 	return serializeInstruction(self)
 }
 
-func (self *ExitImmediatelyInstruction) run(common *common.Common) error {
+func (self *ExitImmediatelyInstruction) run(common *common.Common, input editorInput) error {
 	return nil
 }
 
@@ -174,6 +259,48 @@ func NewCherryPickCommitsInstruction(commits []*models.Commit) Instruction {
 	}
 }
 
+// NewCherryPickCommitsWithMainlineInstruction is like
+// NewCherryPickCommitsInstruction, but also cherry-picks the merge commits that
+// mainlineBySha has a mainline parent for (see git cherry-pick -m). A pick todo
+// has no way of saying which parent to use, so those get an exec todo that
+// runs git cherry-pick itself; if that stops at conflicts, the rest of the
+// todo is still there for when the rebase is continued.
+func NewCherryPickCommitsWithMainlineInstruction(commits []*models.Commit, mainlineBySha map[string]int) Instruction {
+	todoLines := lo.Map(commits, func(commit *models.Commit, _ int) TodoLine {
+		if mainline, ok := mainlineBySha[commit.Sha]; ok && commit.IsMerge() {
+			return TodoLine{
+				Action:      "exec",
+				ExecCommand: mainlineCherryPickExec(mainline, commit.Sha),
+			}
+		}
+		return TodoLine{
+			Action: "pick",
+			Commit: commit,
+		}
+	})
+
+	return &CherryPickCommitsInstruction{
+		Todo: TodoLinesToString(todoLines),
+	}
+}
+
+func mainlineCherryPickExec(mainline int, sha string) string {
+	return fmt.Sprintf("git cherry-pick -m %d %s", mainline, sha)
+}
+
+// IsMainlineCherryPickExec tells whether execCommand is the command of an exec
+// todo that NewCherryPickCommitsWithMainlineInstruction added to cherry-pick
+// the commit with the given sha
+func IsMainlineCherryPickExec(execCommand string, sha string) bool {
+	var mainline int
+	var execSha string
+	if _, err := fmt.Sscanf(execCommand, "git cherry-pick -m %d %s", &mainline, &execSha); err != nil {
+		return false
+	}
+
+	return execSha == sha && execCommand == mainlineCherryPickExec(mainline, sha)
+}
+
 func (self *CherryPickCommitsInstruction) Kind() DaemonKind {
 	return DaemonKindCherryPick
 }
@@ -182,8 +309,8 @@ func (self *CherryPickCommitsInstruction) SerializedInstructions() string {
 	return serializeInstruction(self)
 }
 
-func (self *CherryPickCommitsInstruction) run(common *common.Common) error {
-	return handleInteractiveRebase(common, func(path string) error {
+func (self *CherryPickCommitsInstruction) run(common *common.Common, input editorInput) error {
+	return handleInteractiveRebase(common, input, func(path string) error {
 		return utils.PrependStrToTodoFile(path, []byte(self.Todo))
 	})
 }
@@ -206,24 +333,58 @@ func (self *ChangeTodoActionsInstruction) SerializedInstructions() string {
 	return serializeInstruction(self)
 }
 
-func (self *ChangeTodoActionsInstruction) run(common *common.Common) error {
-	return handleInteractiveRebase(common, func(path string) error {
+func (self *ChangeTodoActionsInstruction) run(common *common.Common, input editorInput) error {
+	messages := map[string]string{}
+	for _, c := range self.Changes {
+		if c.NewMessage != "" {
+			messages[c.Sha] = c.NewMessage
+		}
+	}
+
+	return handleInteractiveRebaseWithMessages(common, input, func(path string) error {
+		// Check all of the changes before making any of them, so that a commit
+		// list that has gone out of date fails the whole rebase rather than
+		// leaving out some of the changes
+		todos, err := utils.ReadRebaseTodoFile(path, getCommentChar())
+		if err != nil {
+			return err
+		}
+		shas := lo.Map(self.Changes, func(c ChangeTodoAction, _ int) string { return c.Sha })
+		if missing := utils.MissingPickTodos(todos, shas); len(missing) > 0 {
+			return fmt.Errorf(common.Tr.RebaseTodoShaNotFound,
+				strings.Join(lo.Map(missing, func(sha string, _ int) string { return utils.ShortSha(sha) }), ", "))
+		}
+
 		for _, c := range self.Changes {
 			if err := utils.EditRebaseTodo(path, c.Sha, todo.Pick, c.NewAction, getCommentChar()); err != nil {
 				return err
 			}
+
+			if c.MoveAfterSha != "" {
+				if err := utils.MoveTodoAfter(path, c.Sha, c.NewAction, c.MoveAfterSha, getCommentChar()); err != nil {
+					return err
+				}
+			}
+
+			if c.MoveBeforeSha != "" {
+				if err := utils.MoveTodoBefore(path, c.Sha, c.NewAction, c.MoveBeforeSha, getCommentChar()); err != nil {
+					return err
+				}
+			}
 		}
 
 		return nil
-	})
+	}, messages)
 }
 
 // Takes the sha of some commit, and the sha of a fixup commit that was created
 // at the end of the branch, then moves the fixup commit down to right after the
-// original commit, changing its type to "fixup"
+// original commit, changing its type to "fixup". If UseFixupMessage is set it
+// becomes a "fixup -C", so the original commit takes on the fixup's message.
 type MoveFixupCommitDownInstruction struct {
-	OriginalSha string
-	FixupSha    string
+	OriginalSha     string
+	FixupSha        string
+	UseFixupMessage bool `json:",omitempty"`
 }
 
 func NewMoveFixupCommitDownInstruction(originalSha string, fixupSha string) Instruction {
@@ -233,6 +394,16 @@ func NewMoveFixupCommitDownInstruction(originalSha string, fixupSha string) Inst
 	}
 }
 
+// Like NewMoveFixupCommitDownInstruction, but for an amend! commit, whose
+// message replaces that of the original commit
+func NewMoveAmendCommitDownInstruction(originalSha string, amendSha string) Instruction {
+	return &MoveFixupCommitDownInstruction{
+		OriginalSha:     originalSha,
+		FixupSha:        amendSha,
+		UseFixupMessage: true,
+	}
+}
+
 func (self *MoveFixupCommitDownInstruction) Kind() DaemonKind {
 	return DaemonKindMoveFixupCommitDown
 }
@@ -241,9 +412,9 @@ func (self *MoveFixupCommitDownInstruction) SerializedInstructions() string {
 	return serializeInstruction(self)
 }
 
-func (self *MoveFixupCommitDownInstruction) run(common *common.Common) error {
-	return handleInteractiveRebase(common, func(path string) error {
-		return utils.MoveFixupCommitDown(path, self.OriginalSha, self.FixupSha, getCommentChar())
+func (self *MoveFixupCommitDownInstruction) run(common *common.Common, input editorInput) error {
+	return handleInteractiveRebase(common, input, func(path string) error {
+		return utils.MoveFixupCommitDown(path, self.OriginalSha, self.FixupSha, self.UseFixupMessage, getCommentChar())
 	})
 }
 
@@ -265,8 +436,8 @@ func (self *MoveTodoUpInstruction) SerializedInstructions() string {
 	return serializeInstruction(self)
 }
 
-func (self *MoveTodoUpInstruction) run(common *common.Common) error {
-	return handleInteractiveRebase(common, func(path string) error {
+func (self *MoveTodoUpInstruction) run(common *common.Common, input editorInput) error {
+	return handleInteractiveRebase(common, input, func(path string) error {
 		return utils.MoveTodoUp(path, self.Sha, todo.Pick, getCommentChar())
 	})
 }
@@ -289,8 +460,8 @@ func (self *MoveTodoDownInstruction) SerializedInstructions() string {
 	return serializeInstruction(self)
 }
 
-func (self *MoveTodoDownInstruction) run(common *common.Common) error {
-	return handleInteractiveRebase(common, func(path string) error {
+func (self *MoveTodoDownInstruction) run(common *common.Common, input editorInput) error {
+	return handleInteractiveRebase(common, input, func(path string) error {
 		return utils.MoveTodoDown(path, self.Sha, todo.Pick, getCommentChar())
 	})
 }
@@ -309,8 +480,152 @@ func (self *InsertBreakInstruction) SerializedInstructions() string {
 	return serializeInstruction(self)
 }
 
-func (self *InsertBreakInstruction) run(common *common.Common) error {
-	return handleInteractiveRebase(common, func(path string) error {
+func (self *InsertBreakInstruction) run(common *common.Common, input editorInput) error {
+	return handleInteractiveRebase(common, input, func(path string) error {
 		return utils.PrependStrToTodoFile(path, []byte("break\n"))
 	})
 }
+
+// Marks the given commit for editing and adds an exec line running CheckCmd
+// right after it, so that git only moves on past the edited commit once the
+// check passes
+type EditCommitWithCheckInstruction struct {
+	Sha      string
+	CheckCmd string
+}
+
+func NewEditCommitWithCheckInstruction(sha string, checkCmd string) Instruction {
+	return &EditCommitWithCheckInstruction{
+		Sha:      sha,
+		CheckCmd: checkCmd,
+	}
+}
+
+func (self *EditCommitWithCheckInstruction) Kind() DaemonKind {
+	return DaemonKindEditCommitWithCheck
+}
+
+func (self *EditCommitWithCheckInstruction) SerializedInstructions() string {
+	return serializeInstruction(self)
+}
+
+func (self *EditCommitWithCheckInstruction) run(common *common.Common, input editorInput) error {
+	return handleInteractiveRebase(common, input, func(path string) error {
+		return utils.EditTodoWithCheck(path, self.Sha, self.CheckCmd, getCommentChar())
+	})
+}
+
+// Rearranges the picks of the given commits so that they appear in the todo in
+// the order given (oldest first)
+type ReorderTodosInstruction struct {
+	Shas []string
+}
+
+func NewReorderTodosInstruction(shas []string) Instruction {
+	return &ReorderTodosInstruction{
+		Shas: shas,
+	}
+}
+
+func (self *ReorderTodosInstruction) Kind() DaemonKind {
+	return DaemonKindReorderTodos
+}
+
+func (self *ReorderTodosInstruction) SerializedInstructions() string {
+	return serializeInstruction(self)
+}
+
+func (self *ReorderTodosInstruction) run(common *common.Common, input editorInput) error {
+	return handleInteractiveRebase(common, input, func(path string) error {
+		return utils.ReorderTodos(path, self.Shas, getCommentChar())
+	})
+}
+
+// Adds an exec line running ExecCmd after each commit in the todo that touches
+// any of the given paths, e.g. to run a linter only where it's relevant. Git's
+// own --exec would run it after every commit.
+type ExecOnPathsInstruction struct {
+	Paths   []string
+	ExecCmd string
+}
+
+func NewExecOnPathsInstruction(paths []string, execCmd string) Instruction {
+	return &ExecOnPathsInstruction{
+		Paths:   paths,
+		ExecCmd: execCmd,
+	}
+}
+
+func (self *ExecOnPathsInstruction) Kind() DaemonKind {
+	return DaemonKindExecOnPaths
+}
+
+func (self *ExecOnPathsInstruction) SerializedInstructions() string {
+	return serializeInstruction(self)
+}
+
+func (self *ExecOnPathsInstruction) run(common *common.Common, input editorInput) error {
+	return handleInteractiveRebase(common, input, func(path string) error {
+		return utils.AddExecAfterTodos(path, self.commitTouchesPaths, self.ExecCmd, getCommentChar())
+	})
+}
+
+func (self *ExecOnPathsInstruction) commitTouchesPaths(sha string) (bool, error) {
+	args := append([]string{"show", "--name-only", "--format=", sha, "--"}, self.Paths...)
+	output, err := exec.Command("git", args...).Output()
+	if err != nil {
+		return false, err
+	}
+
+	return strings.TrimSpace(string(output)) != "", nil
+}
+
+// Used as GIT_EDITOR when continuing a rebase. Opens the user's editor when
+// git asks for the message of a commit that's being reworded or squashed, and
+// keeps the message as is otherwise (e.g. for a pick that stopped at
+// conflicts), so that continuing through several todos only stops for the ones
+// that need a message from the user.
+type EditorForRewordsInstruction struct {
+	Editor string
+}
+
+func NewEditorForRewordsInstruction(editor string) Instruction {
+	return &EditorForRewordsInstruction{
+		Editor: editor,
+	}
+}
+
+func (self *EditorForRewordsInstruction) Kind() DaemonKind {
+	return DaemonKindEditorForRewords
+}
+
+func (self *EditorForRewordsInstruction) SerializedInstructions() string {
+	return serializeInstruction(self)
+}
+
+func (self *EditorForRewordsInstruction) run(common *common.Common, input editorInput) error {
+	path := input.path
+	if !strings.HasSuffix(path, "COMMIT_EDITMSG") {
+		return nil
+	}
+
+	doneFile := filepath.Join(filepath.Dir(path), "rebase-merge", "done")
+	doneTodos, err := utils.ReadRebaseTodoFile(doneFile, getCommentChar())
+	if err != nil {
+		return err
+	}
+	if !utils.DoneTodosNeedMessageEditor(doneTodos) {
+		return nil
+	}
+
+	// Like git, we run the editor through the shell so that it can have
+	// arguments. We can't rely on sh being on the path (it usually isn't on
+	// Windows, where git uses its own), so we use the platform's shell, like
+	// lazygit's other editor commands do.
+	cmdBuilder := oscommands.NewCmdObjBuilder(common, oscommands.GetPlatform())
+	cmd := cmdBuilder.NewShell(self.Editor + " " + cmdBuilder.Quote(path)).GetCmd()
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
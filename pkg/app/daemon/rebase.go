@@ -9,18 +9,36 @@ import (
 	"github.com/jesseduffield/lazygit/pkg/commands/models"
 	"github.com/jesseduffield/lazygit/pkg/common"
 	"github.com/jesseduffield/lazygit/pkg/env"
+	"github.com/jesseduffield/lazygit/pkg/utils"
 	"github.com/samber/lo"
 )
 
 type TodoLine struct {
 	Action string
 	Commit *models.Commit
+	// For label and reset lines, the label to set or go back to; for merge
+	// lines, the label (or any other ref) to merge in
+	Label string
+	// For exec lines, the shell command to run
+	ExecCommand string
 }
 
 func (self *TodoLine) ToString() string {
-	if self.Action == "break" {
+	switch self.Action {
+	case "break":
 		return self.Action + "\n"
-	} else {
+	case "exec":
+		return self.Action + " " + self.ExecCommand + "\n"
+	case "label", "reset":
+		return self.Action + " " + self.Label + "\n"
+	case "merge":
+		// With -C, git reuses the message of the given merge commit; without
+		// it, git makes up a message like "Merge branch '<label>'"
+		if self.Commit != nil {
+			return "merge -C " + self.Commit.Sha + " " + self.Label + " # " + self.Commit.Name + "\n"
+		}
+		return "merge " + self.Label + "\n"
+	default:
 		return self.Action + " " + self.Commit.Sha + " " + self.Commit.Name + "\n"
 	}
 }
@@ -36,18 +54,53 @@ func TodoLinesToString(todoLines []TodoLine) string {
 type ChangeTodoAction struct {
 	Sha       string
 	NewAction todo.TodoCommand
+	// Only used with todo.Reword: the message to give the commit when git asks
+	// for it, so that the user's editor isn't opened
+	NewMessage string `json:",omitempty"`
+	// If set, the todo is moved so that it comes directly after the commit
+	// with this sha (e.g. to fixup a commit that isn't adjacent)
+	MoveAfterSha string `json:",omitempty"`
+	// If set, the todo is moved so that it comes directly before the commit
+	// with this sha
+	MoveBeforeSha string `json:",omitempty"`
+}
+
+// The name of an empty file that we put in the rebase-merge directory when a
+// rebase was started by lazygit, so that we can tell those apart from rebases
+// that the user started from the command line
+const StartedByLazygitMarkerFile = "lazygit-started"
+
+func handleInteractiveRebase(common *common.Common, input editorInput, f func(path string) error) error {
+	return handleInteractiveRebaseWithMessages(common, input, f, nil)
 }
 
-func handleInteractiveRebase(common *common.Common, f func(path string) error) error {
+// Like handleInteractiveRebase, but when git asks for the message of a commit
+// that's being reworded, we supply the message from the given map (keyed by
+// the sha of the original commit) instead of leaving the message as is
+func handleInteractiveRebaseWithMessages(common *common.Common, input editorInput, f func(path string) error, messages map[string]string) error {
 	common.Log.Info("Lazygit invoked as interactive rebase demon")
-	common.Log.Info("args: ", os.Args)
-	path := os.Args[1]
+	common.Log.Info("path: ", input.path)
+	path := input.path
 
 	if strings.HasSuffix(path, "git-rebase-todo") {
-		return f(path)
+		if err := f(path); err != nil {
+			return err
+		}
+		// git deletes this along with the rest of the rebase state once the
+		// rebase is over, so we don't need to clean it up ourselves
+		if err := os.WriteFile(filepath.Join(filepath.Dir(path), StartedByLazygitMarkerFile), nil, 0o644); err != nil {
+			return err
+		}
+		if len(input.committerDates) > 0 {
+			return utils.PreserveCommitterDates(path, input.committerDates, getCommentChar())
+		}
+		return nil
 	} else if strings.HasSuffix(path, filepath.Join(gitDir(), "COMMIT_EDITMSG")) { // TODO: test
 		// if we are rebasing and squashing, we'll see a COMMIT_EDITMSG
 		// but in this case we don't need to edit it, so we'll just return
+		if len(messages) > 0 {
+			return writeMessageForCurrentCommit(path, messages)
+		}
 	} else {
 		common.Log.Info("Lazygit demon did not match on any use cases")
 	}
@@ -62,3 +115,27 @@ func gitDir() string {
 	}
 	return dir
 }
+
+// Git has just applied the commit that it wants a message for, so it's the
+// last entry of the rebase's done file, which lives next to COMMIT_EDITMSG
+func writeMessageForCurrentCommit(commitEditMsgPath string, messages map[string]string) error {
+	doneFile := filepath.Join(filepath.Dir(commitEditMsgPath), "rebase-merge", "done")
+	todos, err := utils.ReadRebaseTodoFile(doneFile, getCommentChar())
+	if err != nil {
+		return err
+	}
+
+	todos = lo.Filter(todos, func(t todo.Todo, _ int) bool { return t.Commit != "" })
+	if len(todos) == 0 {
+		return nil
+	}
+
+	currentSha := todos[len(todos)-1].Commit
+	for sha, message := range messages {
+		if strings.HasPrefix(sha, currentSha) || strings.HasPrefix(currentSha, sha) {
+			return os.WriteFile(commitEditMsgPath, []byte(message+"\n"), 0o644)
+		}
+	}
+
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/go-errors/errors"
 	"github.com/jesseduffield/lazygit/pkg/commands/oscommands"
 	"github.com/jesseduffield/lazygit/pkg/utils"
 	"github.com/mgutz/str"
@@ -188,11 +189,23 @@ func (self *BranchCommands) countDifferences(from, to string) (string, error) {
 	return self.cmd.New(cmdArgs).DontLog().RunWithOutput()
 }
 
-func (self *BranchCommands) IsHeadDetached() bool {
+// IsHeadDetached tells whether HEAD points directly at a commit rather than at
+// a branch. symbolic-ref exits with status 1 in that case; any other failure
+// (e.g. not being in a repo at all) is returned as an error.
+func (self *BranchCommands) IsHeadDetached() (bool, error) {
 	cmdArgs := NewGitCmd("symbolic-ref").Arg("-q", "HEAD").ToArgv()
 
 	err := self.cmd.New(cmdArgs).DontLog().Run()
-	return err != nil
+	if err == nil {
+		return false, nil
+	}
+
+	var exitErr interface{ ExitCode() int }
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		return true, nil
+	}
+
+	return false, err
 }
 
 func (self *BranchCommands) Rename(oldName string, newName string) error {
@@ -274,3 +274,61 @@ func TestBranchCurrentBranchInfo(t *testing.T) {
 		})
 	}
 }
+
+type fakeExitError struct {
+	exitCode int
+}
+
+func (self fakeExitError) Error() string {
+	return "exit status"
+}
+
+func (self fakeExitError) ExitCode() int {
+	return self.exitCode
+}
+
+func TestBranchIsHeadDetached(t *testing.T) {
+	type scenario struct {
+		testName         string
+		runner           *oscommands.FakeCmdObjRunner
+		expectedDetached bool
+		expectedErr      string
+	}
+
+	scenarios := []scenario{
+		{
+			testName: "on a branch",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"symbolic-ref", "-q", "HEAD"}, "refs/heads/master\n", nil),
+			expectedDetached: false,
+		},
+		{
+			testName: "detached",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"symbolic-ref", "-q", "HEAD"}, "", errors.Wrap(fakeExitError{exitCode: 1}, 0)),
+			expectedDetached: true,
+		},
+		{
+			testName: "other failure",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"symbolic-ref", "-q", "HEAD"}, "", errors.New("fatal: not a git repository")),
+			expectedDetached: false,
+			expectedErr:      "fatal: not a git repository",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			instance := buildBranchCommands(commonDeps{runner: s.runner})
+			detached, err := instance.IsHeadDetached()
+			if s.expectedErr != "" {
+				assert.EqualError(t, err, s.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, s.expectedDetached, detached)
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
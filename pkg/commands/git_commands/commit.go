@@ -29,26 +29,32 @@ func (self *CommitCommands) ResetAuthor() error {
 	return self.cmd.New(cmdArgs).Run()
 }
 
-// Sets the commit's author to the supplied value. Value is expected to be of the form 'Name <Email>'
-func (self *CommitCommands) SetAuthor(value string) error {
-	cmdArgs := NewGitCmd("commit").
-		Arg("--allow-empty", "--only", "--no-edit", "--amend", "--author="+value).
-		ToArgv()
-
-	return self.cmd.New(cmdArgs).Run()
-}
-
-// Add a commit's coauthor using Github/Gitlab Co-authored-by metadata. Value is expected to be of the form 'Name <Email>'
-func (self *CommitCommands) AddCoAuthor(sha string, value string) error {
-	message, err := self.GetCommitMessage(sha)
+// ResetAuthorToCommitter makes the current git user the author of the topmost
+// commit, e.g. to claim a commit that was cherry-picked from someone else.
+// Unlike ResetAuthor, this keeps the author date.
+func (self *CommitCommands) ResetAuthorToCommitter() error {
+	// We don't take the committer of HEAD, because that's whoever made the
+	// commit, which isn't necessarily the current user (e.g. at an edit stop
+	// that git didn't need to rebuild the commit for)
+	cmdArgs := NewGitCmd("var").Arg("GIT_COMMITTER_IDENT").ToArgv()
+	ident, err := self.cmd.New(cmdArgs).DontLog().RunWithOutput()
 	if err != nil {
 		return err
 	}
 
-	message = message + fmt.Sprintf("\nCo-authored-by: %s", value)
+	// The ident ends with the timestamp and timezone, after the email
+	end := strings.LastIndex(ident, ">")
+	if end == -1 {
+		return errors.Errorf("unexpected committer ident: %s", strings.TrimSpace(ident))
+	}
+
+	return self.SetAuthor(ident[:end+1])
+}
 
+// Sets the commit's author to the supplied value. Value is expected to be of the form 'Name <Email>'
+func (self *CommitCommands) SetAuthor(value string) error {
 	cmdArgs := NewGitCmd("commit").
-		Arg("--allow-empty", "--amend", "--only", "-m", message).
+		Arg("--allow-empty", "--only", "--no-edit", "--amend", "--author="+value).
 		ToArgv()
 
 	return self.cmd.New(cmdArgs).Run()
@@ -81,12 +87,14 @@ func (self *CommitCommands) CommitCmdObj(summary string, description string) osc
 }
 
 func (self *CommitCommands) RewordLastCommitInEditorCmdObj() oscommands.ICmdObj {
-	return self.cmd.New(NewGitCmd("commit").Arg("--allow-empty", "--amend", "--only").ToArgv())
+	return self.cmd.New(NewGitCmd("commit").Arg("--allow-empty", "--amend", "--only").
+		ArgIf(self.config.GetCommitVerbose(), "--verbose").ToArgv())
 }
 
 func (self *CommitCommands) RewordLastCommitInEditorWithMessageFileCmdObj(tmpMessageFile string) oscommands.ICmdObj {
 	return self.cmd.New(NewGitCmd("commit").
-		Arg("--allow-empty", "--amend", "--only", "--edit", "--file="+tmpMessageFile).ToArgv())
+		Arg("--allow-empty", "--amend", "--only", "--edit", "--file="+tmpMessageFile).
+		ArgIf(self.config.GetCommitVerbose(), "--verbose").ToArgv())
 }
 
 func (self *CommitCommands) CommitInEditorWithMessageFileCmdObj(tmpMessageFile string) oscommands.ICmdObj {
@@ -99,14 +107,35 @@ func (self *CommitCommands) CommitInEditorWithMessageFileCmdObj(tmpMessageFile s
 
 // RewordLastCommit rewords the topmost commit with the given message
 func (self *CommitCommands) RewordLastCommit(summary string, description string) error {
-	messageArgs := self.commitMessageArgs(summary, description)
+	return self.amendMessageCmdObj(self.commitMessageArgs(summary, description), "").Run()
+}
 
+// RewordHeadNonInteractive replaces the message of HEAD with the given one,
+// for when the final message is already known, e.g. because the user typed it
+// into lazygit's commit message panel. The message may span several lines.
+func (self *CommitCommands) RewordHeadNonInteractive(message string) error {
+	return self.amendMessageCmdObj([]string{"-m", message}, "").Run()
+}
+
+// Like RewordLastCommit and RewordHeadNonInteractive, but for rewording the
+// commit that a rebase is stopped at
+func (self *CommitCommands) rewordLastCommitInRebase(summary string, description string) error {
+	return self.amendMessageCmdObj(self.commitMessageArgs(summary, description), self.rebaseSignoffFlag()).Run()
+}
+
+// Since the message is given on the command line, git has no reason to open
+// an editor; we disable it anyway so that e.g. a hook or a wrapper script
+// passing --edit can't leave lazygit waiting on one
+func (self *CommitCommands) amendMessageCmdObj(messageArgs []string, signoffFlag string) oscommands.ICmdObj {
+	cleanup := self.UserConfig.Git.Commit.RewordCleanup
 	cmdArgs := NewGitCmd("commit").
 		Arg("--allow-empty", "--amend", "--only").
+		ArgIf(cleanup != "", "--cleanup="+cleanup).
+		ArgIf(signoffFlag != "", signoffFlag).
 		Arg(messageArgs...).
 		ToArgv()
 
-	return self.cmd.New(cmdArgs).Run()
+	return self.cmd.New(cmdArgs).AddEnvVars("GIT_EDITOR=true")
 }
 
 func (self *CommitCommands) commitMessageArgs(summary string, description string) []string {
@@ -136,13 +165,91 @@ func (self *CommitCommands) signoffFlag() string {
 	}
 }
 
+// When lazygit amends a commit as a step of a rebase (e.g. to reword it), the
+// message may be replaced, which can lose an existing Signed-off-by trailer,
+// so with git.rebase.signOff we ask git to add it back. Git doesn't add the
+// trailer if the message already ends with it. Amends outside of rebases are
+// left alone.
+func (self *CommitCommands) rebaseSignoffFlag() string {
+	if self.UserConfig.Git.Rebase.SignOff {
+		return "--signoff"
+	} else {
+		return ""
+	}
+}
+
 func (self *CommitCommands) GetCommitMessage(commitSha string) (string, error) {
 	cmdArgs := NewGitCmd("log").
 		Arg("--format=%B", "--max-count=1", commitSha).
 		ToArgv()
 
 	message, err := self.cmd.New(cmdArgs).DontLog().RunWithOutput()
-	return strings.TrimSpace(message), err
+	return normalizeCommitMessage(message), err
+}
+
+// git log appends a trailing newline to each message (and %B keeps the
+// message's own trailing newline too), and messages written on Windows may
+// contain CRLF line endings. We want the message exactly as the user would
+// edit it, so that multi-paragraph bodies survive a round trip through our
+// editor unchanged.
+func normalizeCommitMessage(message string) string {
+	message = strings.ReplaceAll(message, "\r\n", "\n")
+	return strings.TrimSpace(message)
+}
+
+// GetCommitMessages returns the full messages of the given commits, keyed by
+// sha, using a single git call regardless of how many shas are passed.
+func (self *CommitCommands) GetCommitMessages(shas []string) (map[string]string, error) {
+	if len(shas) == 0 {
+		return map[string]string{}, nil
+	}
+
+	cmdArgs := NewGitCmd("show").
+		Arg("-s", "--format=%H%x00%B").
+		Arg(shas...).
+		ToArgv()
+
+	output, err := self.cmd.New(cmdArgs).DontLog().RunWithOutput()
+	if err != nil {
+		return nil, err
+	}
+
+	return parseCommitMessages(output)
+}
+
+// The output of `git show -s --format=%H%x00%B` looks like this, where each
+// message is followed by a newline and then the next commit's sha:
+//
+//	<sha1>\x00<message1>\n<sha2>\x00<message2>\n
+//
+// Messages can contain blank lines, so we can't split on lines; instead we
+// split on the null bytes and peel the next sha off the end of each message.
+func parseCommitMessages(output string) (map[string]string, error) {
+	result := map[string]string{}
+
+	parts := strings.Split(output, "\x00")
+	if len(parts) < 2 {
+		return result, nil
+	}
+
+	sha := strings.TrimSpace(parts[0])
+	for i := 1; i < len(parts); i++ {
+		message := parts[i]
+		nextSha := ""
+		if i < len(parts)-1 {
+			idx := strings.LastIndex(message, "\n")
+			if idx == -1 {
+				return nil, errors.New("unexpected git output")
+			}
+			nextSha = strings.TrimSpace(message[idx+1:])
+			message = message[:idx]
+		}
+
+		result[sha] = normalizeCommitMessage(message)
+		sha = nextSha
+	}
+
+	return result, nil
 }
 
 func (self *CommitCommands) GetCommitSubject(commitSha string) (string, error) {
@@ -227,8 +334,18 @@ func (self *CommitCommands) AmendHead() error {
 }
 
 func (self *CommitCommands) AmendHeadCmdObj() oscommands.ICmdObj {
+	return self.amendHeadCmdObj("")
+}
+
+// Like AmendHead, but for amending the commit that a rebase is stopped at
+func (self *CommitCommands) amendHeadInRebase() error {
+	return self.amendHeadCmdObj(self.rebaseSignoffFlag()).Run()
+}
+
+func (self *CommitCommands) amendHeadCmdObj(signoffFlag string) oscommands.ICmdObj {
 	cmdArgs := NewGitCmd("commit").
 		Arg("--amend", "--no-edit", "--allow-empty").
+		ArgIf(signoffFlag != "", signoffFlag).
 		ToArgv()
 
 	return self.cmd.New(cmdArgs)
@@ -276,6 +393,28 @@ func (self *CommitCommands) CreateFixupCommit(sha string) error {
 	return self.cmd.New(cmdArgs).Run()
 }
 
+// CreateRewordFixup creates an empty "amend!" commit that, when autosquashed,
+// replaces the message of the given commit with the given one but leaves its
+// content alone, like `git commit --fixup=reword:<sha>` does. That command
+// insists on opening an editor, so we build the commit ourselves. Any staged
+// changes stay staged.
+func (self *CommitCommands) CreateRewordFixup(targetSha string, message string) error {
+	if !self.version.IsAtLeast(2, 32, 0) {
+		return errors.New(self.Tr.RewordFixupNeedsNewerGit)
+	}
+
+	subject, err := self.GetCommitSubject(targetSha)
+	if err != nil {
+		return err
+	}
+
+	cmdArgs := NewGitCmd("commit").
+		Arg("--allow-empty", "--only", "-m", "amend! "+subject, "-m", message).
+		ToArgv()
+
+	return self.cmd.New(cmdArgs).Run()
+}
+
 // a value of 0 means the head commit, 1 is the parent commit, etc
 func (self *CommitCommands) GetCommitMessageFromHistory(value int) (string, error) {
 	cmdArgs := NewGitCmd("log").Arg("-1", fmt.Sprintf("--skip=%d", value), "--pretty=%H").
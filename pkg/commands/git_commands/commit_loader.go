@@ -92,12 +92,14 @@ func (self *CommitLoader) GetCommits(opts GetCommitsOptions) ([]*models.Commit,
 	wg.Add(2)
 
 	var logErr error
+	logCount := 0
 	go utils.Safe(func() {
 		defer wg.Done()
 
 		logErr = self.getLogCmd(opts).RunAndProcessLines(func(line string) (bool, error) {
 			commit := self.extractCommitFromLine(line, opts.RefToShowDivergenceFrom != "")
 			commits = append(commits, commit)
+			logCount++
 			return false, nil
 		})
 	})
@@ -144,6 +146,12 @@ func (self *CommitLoader) GetCommits(opts GetCommitsOptions) ([]*models.Commit,
 		return commits, nil
 	}
 
+	// If git stopped at the limit, there are most likely more commits beyond
+	// it, so we can't assume that the oldest one we have is the initial commit
+	if self.limitsLog(opts) && logCount >= self.UserConfig.Git.Log.CommitLimit {
+		commits[len(commits)-1].Truncated = true
+	}
+
 	if opts.RefToShowDivergenceFrom != "" {
 		sort.SliceStable(commits, func(i, j int) bool {
 			// In the divergence view we want incoming commits to come first
@@ -648,6 +656,11 @@ func (self *CommitLoader) getFirstPushedCommit(refName string) (string, error) {
 	return ignoringWarnings(output), nil
 }
 
+// limitsLog tells whether we only load the first git.log.commitLimit commits
+func (self *CommitLoader) limitsLog(opts GetCommitsOptions) bool {
+	return opts.Limit && self.UserConfig.Git.Log.CommitLimit > 0
+}
+
 // getLog gets the git log.
 func (self *CommitLoader) getLogCmd(opts GetCommitsOptions) oscommands.ICmdObj {
 	config := self.UserConfig.Git.Log
@@ -664,7 +677,7 @@ func (self *CommitLoader) getLogCmd(opts GetCommitsOptions) oscommands.ICmdObj {
 		Arg("--oneline").
 		Arg(prettyFormat).
 		Arg("--abbrev=40").
-		ArgIf(opts.Limit, "-300").
+		ArgIf(self.limitsLog(opts), fmt.Sprintf("-%d", config.CommitLimit)).
 		ArgIf(opts.FilterPath != "", "--follow").
 		Arg("--no-show-signature").
 		ArgIf(opts.RefToShowDivergenceFrom != "", "--left-right").
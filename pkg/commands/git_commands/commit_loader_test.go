@@ -35,6 +35,7 @@ func TestGetCommits(t *testing.T) {
 		rebaseMode      enums.RebaseMode
 		opts            GetCommitsOptions
 		mainBranches    []string
+		commitLimit     int
 	}
 
 	scenarios := []scenario{
@@ -287,6 +288,35 @@ func TestGetCommits(t *testing.T) {
 			expectedCommits: []*models.Commit{},
 			expectedError:   nil,
 		},
+		{
+			testName:    "should mark the oldest commit if the log was cut short by the commit limit",
+			logOrder:    "default",
+			rebaseMode:  enums.REBASE_MODE_NONE,
+			opts:        GetCommitsOptions{RefName: "HEAD", RefForPushedStatus: "mybranch", Limit: true},
+			commitLimit: 1,
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"merge-base", "mybranch", "mybranch@{u}"}, "b21997d6b4cbdf84b149d8e6a2c4d06a8e9ec164", nil).
+				ExpectGitArgs([]string{"log", "HEAD", "--oneline", "--pretty=format:%H%x00%at%x00%aN%x00%ae%x00%D%x00%p%x00%s%x00%m", "--abbrev=40", "-1", "--no-show-signature", "--"}, singleCommitOutput, nil),
+
+			expectedCommits: []*models.Commit{
+				{
+					Sha:           "0eea75e8c631fba6b58135697835d58ba4c18dbc",
+					Name:          "better typing for rebase mode",
+					Status:        models.StatusUnpushed,
+					Action:        models.ActionNone,
+					Tags:          []string{},
+					ExtraInfo:     "(HEAD -> better-tests)",
+					AuthorName:    "Jesse Duffield",
+					AuthorEmail:   "jessedduffield@gmail.com",
+					UnixTimestamp: 1640826609,
+					Parents: []string{
+						"b21997d6b4cbdf84b149",
+					},
+					Truncated: true,
+				},
+			},
+			expectedError: nil,
+		},
 		{
 			testName:   "should set filter path",
 			logOrder:   "default",
@@ -306,6 +336,9 @@ func TestGetCommits(t *testing.T) {
 		t.Run(scenario.testName, func(t *testing.T) {
 			common := utils.NewDummyCommon()
 			common.UserConfig.Git.Log.Order = scenario.logOrder
+			if scenario.commitLimit != 0 {
+				common.UserConfig.Git.Log.CommitLimit = scenario.commitLimit
+			}
 
 			builder := &CommitLoader{
 				Common:        common,
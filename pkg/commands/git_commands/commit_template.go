@@ -0,0 +1,97 @@
+package git_commands
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/go-errors/errors"
+	"github.com/jesseduffield/lazygit/pkg/commands/models"
+)
+
+// Matches the placeholders of a commit message template, e.g. the {type} and
+// {subject} in "{type}({scope}): {subject}"
+var commitTemplatePlaceholderRegexp = regexp.MustCompile(`\{([A-Za-z0-9_-]+)\}`)
+
+// RewordCommitFromTemplate rewords the commit at the given index with the
+// message that the template renders to, given values for its placeholders.
+// A single-line template only replaces the subject, so the commit keeps its
+// body; a template with more lines replaces the whole message.
+func (self *RebaseCommands) RewordCommitFromTemplate(
+	commits []*models.Commit, index int, template string, values map[string]string,
+) error {
+	if index < 0 || index >= len(commits) {
+		return errors.New("index outside of range of commits")
+	}
+
+	rendered, err := renderCommitTemplate(template, values)
+	if err != nil {
+		return err
+	}
+
+	summary, description, isMultiLine := strings.Cut(rendered, "\n")
+	if !isMultiLine {
+		message, err := self.commit.GetCommitMessage(commits[index].Sha)
+		if err != nil {
+			return err
+		}
+		_, description, _ = strings.Cut(message, "\n")
+	}
+
+	return self.RewordCommit(commits, index, summary, strings.TrimSpace(description))
+}
+
+// Fills in the template's placeholders. It's an error for the template to use
+// a placeholder that there's no value for, rather than leaving it in the
+// message as it is.
+func renderCommitTemplate(template string, values map[string]string) (string, error) {
+	var missing []string
+	rendered := commitTemplatePlaceholderRegexp.ReplaceAllStringFunc(template, func(placeholder string) string {
+		name := placeholder[1 : len(placeholder)-1]
+		value, ok := values[name]
+		if !ok {
+			missing = append(missing, placeholder)
+		}
+		return value
+	})
+
+	if len(missing) > 0 {
+		return "", errors.Errorf("No value for %s in the commit message template", strings.Join(missing, ", "))
+	}
+
+	return rendered, nil
+}
+
+// CommitTemplateValues makes a best-effort guess at the placeholder values
+// that would render the template's first line to the subject of the given
+// message, so that they can be offered as defaults when rewording an existing
+// commit. Returns an empty map if the subject doesn't fit the template.
+func CommitTemplateValues(template string, message string) map[string]string {
+	templateSubject, _, _ := strings.Cut(template, "\n")
+	subject, _, _ := strings.Cut(message, "\n")
+
+	var pattern strings.Builder
+	var names []string
+	pattern.WriteString("^")
+	lastEnd := 0
+	for _, loc := range commitTemplatePlaceholderRegexp.FindAllStringSubmatchIndex(templateSubject, -1) {
+		pattern.WriteString(regexp.QuoteMeta(templateSubject[lastEnd:loc[0]]))
+		pattern.WriteString("(.*?)")
+		names = append(names, templateSubject[loc[2]:loc[3]])
+		lastEnd = loc[1]
+	}
+	pattern.WriteString(regexp.QuoteMeta(templateSubject[lastEnd:]))
+	pattern.WriteString("$")
+
+	values := map[string]string{}
+	match := regexp.MustCompile(pattern.String()).FindStringSubmatch(subject)
+	if match == nil {
+		return values
+	}
+	for i, name := range names {
+		// a placeholder used twice keeps the value of its first use
+		if _, ok := values[name]; !ok {
+			values[name] = match[i+1]
+		}
+	}
+	return values
+}
@@ -0,0 +1,115 @@
+package git_commands
+
+import (
+	"testing"
+
+	"github.com/jesseduffield/lazygit/pkg/commands/models"
+	"github.com/jesseduffield/lazygit/pkg/commands/oscommands"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderCommitTemplate(t *testing.T) {
+	scenarios := []struct {
+		testName    string
+		template    string
+		values      map[string]string
+		expected    string
+		expectedErr string
+	}{
+		{
+			testName: "all placeholders filled",
+			template: "{type}({scope}): {subject}",
+			values:   map[string]string{"type": "fix", "scope": "rebase", "subject": "keep dates"},
+			expected: "fix(rebase): keep dates",
+		},
+		{
+			testName: "placeholder used twice",
+			template: "{type}: {subject}\n\nThis is a {type}",
+			values:   map[string]string{"type": "fix", "subject": "keep dates"},
+			expected: "fix: keep dates\n\nThis is a fix",
+		},
+		{
+			testName: "empty value",
+			template: "{type}{scope}: {subject}",
+			values:   map[string]string{"type": "fix", "scope": "", "subject": "keep dates"},
+			expected: "fix: keep dates",
+		},
+		{
+			testName:    "missing placeholders",
+			template:    "{type}({scope}): {subject}",
+			values:      map[string]string{"type": "fix"},
+			expectedErr: "No value for {scope}, {subject} in the commit message template",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			rendered, err := renderCommitTemplate(s.template, s.values)
+			if s.expectedErr != "" {
+				assert.EqualError(t, err, s.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, s.expected, rendered)
+		})
+	}
+}
+
+func TestCommitTemplateValues(t *testing.T) {
+	template := "{type}({scope}): {subject}"
+
+	assert.Equal(t,
+		map[string]string{"type": "fix", "scope": "rebase", "subject": "keep dates: all of them"},
+		CommitTemplateValues(template, "fix(rebase): keep dates: all of them\n\nSome details"))
+	assert.Equal(t, map[string]string{}, CommitTemplateValues(template, "Keep dates"))
+}
+
+func TestRebaseRewordCommitFromTemplate(t *testing.T) {
+	commits := []*models.Commit{{Name: "keep dates", Sha: "123456"}}
+
+	type scenario struct {
+		testName    string
+		template    string
+		runner      *oscommands.FakeCmdObjRunner
+		expectedErr string
+	}
+
+	scenarios := []scenario{
+		{
+			testName: "subject template keeps the body",
+			template: "{type}({scope}): {subject}",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"log", "--format=%B", "--max-count=1", "123456"}, "keep dates\n\nSome details\n", nil).
+				ExpectGitArgs([]string{"commit", "--allow-empty", "--amend", "--only", "--cleanup=whitespace", "-m", "fix(rebase): keep dates", "-m", "Some details"}, "", nil),
+		},
+		{
+			testName: "multi-line template replaces the whole message",
+			template: "{type}({scope}): {subject}\n\nRefs: #123",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"commit", "--allow-empty", "--amend", "--only", "--cleanup=whitespace", "-m", "fix(rebase): keep dates", "-m", "Refs: #123"}, "", nil),
+		},
+		{
+			testName:    "missing placeholder",
+			template:    "{type}({scope}): {subject} {ticket}",
+			runner:      oscommands.NewFakeRunner(t),
+			expectedErr: "No value for {ticket} in the commit message template",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			instance := buildRebaseCommands(commonDeps{runner: s.runner})
+
+			err := instance.RewordCommitFromTemplate(commits, 0, s.template,
+				map[string]string{"type": "fix", "scope": "rebase", "subject": "keep dates"})
+			if s.expectedErr != "" {
+				assert.EqualError(t, err, s.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
@@ -3,43 +3,147 @@ package git_commands
 import (
 	"testing"
 
+	"github.com/jesseduffield/lazygit/pkg/commands/git_config"
 	"github.com/jesseduffield/lazygit/pkg/commands/oscommands"
 	"github.com/jesseduffield/lazygit/pkg/config"
+	"github.com/samber/lo"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestCommitRewordCommit(t *testing.T) {
 	type scenario struct {
-		testName    string
-		runner      *oscommands.FakeCmdObjRunner
-		summary     string
-		description string
+		testName      string
+		runner        *oscommands.FakeCmdObjRunner
+		summary       string
+		description   string
+		rebaseSignOff bool
+		inRebase      bool
 	}
 	scenarios := []scenario{
 		{
 			"Single line reword",
-			oscommands.NewFakeRunner(t).ExpectGitArgs([]string{"commit", "--allow-empty", "--amend", "--only", "-m", "test"}, "", nil),
+			oscommands.NewFakeRunner(t).ExpectGitArgs([]string{"commit", "--allow-empty", "--amend", "--only", "--cleanup=whitespace", "-m", "test"}, "", nil),
 			"test",
 			"",
+			false,
+			false,
 		},
 		{
 			"Multi line reword",
-			oscommands.NewFakeRunner(t).ExpectGitArgs([]string{"commit", "--allow-empty", "--amend", "--only", "-m", "test", "-m", "line 2\nline 3"}, "", nil),
+			oscommands.NewFakeRunner(t).ExpectGitArgs([]string{"commit", "--allow-empty", "--amend", "--only", "--cleanup=whitespace", "-m", "test", "-m", "line 2\nline 3"}, "", nil),
 			"test",
 			"line 2\nline 3",
+			false,
+			false,
+		},
+		{
+			"Reword with signoff",
+			oscommands.NewFakeRunner(t).ExpectGitArgs([]string{"commit", "--allow-empty", "--amend", "--only", "--cleanup=whitespace", "--signoff", "-m", "test"}, "", nil),
+			"test",
+			"",
+			true,
+			true,
+		},
+		{
+			"Reword outside of a rebase ignores the rebase signoff",
+			oscommands.NewFakeRunner(t).ExpectGitArgs([]string{"commit", "--allow-empty", "--amend", "--only", "--cleanup=whitespace", "-m", "test"}, "", nil),
+			"test",
+			"",
+			true,
+			false,
 		},
 	}
 	for _, s := range scenarios {
 		s := s
 		t.Run(s.testName, func(t *testing.T) {
-			instance := buildCommitCommands(commonDeps{runner: s.runner})
-
-			assert.NoError(t, instance.RewordLastCommit(s.summary, s.description))
+			userConfig := config.GetDefaultConfig()
+			userConfig.Git.Rebase.SignOff = s.rebaseSignOff
+			instance := buildCommitCommands(commonDeps{runner: s.runner, userConfig: userConfig})
+
+			if s.inRebase {
+				assert.NoError(t, instance.rewordLastCommitInRebase(s.summary, s.description))
+			} else {
+				assert.NoError(t, instance.RewordLastCommit(s.summary, s.description))
+			}
 			s.runner.CheckForMissingCalls()
 		})
 	}
 }
 
+func TestCommitRewordCleanup(t *testing.T) {
+	message := "Subject\n\n# On branch master\nbody"
+
+	scenarios := []struct {
+		testName string
+		cleanup  string
+		expected string
+	}{
+		{
+			testName: "strip removes comment lines",
+			cleanup:  "strip",
+			expected: "--cleanup=strip",
+		},
+		{
+			testName: "verbatim keeps comment lines",
+			cleanup:  "verbatim",
+			expected: "--cleanup=verbatim",
+		},
+		{
+			testName: "whitespace",
+			cleanup:  "whitespace",
+			expected: "--cleanup=whitespace",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			userConfig := config.GetDefaultConfig()
+			userConfig.Git.Commit.RewordCleanup = s.cleanup
+			runner := oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"commit", "--allow-empty", "--amend", "--only", s.expected, "-m", message}, "", nil)
+			instance := buildCommitCommands(commonDeps{runner: runner, userConfig: userConfig})
+
+			assert.NoError(t, instance.RewordHeadNonInteractive(message))
+			runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestCommitRewordHeadNonInteractive(t *testing.T) {
+	scenarios := []struct {
+		testName     string
+		message      string
+		expectedArgs []string
+	}{
+		{
+			testName:     "single line",
+			message:      "test",
+			expectedArgs: []string{"git", "commit", "--allow-empty", "--amend", "--only", "--cleanup=whitespace", "-m", "test"},
+		},
+		{
+			testName:     "multiple lines are passed as a single message",
+			message:      "test\n\nline 2\nline 3",
+			expectedArgs: []string{"git", "commit", "--allow-empty", "--amend", "--only", "--cleanup=whitespace", "-m", "test\n\nline 2\nline 3"},
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			runner := oscommands.NewFakeRunner(t).
+				ExpectFunc("amend without editor", func(cmdObj oscommands.ICmdObj) bool {
+					return assert.ObjectsAreEqual(s.expectedArgs, cmdObj.Args()) &&
+						lo.Contains(cmdObj.GetEnvVars(), "GIT_EDITOR=true")
+				}, "", nil)
+			instance := buildCommitCommands(commonDeps{runner: runner})
+
+			assert.NoError(t, instance.RewordHeadNonInteractive(s.message))
+			runner.CheckForMissingCalls()
+		})
+	}
+}
+
 func TestCommitResetToCommit(t *testing.T) {
 	runner := oscommands.NewFakeRunner(t).
 		ExpectGitArgs([]string{"reset", "--hard", "78976bc"}, "", nil)
@@ -150,6 +254,89 @@ func TestCommitCommitEditorCmdObj(t *testing.T) {
 	}
 }
 
+func TestCommitRewordLastCommitInEditorWithMessageFileCmdObj(t *testing.T) {
+	type scenario struct {
+		testName      string
+		commitVerbose string
+		expected      []string
+	}
+
+	scenarios := []scenario{
+		{
+			testName:      "commit.verbose not set",
+			commitVerbose: "",
+			expected:      []string{"commit", "--allow-empty", "--amend", "--only", "--edit", "--file=msg.txt"},
+		},
+		{
+			testName:      "commit.verbose off",
+			commitVerbose: "false",
+			expected:      []string{"commit", "--allow-empty", "--amend", "--only", "--edit", "--file=msg.txt"},
+		},
+		{
+			testName:      "commit.verbose on",
+			commitVerbose: "true",
+			expected:      []string{"commit", "--allow-empty", "--amend", "--only", "--edit", "--file=msg.txt", "--verbose"},
+		},
+		{
+			testName:      "commit.verbose set to a verbosity level",
+			commitVerbose: "2",
+			expected:      []string{"commit", "--allow-empty", "--amend", "--only", "--edit", "--file=msg.txt", "--verbose"},
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			gitConfig := git_config.NewFakeGitConfig(map[string]string{"commit.verbose": s.commitVerbose})
+			runner := oscommands.NewFakeRunner(t).ExpectGitArgs(s.expected, "", nil)
+			instance := buildCommitCommands(commonDeps{gitConfig: gitConfig, runner: runner})
+
+			assert.NoError(t, instance.RewordLastCommitInEditorWithMessageFileCmdObj("msg.txt").Run())
+			runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestCommitResetAuthorToCommitter(t *testing.T) {
+	type scenario struct {
+		testName    string
+		runner      *oscommands.FakeCmdObjRunner
+		expectedErr string
+	}
+
+	scenarios := []scenario{
+		{
+			// The committer of HEAD may be someone else, e.g. a bot that
+			// cherry-picked the commit, so the current user is asked for
+			testName: "current user becomes the author",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"var", "GIT_COMMITTER_IDENT"}, "CI <CI@example.com> 1700000000 +0000\n", nil).
+				ExpectGitArgs([]string{"commit", "--allow-empty", "--only", "--no-edit", "--amend", "--author=CI <CI@example.com>"}, "", nil),
+		},
+		{
+			testName: "ident without an email",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"var", "GIT_COMMITTER_IDENT"}, "CI 1700000000 +0000\n", nil),
+			expectedErr: "unexpected committer ident: CI 1700000000 +0000",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			instance := buildCommitCommands(commonDeps{runner: s.runner})
+
+			err := instance.ResetAuthorToCommitter()
+			if s.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, s.expectedErr)
+			}
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
+
 func TestCommitCreateFixupCommit(t *testing.T) {
 	type scenario struct {
 		testName string
@@ -180,6 +367,45 @@ func TestCommitCreateFixupCommit(t *testing.T) {
 	}
 }
 
+func TestCommitCreateRewordFixup(t *testing.T) {
+	type scenario struct {
+		testName    string
+		gitVersion  *GitVersion
+		runner      *oscommands.FakeCmdObjRunner
+		expectedErr string
+	}
+
+	scenarios := []scenario{
+		{
+			testName:   "amend! commit is created",
+			gitVersion: &GitVersion{2, 32, 0, ""},
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"log", "--format=%s", "--max-count=1", "12345"}, "Old subject\n", nil).
+				ExpectGitArgs([]string{"commit", "--allow-empty", "--only", "-m", "amend! Old subject", "-m", "New subject\n\nNew body"}, "", nil),
+		},
+		{
+			testName:    "git is too old",
+			gitVersion:  &GitVersion{2, 31, 0, ""},
+			runner:      oscommands.NewFakeRunner(t),
+			expectedErr: "Creating an amend! commit requires git 2.32 or later, as older versions don't squash it when autosquashing",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			instance := buildCommitCommands(commonDeps{runner: s.runner, gitVersion: s.gitVersion})
+			err := instance.CreateRewordFixup("12345", "New subject\n\nNew body")
+			if s.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, s.expectedErr)
+			}
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
+
 func TestCommitShowCmdObj(t *testing.T) {
 	type scenario struct {
 		testName         string
@@ -277,6 +503,16 @@ func TestGetCommitMsg(t *testing.T) {
 
 'git-rev parse' should be 'git rev-parse'`,
 		},
+		{
+			"multiple paragraphs with trailing newlines",
+			"subject\n\nfirst paragraph\n\nsecond paragraph\n\n",
+			"subject\n\nfirst paragraph\n\nsecond paragraph",
+		},
+		{
+			"crlf line endings",
+			"subject\r\n\r\nbody\r\n",
+			"subject\n\nbody",
+		},
 	}
 
 	for _, s := range scenarios {
@@ -330,3 +566,54 @@ func TestGetCommitMessageFromHistory(t *testing.T) {
 		})
 	}
 }
+
+func TestGetCommitMessages(t *testing.T) {
+	type scenario struct {
+		testName       string
+		shas           []string
+		runner         *oscommands.FakeCmdObjRunner
+		expectedOutput map[string]string
+	}
+	scenarios := []scenario{
+		{
+			testName:       "no shas",
+			shas:           []string{},
+			runner:         oscommands.NewFakeRunner(t),
+			expectedOutput: map[string]string{},
+		},
+		{
+			testName: "single commit",
+			shas:     []string{"abc"},
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"show", "-s", "--format=%H%x00%B", "abc"}, "abc\x00subject\n\n", nil),
+			expectedOutput: map[string]string{"abc": "subject"},
+		},
+		{
+			testName: "multiple commits with blank lines and unicode in the body",
+			shas:     []string{"abc", "def", "123"},
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"show", "-s", "--format=%H%x00%B", "abc", "def", "123"},
+					"abc\x00first subject\n\nfirst body\n\nwith a second paragraph\n\n"+
+						"def\x00second subject 🚀\n\nbody with ünïcödé\n\n"+
+						"123\x00third subject\n\n", nil),
+			expectedOutput: map[string]string{
+				"abc": "first subject\n\nfirst body\n\nwith a second paragraph",
+				"def": "second subject 🚀\n\nbody with ünïcödé",
+				"123": "third subject",
+			},
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			instance := buildCommitCommands(commonDeps{runner: s.runner})
+
+			output, err := instance.GetCommitMessages(s.shas)
+
+			assert.NoError(t, err)
+			assert.Equal(t, s.expectedOutput, output)
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
@@ -108,6 +108,24 @@ func (self *ConfigCommands) GetCoreCommentChar() byte {
 	return '#'
 }
 
+// GetCommitVerbose tells us whether the user wants to see the diff of the
+// commit below its message when editing it. commit.verbose can also be a
+// number for the verbosity level, in which case anything above zero counts.
+func (self *ConfigCommands) GetCommitVerbose() bool {
+	if self.gitConfig.GetBool("commit.verbose") {
+		return true
+	}
+
+	level, err := strconv.Atoi(self.gitConfig.Get("commit.verbose"))
+	return err == nil && level > 0
+}
+
+// GetCoreHooksPath returns the directory that git runs hooks from if the user
+// has configured one, or "" for the hooks directory in the git dir
+func (self *ConfigCommands) GetCoreHooksPath() string {
+	return self.gitConfig.Get("core.hooksPath")
+}
+
 func (self *ConfigCommands) GetRebaseUpdateRefs() bool {
 	return self.gitConfig.GetBool("rebase.updateRefs")
 }
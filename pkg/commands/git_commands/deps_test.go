@@ -26,6 +26,7 @@ type commonDeps struct {
 	cmd        *oscommands.CmdObjBuilder
 	fs         afero.Fs
 	repoPaths  *RepoPaths
+	logCommand func(str string, commandLine bool)
 }
 
 func buildGitCommon(deps commonDeps) *GitCommon {
@@ -92,6 +93,7 @@ func buildGitCommon(deps commonDeps) *GitCommon {
 		Cmd:          cmd,
 		RemoveFileFn: removeFile,
 		TempDir:      os.TempDir(),
+		LogCommandFn: deps.logCommand,
 	})
 
 	return gitCommon
@@ -158,8 +160,10 @@ func buildRebaseCommands(deps commonDeps) *RebaseCommands {
 	gitCommon := buildGitCommon(deps)
 	workingTreeCommands := buildWorkingTreeCommands(deps)
 	commitCommands := buildCommitCommands(deps)
+	statusCommands := buildStatusCommands(deps)
+	branchCommands := buildBranchCommands(deps)
 
-	return NewRebaseCommands(gitCommon, commitCommands, workingTreeCommands)
+	return NewRebaseCommands(gitCommon, commitCommands, workingTreeCommands, statusCommands, branchCommands)
 }
 
 func buildSyncCommands(deps commonDeps) *SyncCommands {
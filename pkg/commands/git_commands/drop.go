@@ -0,0 +1,210 @@
+package git_commands
+
+import (
+	"strings"
+
+	"github.com/fsmiamoto/git-todo-parser/todo"
+	"github.com/go-errors/errors"
+	"github.com/jesseduffield/lazygit/pkg/app/daemon"
+	"github.com/jesseduffield/lazygit/pkg/commands/models"
+	"github.com/jesseduffield/lazygit/pkg/utils"
+	"github.com/samber/lo"
+)
+
+type DropCommitsOpts struct {
+	// Branches to delete if dropping the commits leaves them without any
+	// commits of their own, e.g. a branch in a stack whose only commit is
+	// dropped. Branches that aren't listed here are never deleted.
+	DeleteBranchesThatBecomeEmpty []string
+}
+
+// DropCommits drops the commits at the given indices in a single rebase, and
+// returns the names of the branches that were deleted because they became
+// empty.
+func (self *RebaseCommands) DropCommits(commits []*models.Commit, indices []int, opts DropCommitsOpts) ([]string, error) {
+	if len(indices) == 0 {
+		return nil, errors.New("no commits to drop")
+	}
+
+	baseIndex := 0
+	changes := make([]daemon.ChangeTodoAction, 0, len(indices))
+	for _, index := range indices {
+		if index < 0 || index >= len(commits) {
+			return nil, errors.New("index outside of range of commits")
+		}
+		if index > baseIndex {
+			baseIndex = index
+		}
+		changes = append(changes, daemon.ChangeTodoAction{
+			Sha:       commits[index].Sha,
+			NewAction: todo.Drop,
+		})
+	}
+
+	// We need to work this out before the rebase, while the commits still
+	// have the shas that we know them by
+	var branchesToDelete []string
+	if len(opts.DeleteBranchesThatBecomeEmpty) > 0 {
+		var err error
+		branchesToDelete, err = self.branchesThatBecomeEmpty(commits, indices, opts.DeleteBranchesThatBecomeEmpty)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	self.os.LogCommand(logTodoChanges(changes), false)
+
+	err := self.runInteractiveRebase(PrepareInteractiveRebaseCommandOpts{
+		baseShaOrRoot:  getBaseShaOrRoot(commits, baseIndex+1),
+		overrideEditor: true,
+		instruction:    daemon.NewChangeTodoActionsInstruction(changes),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, branchName := range branchesToDelete {
+		cmdArgs := NewGitCmd("branch").Arg("-D", branchName).ToArgv()
+		if err := self.cmd.New(cmdArgs).Run(); err != nil {
+			return nil, err
+		}
+	}
+
+	return branchesToDelete, nil
+}
+
+// ApplyKeepDropSelection drops, in a single rebase, every commit that keep
+// maps to false, and keeps (picks) the ones it maps to true. keep is keyed by
+// sha and must have an entry for every commit from HEAD down to the oldest
+// commit it mentions, so that a commit the user never got to choose about
+// isn't silently kept or dropped. The rebase only goes down as far as the
+// oldest dropped commit.
+func (self *RebaseCommands) ApplyKeepDropSelection(commits []*models.Commit, keep map[string]bool) error {
+	keep, err := self.resolveKeepDropShas(commits, keep)
+	if err != nil {
+		return err
+	}
+
+	oldestIndex := -1
+	for sha := range keep {
+		index := lo.IndexOf(lo.Map(commits, func(commit *models.Commit, _ int) string { return commit.Sha }), sha)
+		if index == -1 {
+			return errors.Errorf("commit %s is not in the list of commits", utils.ShortSha(sha))
+		}
+		if index > oldestIndex {
+			oldestIndex = index
+		}
+	}
+
+	baseIndex := -1
+	for index := 0; index <= oldestIndex; index++ {
+		kept, ok := keep[commits[index].Sha]
+		if !ok {
+			return errors.Errorf("no keep/drop choice for commit %s", commits[index].ShortSha())
+		}
+		if !kept {
+			baseIndex = index
+		}
+	}
+
+	if baseIndex == -1 {
+		return errors.New("no commits to drop")
+	}
+
+	changes := make([]daemon.ChangeTodoAction, 0, baseIndex+1)
+	for _, commit := range commits[:baseIndex+1] {
+		// Merges show up as merge lines rather than picks, which the rebase
+		// keeps as they are anyway
+		if keep[commit.Sha] && commit.IsMerge() {
+			continue
+		}
+		changes = append(changes, daemon.ChangeTodoAction{
+			Sha:       commit.Sha,
+			NewAction: lo.Ternary(keep[commit.Sha], todo.Pick, todo.Drop),
+		})
+	}
+	self.os.LogCommand(logTodoChanges(changes), false)
+
+	return self.runInteractiveRebase(PrepareInteractiveRebaseCommandOpts{
+		baseShaOrRoot:  getBaseShaOrRoot(commits, baseIndex+1),
+		overrideEditor: true,
+		instruction:    daemon.NewChangeTodoActionsInstruction(changes),
+	})
+}
+
+func (self *RebaseCommands) resolveKeepDropShas(commits []*models.Commit, keep map[string]bool) (map[string]bool, error) {
+	shas := lo.Keys(keep)
+	resolvedShas, err := self.resolveShas(commits, shas)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]bool, len(keep))
+	for i, sha := range shas {
+		result[resolvedShas[i]] = keep[sha]
+	}
+	return result, nil
+}
+
+// Returns those of the candidate branches all of whose own commits are about
+// to be dropped. A branch's own commits are the ones from its tip down to
+// (but not including) the next commit that another local branch points to.
+// The checked-out branch and branches whose tip isn't among the given commits
+// are never included.
+func (self *RebaseCommands) branchesThatBecomeEmpty(commits []*models.Commit, droppedIndices []int, candidates []string) ([]string, error) {
+	cmdArgs := NewGitCmd("for-each-ref").
+		Arg("--format=%(HEAD)%(objectname) %(refname:short)").
+		Arg("refs/heads").
+		ToArgv()
+	output, err := self.cmd.New(cmdArgs).DontLog().RunWithOutput()
+	if err != nil {
+		return nil, err
+	}
+
+	branchTips := map[string]string{}
+	currentBranch := ""
+	for _, line := range utils.SplitLines(output) {
+		isHead := strings.HasPrefix(line, "*")
+		sha, name, found := strings.Cut(line[1:], " ")
+		if !found {
+			continue
+		}
+		branchTips[name] = sha
+		if isHead {
+			currentBranch = name
+		}
+	}
+
+	result := []string{}
+	for _, candidate := range lo.Uniq(candidates) {
+		tip, ok := branchTips[candidate]
+		if !ok || candidate == currentBranch {
+			continue
+		}
+
+		tipIndex := lo.IndexOf(lo.Map(commits, func(c *models.Commit, _ int) string { return c.Sha }), tip)
+		if tipIndex == -1 {
+			continue
+		}
+
+		allDropped := true
+		for i := tipIndex; i < len(commits); i++ {
+			isOtherBranchTip := lo.SomeBy(lo.Entries(branchTips), func(e lo.Entry[string, string]) bool {
+				return e.Key != candidate && e.Value == commits[i].Sha
+			})
+			if i > tipIndex && isOtherBranchTip {
+				break
+			}
+			if !lo.Contains(droppedIndices, i) {
+				allDropped = false
+				break
+			}
+		}
+
+		if allDropped {
+			result = append(result, candidate)
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,186 @@
+package git_commands
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jesseduffield/lazygit/pkg/app/daemon"
+	"github.com/jesseduffield/lazygit/pkg/commands/models"
+	"github.com/jesseduffield/lazygit/pkg/commands/oscommands"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRebaseDropCommits(t *testing.T) {
+	// A stack of two branches on top of main, with stack2 checked out
+	commits := []*models.Commit{
+		{Name: "commit b2", Sha: "b2b2b2", Parents: []string{"b1b1b1"}},
+		{Name: "commit b1", Sha: "b1b1b1", Parents: []string{"aaaaaa"}},
+		{Name: "commit a", Sha: "aaaaaa"},
+	}
+	branches := "*b2b2b2 stack2\n b1b1b1 stack1\n aaaaaa main\n"
+
+	dropRebase := func(shas ...string) func(cmdObj oscommands.ICmdObj) bool {
+		return func(cmdObj oscommands.ICmdObj) bool {
+			changes := lo.Map(shas, func(sha string, _ int) string {
+				return `{"Sha":"` + sha + `","NewAction":13}`
+			})
+			return lo.Contains(cmdObj.Args(), "rebase") &&
+				lo.Contains(cmdObj.GetEnvVars(), daemon.DaemonInstructionEnvKey+
+					`={"Changes":[`+strings.Join(changes, ",")+`]}`)
+		}
+	}
+
+	type scenario struct {
+		testName        string
+		indices         []int
+		opts            DropCommitsOpts
+		runner          *oscommands.FakeCmdObjRunner
+		expectedDeleted []string
+		expectedErr     string
+	}
+
+	scenarios := []scenario{
+		{
+			testName: "no branches to delete",
+			indices:  []int{1},
+			opts:     DropCommitsOpts{},
+			runner: oscommands.NewFakeRunner(t).
+				ExpectFunc("drop rebase", dropRebase("b1b1b1"), "", nil),
+			expectedDeleted: nil,
+		},
+		{
+			testName: "dropping the only commit of a branch in the stack deletes it",
+			indices:  []int{1},
+			opts:     DropCommitsOpts{DeleteBranchesThatBecomeEmpty: []string{"stack1", "stack2"}},
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"for-each-ref", "--format=%(HEAD)%(objectname) %(refname:short)", "refs/heads"}, branches, nil).
+				ExpectFunc("drop rebase", dropRebase("b1b1b1"), "", nil).
+				ExpectGitArgs([]string{"branch", "-D", "stack1"}, "", nil),
+			expectedDeleted: []string{"stack1"},
+		},
+		{
+			testName: "the checked out branch is never deleted",
+			indices:  []int{0},
+			opts:     DropCommitsOpts{DeleteBranchesThatBecomeEmpty: []string{"stack2"}},
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"for-each-ref", "--format=%(HEAD)%(objectname) %(refname:short)", "refs/heads"}, branches, nil).
+				ExpectFunc("drop rebase", dropRebase("b2b2b2"), "", nil),
+			expectedDeleted: []string{},
+		},
+		{
+			testName: "branches that aren't passed in are never deleted",
+			indices:  []int{1},
+			opts:     DropCommitsOpts{DeleteBranchesThatBecomeEmpty: []string{"stack2"}},
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"for-each-ref", "--format=%(HEAD)%(objectname) %(refname:short)", "refs/heads"}, branches, nil).
+				ExpectFunc("drop rebase", dropRebase("b1b1b1"), "", nil),
+			expectedDeleted: []string{},
+		},
+		{
+			testName:    "no commits",
+			indices:     []int{},
+			runner:      oscommands.NewFakeRunner(t),
+			expectedErr: "no commits to drop",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			instance := buildRebaseCommands(commonDeps{runner: s.runner})
+
+			deleted, err := instance.DropCommits(commits, s.indices, s.opts)
+			if s.expectedErr == "" {
+				assert.NoError(t, err)
+				assert.Equal(t, s.expectedDeleted, deleted)
+			} else {
+				assert.EqualError(t, err, s.expectedErr)
+			}
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestRebaseApplyKeepDropSelection(t *testing.T) {
+	commits := []*models.Commit{
+		{Name: "commit5", Sha: "555555"},
+		{Name: "commit4", Sha: "444444"},
+		{Name: "commit3", Sha: "333333"},
+		{Name: "commit2", Sha: "222222"},
+		{Name: "commit1", Sha: "111111"},
+	}
+
+	keepDropRebase := func(baseSha string, changes string) func(cmdObj oscommands.ICmdObj) bool {
+		return func(cmdObj oscommands.ICmdObj) bool {
+			return cmdObj.Args()[len(cmdObj.Args())-1] == baseSha &&
+				lo.Contains(cmdObj.GetEnvVars(), daemon.DaemonInstructionEnvKey+`={"Changes":[`+changes+`]}`)
+		}
+	}
+
+	type scenario struct {
+		testName    string
+		keep        map[string]bool
+		runner      *oscommands.FakeCmdObjRunner
+		expectedErr string
+	}
+
+	scenarios := []scenario{
+		{
+			testName: "scattered subset is dropped in one rebase",
+			keep:     map[string]bool{"555555": true, "444444": false, "333333": true, "222222": false, "111111": true},
+			runner: oscommands.NewFakeRunner(t).
+				ExpectFunc("keep/drop rebase", keepDropRebase("111111",
+					`{"Sha":"555555","NewAction":1},{"Sha":"444444","NewAction":13},{"Sha":"333333","NewAction":1},{"Sha":"222222","NewAction":13}`,
+				), "", nil),
+		},
+		{
+			testName: "range only needs to reach the oldest commit in the selection",
+			keep:     map[string]bool{"555555": false, "444444": true},
+			runner: oscommands.NewFakeRunner(t).
+				ExpectFunc("keep/drop rebase", keepDropRebase("444444", `{"Sha":"555555","NewAction":13}`), "", nil),
+		},
+		{
+			testName:    "gap in the selection",
+			keep:        map[string]bool{"555555": true, "333333": false},
+			runner:      oscommands.NewFakeRunner(t),
+			expectedErr: "no keep/drop choice for commit 444444",
+		},
+		{
+			testName: "abbreviated shas are resolved",
+			keep:     map[string]bool{"5555": false, "HEAD~1": true},
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"rev-parse", "--verify", "5555^{commit}"}, "555555\n", nil).
+				ExpectGitArgs([]string{"rev-parse", "--verify", "HEAD~1^{commit}"}, "444444\n", nil).
+				ExpectFunc("keep/drop rebase", keepDropRebase("444444", `{"Sha":"555555","NewAction":13}`), "", nil),
+		},
+		{
+			testName: "unknown commit",
+			keep:     map[string]bool{"555555": true, "999999": false},
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"rev-parse", "--verify", "999999^{commit}"}, "999999\n", nil),
+			expectedErr: "commit 999999 is not in the list of commits",
+		},
+		{
+			testName:    "everything kept",
+			keep:        map[string]bool{"555555": true, "444444": true},
+			runner:      oscommands.NewFakeRunner(t),
+			expectedErr: "no commits to drop",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			instance := buildRebaseCommands(commonDeps{runner: s.runner})
+
+			err := instance.ApplyKeepDropSelection(commits, s.keep)
+			if s.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, s.expectedErr)
+			}
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
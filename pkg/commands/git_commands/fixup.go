@@ -0,0 +1,185 @@
+package git_commands
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/go-errors/errors"
+	"github.com/jesseduffield/lazygit/pkg/commands/models"
+	"github.com/jesseduffield/lazygit/pkg/utils"
+	"github.com/samber/lo"
+	"golang.org/x/exp/slices"
+)
+
+var abbreviatedShaRegexp = regexp.MustCompile(`^[0-9a-f]{4,40}$`)
+
+// Returned when a marker commit's subject matches the subject of more than
+// one earlier commit, so we can't tell which one it targets
+type AmbiguousFixupTargetError struct {
+	MarkerSha     string
+	CandidateShas []string
+}
+
+func (self *AmbiguousFixupTargetError) Error() string {
+	return fmt.Sprintf(
+		"fixup commit %s matches multiple commits: %s",
+		utils.ShortSha(self.MarkerSha),
+		strings.Join(lo.Map(self.CandidateShas, func(sha string, _ int) string {
+			return utils.ShortSha(sha)
+		}), ", "),
+	)
+}
+
+// A fixup!, squash! or amend! commit and the commit that autosquashing would
+// squash it into
+type AutosquashPair struct {
+	MarkerSha string
+	TargetSha string
+}
+
+// What autosquashPairs does when a marker's subject matches several commits
+type fixupAmbiguity int
+
+const (
+	failOnAmbiguousFixupTarget fixupAmbiguity = iota
+	pickOldestFixupTarget
+)
+
+// If the subject starts with one or more of the prefixes that the git version
+// understands (amend! needs git 2.32), returns what's left after stripping
+// them, along with any whitespace between them
+func (self *RebaseCommands) fixupMarkerTarget(subject string) (string, bool) {
+	prefixes := []string{"fixup! ", "squash! "}
+	if self.version.IsAtLeast(2, 32, 0) {
+		prefixes = append(prefixes, "amend! ")
+	}
+
+	isMarker := false
+	for {
+		prefix, found := lo.Find(prefixes, func(prefix string) bool {
+			return strings.HasPrefix(subject, prefix)
+		})
+		if !found {
+			return subject, isMarker
+		}
+		subject = strings.TrimLeft(strings.TrimPrefix(subject, prefix), " \t")
+		isMarker = true
+	}
+}
+
+// autosquashPairs works out which commit autosquashing would squash each
+// marker commit into. Commits are expected newest first, as in the commits
+// view, and so are the returned pairs. Like git's autosquash, we look up what's
+// left of the subject after the prefixes among the subjects of the older
+// commits that aren't squashed into anything themselves, then among the shas of
+// all the commits, and then among the starts of the older subjects. Where
+// several subjects match, the oldest one wins. Markers whose target isn't among the commits are left out.
+func (self *RebaseCommands) autosquashPairs(commits []*models.Commit, onAmbiguity fixupAmbiguity) ([]AutosquashPair, error) {
+	oldestFirst := lo.Reverse(slices.Clone(commits))
+	candidatesBySubject := map[string][]*models.Commit{}
+	pairs := []AutosquashPair{}
+
+	for i, commit := range oldestFirst {
+		var target *models.Commit
+		if rest, isMarker := self.fixupMarkerTarget(commit.Name); isMarker {
+			candidates := candidatesBySubject[rest]
+			if len(candidates) > 1 && onAmbiguity == failOnAmbiguousFixupTarget {
+				return nil, &AmbiguousFixupTargetError{
+					MarkerSha: commit.Sha,
+					CandidateShas: lo.Reverse(lo.Map(candidates, func(c *models.Commit, _ int) string {
+						return c.Sha
+					})),
+				}
+			}
+			if len(candidates) > 0 {
+				// git keeps the first commit it sees with a subject
+				target = candidates[0]
+			}
+
+			if target == nil && abbreviatedShaRegexp.MatchString(rest) {
+				shaMatches := lo.Filter(oldestFirst, func(c *models.Commit, _ int) bool {
+					return c != commit && strings.HasPrefix(c.Sha, rest)
+				})
+				if len(shaMatches) == 1 {
+					target = shaMatches[0]
+				}
+			}
+
+			if target == nil {
+				target, _ = lo.Find(oldestFirst[:i], func(c *models.Commit) bool {
+					return strings.HasPrefix(c.Name, rest)
+				})
+			}
+		}
+
+		if target != nil {
+			pairs = append(pairs, AutosquashPair{MarkerSha: commit.Sha, TargetSha: target.Sha})
+		} else {
+			candidatesBySubject[commit.Name] = append(candidatesBySubject[commit.Name], commit)
+		}
+	}
+
+	return lo.Reverse(pairs), nil
+}
+
+// FindFixupTargets returns a map from the sha of each fixup!/squash!/amend!
+// commit to the sha of the commit it will be squashed into. Commits are
+// expected newest first, as in the commits view. A marker can refer to its
+// target by subject, by sha, or by a prefix of the subject (see
+// autosquashPairs). If a subject matches several commits, we return an
+// AmbiguousFixupTargetError rather than guess.
+func (self *RebaseCommands) FindFixupTargets(commits []*models.Commit) (map[string]string, error) {
+	pairs, err := self.autosquashPairs(commits, failOnAmbiguousFixupTarget)
+	if err != nil {
+		return nil, err
+	}
+
+	return lo.SliceToMap(pairs, func(pair AutosquashPair) (string, string) {
+		return pair.MarkerSha, pair.TargetSha
+	}), nil
+}
+
+// HasPendingFixups tells us whether the current branch has fixup!/squash!/
+// amend! commits that still need squashing, so that the UI can suggest doing
+// that before pushing. Commits are expected newest first, as in the commits
+// view; we only look for markers among the branch's own commits, i.e. the ones
+// above the first commit that's already on a main branch. A marker counts
+// unless its target is already on a main branch too.
+func (self *RebaseCommands) HasPendingFixups(commits []*models.Commit) (bool, error) {
+	loadedCommits := lo.Filter(commits, func(c *models.Commit, _ int) bool { return !c.IsTODO() })
+	branchCommits := loadedCommits
+	if _, mergedIndex, found := lo.FindIndexOf(loadedCommits, func(c *models.Commit) bool {
+		return c.Status == models.StatusMerged
+	}); found {
+		branchCommits = loadedCommits[:mergedIndex]
+	}
+
+	targets, err := self.FindFixupTargets(branchCommits)
+	if err != nil {
+		// Whichever of the branch's commits the marker targets, it's pending
+		var ambiguousErr *AmbiguousFixupTargetError
+		if errors.As(err, &ambiguousErr) {
+			return true, nil
+		}
+		return false, err
+	}
+	if len(targets) > 0 {
+		return true, nil
+	}
+
+	// A marker whose target isn't among the loaded commits at all may target
+	// a commit of the branch that's further down than we've loaded
+	pairs, err := self.PreviewAutosquash(loadedCommits)
+	if err != nil {
+		return false, err
+	}
+	loadedTargets := lo.SliceToMap(pairs, func(pair AutosquashPair) (string, string) {
+		return pair.MarkerSha, pair.TargetSha
+	})
+	return lo.SomeBy(branchCommits, func(c *models.Commit) bool {
+		_, isMarker := self.fixupMarkerTarget(c.Name)
+		_, targetLoaded := loadedTargets[c.Sha]
+		return isMarker && !targetLoaded
+	}), nil
+}
@@ -0,0 +1,220 @@
+package git_commands
+
+import (
+	"testing"
+
+	"github.com/jesseduffield/lazygit/pkg/commands/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRebaseFindFixupTargets(t *testing.T) {
+	type scenario struct {
+		testName       string
+		gitVersion     *GitVersion
+		commits        []*models.Commit
+		expectedResult map[string]string
+		expectedErr    error
+	}
+
+	scenarios := []scenario{
+		{
+			testName: "no markers",
+			commits: []*models.Commit{
+				{Sha: "222222", Name: "second"},
+				{Sha: "111111", Name: "first"},
+			},
+			expectedResult: map[string]string{},
+		},
+		{
+			testName:   "fixup, squash and amend by subject",
+			gitVersion: &GitVersion{2, 32, 0, ""},
+			commits: []*models.Commit{
+				{Sha: "666666", Name: "amend! second"},
+				{Sha: "555555", Name: "squash! first"},
+				{Sha: "444444", Name: "fixup! second"},
+				{Sha: "333333", Name: "third"},
+				{Sha: "222222", Name: "second"},
+				{Sha: "111111", Name: "first"},
+			},
+			expectedResult: map[string]string{
+				"666666": "222222",
+				"555555": "111111",
+				"444444": "222222",
+			},
+		},
+		{
+			testName: "amend! is not understood by older git",
+			commits: []*models.Commit{
+				{Sha: "333333", Name: "amend! second"},
+				{Sha: "222222", Name: "second"},
+				{Sha: "111111", Name: "first"},
+			},
+			expectedResult: map[string]string{},
+		},
+		{
+			testName: "by sha",
+			commits: []*models.Commit{
+				{Sha: "444444", Name: "fixup! 1a2b3c4d"},
+				{Sha: "1a2b3c4d5e", Name: "first"},
+			},
+			expectedResult: map[string]string{
+				"444444": "1a2b3c4d5e",
+			},
+		},
+		{
+			testName: "by subject prefix",
+			commits: []*models.Commit{
+				{Sha: "444444", Name: "fixup! Add a"},
+				{Sha: "222222", Name: "Add a new feature"},
+				{Sha: "111111", Name: "Add a bug"},
+			},
+			expectedResult: map[string]string{
+				"444444": "111111",
+			},
+		},
+		{
+			testName: "nested markers",
+			commits: []*models.Commit{
+				{Sha: "333333", Name: "fixup! fixup! first"},
+				{Sha: "222222", Name: "fixup! first"},
+				{Sha: "111111", Name: "first"},
+			},
+			expectedResult: map[string]string{
+				"333333": "111111",
+				"222222": "111111",
+			},
+		},
+		{
+			testName: "target must be older than the marker",
+			commits: []*models.Commit{
+				{Sha: "222222", Name: "first"},
+				{Sha: "111111", Name: "fixup! first"},
+			},
+			expectedResult: map[string]string{},
+		},
+		{
+			testName: "by the sha of a newer commit",
+			commits: []*models.Commit{
+				{Sha: "1a2b3c4d5e", Name: "second"},
+				{Sha: "111111", Name: "fixup! 1a2b3c4d"},
+			},
+			expectedResult: map[string]string{
+				"111111": "1a2b3c4d5e",
+			},
+		},
+		{
+			testName: "whitespace between nested prefixes",
+			commits: []*models.Commit{
+				{Sha: "222222", Name: "fixup!  squash!   first"},
+				{Sha: "111111", Name: "first"},
+			},
+			expectedResult: map[string]string{
+				"222222": "111111",
+			},
+		},
+		{
+			testName: "ambiguous subject",
+			commits: []*models.Commit{
+				{Sha: "333333", Name: "fixup! wip"},
+				{Sha: "222222", Name: "wip"},
+				{Sha: "111111", Name: "wip"},
+			},
+			expectedResult: nil,
+			expectedErr: &AmbiguousFixupTargetError{
+				MarkerSha:     "333333",
+				CandidateShas: []string{"222222", "111111"},
+			},
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			instance := buildRebaseCommands(commonDeps{gitVersion: s.gitVersion})
+			result, err := instance.FindFixupTargets(s.commits)
+			assert.Equal(t, s.expectedErr, err)
+			assert.Equal(t, s.expectedResult, result)
+		})
+	}
+}
+
+func TestRebaseHasPendingFixups(t *testing.T) {
+	scenarios := []struct {
+		testName       string
+		commits        []*models.Commit
+		expectedResult bool
+	}{
+		{
+			testName: "no fixups",
+			commits: []*models.Commit{
+				{Sha: "222222", Name: "second"},
+				{Sha: "111111", Name: "first"},
+			},
+			expectedResult: false,
+		},
+		{
+			testName: "fixup for a commit of the branch",
+			commits: []*models.Commit{
+				{Sha: "333333", Name: "fixup! first"},
+				{Sha: "222222", Name: "second"},
+				{Sha: "111111", Name: "first"},
+			},
+			expectedResult: true,
+		},
+		{
+			testName: "squash for a commit of the branch",
+			commits: []*models.Commit{
+				{Sha: "333333", Name: "squash! second"},
+				{Sha: "222222", Name: "second"},
+				{Sha: "111111", Name: "first", Status: models.StatusMerged},
+			},
+			expectedResult: true,
+		},
+		{
+			testName: "fixup for a commit that's already on the main branch",
+			commits: []*models.Commit{
+				{Sha: "333333", Name: "fixup! first"},
+				{Sha: "222222", Name: "second"},
+				{Sha: "111111", Name: "first", Status: models.StatusMerged},
+			},
+			expectedResult: false,
+		},
+		{
+			testName: "fixup with an ambiguous target",
+			commits: []*models.Commit{
+				{Sha: "333333", Name: "fixup! wip"},
+				{Sha: "222222", Name: "wip"},
+				{Sha: "111111", Name: "wip"},
+			},
+			expectedResult: true,
+		},
+		{
+			testName: "fixup for a commit further down than the loaded commits",
+			commits: []*models.Commit{
+				{Sha: "333333", Name: "fixup! not loaded"},
+				{Sha: "222222", Name: "second"},
+				{Sha: "111111", Name: "first"},
+			},
+			expectedResult: true,
+		},
+		{
+			testName: "fixups on the main branch are ignored",
+			commits: []*models.Commit{
+				{Sha: "333333", Name: "third"},
+				{Sha: "222222", Name: "fixup! first", Status: models.StatusMerged},
+				{Sha: "111111", Name: "first", Status: models.StatusMerged},
+			},
+			expectedResult: false,
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			instance := buildRebaseCommands(commonDeps{})
+			result, err := instance.HasPendingFixups(s.commits)
+			assert.NoError(t, err)
+			assert.Equal(t, s.expectedResult, result)
+		})
+	}
+}
@@ -100,14 +100,14 @@ func (self *PatchCommands) DeletePatchesFromCommit(commits []*models.Commit, com
 	}
 
 	// time to amend the selected commit
-	if err := self.commit.AmendHead(); err != nil {
+	if err := self.commit.amendHeadInRebase(); err != nil {
 		return err
 	}
 
-	self.rebase.onSuccessfulContinue = func() error {
+	self.rebase.setOnSuccessfulContinue(self.Tr.Actions.RemovePatchFromCommit, func() error {
 		self.PatchBuilder.Reset()
 		return nil
-	}
+	})
 
 	// continue
 	return self.rebase.ContinueRebase()
@@ -130,14 +130,14 @@ func (self *PatchCommands) MovePatchToSelectedCommit(commits []*models.Commit, s
 		}
 
 		// amend the destination commit
-		if err := self.commit.AmendHead(); err != nil {
+		if err := self.commit.amendHeadInRebase(); err != nil {
 			return err
 		}
 
-		self.rebase.onSuccessfulContinue = func() error {
+		self.rebase.setOnSuccessfulContinue(self.Tr.Actions.MovePatchToSelectedCommit, func() error {
 			self.PatchBuilder.Reset()
 			return nil
-		}
+		})
 
 		// continue
 		return self.rebase.ContinueRebase()
@@ -162,11 +162,11 @@ func (self *PatchCommands) MovePatchToSelectedCommit(commits []*models.Commit, s
 	}
 	self.os.LogCommand(logTodoChanges(changes), false)
 
-	err := self.rebase.PrepareInteractiveRebaseCommand(PrepareInteractiveRebaseCommandOpts{
+	err := self.rebase.runInteractiveRebase(PrepareInteractiveRebaseCommandOpts{
 		baseShaOrRoot:  commits[baseIndex].Sha,
 		overrideEditor: true,
 		instruction:    daemon.NewChangeTodoActionsInstruction(changes),
-	}).Run()
+	})
 	if err != nil {
 		return err
 	}
@@ -178,7 +178,7 @@ func (self *PatchCommands) MovePatchToSelectedCommit(commits []*models.Commit, s
 	}
 
 	// amend the source commit
-	if err := self.commit.AmendHead(); err != nil {
+	if err := self.commit.amendHeadInRebase(); err != nil {
 		return err
 	}
 
@@ -192,7 +192,7 @@ func (self *PatchCommands) MovePatchToSelectedCommit(commits []*models.Commit, s
 		return errors.New("You are midway through another rebase operation. Please abort to start again")
 	}
 
-	self.rebase.onSuccessfulContinue = func() error {
+	self.rebase.setOnSuccessfulContinue(self.Tr.Actions.MovePatchToSelectedCommit, func() error {
 		// now we should be up to the destination, so let's apply forward these patches to that.
 		// ideally we would ensure we're on the right commit but I'm not sure if that check is necessary
 		if err := self.ApplyPatch(patch, ApplyPatchOpts{Index: true, ThreeWay: true}); err != nil {
@@ -202,17 +202,17 @@ func (self *PatchCommands) MovePatchToSelectedCommit(commits []*models.Commit, s
 		}
 
 		// amend the destination commit
-		if err := self.commit.AmendHead(); err != nil {
+		if err := self.commit.amendHeadInRebase(); err != nil {
 			return err
 		}
 
-		self.rebase.onSuccessfulContinue = func() error {
+		self.rebase.setOnSuccessfulContinue(self.Tr.Actions.MovePatchToSelectedCommit, func() error {
 			self.PatchBuilder.Reset()
 			return nil
-		}
+		})
 
 		return self.rebase.ContinueRebase()
-	}
+	})
 
 	return self.rebase.ContinueRebase()
 }
@@ -236,7 +236,7 @@ func (self *PatchCommands) MovePatchIntoIndex(commits []*models.Commit, commitId
 	}
 
 	// amend the commit
-	if err := self.commit.AmendHead(); err != nil {
+	if err := self.commit.amendHeadInRebase(); err != nil {
 		return err
 	}
 
@@ -250,7 +250,7 @@ func (self *PatchCommands) MovePatchIntoIndex(commits []*models.Commit, commitId
 		return errors.New("You are midway through another rebase operation. Please abort to start again")
 	}
 
-	self.rebase.onSuccessfulContinue = func() error {
+	self.rebase.setOnSuccessfulContinue(self.Tr.Actions.MovePatchIntoIndex, func() error {
 		// add patches to index
 		if err := self.ApplyPatch(patch, ApplyPatchOpts{Index: true, ThreeWay: true}); err != nil {
 			if self.status.WorkingTreeState() == enums.REBASE_MODE_REBASING {
@@ -267,7 +267,7 @@ func (self *PatchCommands) MovePatchIntoIndex(commits []*models.Commit, commitId
 
 		self.PatchBuilder.Reset()
 		return nil
-	}
+	})
 
 	return self.rebase.ContinueRebase()
 }
@@ -288,7 +288,7 @@ func (self *PatchCommands) PullPatchIntoNewCommit(
 	}
 
 	// amend the commit
-	if err := self.commit.AmendHead(); err != nil {
+	if err := self.commit.amendHeadInRebase(); err != nil {
 		return err
 	}
 
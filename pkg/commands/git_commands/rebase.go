@@ -1,36 +1,58 @@
 package git_commands
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"os"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/fsmiamoto/git-todo-parser/todo"
 	"github.com/go-errors/errors"
+	"github.com/jesseduffield/gocui"
 	"github.com/jesseduffield/lazygit/pkg/app/daemon"
 	"github.com/jesseduffield/lazygit/pkg/commands/models"
 	"github.com/jesseduffield/lazygit/pkg/commands/oscommands"
+	"github.com/jesseduffield/lazygit/pkg/commands/types/enums"
 	"github.com/jesseduffield/lazygit/pkg/utils"
 	"github.com/samber/lo"
+	"golang.org/x/exp/slices"
 )
 
 type RebaseCommands struct {
 	*GitCommon
 	commit      *CommitCommands
 	workingTree *WorkingTreeCommands
+	status      *StatusCommands
+	branch      *BranchCommands
 
 	onSuccessfulContinue func() error
+	// The rebase that onSuccessfulContinue was queued up in
+	onSuccessfulContinueRebase rebaseIdentity
+
+	// The commits that RewordCommitsInteractive asked git to reword, by their
+	// original shas, oldest first. This is the queue of stops at which the
+	// user still needs to get the editor (see PendingRewords).
+	rewordQueue []string
 }
 
 func NewRebaseCommands(
 	gitCommon *GitCommon,
 	commitCommands *CommitCommands,
 	workingTreeCommands *WorkingTreeCommands,
+	statusCommands *StatusCommands,
+	branchCommands *BranchCommands,
 ) *RebaseCommands {
 	return &RebaseCommands{
 		GitCommon:   gitCommon,
 		commit:      commitCommands,
 		workingTree: workingTreeCommands,
+		status:      statusCommands,
+		branch:      branchCommands,
 	}
 }
 
@@ -40,13 +62,20 @@ func (self *RebaseCommands) RewordCommit(commits []*models.Commit, index int, su
 		return self.commit.RewordLastCommit(summary, description)
 	}
 
+	// Without --rebase-merges, git would rebuild the history above the commit
+	// without its merges, so the commit must not be inside a merged branch
+	if !self.version.IsAtLeast(2, 22, 0) && index < len(commits) &&
+		lo.SomeBy(commits[:index], func(c *models.Commit) bool { return c.IsMerge() }) {
+		return errors.New(self.Tr.RewordBelowMergeNeedsNewerGit)
+	}
+
 	err := self.BeginInteractiveRebaseForCommit(commits, index, false)
 	if err != nil {
 		return err
 	}
 
 	// now the selected commit should be our head so we'll amend it with the new message
-	err = self.commit.RewordLastCommit(summary, description)
+	err = self.commit.rewordLastCommitInRebase(summary, description)
 	if err != nil {
 		return err
 	}
@@ -61,30 +90,436 @@ func (self *RebaseCommands) RewordCommitInEditor(commits []*models.Commit, index
 	}}
 	self.os.LogCommand(logTodoChanges(changes), false)
 
-	return self.PrepareInteractiveRebaseCommand(PrepareInteractiveRebaseCommandOpts{
+	return self.startOrPrepareInteractiveRebase(PrepareInteractiveRebaseCommandOpts{
 		baseShaOrRoot: getBaseShaOrRoot(commits, index+1),
 		instruction:   daemon.NewChangeTodoActionsInstruction(changes),
+	})
+}
+
+// RewordCommitsInteractive is like RewordCommitInEditor for several commits:
+// it marks each of them as a reword, so that git opens the editor for one
+// after the other, oldest first. The returned command must be run as a
+// subprocess so that the editor can take over the terminal. If the rebase
+// stops on the way (e.g. at conflicts, or because the editor failed), the
+// commits that haven't been reworded yet stay queued up, and
+// ContinueRebaseWithEditorForRewords hands the editor through for each of
+// them.
+func (self *RebaseCommands) RewordCommitsInteractive(commits []*models.Commit, indices []int) (oscommands.ICmdObj, error) {
+	if len(indices) == 0 {
+		return nil, errors.New("no commits to reword")
+	}
+
+	uniqueIndices := lo.Uniq(indices)
+	baseIndex := 0
+	changes := make([]daemon.ChangeTodoAction, 0, len(indices))
+	for _, index := range uniqueIndices {
+		if index < 0 || index >= len(commits) {
+			return nil, errors.New("index outside of range of commits")
+		}
+		if commits[index].IsMerge() {
+			return nil, errors.New("cannot reword a merge commit this way")
+		}
+		if index > baseIndex {
+			baseIndex = index
+		}
+		changes = append(changes, daemon.ChangeTodoAction{
+			Sha:       commits[index].Sha,
+			NewAction: todo.Reword,
+		})
+	}
+	self.os.LogCommand(logTodoChanges(changes), false)
+
+	// Higher indices are older commits
+	slices.Sort(uniqueIndices)
+	self.rewordQueue = lo.Map(lo.Reverse(uniqueIndices), func(index int, _ int) string {
+		return commits[index].Sha
+	})
+
+	return self.startOrPrepareInteractiveRebase(PrepareInteractiveRebaseCommandOpts{
+		baseShaOrRoot: getBaseShaOrRoot(commits, baseIndex+1),
+		instruction:   daemon.NewChangeTodoActionsInstruction(changes),
+	})
+}
+
+// PendingRewords returns the commits queued up by RewordCommitsInteractive
+// that still need a message from the user, by their original shas, oldest
+// first: those that git hasn't got to yet, and the one that it gave up on, if
+// any. The queue is dropped once the rebase is over.
+func (self *RebaseCommands) PendingRewords() ([]string, error) {
+	if len(self.rewordQueue) == 0 {
+		return nil, nil
+	}
+
+	done, err := self.RebaseDoneSteps()
+	if err != nil {
+		return nil, err
+	}
+	if len(done) == 0 {
+		self.rewordQueue = nil
+		return nil, nil
+	}
+
+	givenUpOn, err := self.rewordGivenUpOn(done)
+	if err != nil {
+		return nil, err
+	}
+
+	return lo.Filter(self.rewordQueue, func(sha string, _ int) bool {
+		return sha == givenUpOn || !lo.SomeBy(done, func(t todo.Todo) bool {
+			return t.Command == todo.Reword && strings.HasPrefix(t.Commit, sha)
+		})
 	}), nil
 }
 
+// When the editor fails at a reword (e.g. because the user quit it without
+// saving), git makes the commit with its old message and stops. A plain
+// continue would then carry on without the new message, so if it's one of the
+// commits in our queue, we return its original sha, so that the editor can be
+// opened for it again. We can tell this apart from a reword that stopped at
+// conflicts by HEAD being a copy of the commit, with the same author, date and
+// message; git doesn't leave a file behind for it in every case.
+func (self *RebaseCommands) rewordGivenUpOn(done []todo.Todo) (string, error) {
+	if len(done) == 0 || done[len(done)-1].Command != todo.Reword {
+		return "", nil
+	}
+	sha, found := lo.Find(self.rewordQueue, func(sha string) bool {
+		return strings.HasPrefix(done[len(done)-1].Commit, sha)
+	})
+	if !found {
+		return "", nil
+	}
+
+	authorAndMessage := func(ref string) (string, error) {
+		cmdArgs := NewGitCmd("log").Arg("-1", "--format=%an%n%ae%n%ad%n%B", "--date=raw", ref).ToArgv()
+		return self.cmd.New(cmdArgs).DontLog().RunWithOutput()
+	}
+	original, err := authorAndMessage(sha)
+	if err != nil {
+		return "", err
+	}
+	head, err := authorAndMessage("HEAD")
+	if err != nil || head != original {
+		return "", err
+	}
+
+	return sha, nil
+}
+
+// RewordAndFixup gives the target commit a new message and folds the commits
+// at fixupIndices into it, all in a single rebase. The fixups are moved so
+// that they come directly after the target, in their original order.
+func (self *RebaseCommands) RewordAndFixup(commits []*models.Commit, targetIndex int, message string, fixupIndices []int) error {
+	if targetIndex < 0 || targetIndex >= len(commits) {
+		return errors.New("index outside of range of commits")
+	}
+
+	baseIndex := targetIndex
+	for _, index := range fixupIndices {
+		if index < 0 || index >= len(commits) {
+			return errors.New("index outside of range of commits")
+		}
+		if index == targetIndex {
+			return errors.New("cannot fixup a commit into itself")
+		}
+		if index > baseIndex {
+			baseIndex = index
+		}
+	}
+
+	targetSha := commits[targetIndex].Sha
+	changes := []daemon.ChangeTodoAction{{
+		Sha:        targetSha,
+		NewAction:  todo.Reword,
+		NewMessage: message,
+	}}
+
+	// Fixups are moved one at a time to the end of the target's fixup group,
+	// so we process them oldest first to keep them in their original order
+	sortedFixupIndices := slices.Clone(fixupIndices)
+	slices.Sort(sortedFixupIndices)
+	for _, index := range lo.Reverse(sortedFixupIndices) {
+		changes = append(changes, daemon.ChangeTodoAction{
+			Sha:          commits[index].Sha,
+			NewAction:    todo.Fixup,
+			MoveAfterSha: targetSha,
+		})
+	}
+	self.os.LogCommand(logTodoChanges(changes), false)
+
+	return self.runInteractiveRebase(PrepareInteractiveRebaseCommandOpts{
+		baseShaOrRoot:  getBaseShaOrRoot(commits, baseIndex+1),
+		overrideEditor: true,
+		instruction:    daemon.NewChangeTodoActionsInstruction(changes),
+		// The message is exactly what the user wants, so lines like "#123"
+		// must not be taken for comments. Only trailing whitespace is
+		// trimmed, and since there are no squashes, git doesn't add any
+		// comments of its own that would then be left in.
+		commitCleanup: "whitespace",
+	})
+}
+
+// SquashCommitInto folds the commit at sourceIndex into the older commit at
+// targetIndex in a single rebase, however many commits lie between them. The
+// source's todo is moved to directly after the target and given the action,
+// which must be todo.Squash (to keep both messages) or todo.Fixup (to keep only
+// the target's).
+func (self *RebaseCommands) SquashCommitInto(commits []*models.Commit, sourceIndex int, targetIndex int, action todo.TodoCommand) error {
+	if sourceIndex < 0 || targetIndex >= len(commits) {
+		return errors.New("index outside of range of commits")
+	}
+	if sourceIndex >= targetIndex {
+		return errors.New("a commit can only be squashed into an older commit")
+	}
+	if action != todo.Squash && action != todo.Fixup {
+		return errors.New("a commit can only be squashed or fixed up into another one")
+	}
+
+	changes := []daemon.ChangeTodoAction{{
+		Sha:          commits[sourceIndex].Sha,
+		NewAction:    action,
+		MoveAfterSha: commits[targetIndex].Sha,
+	}}
+	self.os.LogCommand(logTodoChanges(changes), false)
+
+	return self.runInteractiveRebase(PrepareInteractiveRebaseCommandOpts{
+		baseShaOrRoot:  getBaseShaOrRoot(commits, targetIndex+1),
+		overrideEditor: true,
+		instruction:    daemon.NewChangeTodoActionsInstruction(changes),
+	})
+}
+
+// MoveCommitToTargetSha moves the commit with sha movingSha so that it sits
+// directly before (i.e. below, as its parent) or after (above, as its child)
+// the commit with sha targetSha, depending on placement, in a single rebase.
+// Going by shas rather than indices means callers that only know the target
+// from e.g. a search don't have to work out where it is in the commits slice.
+func (self *RebaseCommands) MoveCommitToTargetSha(commits []*models.Commit, movingSha string, targetSha string, placement string) error {
+	if placement != "before" && placement != "after" {
+		return errors.Errorf("invalid placement '%s'; must be 'before' or 'after'", placement)
+	}
+
+	_, movingIndex, found := lo.FindIndexOf(commits, func(c *models.Commit) bool { return c.Sha == movingSha })
+	if !found {
+		return errors.Errorf("commit %s not found", utils.ShortSha(movingSha))
+	}
+	_, targetIndex, found := lo.FindIndexOf(commits, func(c *models.Commit) bool { return c.Sha == targetSha })
+	if !found {
+		return errors.Errorf("commit %s not found", utils.ShortSha(targetSha))
+	}
+	if movingIndex == targetIndex {
+		return errors.New("a commit can't be moved relative to itself")
+	}
+
+	change := daemon.ChangeTodoAction{Sha: movingSha, NewAction: todo.Pick}
+	if placement == "before" {
+		change.MoveBeforeSha = targetSha
+	} else {
+		change.MoveAfterSha = targetSha
+	}
+	changes := []daemon.ChangeTodoAction{change}
+	self.os.LogCommand(logTodoChanges(changes), false)
+
+	return self.runInteractiveRebase(PrepareInteractiveRebaseCommandOpts{
+		baseShaOrRoot:  getBaseShaOrRoot(commits, utils.Max(movingIndex, targetIndex)+1),
+		overrideEditor: true,
+		instruction:    daemon.NewChangeTodoActionsInstruction(changes),
+	})
+}
+
+// PreviewSquashMessage returns the message that git would give the commit that
+// results from squashing child into parent, so that the user can edit it
+// before we squash with RewordAndFixup.
+func (self *RebaseCommands) PreviewSquashMessage(parent *models.Commit, child *models.Commit) (string, error) {
+	messages, err := self.commit.GetCommitMessages([]string{parent.Sha, child.Sha})
+	if err != nil {
+		return "", err
+	}
+
+	return combineSquashMessages(messages[parent.Sha], messages[child.Sha]), nil
+}
+
+// Git puts the messages one after the other, separated by comments that its
+// default cleanup mode strips again, leaving a blank line between them. The
+// subject of a fixup!, squash! or amend! commit is commented out too, since it
+// only repeats the parent's subject.
+func combineSquashMessages(parentMessage string, childMessage string) string {
+	if lo.SomeBy([]string{"fixup! ", "squash! ", "amend! "}, func(prefix string) bool {
+		return strings.HasPrefix(childMessage, prefix)
+	}) {
+		_, childMessage, _ = strings.Cut(childMessage, "\n")
+		childMessage = strings.TrimSpace(childMessage)
+	}
+
+	return strings.Join(lo.Compact([]string{parentMessage, childMessage}), "\n\n")
+}
+
+// RewordCommitsMatching rewords, in a single rebase, every commit whose
+// message matches the pattern, replacing the matches with the replacement
+// (which can refer to submatches, as in regexp.ReplaceAllString). The pattern
+// is applied to the whole message, so it can match in the body too. Commits
+// whose message comes out the same are left as picks, so that they keep their
+// shas where possible.
+func (self *RebaseCommands) RewordCommitsMatching(commits []*models.Commit, pattern *regexp.Regexp, replacement string) error {
+	candidates := lo.Filter(commits, func(commit *models.Commit, _ int) bool { return !commit.IsTODO() })
+	messages, err := self.commit.GetCommitMessages(lo.Map(candidates, func(commit *models.Commit, _ int) string {
+		return commit.Sha
+	}))
+	if err != nil {
+		return err
+	}
+
+	baseIndex := 0
+	changes := []daemon.ChangeTodoAction{}
+	for index, commit := range commits {
+		message, ok := messages[commit.Sha]
+		if !ok {
+			continue
+		}
+		newMessage := pattern.ReplaceAllString(message, replacement)
+		if newMessage == message {
+			continue
+		}
+
+		baseIndex = index
+		changes = append(changes, daemon.ChangeTodoAction{
+			Sha:        commit.Sha,
+			NewAction:  todo.Reword,
+			NewMessage: newMessage,
+		})
+	}
+	if len(changes) == 0 {
+		return errors.New("no commit messages would change")
+	}
+	self.os.LogCommand(logTodoChanges(changes), false)
+
+	return self.runInteractiveRebase(PrepareInteractiveRebaseCommandOpts{
+		baseShaOrRoot:  getBaseShaOrRoot(commits, baseIndex+1),
+		overrideEditor: true,
+		instruction:    daemon.NewChangeTodoActionsInstruction(changes),
+	})
+}
+
+// SquashByPrefix squashes, in a single rebase, each run of consecutive
+// commits whose subjects share a prefix into the oldest commit of the run. The
+// prefix is the pattern's first submatch if it has one, and otherwise the whole
+// match, e.g. `^\[[^\]]+\]` groups commits by a "[feature]" tag. Commits whose
+// subject doesn't match, and merge commits, are left alone and end any run.
+func (self *RebaseCommands) SquashByPrefix(commits []*models.Commit, pattern *regexp.Regexp) error {
+	prefixOf := func(commit *models.Commit) string {
+		if commit.IsTODO() || commit.IsMerge() {
+			return ""
+		}
+		match := pattern.FindStringSubmatch(commit.Name)
+		if len(match) > 1 {
+			return match[1]
+		}
+		if len(match) == 1 {
+			return match[0]
+		}
+		return ""
+	}
+
+	baseIndex := 0
+	changes := []daemon.ChangeTodoAction{}
+	for index := 0; index < len(commits)-1; index++ {
+		prefix := prefixOf(commits[index])
+		if prefix == "" || prefix != prefixOf(commits[index+1]) {
+			continue
+		}
+
+		// The commits list is newest first, so the older commit that this
+		// one is squashed into comes next
+		baseIndex = index + 1
+		changes = append(changes, daemon.ChangeTodoAction{
+			Sha:       commits[index].Sha,
+			NewAction: todo.Squash,
+		})
+	}
+	if len(changes) == 0 {
+		return errors.New("no consecutive commits share a prefix")
+	}
+	self.os.LogCommand(logTodoChanges(changes), false)
+
+	return self.runInteractiveRebase(PrepareInteractiveRebaseCommandOpts{
+		baseShaOrRoot:  getBaseShaOrRoot(commits, baseIndex+1),
+		overrideEditor: true,
+		instruction:    daemon.NewChangeTodoActionsInstruction(changes),
+	})
+}
+
 func (self *RebaseCommands) ResetCommitAuthor(commits []*models.Commit, index int) error {
 	return self.GenericAmend(commits, index, func() error {
 		return self.commit.ResetAuthor()
 	})
 }
 
-func (self *RebaseCommands) SetCommitAuthor(commits []*models.Commit, index int, value string) error {
+func (self *RebaseCommands) ResetAuthorToCommitter(commits []*models.Commit, index int) error {
 	return self.GenericAmend(commits, index, func() error {
-		return self.commit.SetAuthor(value)
+		return self.commit.ResetAuthorToCommitter()
 	})
 }
 
-func (self *RebaseCommands) AddCommitCoAuthor(commits []*models.Commit, index int, value string) error {
+func (self *RebaseCommands) SetCommitAuthor(commits []*models.Commit, index int, value string) error {
 	return self.GenericAmend(commits, index, func() error {
-		return self.commit.AddCoAuthor(commits[index].Sha, value)
+		return self.commit.SetAuthor(value)
 	})
 }
 
+// RewriteAuthorEmail gives every commit authored with oldEmail the author
+// "newName <newEmail>", e.g. to fix commits made with a misconfigured
+// identity. It stops at each of those commits for editing, and sets the author
+// before continuing to the next.
+func (self *RebaseCommands) RewriteAuthorEmail(commits []*models.Commit, oldEmail string, newName string, newEmail string) error {
+	indices := []int{}
+	shas := map[string]bool{}
+	for index, commit := range commits {
+		if !commit.IsTODO() && strings.EqualFold(commit.AuthorEmail, oldEmail) {
+			indices = append(indices, index)
+			shas[commit.Sha] = true
+		}
+	}
+	if len(indices) == 0 {
+		return errors.Errorf("no commits authored by %s", oldEmail)
+	}
+
+	if err := self.EditCommits(commits, indices); err != nil {
+		return err
+	}
+
+	return self.setAuthorAtEditStops(shas, fmt.Sprintf("%s <%s>", newName, newEmail))
+}
+
+// Sets the author of the commit that the rebase is stopped at, and continues,
+// for as long as the rebase keeps stopping at edits of the given commits
+func (self *RebaseCommands) setAuthorAtEditStops(shas map[string]bool, author string) error {
+	for {
+		done, err := self.RebaseDoneSteps()
+		if err != nil {
+			return err
+		}
+		if len(done) == 0 {
+			return nil
+		}
+		current := done[len(done)-1]
+		if current.Command != todo.Edit || !shas[current.Commit] {
+			return nil
+		}
+
+		if err := self.commit.SetAuthor(author); err != nil {
+			return err
+		}
+
+		if err := self.ContinueRebase(); err != nil {
+			// Probably conflicts; carry on with the remaining stops once the
+			// user has resolved them and continued
+			self.setOnSuccessfulContinue(self.Tr.Actions.SetCommitAuthor, func() error {
+				return self.setAuthorAtEditStops(shas, author)
+			})
+			return err
+		}
+	}
+}
+
 func (self *RebaseCommands) GenericAmend(commits []*models.Commit, index int, f func() error) error {
 	if models.IsHeadCommit(commits, index) {
 		// we've selected the top commit so no rebase is required
@@ -118,11 +553,30 @@ func (self *RebaseCommands) MoveCommitDown(commits []*models.Commit, index int)
 	)
 	self.os.LogCommand(msg, false)
 
-	return self.PrepareInteractiveRebaseCommand(PrepareInteractiveRebaseCommandOpts{
+	return self.runInteractiveRebase(PrepareInteractiveRebaseCommandOpts{
 		baseShaOrRoot:  baseShaOrRoot,
 		instruction:    daemon.NewMoveTodoDownInstruction(sha),
 		overrideEditor: true,
-	}).Run()
+	})
+}
+
+// SwapCommits swaps the two commits at the given indices, which must be next
+// to each other. This is the same as moving the newer one down, so it only
+// takes a single rebase.
+func (self *RebaseCommands) SwapCommits(commits []*models.Commit, indexA int, indexB int) error {
+	if indexA < 0 || indexA >= len(commits) || indexB < 0 || indexB >= len(commits) {
+		return errors.New("index outside of range of commits")
+	}
+	if indexA-indexB != 1 && indexB-indexA != 1 {
+		return errors.New("only adjacent commits can be swapped")
+	}
+
+	newerIndex := indexA
+	if indexB < indexA {
+		newerIndex = indexB
+	}
+
+	return self.MoveCommitDown(commits, newerIndex)
 }
 
 func (self *RebaseCommands) MoveCommitUp(commits []*models.Commit, index int) error {
@@ -138,20 +592,82 @@ func (self *RebaseCommands) MoveCommitUp(commits []*models.Commit, index int) er
 	)
 	self.os.LogCommand(msg, false)
 
-	return self.PrepareInteractiveRebaseCommand(PrepareInteractiveRebaseCommandOpts{
+	return self.runInteractiveRebase(PrepareInteractiveRebaseCommandOpts{
 		baseShaOrRoot:  baseShaOrRoot,
 		instruction:    daemon.NewMoveTodoUpInstruction(sha),
 		overrideEditor: true,
-	}).Run()
+	})
 }
 
-func (self *RebaseCommands) InteractiveRebase(commits []*models.Commit, index int, action todo.TodoCommand) error {
-	baseIndex := index + 1
-	if action == todo.Squash || action == todo.Fixup {
-		baseIndex++
+// ReorderCommits rearranges the topmost commits of the branch in a single
+// rebase. newOrder holds the shas of the commits to reorder, oldest first; it
+// must contain exactly the shas of the top len(newOrder) commits.
+func (self *RebaseCommands) ReorderCommits(commits []*models.Commit, newOrder []string) error {
+	if len(newOrder) == 0 || len(newOrder) > len(commits) {
+		return errors.New("invalid commit order")
+	}
+
+	newOrder, err := self.resolveShas(commits, newOrder)
+	if err != nil {
+		return err
+	}
+
+	currentShas := lo.Map(commits[:len(newOrder)], func(c *models.Commit, _ int) string { return c.Sha })
+	if len(lo.Uniq(newOrder)) != len(newOrder) || len(lo.Intersect(currentShas, newOrder)) != len(newOrder) {
+		return errors.New("new commit order must contain each of the reordered commits exactly once")
+	}
+
+	return self.runInteractiveRebase(PrepareInteractiveRebaseCommandOpts{
+		baseShaOrRoot:  getBaseShaOrRoot(commits, len(newOrder)),
+		instruction:    daemon.NewReorderTodosInstruction(newOrder),
+		overrideEditor: true,
+	})
+}
+
+// UncommitRange undoes the commits from startIndex down to endIndex (inclusive)
+// but keeps their changes staged. If the range isn't at the top of the branch,
+// we first move its commits to the top in a rebase, so that the commits above
+// the range are kept and the changes in the index are exactly the range's.
+func (self *RebaseCommands) UncommitRange(commits []*models.Commit, startIndex int, endIndex int) error {
+	if startIndex < 0 || endIndex >= len(commits) || startIndex > endIndex {
+		return errors.New("index outside of range of commits")
+	}
+
+	if commits[endIndex].IsFirstCommit() {
+		return errors.New("cannot uncommit the first commit")
+	}
+
+	if startIndex == 0 {
+		return self.commit.ResetToCommit(commits[endIndex].ParentRefName(), "soft", nil)
+	}
+
+	if lo.SomeBy(commits[:endIndex+1], func(c *models.Commit) bool { return c.IsMerge() }) {
+		return errors.New("cannot uncommit commits from below a merge commit")
+	}
+
+	// newest first, the range goes on top of the commits that are above it now
+	shas := lo.Map(commits[:endIndex+1], func(c *models.Commit, _ int) string { return c.Sha })
+	newOrder := lo.Reverse(append(slices.Clone(shas[startIndex:]), shas[:startIndex]...))
+
+	resetCount := endIndex - startIndex + 1
+	reset := func() error {
+		return self.commit.ResetToCommit(fmt.Sprintf("HEAD~%d", resetCount), "soft", nil)
 	}
 
-	baseShaOrRoot := getBaseShaOrRoot(commits, baseIndex)
+	if err := self.ReorderCommits(commits, newOrder); err != nil {
+		// moving the commits may have caused conflicts; once the user has
+		// resolved them and continued, we can do the reset
+		if self.status.WorkingTreeState() == enums.REBASE_MODE_REBASING {
+			self.setOnSuccessfulContinue(self.Tr.Actions.UncommitCommits, reset)
+		}
+		return err
+	}
+
+	return reset()
+}
+
+func (self *RebaseCommands) InteractiveRebase(commits []*models.Commit, index int, action todo.TodoCommand) error {
+	baseShaOrRoot := getBaseShaOrRoot(commits, interactiveRebaseBaseIndex(index, action))
 
 	changes := []daemon.ChangeTodoAction{{
 		Sha:       commits[index].Sha,
@@ -159,14 +675,58 @@ func (self *RebaseCommands) InteractiveRebase(commits []*models.Commit, index in
 	}}
 	self.os.LogCommand(logTodoChanges(changes), false)
 
-	return self.PrepareInteractiveRebaseCommand(PrepareInteractiveRebaseCommandOpts{
+	return self.runInteractiveRebase(PrepareInteractiveRebaseCommandOpts{
 		baseShaOrRoot:  baseShaOrRoot,
 		overrideEditor: true,
 		instruction:    daemon.NewChangeTodoActionsInstruction(changes),
-	}).Run()
+	})
+}
+
+// Returns the index of the commit that InteractiveRebase uses as the base when
+// applying the given action to the commit at the given index. Squashes and
+// fixups also rewrite the commit below, so we need to go one further back.
+func interactiveRebaseBaseIndex(index int, action todo.TodoCommand) int {
+	baseIndex := index + 1
+	if action == todo.Squash || action == todo.Fixup {
+		baseIndex++
+	}
+	return baseIndex
+}
+
+// ResolveRebaseTarget tells us what InteractiveRebase would do when applying
+// the given action to the commit at the given index, so that we can ask the
+// user to confirm before rewriting anything. It returns the sha that the
+// rebase would start from (empty if it would need to start from the root
+// commit), and the shas of the commits that would be rewritten, newest first.
+func (self *RebaseCommands) ResolveRebaseTarget(
+	commits []*models.Commit, index int, action todo.TodoCommand,
+) (string, []string, error) {
+	if index < 0 || index >= len(commits) {
+		return "", nil, errors.New("index outside of range of commits")
+	}
+
+	baseIndex := interactiveRebaseBaseIndex(index, action)
+	baseSha := getBaseShaOrRoot(commits, baseIndex)
+	if baseSha == "--root" {
+		baseSha = ""
+	}
+
+	affectedCommits := commits
+	if baseIndex < len(commits) {
+		affectedCommits = commits[:baseIndex]
+	}
+	affectedShas := lo.Map(affectedCommits, func(commit *models.Commit, _ int) string {
+		return commit.Sha
+	})
+
+	return baseSha, affectedShas, nil
 }
 
 func (self *RebaseCommands) EditRebase(branchRef string) error {
+	if err := self.checkCanRebaseWithoutAutostash(); err != nil {
+		return err
+	}
+
 	msg := utils.ResolvePlaceholderString(
 		self.Tr.Log.EditRebase,
 		map[string]string{
@@ -174,13 +734,21 @@ func (self *RebaseCommands) EditRebase(branchRef string) error {
 		},
 	)
 	self.os.LogCommand(msg, false)
-	return self.PrepareInteractiveRebaseCommand(PrepareInteractiveRebaseCommandOpts{
+	return self.runInteractiveRebase(PrepareInteractiveRebaseCommandOpts{
 		baseShaOrRoot: branchRef,
 		instruction:   daemon.NewInsertBreakInstruction(),
-	}).Run()
+	})
 }
 
 func (self *RebaseCommands) EditRebaseFromBaseCommit(targetBranchName string, baseCommit string) error {
+	if err := self.checkCanRebaseWithoutAutostash(); err != nil {
+		return err
+	}
+
+	if err := self.checkBaseIsAncestorOfHead(baseCommit); err != nil {
+		return err
+	}
+
 	msg := utils.ResolvePlaceholderString(
 		self.Tr.Log.EditRebaseFromBaseCommit,
 		map[string]string{
@@ -189,11 +757,52 @@ func (self *RebaseCommands) EditRebaseFromBaseCommit(targetBranchName string, ba
 		},
 	)
 	self.os.LogCommand(msg, false)
-	return self.PrepareInteractiveRebaseCommand(PrepareInteractiveRebaseCommandOpts{
+	return self.runInteractiveRebase(PrepareInteractiveRebaseCommandOpts{
 		baseShaOrRoot: baseCommit,
 		onto:          targetBranchName,
 		instruction:   daemon.NewInsertBreakInstruction(),
-	}).Run()
+	})
+}
+
+// RebaseUntilFirstFailure rebases the current branch onto the given ref,
+// running checkCmd after each commit. If the check fails, git leaves the
+// rebase paused at the commit that failed it, and we return that commit's sha
+// so that the user can see which commit broke things. The sha is empty if
+// every commit passed. Any other reason for the rebase to stop (e.g.
+// conflicts) is returned as an error.
+func (self *RebaseCommands) RebaseUntilFirstFailure(branchRef string, checkCmd string) (string, error) {
+	msg := utils.ResolvePlaceholderString(
+		self.Tr.Log.RebaseUntilFirstFailure,
+		map[string]string{
+			"ref":      branchRef,
+			"checkCmd": checkCmd,
+		},
+	)
+	self.os.LogCommand(msg, false)
+
+	err := self.runInteractiveRebase(PrepareInteractiveRebaseCommandOpts{
+		baseShaOrRoot: branchRef,
+		exec:          checkCmd,
+	})
+	if err == nil {
+		return "", nil
+	}
+	self.recordFailedExec(err)
+
+	// When an exec fails, git has already moved it to the done file, so if
+	// that's where the rebase stopped, HEAD is the commit that was checked
+	done, doneErr := self.RebaseDoneSteps()
+	if doneErr != nil || len(done) == 0 || done[len(done)-1].Command != todo.Exec {
+		return "", err
+	}
+
+	cmdArgs := NewGitCmd("rev-parse").Arg("HEAD").ToArgv()
+	sha, shaErr := self.cmd.New(cmdArgs).DontLog().RunWithOutput()
+	if shaErr != nil {
+		return "", shaErr
+	}
+
+	return strings.TrimSpace(sha), nil
 }
 
 func logTodoChanges(changes []daemon.ChangeTodoAction) string {
@@ -203,29 +812,183 @@ func logTodoChanges(changes []daemon.ChangeTodoAction) string {
 	return fmt.Sprintf("Changing TODO actions: %s", changeTodoStr)
 }
 
+// What a rebase does with commits that become empty because their changes are
+// already in the new base (git's --empty). Commits that were empty to begin
+// with, e.g. deliberate marker commits, are always kept.
+type EmptyCommitsMode string
+
+const (
+	// Leave it to git, which stops at such commits in an interactive rebase
+	EmptyCommitsDefault EmptyCommitsMode = ""
+	EmptyCommitsDrop    EmptyCommitsMode = "drop"
+	EmptyCommitsKeep    EmptyCommitsMode = "keep"
+	// Stop at the commit so that the user can decide. Git 2.45 renamed this
+	// to "stop", but still understands "ask".
+	EmptyCommitsStop EmptyCommitsMode = "ask"
+)
+
+// Which of git's two rebase implementations to use (git's --merge/--apply).
+// Interactive rebases only work with the merge backend.
+type RebaseBackend string
+
+const (
+	// Leave it to git, which uses the merge backend unless configured otherwise
+	RebaseBackendDefault RebaseBackend = ""
+	RebaseBackendMerge   RebaseBackend = "merge"
+	RebaseBackendApply   RebaseBackend = "apply"
+)
+
 type PrepareInteractiveRebaseCommandOpts struct {
-	baseShaOrRoot              string
-	onto                       string
-	instruction                daemon.Instruction
-	overrideEditor             bool
-	keepCommitsThatBecomeEmpty bool
+	baseShaOrRoot  string
+	onto           string
+	instruction    daemon.Instruction
+	overrideEditor bool
+	// Requires git 2.26; older versions always use the default
+	emptyCommits EmptyCommitsMode
+	// Add a Signed-off-by trailer to each rebuilt commit.
+	// startOrPrepareInteractiveRebase sets this from the git.rebase.signOff
+	// config. Git 2.34 can do this itself; with older versions, each rebuilt
+	// commit is amended to sign it off (see signoffExec).
+	signoff bool
+	// Move branches that point at rebased commits along with them, so that
+	// stacked branches stay stacked. This is also turned on by the
+	// git.rebase.updateRefs config. Requires git 2.38; with older versions the
+	// branches are left where they are.
+	updateRefs bool
+	// If set, git runs this as the sequence editor instead of lazygit, e.g. so
+	// that tests can edit the todo deterministically or so that a script can
+	// transform it. The daemon env vars are still set, so the command can hand
+	// over to lazygit if it wants to. Leave empty to get the normal behavior.
+	sequenceEditorOverride string
+	// If set, git runs this after each commit that the rebase creates, and
+	// stops the rebase if it fails (git's --exec)
+	exec string
+	// Falls back to the git.rebase.backend config. Since this is always an
+	// interactive rebase, the apply backend can't be used (see
+	// checkInteractiveBackend).
+	backend RebaseBackend
+	// If set, git (and the daemon it runs as its sequence editor) is killed when
+	// the context is done, and runInteractiveRebase aborts the rebase.
+	ctx context.Context
+	// Passed to the merge strategy with -X, e.g. "ignore-all-space". Git
+	// remembers them for the rest of the rebase, so they also apply after a
+	// continue.
+	strategyOptions []string
+	// If set, the rebase runs in the worktree at this path rather than in the
+	// current one
+	worktreeDir string
+	// How git cleans up the messages that it gets from the editor (git's
+	// commit.cleanup config), e.g. "whitespace" to keep lines that start with
+	// the comment char. Leave empty for git's default, which strips them.
+	commitCleanup string
+	// Give every rebuilt commit the current time as its author date (git's
+	// --ignore-date). Requires git 2.29; check with checkDateOpts first.
+	ignoreDate bool
+	// Give every rebuilt commit its author date as its committer date (git's
+	// --committer-date-is-author-date). Requires git 2.29; check with
+	// checkDateOpts first.
+	committerDateIsAuthorDate bool
+	// Point a ref at the tip of the checked-out branch just before the rebase
+	// starts (see createPreRebaseRef). This is also turned on by the
+	// git.rebase.preRebaseRefs config. Only rebases that we start ourselves
+	// get the ref, not the ones whose command is handed back to the caller.
+	preRebaseRef bool
+	// If set, this is called with each step of the rebase as git gets to it
+	// (see rebaseProgressWriter), from the goroutine that reads git's output
+	onProgress func(RebaseProgress)
+	// Set by startInteractiveRebase, which runs the rebase itself, so that it
+	// can continue it one commit at a time to preserve the committer dates (see
+	// runPreservingCommitterDates). Rebases whose command is handed back to
+	// the caller, e.g. to run it with the user's editor, don't preserve them.
+	runByUs bool
+	// The hooks directory that git runs the rebase with, so that we get to
+	// keep its result (see installRebaseResultHook); "" for the user's own
+	hooksPath string
 }
 
-// PrepareInteractiveRebaseCommand returns the cmd for an interactive rebase
-// we tell git to run lazygit to edit the todo list, and we pass the client
-// lazygit a todo string to write to the todo file
-func (self *RebaseCommands) PrepareInteractiveRebaseCommand(opts PrepareInteractiveRebaseCommandOpts) oscommands.ICmdObj {
-	ex := oscommands.GetLazygitPath()
+// Before git 2.34, an interactive rebase can't sign off the commits that it
+// rebuilds, so we amend each of them instead. Git doesn't add the trailer if
+// the message already ends with it, so commits that are already signed off
+// (e.g. because we amended them with --signoff while the rebase was stopped at
+// them) don't get it twice. The amends aren't in git's rewritten-list, so its
+// entries point at the commits before they were signed off.
+const signoffExec = "git commit --amend --no-edit --allow-empty --no-verify --signoff"
 
-	cmdArgs := NewGitCmd("rebase").
-		Arg("--interactive").
-		Arg("--autostash").
-		Arg("--keep-empty").
-		ArgIf(opts.keepCommitsThatBecomeEmpty && self.version.IsAtLeast(2, 26, 0), "--empty=keep").
+// Interactive rebases can only use the merge backend. Asking for the apply
+// backend in the opts is a mistake, so we refuse. The git.rebase.backend
+// config is meant for plain rebases, so when it asks for the apply backend we
+// go ahead with the merge backend, and say so in the command log so that the
+// user isn't left wondering which one was used.
+func (self *RebaseCommands) checkInteractiveBackend(opts PrepareInteractiveRebaseCommandOpts) error {
+	if opts.backend == RebaseBackendApply {
+		return errors.New(self.Tr.InteractiveRebaseNeedsMergeBackend)
+	}
+
+	if opts.backend == RebaseBackendDefault && RebaseBackend(self.UserConfig.Git.Rebase.Backend) == RebaseBackendApply {
+		self.os.LogCommand(self.Tr.Log.MergeBackendInstead, false)
+	}
+
+	return nil
+}
+
+// Returns an error if the date opts contradict each other or need a newer git
+// than we have. Git itself accepts both date options at once, but only one of
+// them can win, so we refuse rather than guess which one was meant.
+func (self *RebaseCommands) checkDateOpts(opts PrepareInteractiveRebaseCommandOpts) error {
+	if opts.ignoreDate && opts.committerDateIsAuthorDate {
+		return errors.New("cannot both ignore the author dates and use them as committer dates")
+	}
+
+	if (opts.ignoreDate || opts.committerDateIsAuthorDate) && !self.version.IsAtLeast(2, 29, 0) {
+		return errors.New("changing the dates of rebased commits requires git 2.29 or later")
+	}
+
+	return nil
+}
+
+// PrepareInteractiveRebaseCommand returns the cmd for an interactive rebase
+// we tell git to run lazygit to edit the todo list, and we pass the client
+// lazygit a todo string to write to the todo file
+func (self *RebaseCommands) PrepareInteractiveRebaseCommand(opts PrepareInteractiveRebaseCommandOpts) oscommands.ICmdObj {
+	ex := oscommands.GetLazygitPath()
+
+	updateRefs := opts.updateRefs || self.UserConfig.Git.Rebase.UpdateRefs
+	if updateRefs && !self.version.IsAtLeast(2, 38, 0) {
+		self.Log.Warn("Not updating refs during the rebase because git 2.38 or later is required")
+		updateRefs = false
+	}
+
+	backend := opts.backend
+	if backend == RebaseBackendDefault {
+		backend = RebaseBackend(self.UserConfig.Git.Rebase.Backend)
+	}
+	// checkInteractiveBackend has told the user about this
+	if backend == RebaseBackendApply {
+		backend = RebaseBackendDefault
+	}
+
+	conflictStyle := self.conflictStyle()
+	cmdArgs := NewGitCmd("rebase").
+		ConfigIf(opts.hooksPath != "", "core.hooksPath="+opts.hooksPath).
+		ConfigIf(opts.commitCleanup != "", "commit.cleanup="+opts.commitCleanup).
+		ConfigIf(conflictStyle != "", "merge.conflictStyle="+conflictStyle).
+		Arg("--interactive").
+		ArgIf(backend == RebaseBackendMerge, "--merge").
+		ArgIfElse(self.UserConfig.Git.Rebase.AutoStash, "--autostash", "--no-autostash").
+		Arg("--keep-empty").
+		ArgIf(opts.emptyCommits != EmptyCommitsDefault && self.version.IsAtLeast(2, 26, 0), "--empty="+string(opts.emptyCommits)).
 		Arg("--no-autosquash").
 		ArgIf(self.version.IsAtLeast(2, 22, 0), "--rebase-merges").
+		ArgIf(opts.signoff && self.version.IsAtLeast(2, 34, 0), "--signoff").
+		ArgIf(opts.signoff && !self.version.IsAtLeast(2, 34, 0), "--exec", signoffExec).
+		ArgIf(updateRefs, "--update-refs").
+		ArgIf(opts.ignoreDate, "--ignore-date").
+		ArgIf(opts.committerDateIsAuthorDate, "--committer-date-is-author-date").
+		ArgIf(opts.exec != "", "--exec", opts.exec).
+		Arg(lo.Map(opts.strategyOptions, func(option string, _ int) string { return "--strategy-option=" + option })...).
 		ArgIf(opts.onto != "", "--onto", opts.onto).
 		Arg(opts.baseShaOrRoot).
+		DirIf(opts.worktreeDir != "", opts.worktreeDir).
 		ToArgv()
 
 	debug := "FALSE"
@@ -236,13 +999,34 @@ func (self *RebaseCommands) PrepareInteractiveRebaseCommand(opts PrepareInteract
 	self.Log.WithField("command", cmdArgs).Debug("RunCommand")
 
 	cmdObj := self.cmd.New(cmdArgs)
+	// A context that can never be done (e.g. context.Background()) needs no
+	// special handling
+	if opts.ctx != nil && opts.ctx.Done() != nil {
+		cmdObj.WithContext(opts.ctx)
+	}
 
 	gitSequenceEditor := ex
 
-	if opts.instruction != nil {
-		cmdObj.AddEnvVars(daemon.ToEnvVars(opts.instruction)...)
-	} else {
+	if opts.instruction == nil {
 		gitSequenceEditor = "true"
+	} else if !self.UserConfig.Git.Rebase.UseDaemon {
+		// startRebaseForInstruction carries out the instruction once git has
+		// stopped
+		gitSequenceEditor = stopAtStartSequenceEditor
+	} else {
+		cmdObj.AddEnvVars(daemon.ToEnvVars(opts.instruction)...)
+
+		if committerDates := self.committerDatesToPreserve(opts); len(committerDates) > 0 {
+			cmdObj.AddEnvVars(daemon.CommitterDatesToEnvVars(committerDates)...)
+		}
+	}
+
+	if opts.sequenceEditorOverride != "" {
+		gitSequenceEditor = opts.sequenceEditorOverride
+	}
+
+	if opts.onProgress != nil {
+		cmdObj.WithOutputWriter(self.newRebaseProgressWriter(opts))
 	}
 
 	cmdObj.AddEnvVars(
@@ -253,234 +1037,3582 @@ func (self *RebaseCommands) PrepareInteractiveRebaseCommand(opts PrepareInteract
 	)
 
 	if opts.overrideEditor {
-		cmdObj.AddEnvVars("GIT_EDITOR=" + ex)
+		cmdObj.AddEnvVars("GIT_EDITOR=" + self.skipEditor())
 	}
 
 	return cmdObj
 }
 
-// AmendTo amends the given commit with whatever files are staged
-func (self *RebaseCommands) AmendTo(commits []*models.Commit, commitIndex int) error {
-	commit := commits[commitIndex]
+// The editor to give git when it mustn't open the user's: lazygit, which
+// leaves the file as it is unless it has been told otherwise, or "true" if
+// lazygit can't be run by git (see git.rebase.useDaemon)
+func (self *RebaseCommands) skipEditor() string {
+	if !self.UserConfig.Git.Rebase.UseDaemon {
+		return "true"
+	}
 
-	if err := self.commit.CreateFixupCommit(commit.Sha); err != nil {
-		return err
+	return oscommands.GetLazygitPath()
+}
+
+// Returns the committer dates that the daemon should give the commits that the
+// rebase rebuilds, keyed by sha, or nil if they aren't to be preserved
+func (self *RebaseCommands) committerDatesToPreserve(opts PrepareInteractiveRebaseCommandOpts) map[string]string {
+	// Setting the dates is the whole point of the date opts, so don't put the
+	// old ones back
+	if !self.UserConfig.Git.Rebase.PreserveCommitterDates || opts.ignoreDate || opts.committerDateIsAuthorDate {
+		return nil
 	}
 
-	// Get the sha of the commit we just created
-	cmdArgs := NewGitCmd("rev-parse").Arg("--verify", "HEAD").ToArgv()
-	fixupSha, err := self.cmd.New(cmdArgs).RunWithOutput()
-	if err != nil {
-		return err
+	if !opts.runByUs {
+		self.Log.Warn("Not preserving committer dates because the rebase isn't run by lazygit")
+		return nil
 	}
 
-	return self.PrepareInteractiveRebaseCommand(PrepareInteractiveRebaseCommandOpts{
-		baseShaOrRoot:  getBaseShaOrRoot(commits, commitIndex+1),
-		overrideEditor: true,
-		instruction:    daemon.NewMoveFixupCommitDownInstruction(commit.Sha, fixupSha),
-	}).Run()
-}
+	committerDates, err := self.getCommitterDates(opts.baseShaOrRoot)
+	if err != nil {
+		self.Log.Warnf("Failed to get committer dates; they won't be preserved: %v", err)
+		return nil
+	}
 
-// EditRebaseTodo sets the action for a given rebase commit in the git-rebase-todo file
-func (self *RebaseCommands) EditRebaseTodo(commit *models.Commit, action todo.TodoCommand) error {
-	return utils.EditRebaseTodo(
-		filepath.Join(self.repoPaths.WorktreeGitDirPath(), "rebase-merge/git-rebase-todo"), commit.Sha, commit.Action, action, self.config.GetCoreCommentChar())
+	return committerDates
 }
 
-// MoveTodoDown moves a rebase todo item down by one position
-func (self *RebaseCommands) MoveTodoDown(commit *models.Commit) error {
-	fileName := filepath.Join(self.repoPaths.WorktreeGitDirPath(), "rebase-merge/git-rebase-todo")
-	return utils.MoveTodoDown(fileName, commit.Sha, commit.Action, self.config.GetCoreCommentChar())
-}
+// A sequence editor that puts a break before the todos that git generated, so
+// that the rebase stops before doing anything. Git runs it through the shell
+// with the path of the todo file as its argument.
+const stopAtStartSequenceEditor = `f() { { echo break; cat "$1"; } > "$1.lazygit" && mv "$1.lazygit" "$1"; }; f`
 
-// MoveTodoDown moves a rebase todo item down by one position
-func (self *RebaseCommands) MoveTodoUp(commit *models.Commit) error {
-	fileName := filepath.Join(self.repoPaths.WorktreeGitDirPath(), "rebase-merge/git-rebase-todo")
-	return utils.MoveTodoUp(fileName, commit.Sha, commit.Action, self.config.GetCoreCommentChar())
-}
+// Runs the command that PrepareInteractiveRebaseCommand returns. With
+// git.rebase.useDaemon turned off, git can't run lazygit to carry out the
+// instruction, so the rebase stops at a break before its first todo, we edit
+// the todo file ourselves, and then continue.
+func (self *RebaseCommands) startInteractiveRebase(opts PrepareInteractiveRebaseCommandOpts) error {
+	if self.returnsStartCommand(opts) {
+		self.markPreRebaseTip(opts)
+	}
 
-// SquashAllAboveFixupCommits squashes all fixup! commits above the given one
-func (self *RebaseCommands) SquashAllAboveFixupCommits(commit *models.Commit) error {
-	shaOrRoot := commit.Sha + "^"
-	if commit.IsFirstCommit() {
-		shaOrRoot = "--root"
+	opts.runByUs = true
+	opts.hooksPath = self.installRebaseResultHook()
+	if opts.hooksPath != "" && opts.worktreeDir == "" {
+		// What's left from the previous rebase no longer describes the
+		// commits, and if this one is aborted there's nothing to replace it
+		if err := os.RemoveAll(filepath.Join(self.repoPaths.WorktreeGitDirPath(), lastRebaseDirName)); err != nil {
+			return err
+		}
+	}
+	cmdObj, err := self.startOrPrepareInteractiveRebase(opts)
+	if err != nil {
+		return err
 	}
 
-	cmdArgs := NewGitCmd("rebase").
-		Arg("--interactive", "--rebase-merges", "--autostash", "--autosquash", shaOrRoot).
-		ToArgv()
+	if !self.UserConfig.Git.Rebase.PreserveCommitterDates {
+		return cmdObj.Run()
+	}
 
-	return self.runSkipEditorCommand(self.cmd.New(cmdArgs))
+	continueCmdObj := func() (oscommands.ICmdObj, error) {
+		cmdObj := self.continueRebaseCmdObj(opts)
+		// so that the daemon can still supply the messages of rewords
+		if opts.instruction != nil && self.UserConfig.Git.Rebase.UseDaemon {
+			cmdObj.AddEnvVars(daemon.ToEnvVars(opts.instruction)...)
+		}
+		return cmdObj, nil
+	}
+	return self.runPreservingCommitterDates(cmdObj, opts.worktreeDir, continueCmdObj, func(cmdObj oscommands.ICmdObj) error {
+		return cmdObj.Run()
+	})
 }
 
-// BeginInteractiveRebaseForCommit starts an interactive rebase to edit the current
-// commit and pick all others. After this you'll want to call `self.ContinueRebase()
-func (self *RebaseCommands) BeginInteractiveRebaseForCommit(
-	commits []*models.Commit, commitIndex int, keepCommitsThatBecomeEmpty bool,
+// With committer dates to preserve, the rebase stops at a break before each
+// commit that it rebuilds (see utils.PreserveCommitterDates). This runs
+// cmdObj, which starts or continues the rebase, and then keeps continuing it
+// with the command from continueCmdObj for as long as it stops at one of
+// those breaks, each time with GIT_COMMITTER_DATE set to the date of the
+// commit that git makes next, so that git gives the commit that date when it
+// makes it. Once the rebase stops for any other reason, the breaks are taken
+// out of the todo file again; continueWithCommitterDates puts them back.
+func (self *RebaseCommands) runPreservingCommitterDates(
+	cmdObj oscommands.ICmdObj,
+	worktreeDir string,
+	continueCmdObj func() (oscommands.ICmdObj, error),
+	run func(oscommands.ICmdObj) error,
 ) error {
-	if len(commits)-1 < commitIndex {
-		return errors.New("index outside of range of commits")
+	err := run(cmdObj)
+
+	todoPath, pathErr := self.rebaseTodoPath(worktreeDir)
+	if pathErr != nil {
+		return err
+	}
+	// The file is gone once the rebase is over
+	committerDates, datesErr := utils.ReadCommitterDates(filepath.Join(filepath.Dir(todoPath), utils.CommitterDatesFile))
+	if datesErr != nil {
+		return err
 	}
 
-	// we can make this GPG thing possible it just means we need to do this in two parts:
-	// one where we handle the possibility of a credential request, and the other
-	// where we continue the rebase
-	if self.config.UsingGpg() {
-		return errors.New(self.Tr.DisabledForGPG)
+	for err == nil {
+		date, ok := self.stoppedAtCommitterDateBreak(todoPath, committerDates)
+		if !ok {
+			break
+		}
+		cmdObj, err = continueCmdObj()
+		if err != nil {
+			break
+		}
+		err = run(cmdObj.AddEnvVars("GIT_COMMITTER_DATE=" + date))
 	}
 
-	changes := []daemon.ChangeTodoAction{{
-		Sha:       commits[commitIndex].Sha,
-		NewAction: todo.Edit,
-	}}
-	self.os.LogCommand(logTodoChanges(changes), false)
+	self.updateTodoFile(todoPath, func(todos []todo.Todo) []todo.Todo {
+		return utils.RemoveCommitterDateBreaks(todos, committerDates)
+	})
 
-	return self.PrepareInteractiveRebaseCommand(PrepareInteractiveRebaseCommandOpts{
-		baseShaOrRoot:              getBaseShaOrRoot(commits, commitIndex+1),
-		overrideEditor:             true,
-		keepCommitsThatBecomeEmpty: keepCommitsThatBecomeEmpty,
-		instruction:                daemon.NewChangeTodoActionsInstruction(changes),
-	}).Run()
+	return err
 }
 
-// RebaseBranch interactive rebases onto a branch
-func (self *RebaseCommands) RebaseBranch(branchName string) error {
-	return self.PrepareInteractiveRebaseCommand(PrepareInteractiveRebaseCommandOpts{baseShaOrRoot: branchName}).Run()
-}
+// Like runPreservingCommitterDates, for continuing (or skipping in) a rebase
+// that we started. It first puts the breaks back that
+// runPreservingCommitterDates took out when the rebase stopped, and gives the
+// commit that git stopped at, if any, its date.
+func (self *RebaseCommands) continueWithCommitterDates(
+	cmdObj oscommands.ICmdObj,
+	continueCmdObj func() (oscommands.ICmdObj, error),
+	run func(oscommands.ICmdObj) error,
+) error {
+	todoPath, _ := self.rebaseTodoPath("")
+	committerDates, err := utils.ReadCommitterDates(filepath.Join(filepath.Dir(todoPath), utils.CommitterDatesFile))
+	if err != nil {
+		return run(cmdObj)
+	}
 
-func (self *RebaseCommands) RebaseBranchFromBaseCommit(targetBranchName string, baseCommit string) error {
-	return self.PrepareInteractiveRebaseCommand(PrepareInteractiveRebaseCommandOpts{
-		baseShaOrRoot: baseCommit,
-		onto:          targetBranchName,
-	}).Run()
+	self.updateTodoFile(todoPath, func(todos []todo.Todo) []todo.Todo {
+		return utils.AddCommitterDateBreaks(todos, committerDates)
+	})
+	if date := self.committerDateOfStoppedCommit(todoPath, committerDates); date != "" {
+		cmdObj.AddEnvVars("GIT_COMMITTER_DATE=" + date)
+	}
+
+	return self.runPreservingCommitterDates(cmdObj, "", continueCmdObj, run)
 }
 
-func (self *RebaseCommands) GenericMergeOrRebaseActionCmdObj(commandType string, command string) oscommands.ICmdObj {
-	cmdArgs := NewGitCmd(commandType).Arg("--" + command).ToArgv()
+// Tells whether the rebase is stopped at one of the breaks that
+// utils.PreserveCommitterDates puts in, and if so, returns the date of the
+// commit that comes next
+func (self *RebaseCommands) stoppedAtCommitterDateBreak(todoPath string, committerDates map[string]string) (string, bool) {
+	commentChar := self.config.GetCoreCommentChar()
+	isTodo := func(t todo.Todo, _ int) bool { return t.Command != todo.Comment }
+	done, err := utils.ReadRebaseTodoFile(filepath.Join(filepath.Dir(todoPath), "done"), commentChar)
+	if err != nil {
+		return "", false
+	}
+	pending, err := utils.ReadRebaseTodoFile(todoPath, commentChar)
+	if err != nil {
+		return "", false
+	}
+	done = lo.Filter(done, isTodo)
+	pending = lo.Filter(pending, isTodo)
+	if len(done) == 0 || done[len(done)-1].Command != todo.Break || len(pending) == 0 {
+		return "", false
+	}
 
-	return self.cmd.New(cmdArgs)
+	date := utils.CommitterDateOf(pending[0], committerDates)
+	return date, date != ""
 }
 
-func (self *RebaseCommands) ContinueRebase() error {
-	return self.GenericMergeOrRebaseAction("rebase", "continue")
-}
+// Returns the date of the commit that the rebase is stopped at, e.g. because
+// of conflicts, so that git gives it that date when the rebase is continued.
+// If the rebase stopped at fixups or squashes, that's the date of the commit
+// they're folded into. Returns "" if git isn't stopped at a commit.
+func (self *RebaseCommands) committerDateOfStoppedCommit(todoPath string, committerDates map[string]string) string {
+	done, err := utils.ReadRebaseTodoFile(filepath.Join(filepath.Dir(todoPath), "done"), self.config.GetCoreCommentChar())
+	if err != nil {
+		return ""
+	}
 
-func (self *RebaseCommands) AbortRebase() error {
-	return self.GenericMergeOrRebaseAction("rebase", "abort")
+	for i := len(done) - 1; i >= 0; i-- {
+		switch done[i].Command {
+		case todo.Comment, todo.Fixup, todo.Squash:
+			continue
+		}
+		return utils.CommitterDateOf(done[i], committerDates)
+	}
+
+	return ""
 }
 
-// GenericMerge takes a commandType of "merge" or "rebase" and a command of "abort", "skip" or "continue"
-// By default we skip the editor in the case where a commit will be made
-func (self *RebaseCommands) GenericMergeOrRebaseAction(commandType string, command string) error {
-	err := self.runSkipEditorCommand(self.GenericMergeOrRebaseActionCmdObj(commandType, command))
+// Rewrites the todo file with what f makes of its todos. Failing to do so
+// only costs us the committer dates, so we just log it.
+func (self *RebaseCommands) updateTodoFile(todoPath string, f func([]todo.Todo) []todo.Todo) {
+	commentChar := self.config.GetCoreCommentChar()
+	todos, err := utils.ReadRebaseTodoFile(todoPath, commentChar)
 	if err != nil {
-		if !strings.Contains(err.Error(), "no rebase in progress") {
-			return err
+		if !os.IsNotExist(err) {
+			self.Log.Warnf("Failed to read the todo file: %v", err)
 		}
-		self.Log.Warn(err)
+		return
 	}
 
-	// sometimes we need to do a sequence of things in a rebase but the user needs to
-	// fix merge conflicts along the way. When this happens we queue up the next step
-	// so that after the next successful rebase continue we can continue from where we left off
-	if commandType == "rebase" && command == "continue" && self.onSuccessfulContinue != nil {
-		f := self.onSuccessfulContinue
-		self.onSuccessfulContinue = nil
-		return f()
+	if err := utils.WriteRebaseTodoFile(todoPath, f(todos), commentChar); err != nil {
+		self.Log.Warnf("Failed to write the todo file: %v", err)
 	}
-	if command == "abort" {
-		self.onSuccessfulContinue = nil
+}
+
+// Whether startOrPrepareInteractiveRebase returns the command that starts the rebase,
+// rather than starting the rebase itself and returning the command that
+// continues it
+func (self *RebaseCommands) returnsStartCommand(opts PrepareInteractiveRebaseCommandOpts) bool {
+	return self.UserConfig.Git.Rebase.UseDaemon || opts.instruction == nil || opts.sequenceEditorOverride != ""
+}
+
+// Calls createPreRebaseRef if opts or the config ask for it. This must only be
+// called right before the rebase is started, so that we don't leave refs
+// behind for rebases that never ran. Not getting the ref doesn't stop the
+// rebase, so we only log it.
+func (self *RebaseCommands) markPreRebaseTip(opts PrepareInteractiveRebaseCommandOpts) {
+	// In a temporary worktree HEAD is detached, and the branch is only moved
+	// once the rebase has succeeded anyway
+	if !(opts.preRebaseRef || self.UserConfig.Git.Rebase.PreRebaseRefs > 0) || opts.worktreeDir != "" {
+		return
+	}
+
+	if err := self.createPreRebaseRef(); err != nil {
+		self.Log.Warnf("Failed to create a ref marking the branch before the rebase: %v", err)
 	}
-	return nil
 }
 
-func (self *RebaseCommands) runSkipEditorCommand(cmdObj oscommands.ICmdObj) error {
-	instruction := daemon.NewExitImmediatelyInstruction()
-	lazyGitPath := oscommands.GetLazygitPath()
-	return cmdObj.
-		AddEnvVars(
-			"GIT_EDITOR="+lazyGitPath,
-			"GIT_SEQUENCE_EDITOR="+lazyGitPath,
-			"EDITOR="+lazyGitPath,
-			"VISUAL="+lazyGitPath,
-		).
-		AddEnvVars(daemon.ToEnvVars(instruction)...).
-		Run()
+// Returns the command that does the rebase that opts describe, so that it can
+// be run as a subprocess to let git open the user's editor. With
+// git.rebase.useDaemon turned off, git can't run lazygit to carry out the
+// instruction, so this already starts the rebase (see
+// startRebaseForInstruction), and the returned command continues it.
+func (self *RebaseCommands) startOrPrepareInteractiveRebase(opts PrepareInteractiveRebaseCommandOpts) (oscommands.ICmdObj, error) {
+	if err := self.checkInteractiveBackend(opts); err != nil {
+		return nil, err
+	}
+
+	if self.UserConfig.Git.Rebase.SignOff {
+		opts.signoff = true
+	}
+
+	if self.returnsStartCommand(opts) {
+		return self.PrepareInteractiveRebaseCommand(opts), nil
+	}
+
+	return self.startRebaseForInstruction(opts)
 }
 
-// DiscardOldFileChanges discards changes to a file from an old commit
-func (self *RebaseCommands) DiscardOldFileChanges(commits []*models.Commit, commitIndex int, fileName string) error {
-	if err := self.BeginInteractiveRebaseForCommit(commits, commitIndex, false); err != nil {
-		return err
+// Starts the rebase stopped at a break before its first todo, carries out the
+// instruction by editing the todo file ourselves, and returns the command that
+// continues the rebase. If the todo file can't be edited, the rebase is
+// aborted, so that the user isn't left in a rebase that they didn't ask for.
+func (self *RebaseCommands) startRebaseForInstruction(opts PrepareInteractiveRebaseCommandOpts) (oscommands.ICmdObj, error) {
+	if daemon.SuppliesMessages(opts.instruction) {
+		return nil, errors.New(self.Tr.RewordWithMessageNeedsDaemon)
 	}
 
-	// check if file exists in previous commit (this command returns an error if the file doesn't exist)
-	cmdArgs := NewGitCmd("cat-file").Arg("-e", "HEAD^:"+fileName).ToArgv()
+	self.markPreRebaseTip(opts)
+	if err := self.PrepareInteractiveRebaseCommand(opts).Run(); err != nil {
+		return nil, err
+	}
 
-	if err := self.cmd.New(cmdArgs).Run(); err != nil {
-		if err := self.os.Remove(fileName); err != nil {
-			return err
-		}
-		if err := self.workingTree.StageFile(fileName); err != nil {
-			return err
+	if err := self.editTodoForInstruction(opts); err != nil {
+		abortArgs := NewGitCmd("rebase").Arg("--abort").DirIf(opts.worktreeDir != "", opts.worktreeDir).ToArgv()
+		if abortErr := self.cmd.New(abortArgs).Run(); abortErr != nil {
+			self.Log.Warnf("Failed to abort the rebase: %v", abortErr)
 		}
-	} else if err := self.workingTree.CheckoutFile("HEAD^", fileName); err != nil {
-		return err
+		return nil, err
 	}
 
-	// amend the commit
-	err := self.commit.AmendHead()
+	return self.continueRebaseCmdObj(opts), nil
+}
+
+// Returns the command that continues the rebase that opts describe once it
+// has stopped
+func (self *RebaseCommands) continueRebaseCmdObj(opts PrepareInteractiveRebaseCommandOpts) oscommands.ICmdObj {
+	conflictStyle := self.conflictStyle()
+	cmdArgs := NewGitCmd("rebase").
+		ConfigIf(opts.hooksPath != "", "core.hooksPath="+opts.hooksPath).
+		ConfigIf(conflictStyle != "", "merge.conflictStyle="+conflictStyle).
+		Arg("--continue").
+		DirIf(opts.worktreeDir != "", opts.worktreeDir).
+		ToArgv()
+	cmdObj := self.cmd.New(cmdArgs)
+	if opts.ctx != nil && opts.ctx.Done() != nil {
+		cmdObj.WithContext(opts.ctx)
+	}
+	cmdObj.AddEnvVars(
+		"LANG=en_US.UTF-8",   // Force using EN as language
+		"LC_ALL=en_US.UTF-8", // Force using EN as language
+	)
+	if opts.overrideEditor {
+		cmdObj.AddEnvVars("GIT_EDITOR=" + self.skipEditor())
+	}
+	if opts.onProgress != nil {
+		cmdObj.WithOutputWriter(self.newRebaseProgressWriter(opts))
+	}
+
+	return cmdObj
+}
+
+func (self *RebaseCommands) editTodoForInstruction(opts PrepareInteractiveRebaseCommandOpts) error {
+	todoPath, err := self.rebaseTodoPath(opts.worktreeDir)
 	if err != nil {
 		return err
 	}
 
-	// continue
-	return self.ContinueRebase()
+	return daemon.EditTodoFile(self.Common, opts.instruction, todoPath, self.committerDatesToPreserve(opts))
 }
 
-// CherryPickCommits begins an interactive rebase with the given shas being cherry picked onto HEAD
-func (self *RebaseCommands) CherryPickCommits(commits []*models.Commit) error {
-	commitLines := lo.Map(commits, func(commit *models.Commit, _ int) string {
-		return fmt.Sprintf("%s %s", utils.ShortSha(commit.Sha), commit.Name)
-	})
-	msg := utils.ResolvePlaceholderString(
-		self.Tr.Log.CherryPickCommits,
-		map[string]string{
-			"commitLines": strings.Join(commitLines, "\n"),
-		},
-	)
-	self.os.LogCommand(msg, false)
+// The todo file of the rebase in progress in the given worktree, or in the
+// current one if worktreeDir is empty
+func (self *RebaseCommands) rebaseTodoPath(worktreeDir string) (string, error) {
+	if worktreeDir == "" {
+		return filepath.Join(self.repoPaths.WorktreeGitDirPath(), "rebase-merge", "git-rebase-todo"), nil
+	}
 
-	return self.PrepareInteractiveRebaseCommand(PrepareInteractiveRebaseCommandOpts{
-		baseShaOrRoot: "HEAD",
-		instruction:   daemon.NewCherryPickCommitsInstruction(commits),
-	}).Run()
+	cmdArgs := NewGitCmd("rev-parse").Arg("--absolute-git-dir").Dir(worktreeDir).ToArgv()
+	gitDir, err := self.cmd.New(cmdArgs).DontLog().RunWithOutput()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(strings.TrimSpace(gitDir), "rebase-merge", "git-rebase-todo"), nil
 }
 
-// CherryPickCommitsDuringRebase simply prepends the given commits to the existing git-rebase-todo file
-func (self *RebaseCommands) CherryPickCommitsDuringRebase(commits []*models.Commit) error {
-	todoLines := lo.Map(commits, func(commit *models.Commit, _ int) daemon.TodoLine {
-		return daemon.TodoLine{
-			Action: "pick",
-			Commit: commit,
+// Runs the rebase that opts describe. If git.rebase.timeoutSeconds is set and
+// git takes longer than that (e.g. because a hook hangs, or an editor that
+// never closes), git is killed and the rebase aborted like when opts.ctx is
+// cancelled, which puts HEAD and any autostashed changes back the way they
+// were.
+func (self *RebaseCommands) runInteractiveRebase(opts PrepareInteractiveRebaseCommandOpts) error {
+	timeoutSeconds := self.UserConfig.Git.Rebase.TimeoutSeconds
+	if timeoutSeconds > 0 {
+		parent := opts.ctx
+		if parent == nil {
+			parent = context.Background()
 		}
-	})
+		ctx, cancel := context.WithTimeout(parent, time.Duration(timeoutSeconds)*time.Second)
+		defer cancel()
+		opts.ctx = ctx
+	}
 
-	todo := daemon.TodoLinesToString(todoLines)
-	filePath := filepath.Join(self.repoPaths.worktreeGitDirPath, "rebase-merge/git-rebase-todo")
-	return utils.PrependStrToTodoFile(filePath, []byte(todo))
+	// A context that can never be done (e.g. context.Background()) needs no
+	// special handling
+	if opts.ctx == nil || opts.ctx.Done() == nil {
+		err := self.startInteractiveRebase(opts)
+		if err == nil {
+			self.noteRebuiltCommits()
+		}
+		return err
+	}
+
+	ctx := opts.ctx
+	startedAt := time.Now()
+	gitCtx, stopWatching := self.watchIndexLock(ctx)
+	opts.ctx = gitCtx
+	err := self.startInteractiveRebase(opts)
+	lockAtKill := stopWatching()
+	if err == nil {
+		self.noteRebuiltCommits()
+	}
+
+	err = self.abortIfCancelled(ctx, err, startedAt, lockAtKill)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return errors.Errorf(self.Tr.RebaseTimedOut, timeoutSeconds)
+	}
+	return err
 }
 
-// we can't start an interactive rebase from the first commit without passing the
-// '--root' arg
-func getBaseShaOrRoot(commits []*models.Commit, index int) string {
-	// We assume that the commits slice contains the initial commit of the repo.
-	// Technically this assumption could prove false, but it's unlikely you'll
-	// be starting a rebase from 300 commits ago (which is the original commit limit
-	// at time of writing)
-	if index < len(commits) {
-		return commits[index].Sha
-	} else {
-		return "--root"
+// Points refs/lazygit/pre-rebase/<branch>/<timestamp> at the tip of the
+// checked-out branch, so that the branch can be reset to where it was before
+// the rebase even after lazygit has been restarted (see UndoLastRebase). If
+// git.rebase.preRebaseRefs is set, only that many of the branch's refs are
+// kept, newest first. With a detached HEAD there's no branch to mark, so we do
+// nothing.
+func (self *RebaseCommands) createPreRebaseRef() error {
+	branchName, ok := self.checkedOutBranchName()
+	if !ok {
+		return nil
 	}
+
+	// The timestamp has nanoseconds after the seconds, so that two rebases in
+	// the same second get a ref each. Seconds always have ten digits, so refs
+	// made with seconds only still sort in the right place. The empty old
+	// value makes git refuse to overwrite an existing ref, so an earlier
+	// recovery point is never lost.
+	now := time.Now()
+	refName := preRebaseRefPrefix(branchName) + fmt.Sprintf("%d.%09d", now.Unix(), now.Nanosecond())
+	updateRefArgs := NewGitCmd("update-ref").Arg("-m", "lazygit: before rebase", refName, "HEAD", "").ToArgv()
+	if err := self.cmd.New(updateRefArgs).Run(); err != nil {
+		return err
+	}
+
+	limit := self.UserConfig.Git.Rebase.PreRebaseRefs
+	if limit <= 0 {
+		return nil
+	}
+
+	refNames, err := self.preRebaseRefs(branchName)
+	if err != nil {
+		return err
+	}
+	for i := limit; i < len(refNames); i++ {
+		deleteArgs := NewGitCmd("update-ref").Arg("-d", refNames[i]).ToArgv()
+		if err := self.cmd.New(deleteArgs).Run(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// UndoLastRebase resets the checked-out branch to the newest of the refs that
+// createPreRebaseRef made for it, and deletes that ref, so that calling it
+// again goes back another rebase. Uncommitted changes are kept (git's reset
+// --keep); if they touch files that differ, git refuses and nothing changes.
+func (self *RebaseCommands) UndoLastRebase() error {
+	if self.status.WorkingTreeState() != enums.REBASE_MODE_NONE {
+		return errors.New(self.Tr.UndoLastRebaseDuringRebase)
+	}
+
+	branchName, ok := self.checkedOutBranchName()
+	if !ok {
+		return errors.New(self.Tr.UndoLastRebaseDetachedHead)
+	}
+
+	refNames, err := self.preRebaseRefs(branchName)
+	if err != nil {
+		return err
+	}
+	if len(refNames) == 0 {
+		return errors.Errorf(self.Tr.NoPreRebaseRef, branchName)
+	}
+
+	if err := self.commit.ResetToCommit(refNames[0], "keep", nil); err != nil {
+		return err
+	}
+
+	deleteArgs := NewGitCmd("update-ref").Arg("-d", refNames[0]).ToArgv()
+	return self.cmd.New(deleteArgs).Run()
+}
+
+// The name of the checked-out branch, or false if HEAD is detached
+func (self *RebaseCommands) checkedOutBranchName() (string, bool) {
+	cmdArgs := NewGitCmd("symbolic-ref").Arg("--quiet", "--short", "HEAD").ToArgv()
+	output, err := self.cmd.New(cmdArgs).DontLog().RunWithOutput()
+	if err != nil {
+		return "", false
+	}
+
+	return strings.TrimSpace(output), true
+}
+
+func preRebaseRefPrefix(branchName string) string {
+	return "refs/lazygit/pre-rebase/" + branchName + "/"
+}
+
+// The refs that createPreRebaseRef made for the given branch, newest first
+func (self *RebaseCommands) preRebaseRefs(branchName string) ([]string, error) {
+	prefix := preRebaseRefPrefix(branchName)
+	// The timestamps all start with the same number of digits, so sorting by
+	// name puts the newest first
+	cmdArgs := NewGitCmd("for-each-ref").Arg("--sort=-refname", "--format=%(refname)", prefix).ToArgv()
+	output, err := self.cmd.New(cmdArgs).DontLog().RunWithOutput()
+	if err != nil {
+		return nil, err
+	}
+
+	// A branch whose name starts with this one's followed by a slash would have
+	// its refs under the same prefix
+	return lo.Filter(utils.SplitLines(output), func(ref string, _ int) bool {
+		return !strings.Contains(strings.TrimPrefix(ref, prefix), "/")
+	}), nil
+}
+
+// Returns a map from sha to the raw committer date (e.g. '1700000000 +0100')
+// of each commit between the given base and HEAD
+func (self *RebaseCommands) getCommitterDates(baseShaOrRoot string) (map[string]string, error) {
+	revisionRange := "HEAD"
+	if baseShaOrRoot != "--root" {
+		revisionRange = baseShaOrRoot + "..HEAD"
+	}
+
+	cmdArgs := NewGitCmd("log").
+		Arg("--format=%H %cd", "--date=raw", revisionRange).
+		ToArgv()
+
+	output, err := self.cmd.New(cmdArgs).DontLog().RunWithOutput()
+	if err != nil {
+		return nil, err
+	}
+
+	committerDates := map[string]string{}
+	for _, line := range utils.SplitLines(output) {
+		sha, date, found := strings.Cut(line, " ")
+		if found {
+			committerDates[sha] = date
+		}
+	}
+
+	return committerDates, nil
+}
+
+// AmendTo amends the given commit with whatever files are staged
+func (self *RebaseCommands) AmendTo(commits []*models.Commit, commitIndex int) error {
+	commit := commits[commitIndex]
+
+	if err := self.commit.CreateFixupCommit(commit.Sha); err != nil {
+		return err
+	}
+
+	// Get the sha of the commit we just created
+	cmdArgs := NewGitCmd("rev-parse").Arg("--verify", "HEAD").ToArgv()
+	fixupSha, err := self.cmd.New(cmdArgs).RunWithOutput()
+	if err != nil {
+		return err
+	}
+
+	return self.runInteractiveRebase(PrepareInteractiveRebaseCommandOpts{
+		baseShaOrRoot:  getBaseShaOrRoot(commits, commitIndex+1),
+		overrideEditor: true,
+		instruction:    daemon.NewMoveFixupCommitDownInstruction(commit.Sha, fixupSha),
+	})
+}
+
+// AmendToByIndex amends the commit at the given index with whatever files are
+// staged. If that's the head commit we amend it directly, which is much quicker
+// than creating a fixup commit and squashing it in with a rebase.
+func (self *RebaseCommands) AmendToByIndex(commits []*models.Commit, index int) error {
+	if index < 0 || index >= len(commits) {
+		return errors.New("index outside of range of commits")
+	}
+
+	if models.IsHeadCommit(commits, index) {
+		return self.commit.AmendHead()
+	}
+
+	return self.AmendTo(commits, index)
+}
+
+// SquashStagedInto squashes the staged changes into the given commit and gives
+// it the given message, in a single rebase. Like git commit --fixup=amend:, we
+// create an amend! commit and squash it in with "fixup -C". Git only supports
+// that from 2.32 on, so before that we create a plain fixup commit and reword
+// the target while squashing it in.
+func (self *RebaseCommands) SquashStagedInto(commit *models.Commit, message string) error {
+	baseShaOrRoot := "--root"
+	if !commit.IsFirstCommit() {
+		baseShaOrRoot = commit.Parents[0]
+	}
+
+	if !self.version.IsAtLeast(2, 32, 0) {
+		return self.squashStagedIntoWithReword(commit, message, baseShaOrRoot)
+	}
+
+	// git commit won't take a message together with --fixup=amend:, so we
+	// write the amend! message ourselves; git drops its first paragraph when
+	// squashing it in
+	cmdArgs := NewGitCmd("commit").Arg("-m", "amend! "+commit.Name, "-m", message).ToArgv()
+	if err := self.cmd.New(cmdArgs).Run(); err != nil {
+		return err
+	}
+
+	amendSha, err := self.headSha()
+	if err != nil {
+		return err
+	}
+
+	return self.runInteractiveRebase(PrepareInteractiveRebaseCommandOpts{
+		baseShaOrRoot:  baseShaOrRoot,
+		overrideEditor: true,
+		instruction:    daemon.NewMoveAmendCommitDownInstruction(commit.Sha, amendSha),
+	})
+}
+
+func (self *RebaseCommands) squashStagedIntoWithReword(commit *models.Commit, message string, baseShaOrRoot string) error {
+	if err := self.commit.CreateFixupCommit(commit.Sha); err != nil {
+		return err
+	}
+
+	fixupSha, err := self.headSha()
+	if err != nil {
+		return err
+	}
+
+	changes := []daemon.ChangeTodoAction{
+		{Sha: commit.Sha, NewAction: todo.Reword, NewMessage: message},
+		{Sha: fixupSha, NewAction: todo.Fixup, MoveAfterSha: commit.Sha},
+	}
+	self.os.LogCommand(logTodoChanges(changes), false)
+
+	return self.runInteractiveRebase(PrepareInteractiveRebaseCommandOpts{
+		baseShaOrRoot:  baseShaOrRoot,
+		overrideEditor: true,
+		instruction:    daemon.NewChangeTodoActionsInstruction(changes),
+	})
+}
+
+func (self *RebaseCommands) headSha() (string, error) {
+	cmdArgs := NewGitCmd("rev-parse").Arg("--verify", "HEAD").ToArgv()
+	output, err := self.cmd.New(cmdArgs).RunWithOutput()
+	return strings.TrimSpace(output), err
+}
+
+// InsertCommitBefore creates a new commit with the given message out of the
+// staged changes (or out of all changes, if stageAll is set) and moves it into
+// history directly before the commit at the given index. If there is nothing
+// to commit we return an error, unless allowEmpty is set.
+func (self *RebaseCommands) InsertCommitBefore(
+	commits []*models.Commit, index int, message string, stageAll bool, allowEmpty bool,
+) error {
+	if index < 0 || index >= len(commits) {
+		return errors.New("index outside of range of commits")
+	}
+
+	if self.config.UsingGpg() {
+		return errors.New(self.Tr.DisabledForGPG)
+	}
+
+	// Check before staging anything, so that a rejected call leaves the index
+	// as it was. Staging everything would pick up untracked files too, so
+	// then any change at all will do.
+	if !allowEmpty {
+		cmdArgs := NewGitCmd("diff").Arg("--cached", "--name-only").ToArgv()
+		errMessage := "there are no staged changes to create the commit from"
+		if stageAll {
+			cmdArgs = NewGitCmd("status").Arg("--porcelain").ToArgv()
+			errMessage = "there are no changes to create the commit from"
+		}
+		changes, err := self.cmd.New(cmdArgs).DontLog().RunWithOutput()
+		if err != nil {
+			return err
+		}
+		if strings.TrimSpace(changes) == "" {
+			return errors.New(errMessage)
+		}
+	}
+
+	if stageAll {
+		if err := self.workingTree.StageAll(); err != nil {
+			return err
+		}
+	}
+
+	commitArgs := NewGitCmd("commit").
+		ArgIf(allowEmpty, "--allow-empty").
+		ArgIf(self.commit.signoffFlag() != "", self.commit.signoffFlag()).
+		Arg("-m", message).
+		ToArgv()
+	if err := self.cmd.New(commitArgs).Run(); err != nil {
+		return err
+	}
+
+	// Get the sha of the commit we just created
+	cmdArgs := NewGitCmd("rev-parse").Arg("--verify", "HEAD").ToArgv()
+	newSha, err := self.cmd.New(cmdArgs).RunWithOutput()
+	if err != nil {
+		return err
+	}
+
+	changes := []daemon.ChangeTodoAction{{
+		Sha:           strings.TrimSpace(newSha),
+		NewAction:     todo.Pick,
+		MoveBeforeSha: commits[index].Sha,
+	}}
+
+	return self.runInteractiveRebase(PrepareInteractiveRebaseCommandOpts{
+		baseShaOrRoot:  getBaseShaOrRoot(commits, index+1),
+		overrideEditor: true,
+		instruction:    daemon.NewChangeTodoActionsInstruction(changes),
+	})
+}
+
+// ReadRebaseTodos returns the todos of the current rebase, including the
+// commented-out lines, so that they survive being written back with
+// WriteRebaseTodos
+func (self *RebaseCommands) ReadRebaseTodos() ([]todo.Todo, error) {
+	fileName := filepath.Join(self.repoPaths.WorktreeGitDirPath(), "rebase-merge/git-rebase-todo")
+	return utils.ReadRebaseTodoFile(fileName, self.config.GetCoreCommentChar())
+}
+
+// WriteRebaseTodos replaces the todos of the current rebase
+func (self *RebaseCommands) WriteRebaseTodos(todos []todo.Todo) error {
+	fileName := filepath.Join(self.repoPaths.WorktreeGitDirPath(), "rebase-merge/git-rebase-todo")
+	return utils.WriteRebaseTodoFile(fileName, todos, self.config.GetCoreCommentChar())
+}
+
+// ToggleTodoLineEnabled comments out the todo for the given sha so that git
+// skips it, or comments it back in if it was commented out before
+func (self *RebaseCommands) ToggleTodoLineEnabled(sha string) error {
+	fileName := filepath.Join(self.repoPaths.WorktreeGitDirPath(), "rebase-merge/git-rebase-todo")
+	return utils.ToggleTodoLineEnabled(fileName, sha, self.config.GetCoreCommentChar())
+}
+
+// ConvertTodoLine turns the todo for the given sha into a different kind of
+// todo, using git's rebase-merges grammar: newAction is e.g. "pick", "label",
+// "reset" or "merge", and arg is the label for the last three. Labels that
+// the rebase has already created count as well as those further up the todo.
+func (self *RebaseCommands) ConvertTodoLine(sha string, newAction string, arg string) error {
+	doneTodos, err := self.RebaseDoneSteps()
+	if err != nil {
+		return err
+	}
+
+	fileName := filepath.Join(self.repoPaths.WorktreeGitDirPath(), "rebase-merge/git-rebase-todo")
+	return utils.ConvertTodoLine(fileName, doneTodos, sha, newAction, arg, self.config.GetCoreCommentChar())
+}
+
+// EditRebaseTodo sets the action for a given rebase commit in the git-rebase-todo file
+func (self *RebaseCommands) EditRebaseTodo(commit *models.Commit, action todo.TodoCommand) error {
+	return utils.EditRebaseTodo(
+		filepath.Join(self.repoPaths.WorktreeGitDirPath(), "rebase-merge/git-rebase-todo"), commit.Sha, commit.Action, action, self.config.GetCoreCommentChar())
+}
+
+// MoveTodoDown moves a rebase todo item down by one position
+func (self *RebaseCommands) MoveTodoDown(commit *models.Commit) error {
+	fileName := filepath.Join(self.repoPaths.WorktreeGitDirPath(), "rebase-merge/git-rebase-todo")
+	return utils.MoveTodoDown(fileName, commit.Sha, commit.Action, self.config.GetCoreCommentChar())
+}
+
+// MoveTodoDown moves a rebase todo item down by one position
+func (self *RebaseCommands) MoveTodoUp(commit *models.Commit) error {
+	fileName := filepath.Join(self.repoPaths.WorktreeGitDirPath(), "rebase-merge/git-rebase-todo")
+	return utils.MoveTodoUp(fileName, commit.Sha, commit.Action, self.config.GetCoreCommentChar())
+}
+
+// SquashAllAboveFixupCommits squashes all fixup! commits above the given one
+func (self *RebaseCommands) SquashAllAboveFixupCommits(commit *models.Commit) error {
+	shaOrRoot := commit.Sha + "^"
+	if commit.IsFirstCommit() {
+		shaOrRoot = "--root"
+	}
+
+	return self.autosquash(shaOrRoot)
+}
+
+func (self *RebaseCommands) autosquash(shaOrRoot string) error {
+	cmdArgs := NewGitCmd("rebase").
+		Arg("--interactive", "--rebase-merges").
+		ArgIfElse(self.UserConfig.Git.Rebase.AutoStash, "--autostash", "--no-autostash").
+		Arg("--autosquash", shaOrRoot).
+		ToArgv()
+
+	return self.runSkipEditorCommand(self.cmd.New(cmdArgs))
+}
+
+// CollapseFixups squashes the fixup! commits above the given commit into it,
+// like SquashAllAboveFixupCommits, and gives the result the given message
+// instead of the commit's old one. We do both in the same rebase by adding an
+// amend! commit with the new message before autosquashing; it names the
+// target by sha so that it can't end up in another commit with the same
+// subject.
+func (self *RebaseCommands) CollapseFixups(targetSha string, message string) error {
+	if !self.version.IsAtLeast(2, 32, 0) {
+		return errors.New(self.Tr.RewordFixupNeedsNewerGit)
+	}
+
+	if err := self.checkCanRebaseWithoutAutostash(); err != nil {
+		return err
+	}
+
+	fullSha, err := self.ResolveSha(targetSha)
+	if err != nil {
+		return err
+	}
+
+	shaOrRoot := fullSha + "^"
+	if _, err := self.ResolveSha(shaOrRoot); err != nil {
+		shaOrRoot = "--root"
+	}
+
+	cmdArgs := NewGitCmd("commit").
+		Arg("--allow-empty", "--only", "-m", "amend! "+fullSha, "-m", message).
+		ToArgv()
+	if err := self.cmd.New(cmdArgs).Run(); err != nil {
+		return err
+	}
+
+	return self.autosquash(shaOrRoot)
+}
+
+// PreviewAutosquash tells which commit an autosquashing rebase of the given
+// commits (newest first) would squash each marker commit into, so that the
+// user can check before SquashAllAboveFixupCommits. The pairs are newest
+// marker first. Where several commits share the subject that a marker refers
+// to, we pick the oldest of them, as git does.
+func (self *RebaseCommands) PreviewAutosquash(commits []*models.Commit) ([]AutosquashPair, error) {
+	return self.autosquashPairs(commits, pickOldestFixupTarget)
+}
+
+// BeginInteractiveRebaseForCommit starts an interactive rebase to edit the current
+// commit and pick all others. After this you'll want to call `self.ContinueRebase()
+func (self *RebaseCommands) BeginInteractiveRebaseForCommit(
+	commits []*models.Commit, commitIndex int, keepCommitsThatBecomeEmpty bool,
+) error {
+	if len(commits)-1 < commitIndex {
+		return errors.New("index outside of range of commits")
+	}
+
+	// we can make this GPG thing possible it just means we need to do this in two parts:
+	// one where we handle the possibility of a credential request, and the other
+	// where we continue the rebase
+	if self.config.UsingGpg() {
+		return errors.New(self.Tr.DisabledForGPG)
+	}
+
+	changes := []daemon.ChangeTodoAction{{
+		Sha:       commits[commitIndex].Sha,
+		NewAction: todo.Edit,
+	}}
+	self.os.LogCommand(logTodoChanges(changes), false)
+
+	emptyCommits := EmptyCommitsDefault
+	if keepCommitsThatBecomeEmpty {
+		emptyCommits = EmptyCommitsKeep
+	}
+
+	return self.runInteractiveRebase(PrepareInteractiveRebaseCommandOpts{
+		baseShaOrRoot:  getBaseShaOrRoot(commits, commitIndex+1),
+		overrideEditor: true,
+		emptyCommits:   emptyCommits,
+		instruction:    daemon.NewChangeTodoActionsInstruction(changes),
+	})
+}
+
+// EditCommits starts an interactive rebase that stops at each of the given
+// commits in turn, oldest first, so that the user can amend them. Each call to
+// ContinueRebase moves on to the next stop.
+func (self *RebaseCommands) EditCommits(commits []*models.Commit, indices []int) error {
+	if len(indices) == 0 {
+		return errors.New("no commits to edit")
+	}
+
+	if self.config.UsingGpg() {
+		return errors.New(self.Tr.DisabledForGPG)
+	}
+
+	baseIndex := 0
+	changes := make([]daemon.ChangeTodoAction, 0, len(indices))
+	for _, index := range indices {
+		if index < 0 || index >= len(commits) {
+			return errors.New("index outside of range of commits")
+		}
+		if index > baseIndex {
+			baseIndex = index
+		}
+		changes = append(changes, daemon.ChangeTodoAction{
+			Sha:       commits[index].Sha,
+			NewAction: todo.Edit,
+		})
+	}
+	self.os.LogCommand(logTodoChanges(changes), false)
+
+	return self.runInteractiveRebase(PrepareInteractiveRebaseCommandOpts{
+		baseShaOrRoot:  getBaseShaOrRoot(commits, baseIndex+1),
+		overrideEditor: true,
+		instruction:    daemon.NewChangeTodoActionsInstruction(changes),
+	})
+}
+
+// The name of a file in the rebase-merge directory that EditCommitForRestage
+// writes the sha of the commit being restaged to, so that continuing the
+// rebase knows to commit whatever the user left staged. Git removes it along
+// with the rest of the rebase state.
+const restageMarkerFile = "lazygit-restage"
+
+// EditCommitForRestage starts an interactive rebase that stops at the given
+// commit, then soft-resets the commit so that its changes are staged and the
+// user can split them up into new commits. When the rebase is continued,
+// anything that's still staged is committed with the original commit's
+// message and author before moving on.
+func (self *RebaseCommands) EditCommitForRestage(commits []*models.Commit, index int) error {
+	if index < 0 || index >= len(commits) {
+		return errors.New("index outside of range of commits")
+	}
+	if commits[index].IsFirstCommit() {
+		return errors.New("cannot restage the initial commit")
+	}
+
+	if err := self.BeginInteractiveRebaseForCommit(commits, index, false); err != nil {
+		return err
+	}
+
+	markerPath := filepath.Join(self.repoPaths.WorktreeGitDirPath(), "rebase-merge", restageMarkerFile)
+	if err := os.WriteFile(markerPath, []byte(commits[index].Sha), 0o644); err != nil {
+		return err
+	}
+
+	cmdArgs := NewGitCmd("reset").Arg("--soft", "HEAD~1").ToArgv()
+	return self.cmd.New(cmdArgs).Run()
+}
+
+// Git won't continue from an edit stop while there are staged changes, so if
+// the user restaged the commit with EditCommitForRestage, we commit what's
+// left of it first. Skipping the commit drops those changes instead, like it
+// would for any other edit.
+func (self *RebaseCommands) finishRestage(command string) error {
+	markerPath := filepath.Join(self.repoPaths.WorktreeGitDirPath(), "rebase-merge", restageMarkerFile)
+	content, err := os.ReadFile(markerPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	if command == "continue" {
+		cmdArgs := NewGitCmd("diff").Arg("--cached", "--name-only").ToArgv()
+		stagedFiles, err := self.cmd.New(cmdArgs).DontLog().RunWithOutput()
+		if err != nil {
+			return err
+		}
+		if strings.TrimSpace(stagedFiles) != "" {
+			// -C reuses the original commit's message and author
+			cmdArgs := NewGitCmd("commit").Arg("-C", strings.TrimSpace(string(content))).ToArgv()
+			if err := self.cmd.New(cmdArgs).Run(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return os.Remove(markerPath)
+}
+
+// If an exec todo of CherryPickCommitsWithMainline stopped at conflicts, git
+// won't continue the rebase until the cherry-pick is committed, since it's not
+// one of its own picks. Once the user has resolved the conflicts, we commit it
+// with the message and author that git cherry-pick prepared. Cherry-picks that
+// anything else left behind are none of our business.
+func (self *RebaseCommands) finishExecCherryPick() error {
+	content, err := os.ReadFile(filepath.Join(self.repoPaths.WorktreeGitDirPath(), "CHERRY_PICK_HEAD"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	done, err := self.RebaseDoneSteps()
+	if err != nil {
+		return err
+	}
+	if len(done) == 0 {
+		return nil
+	}
+	lastDone := done[len(done)-1]
+	if lastDone.Command != todo.Exec || !daemon.IsMainlineCherryPickExec(lastDone.ExecCommand, strings.TrimSpace(string(content))) {
+		return nil
+	}
+
+	cmdArgs := NewGitCmd("commit").Arg("--no-edit").ToArgv()
+	return self.cmd.New(cmdArgs).Run()
+}
+
+// RemainingEditStops returns the shas of the commits that the current rebase
+// has yet to stop at for editing, oldest first. We read these from the todo
+// file rather than keeping track of them ourselves, so that the answer is
+// still right if the user edits the todo or restarts lazygit mid-rebase.
+func (self *RebaseCommands) RemainingEditStops() ([]string, error) {
+	fileName := filepath.Join(self.repoPaths.WorktreeGitDirPath(), "rebase-merge/git-rebase-todo")
+	todos, err := utils.ReadRebaseTodoFile(fileName, self.config.GetCoreCommentChar())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	editTodos := lo.Filter(todos, func(t todo.Todo, _ int) bool { return t.Command == todo.Edit })
+	return lo.Map(editTodos, func(t todo.Todo, _ int) string { return t.Commit }), nil
+}
+
+// RebasingBranchName returns the short name of the branch that the current
+// rebase is rebasing (e.g. "feature"), which we can't get from HEAD because
+// that is detached until the rebase is done. Returns an empty string if we're
+// not rebasing, or if the rebase was started on a detached head.
+func (self *RebaseCommands) RebasingBranchName() (string, error) {
+	for _, dir := range []string{"rebase-merge", "rebase-apply"} {
+		content, err := os.ReadFile(filepath.Join(self.repoPaths.WorktreeGitDirPath(), dir, "head-name"))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return "", err
+		}
+
+		// git writes "detached HEAD" here if there was no branch checked out
+		branchName, ok := strings.CutPrefix(strings.TrimSpace(string(content)), "refs/heads/")
+		if !ok {
+			return "", nil
+		}
+		return branchName, nil
+	}
+
+	return "", nil
+}
+
+// RebaseDoneSteps returns the todos that the current rebase has already
+// carried out, oldest first. Returns nil if we're not rebasing, or if the
+// rebase is using the apply backend, which doesn't keep track of these.
+func (self *RebaseCommands) RebaseDoneSteps() ([]todo.Todo, error) {
+	fileName := filepath.Join(self.repoPaths.WorktreeGitDirPath(), "rebase-merge/done")
+	todos, err := utils.ReadRebaseTodoFile(fileName, self.config.GetCoreCommentChar())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return todos, nil
+}
+
+// Where a rebase has got to, e.g. for showing "reword abc123 (3/8)"
+type RebaseProgress struct {
+	// The todo that git is carrying out, or stopped at. Sha is empty for todos
+	// without a commit, e.g. execs.
+	Sha    string
+	Action todo.TodoCommand
+	// The 1-based position of that step among all of the rebase's steps
+	Index int
+	Total int
+}
+
+func (self RebaseProgress) String() string {
+	if self.Sha == "" {
+		return fmt.Sprintf("%s (%d/%d)", self.Action, self.Index, self.Total)
+	}
+	return fmt.Sprintf("%s %s (%d/%d)", self.Action, utils.ShortSha(self.Sha), self.Index, self.Total)
+}
+
+// Follows the "Rebasing (3/8)" messages that git prints as it gets to each
+// step of an interactive rebase, and tells onProgress about them. Git only
+// prints the step's number, so we look the step up in the rebase-merge/done
+// file, to which git has added it by then.
+type rebaseProgressWriter struct {
+	// Returns the path of the rebase's todo file; the done file is next to it
+	todoPath    func() (string, error)
+	commentChar byte
+	onProgress  func(RebaseProgress)
+
+	// What git has printed since the last line break. Git ends its progress
+	// messages with a carriage return, so that a terminal overwrites them.
+	pending []byte
+}
+
+var rebaseProgressRegexp = regexp.MustCompile(`Rebasing \((\d+)/(\d+)\)`)
+
+func (self *RebaseCommands) newRebaseProgressWriter(opts PrepareInteractiveRebaseCommandOpts) *rebaseProgressWriter {
+	return &rebaseProgressWriter{
+		todoPath:    func() (string, error) { return self.rebaseTodoPath(opts.worktreeDir) },
+		commentChar: self.config.GetCoreCommentChar(),
+		onProgress:  opts.onProgress,
+	}
+}
+
+func (self *rebaseProgressWriter) Write(p []byte) (int, error) {
+	self.pending = append(self.pending, p...)
+	for {
+		i := bytes.IndexAny(self.pending, "\r\n")
+		if i < 0 {
+			break
+		}
+		self.processLine(string(self.pending[:i]))
+		self.pending = self.pending[i+1:]
+	}
+
+	return len(p), nil
+}
+
+func (self *rebaseProgressWriter) processLine(line string) {
+	match := rebaseProgressRegexp.FindStringSubmatch(line)
+	if match == nil {
+		return
+	}
+
+	progress := RebaseProgress{}
+	progress.Index, _ = strconv.Atoi(match[1])
+	progress.Total, _ = strconv.Atoi(match[2])
+
+	// Git counts the steps without the comments
+	done := lo.Filter(self.doneTodos(), func(t todo.Todo, _ int) bool { return t.Command != todo.Comment })
+	if progress.Index < 1 || progress.Index > len(done) {
+		return
+	}
+	progress.Action = done[progress.Index-1].Command
+	progress.Sha = done[progress.Index-1].Commit
+
+	self.onProgress(progress)
+}
+
+func (self *rebaseProgressWriter) doneTodos() []todo.Todo {
+	todoPath, err := self.todoPath()
+	if err != nil {
+		return nil
+	}
+
+	todos, err := utils.ReadRebaseTodoFile(filepath.Join(filepath.Dir(todoPath), "done"), self.commentChar)
+	if err != nil {
+		return nil
+	}
+
+	return todos
+}
+
+// The file in the rebase-merge directory where we keep what a failed exec
+// printed, so that it can still be shown after lazygit has been restarted. Git
+// deletes it with the rest of the rebase state.
+const failedExecFile = "lazygit-failed-exec"
+
+// An exec that failed, leaving the rebase paused right after it
+type FailedRebaseExec struct {
+	Command string
+	// The commit that HEAD was at when the command ran, i.e. the one it checked
+	Sha string
+	// What the command printed to stdout and stderr
+	Output string
+}
+
+// FailedExec returns the exec that the current rebase is paused at because it
+// failed, along with what it printed. Returns nil if the rebase isn't paused at
+// a failed exec, or if it failed while lazygit wasn't watching (e.g. when the
+// rebase was continued on the command line).
+func (self *RebaseCommands) FailedExec() (*FailedRebaseExec, error) {
+	content, err := os.ReadFile(filepath.Join(self.repoPaths.WorktreeGitDirPath(), "rebase-merge", failedExecFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	// If the rebase has been continued since, the file is about an earlier step
+	doneCount, output, _ := strings.Cut(string(content), "\n")
+	done, err := self.RebaseDoneSteps()
+	if err != nil {
+		return nil, err
+	}
+	if len(done) == 0 || strconv.Itoa(len(done)) != doneCount || done[len(done)-1].Command != todo.Exec {
+		return nil, nil
+	}
+
+	sha, err := self.headSha()
+	if err != nil {
+		return nil, err
+	}
+
+	return &FailedRebaseExec{Command: done[len(done)-1].ExecCommand, Sha: sha, Output: output}, nil
+}
+
+// If the given error of a rebase command says that the rebase stopped because
+// an exec failed, keep what the exec printed for FailedExec. Along with it we
+// note how many steps were done, which tells FailedExec whether the rebase is
+// still paused at that exec.
+func (self *RebaseCommands) recordFailedExec(err error) {
+	if err == nil {
+		return
+	}
+
+	output, ok := failedExecOutput(err.Error())
+	if !ok {
+		return
+	}
+
+	done, doneErr := self.RebaseDoneSteps()
+	if doneErr != nil || len(done) == 0 || done[len(done)-1].Command != todo.Exec {
+		return
+	}
+
+	content := strconv.Itoa(len(done)) + "\n" + output
+	path := filepath.Join(self.repoPaths.WorktreeGitDirPath(), "rebase-merge", failedExecFile)
+	if writeErr := os.WriteFile(path, []byte(content), 0o644); writeErr != nil {
+		self.Log.Warnf("Failed to record the output of the failed exec: %v", writeErr)
+	}
+}
+
+// Git announces each exec with "Executing: <command>" and, if it fails,
+// follows what the command printed with "warning: execution failed:
+// <command>". Returns what the last failed command printed in between.
+func failedExecOutput(rebaseOutput string) (string, bool) {
+	lines := strings.Split(rebaseOutput, "\n")
+	_, failedIdx, found := lo.FindLastIndexOf(lines, func(line string) bool {
+		return strings.HasPrefix(line, "warning: execution failed: ")
+	})
+	if !found {
+		return "", false
+	}
+
+	// The progress that git prints can end up on the same line as the
+	// announcement
+	startIdx := 0
+	for i := failedIdx - 1; i >= 0; i-- {
+		if strings.Contains(lines[i], "Executing: ") {
+			startIdx = i + 1
+			break
+		}
+	}
+
+	return strings.Join(lines[startIdx:failedIdx], "\n"), true
+}
+
+// RebaseWithExecOnPaths rebases the commits above the given base, running
+// execCmd after each commit that touches any of the given paths (which can be
+// any pathspecs git understands). Like with git's --exec, the rebase stops if
+// the command fails, so that the user can fix things up and continue.
+func (self *RebaseCommands) RebaseWithExecOnPaths(baseShaOrRoot string, paths []string, execCmd string) error {
+	if len(paths) == 0 {
+		return errors.New("no paths given")
+	}
+
+	msg := utils.ResolvePlaceholderString(
+		self.Tr.Log.ExecOnPaths,
+		map[string]string{
+			"baseSha": utils.ShortSha(baseShaOrRoot),
+			"execCmd": execCmd,
+			"paths":   strings.Join(paths, ", "),
+		},
+	)
+	self.os.LogCommand(msg, false)
+
+	err := self.runInteractiveRebase(PrepareInteractiveRebaseCommandOpts{
+		baseShaOrRoot:  baseShaOrRoot,
+		overrideEditor: true,
+		instruction:    daemon.NewExecOnPathsInstruction(paths, execCmd),
+	})
+	self.recordFailedExec(err)
+	return err
+}
+
+// The commits of a paused rebase, split into the ones that it has created so
+// far and the todos that it still has to carry out
+type PausedRebaseCommits struct {
+	// Newest first, like the commits view
+	Applied []*AppliedRebaseCommit
+	// In the order that git will carry them out
+	Pending []todo.Todo
+}
+
+// A commit that a paused rebase has created, with its new sha
+type AppliedRebaseCommit struct {
+	*models.Commit
+	// The shas of the commits this one was made from, oldest first. There's
+	// more than one if commits were squashed or fixed up into it, and none if
+	// it didn't come from the todo, e.g. because the user created it while the
+	// rebase was stopped.
+	OriginalShas []string
+}
+
+// PausedRebaseCommits returns the commits that the current rebase has applied
+// so far along with the todos it has yet to do, so that the two can be shown
+// differently. Returns nil if we're not rebasing (or the rebase is using the
+// apply backend, which doesn't keep the files we need).
+func (self *RebaseCommands) PausedRebaseCommits() (*PausedRebaseCommits, error) {
+	rebaseMergeDir := filepath.Join(self.repoPaths.WorktreeGitDirPath(), "rebase-merge")
+	onto, err := os.ReadFile(filepath.Join(rebaseMergeDir, "onto"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	originalShas, err := readRewrittenList(rebaseMergeDir)
+	if err != nil {
+		return nil, err
+	}
+
+	// Git doesn't record the picks that it could fast-forward, because they
+	// keep their sha, so we recognize those by finding them in the done file
+	done, err := self.RebaseDoneSteps()
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range done {
+		if t.Commit != "" && originalShas[t.Commit] == nil {
+			originalShas[t.Commit] = []string{t.Commit}
+		}
+	}
+
+	cmdArgs := NewGitCmd("log").
+		Arg("--first-parent", "--format=%H%x00%P%x00%s").
+		Arg(strings.TrimSpace(string(onto)) + "..HEAD").
+		ToArgv()
+	output, err := self.cmd.New(cmdArgs).DontLog().RunWithOutput()
+	if err != nil {
+		return nil, err
+	}
+
+	applied := []*AppliedRebaseCommit{}
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		fields := strings.SplitN(line, "\x00", 3)
+		if len(fields) < 3 {
+			continue
+		}
+		applied = append(applied, &AppliedRebaseCommit{
+			Commit: &models.Commit{
+				Sha:     fields[0],
+				Parents: strings.Fields(fields[1]),
+				Name:    fields[2],
+			},
+			OriginalShas: originalShas[fields[0]],
+		})
+	}
+
+	todoPath := filepath.Join(rebaseMergeDir, "git-rebase-todo")
+	pending, err := utils.ReadRebaseTodoFile(todoPath, self.config.GetCoreCommentChar())
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	pending = lo.Filter(pending, func(t todo.Todo, _ int) bool { return t.Command != todo.Comment })
+
+	return &PausedRebaseCommits{Applied: applied, Pending: pending}, nil
+}
+
+// The directory in the git dir where our post-rewrite hook keeps copies of the
+// done and rewritten-list files of the last rebase, so that we can still tell
+// which commits it rewrote once it's over and git has deleted the rebase-merge
+// directory
+const lastRebaseDirName = "lazygit-last-rebase"
+
+// The directory in the git dir that installRebaseResultHook sets up as the
+// hooks directory of the rebases we run
+const rebaseHooksDirName = "lazygit-hooks"
+
+// The name that the user's own post-rewrite hook is forwarded to under in
+// rebaseHooksDirName, so that ours can run it
+const userPostRewriteHookName = "lazygit-user-post-rewrite"
+
+// Git runs the post-rewrite hook with "rebase" once a rebase has made all of
+// its commits, right before it deletes the rebase-merge directory, so by then
+// the done and rewritten-list files in there are complete. The hook only runs
+// if the rebase rewrote any commits.
+const rebaseResultPostRewriteHook = `#!/bin/sh
+if test "$1" = rebase; then
+	src="$(git rev-parse --git-path rebase-merge)"
+	dst="$(git rev-parse --git-path ` + lastRebaseDirName + `)"
+	rm -rf "$dst" && mkdir -p "$dst" && cp "$src/done" "$src/rewritten-list" "$dst/"
+fi
+hook="$(dirname "$0")/` + userPostRewriteHookName + `"
+if test -x "$hook"; then
+	exec "$hook" "$@"
+fi
+`
+
+// Sets up the hooks directory that the rebases we run get as core.hooksPath,
+// so that our post-rewrite hook keeps their result in lastRebaseDirName. Each
+// of the user's hooks gets a script there that runs it, so they run just as
+// they would otherwise. Returns the directory, or "" if it couldn't be set up,
+// in which case git runs the user's hooks directly and the result of the
+// rebase isn't kept.
+func (self *RebaseCommands) installRebaseResultHook() string {
+	hooksDir := filepath.Join(self.repoPaths.WorktreeGitDirPath(), rebaseHooksDirName)
+	if err := self.writeRebaseHooks(hooksDir); err != nil {
+		self.Log.Warnf("Failed to set up the hook that keeps the result of the rebase: %v", err)
+		return ""
+	}
+
+	return hooksDir
+}
+
+func (self *RebaseCommands) writeRebaseHooks(hooksDir string) error {
+	userHooksDir := self.config.GetCoreHooksPath()
+	if userHooksDir == "" {
+		userHooksDir = filepath.Join(self.repoPaths.RepoGitDirPath(), "hooks")
+	} else if rest, ok := strings.CutPrefix(userHooksDir, "~/"); ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return err
+		}
+		userHooksDir = filepath.Join(home, rest)
+	} else if !filepath.IsAbs(userHooksDir) {
+		// git runs hooks from the top of the worktree
+		userHooksDir = filepath.Join(self.repoPaths.WorktreePath(), userHooksDir)
+	}
+	userHooksDir, err := filepath.Abs(userHooksDir)
+	if err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(userHooksDir)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	// Start afresh, so that hooks that the user has removed since don't
+	// linger. The git dir itself has to be there already.
+	if err := os.RemoveAll(hooksDir); err != nil {
+		return err
+	}
+	if err := os.Mkdir(hooksDir, 0o755); err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		// Git skips hooks that aren't executable
+		if !info.Mode().IsRegular() || info.Mode().Perm()&0o111 == 0 {
+			continue
+		}
+		name := entry.Name()
+		if name == "post-rewrite" {
+			name = userPostRewriteHookName
+		}
+		// Running the hook from where it is, rather than linking to it, keeps
+		// $0 pointing there for hooks that look for files next to them
+		hookPath := filepath.Join(userHooksDir, entry.Name())
+		script := "#!/bin/sh\nexec '" + strings.ReplaceAll(hookPath, "'", `'\''`) + `' "$@"` + "\n"
+		if err := os.WriteFile(filepath.Join(hooksDir, name), []byte(script), 0o755); err != nil {
+			return err
+		}
+	}
+
+	return os.WriteFile(filepath.Join(hooksDir, "post-rewrite"), []byte(rebaseResultPostRewriteHook), 0o755)
+}
+
+// RebasedCommitMapping tells us which commits the current rebase has rewritten
+// so far or, if we're not rebasing, which ones the last rebase that lazygit
+// ran or continued rewrote. It maps the sha of each rewritten commit to
+// the sha of the commit it became. Commits that were squashed or fixed up into
+// another all map to the same new commit, and dropped commits map to "".
+// Commits that git could keep as they were are left out. Returns nil if there's
+// nothing to go by, e.g. because the last rebase was aborted, was finished
+// outside of lazygit, or didn't rewrite any commits (git only runs the hook
+// that keeps the result when it did).
+func (self *RebaseCommands) RebasedCommitMapping() (map[string]string, error) {
+	dir := filepath.Join(self.repoPaths.WorktreeGitDirPath(), "rebase-merge")
+	if _, err := os.Stat(dir); err != nil {
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+		dir = filepath.Join(self.repoPaths.WorktreeGitDirPath(), lastRebaseDirName)
+	}
+
+	done, err := utils.ReadRebaseTodoFile(filepath.Join(dir, "done"), self.config.GetCoreCommentChar())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, "rewritten-list"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	mapping := map[string]string{}
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] != fields[1] {
+			mapping[fields[0]] = fields[1]
+		}
+	}
+	for _, t := range done {
+		if t.Command == todo.Drop && t.Commit != "" {
+			mapping[t.Commit] = ""
+		}
+	}
+
+	return mapping, nil
+}
+
+// RestoreOriginalMessage undoes the last reword of the commit at index by
+// giving it back the message it had before. We find the commit it was made
+// from in the mapping of the last rebase, or, for a commit that was reworded
+// by amending HEAD, in the reflog. Returns an error if neither has it any more
+// (e.g. because the reflog has expired).
+func (self *RebaseCommands) RestoreOriginalMessage(commits []*models.Commit, index int) error {
+	if index < 0 || index >= len(commits) {
+		return errors.New("index outside of range of commits")
+	}
+
+	sha := commits[index].Sha
+	originalSha, err := self.preRewordSha(sha)
+	if err != nil {
+		return err
+	}
+	if originalSha == "" {
+		return errors.Errorf("the original message of commit %s can't be found; the reflog may have expired", utils.ShortSha(sha))
+	}
+
+	message, err := self.commit.GetCommitMessage(originalSha)
+	if err != nil {
+		return err
+	}
+	summary, description, _ := strings.Cut(message, "\n")
+	return self.RewordCommit(commits, index, summary, strings.TrimSpace(description))
+}
+
+// Returns the sha of the commit that the given one was reworded from, or "" if
+// we can't find it. A reword keeps the tree, so a candidate only counts if it
+// has the same tree but a different message.
+func (self *RebaseCommands) preRewordSha(sha string) (string, error) {
+	candidates := []string{}
+
+	mapping, err := self.RebasedCommitMapping()
+	if err != nil {
+		return "", err
+	}
+	for oldSha, newSha := range mapping {
+		if newSha == sha {
+			candidates = append(candidates, oldSha)
+		}
+	}
+
+	// Amending HEAD leaves the commit from before right below the new one in
+	// the reflog
+	reflogArgs := NewGitCmd("reflog").Arg("--format=%H").ToArgv()
+	reflog, err := self.cmd.New(reflogArgs).DontLog().RunWithOutput()
+	if err != nil {
+		return "", err
+	}
+	entries := utils.SplitLines(reflog)
+	for i := 0; i < len(entries)-1; i++ {
+		if entries[i] == sha {
+			candidates = append(candidates, entries[i+1])
+		}
+	}
+
+	treeAndMessage := func(ref string) (string, error) {
+		cmdArgs := NewGitCmd("log").Arg("-1", "--format=%T%n%B", ref).ToArgv()
+		return self.cmd.New(cmdArgs).DontLog().RunWithOutput()
+	}
+	current, err := treeAndMessage(sha)
+	if err != nil {
+		return "", err
+	}
+	currentTree, _, _ := strings.Cut(current, "\n")
+	for _, candidate := range candidates {
+		original, err := treeAndMessage(candidate)
+		if err != nil {
+			// pruned since
+			continue
+		}
+		if tree, _, _ := strings.Cut(original, "\n"); tree == currentTree && original != current {
+			return candidate, nil
+		}
+	}
+
+	return "", nil
+}
+
+// The name of an empty file that noteRebuiltCommits puts in
+// lastRebaseDirName once it has noted the commits of that rebase
+const rebuiltCommitsNotedFile = "lazygit-noted"
+
+// With git.rebase.noteRebuiltCommits, attaches a note under refs/notes/lazygit
+// to each commit that the last rebase rebuilt, once it has finished, listing
+// the todos and original commits it was made from. We go by what our
+// post-rewrite hook kept in lastRebaseDirName, so only rebases that lazygit
+// ran to the end are covered.
+// Failing to add the notes doesn't make the rebase fail, so we only log it.
+func (self *RebaseCommands) noteRebuiltCommits() {
+	if !self.UserConfig.Git.Rebase.NoteRebuiltCommits || self.status.WorkingTreeState() == enums.REBASE_MODE_REBASING {
+		return
+	}
+
+	lastRebaseDir := filepath.Join(self.repoPaths.WorktreeGitDirPath(), lastRebaseDirName)
+	notedPath := filepath.Join(lastRebaseDir, rebuiltCommitsNotedFile)
+	if _, err := os.Stat(lastRebaseDir); err != nil {
+		return
+	}
+	if _, err := os.Stat(notedPath); err == nil {
+		return
+	}
+
+	if err := self.addRebuiltCommitNotes(lastRebaseDir); err != nil {
+		self.Log.Warnf("Failed to add notes to the rebuilt commits: %v", err)
+		return
+	}
+	if err := os.WriteFile(notedPath, nil, 0o644); err != nil {
+		self.Log.Warnf("Failed to mark the rebuilt commits as noted: %v", err)
+	}
+}
+
+func (self *RebaseCommands) addRebuiltCommitNotes(lastRebaseDir string) error {
+	mapping, err := self.RebasedCommitMapping()
+	if err != nil {
+		return err
+	}
+	done, err := utils.ReadRebaseTodoFile(filepath.Join(lastRebaseDir, "done"), self.config.GetCoreCommentChar())
+	if err != nil {
+		return err
+	}
+
+	sourcesByNewSha := map[string][]string{}
+	for _, t := range done {
+		newSha := mapping[t.Commit]
+		if t.Commit == "" || newSha == "" {
+			continue
+		}
+		sourcesByNewSha[newSha] = append(sourcesByNewSha[newSha], t.Command.String()+" "+t.Commit)
+	}
+
+	newShas := lo.Keys(sourcesByNewSha)
+	slices.Sort(newShas)
+	for _, newSha := range newShas {
+		note := "Rebuilt by lazygit in a rebase from:\n" + strings.Join(sourcesByNewSha[newSha], "\n")
+		cmdArgs := NewGitCmd("notes").Arg("--ref=lazygit", "add", "--force", "--message", note, newSha).ToArgv()
+		if err := self.cmd.New(cmdArgs).DontLog().Run(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Git records which original commits each new commit was made from in the
+// rewritten-list file, as "<old sha> <new sha>" lines, so that it can pass
+// them to the post-rewrite hook. Squashed commits get a line each, all with
+// the same new sha. We return the old shas keyed by the new one.
+func readRewrittenList(rebaseMergeDir string) (map[string][]string, error) {
+	result := map[string][]string{}
+
+	content, err := os.ReadFile(filepath.Join(rebaseMergeDir, "rewritten-list"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		result[fields[1]] = append(result[fields[1]], fields[0])
+	}
+
+	// The commit we're stopped at for editing only gets its line once the
+	// rebase moves on, but git tells us where it came from in the meantime
+	amend, amendErr := os.ReadFile(filepath.Join(rebaseMergeDir, "amend"))
+	stoppedSha, stoppedErr := os.ReadFile(filepath.Join(rebaseMergeDir, "stopped-sha"))
+	if amendErr == nil && stoppedErr == nil {
+		newSha := strings.TrimSpace(string(amend))
+		if _, ok := result[newSha]; !ok {
+			result[newSha] = []string{strings.TrimSpace(string(stoppedSha))}
+		}
+	}
+
+	return result, nil
+}
+
+// PendingCommitDiff returns the diff of the given commit, e.g. one that is
+// still in the todo list of a paused rebase, so that the user can see what
+// picking it will do. This is the same as showing the commit normally, so it
+// also works when no rebase is in progress.
+func (self *RebaseCommands) PendingCommitDiff(sha string) (string, error) {
+	return self.commit.ShowCmdObj(sha, "").RunWithOutput()
+}
+
+// EditCommitWithCheck starts an interactive rebase that stops at the given
+// commit for editing, like BeginInteractiveRebaseForCommit, but also runs
+// checkCmd once the user continues. If the check fails, git halts the rebase
+// right after the edited commit so that the user can fix things up and
+// continue again.
+func (self *RebaseCommands) EditCommitWithCheck(commits []*models.Commit, commitIndex int, checkCmd string) error {
+	if len(commits)-1 < commitIndex {
+		return errors.New("index outside of range of commits")
+	}
+
+	if self.config.UsingGpg() {
+		return errors.New(self.Tr.DisabledForGPG)
+	}
+
+	sha := commits[commitIndex].Sha
+	msg := utils.ResolvePlaceholderString(
+		self.Tr.Log.EditCommitWithCheck,
+		map[string]string{
+			"shortSha": utils.ShortSha(sha),
+			"checkCmd": checkCmd,
+		},
+	)
+	self.os.LogCommand(msg, false)
+
+	return self.runInteractiveRebase(PrepareInteractiveRebaseCommandOpts{
+		baseShaOrRoot:  getBaseShaOrRoot(commits, commitIndex+1),
+		overrideEditor: true,
+		instruction:    daemon.NewEditCommitWithCheckInstruction(sha, checkCmd),
+	})
+}
+
+// RebaseBranch interactive rebases onto a branch. If onProgress isn't nil, it's
+// told about each step of the rebase as git gets to it; rebases with the apply
+// backend don't report their steps.
+func (self *RebaseCommands) RebaseBranch(branchName string, onProgress func(RebaseProgress)) error {
+	if self.UserConfig.Git.Rebase.UseWorktree {
+		return self.rebaseBranchInWorktree(branchName, onProgress)
+	}
+
+	if err := self.checkCanRebaseWithoutAutostash(); err != nil {
+		return err
+	}
+
+	if RebaseBackend(self.UserConfig.Git.Rebase.Backend) == RebaseBackendApply {
+		return self.rebaseBranchWithApplyBackend(branchName)
+	}
+
+	return self.runInteractiveRebase(PrepareInteractiveRebaseCommandOpts{baseShaOrRoot: branchName, onProgress: onProgress})
+}
+
+// RebaseThenPushWithLease rebases the checked-out branch onto upstream and
+// then force-pushes it (with lease) to the branch it tracks. If the rebase
+// stops at conflicts, the push waits until the user has resolved them and
+// continued that same rebase; aborting it drops the push, so we never push a
+// branch whose rebase didn't finish. The task is only used for a push that
+// happens right away; a push after a continue isn't part of any task. With
+// git.rebase.pushAfterRebase turned off this is just a rebase.
+func (self *RebaseCommands) RebaseThenPushWithLease(task gocui.Task, upstream string) error {
+	var push func(task gocui.Task) error
+	if self.UserConfig.Git.Rebase.PushAfterRebase {
+		// Read before the rebase, so that the lease is on the tip that the
+		// branch was rebased from rather than on whatever a fetch during a
+		// conflict pause brings in
+		target, err := self.upstreamPushTarget()
+		if err != nil {
+			return err
+		}
+		push = func(task gocui.Task) error {
+			// We name the remote and the branch on it rather than leaving it to
+			// push.default, which might push elsewhere (or other branches too)
+			cmdArgs := NewGitCmd("push").
+				Arg("--force-with-lease="+target.branch+":"+target.sha, target.remote, "HEAD:"+target.branch).
+				ToArgv()
+			return self.cmd.New(cmdArgs).PromptOnCredentialRequest(task).Run()
+		}
+	} else {
+		push = func(gocui.Task) error { return nil }
+	}
+
+	if err := self.RebaseBranch(upstream, nil); err != nil {
+		// Only queue the push if git is waiting for the user to continue; if the
+		// rebase couldn't start or was given up, there's nothing to push
+		operation, stateErr := self.status.CurrentOperation()
+		if stateErr == nil && operation == enums.OPERATION_REBASE {
+			self.setOnSuccessfulContinue(self.Tr.Actions.RebaseBranchThenPush, func() error {
+				return push(nil)
+			})
+		}
+		return err
+	}
+
+	return push(task)
+}
+
+// Where RebaseThenPushWithLease pushes the checked-out branch to
+type rebasePushTarget struct {
+	remote string
+	// The full name of the branch on the remote, e.g. refs/heads/main
+	branch string
+	// The sha of the remote-tracking branch, i.e. where we last saw the branch
+	// on the remote. Empty if we've never seen it, in which case the lease
+	// requires that the branch doesn't exist on the remote.
+	sha string
+}
+
+// The upstream of the checked-out branch, which needs to be a branch on a
+// remote
+func (self *RebaseCommands) upstreamPushTarget() (rebasePushTarget, error) {
+	branchName, ok := self.checkedOutBranchName()
+	if !ok {
+		return rebasePushTarget{}, errors.New(self.Tr.RebaseThenPushNoUpstream)
+	}
+
+	cmdArgs := NewGitCmd("for-each-ref").
+		Arg("--format=%(upstream:remotename)%00%(upstream:remoteref)", "refs/heads/"+branchName).
+		ToArgv()
+	output, err := self.cmd.New(cmdArgs).DontLog().RunWithOutput()
+	if err != nil {
+		return rebasePushTarget{}, err
+	}
+	remote, branch, _ := strings.Cut(strings.TrimSpace(output), "\x00")
+	// A remote of "." means that the upstream is a local branch
+	if remote == "" || remote == "." || branch == "" {
+		return rebasePushTarget{}, errors.New(self.Tr.RebaseThenPushNoUpstream)
+	}
+
+	revParseArgs := NewGitCmd("rev-parse").Arg("--verify", "--quiet", branchName+"@{upstream}").ToArgv()
+	sha, err := self.cmd.New(revParseArgs).DontLog().RunWithOutput()
+	if err != nil {
+		sha = ""
+	}
+
+	return rebasePushTarget{remote: remote, branch: branch, sha: strings.TrimSpace(sha)}, nil
+}
+
+// Rebases a copy of HEAD in a temporary worktree, and only once that has
+// succeeded moves the checked-out branch to the result. We move it the way a
+// checkout would: the files that the rebase changed are updated, and staged
+// or unstaged changes to other files are left alone, so nothing needs to be
+// stashed. The temporary worktree lives in the git dir and is removed whether
+// or not the rebase succeeds.
+func (self *RebaseCommands) rebaseBranchInWorktree(branchName string, onProgress func(RebaseProgress)) error {
+	worktreePath := filepath.Join(self.repoPaths.WorktreeGitDirPath(), "lazygit-rebase-worktree")
+
+	originalSha, err := self.headSha()
+	if err != nil {
+		return err
+	}
+
+	// A worktree left behind by a lazygit that was killed mid-rebase would be
+	// in the way; --force makes git forget about it
+	if err := os.RemoveAll(worktreePath); err != nil {
+		return err
+	}
+	addArgs := NewGitCmd("worktree").Arg("add", "--force", "--detach", worktreePath, originalSha).ToArgv()
+	if err := self.cmd.New(addArgs).Run(); err != nil {
+		return err
+	}
+	defer func() {
+		removeArgs := NewGitCmd("worktree").Arg("remove", "--force", worktreePath).ToArgv()
+		if err := self.cmd.New(removeArgs).Run(); err != nil {
+			self.Log.Warnf("Failed to remove the temporary rebase worktree: %v", err)
+		}
+	}()
+
+	err = self.runInteractiveRebase(PrepareInteractiveRebaseCommandOpts{
+		baseShaOrRoot: branchName,
+		worktreeDir:   worktreePath,
+		onProgress:    onProgress,
+	})
+	if err != nil {
+		abortArgs := NewGitCmd("rebase").Arg("--abort").Dir(worktreePath).ToArgv()
+		if abortErr := self.cmd.New(abortArgs).Run(); abortErr != nil {
+			self.Log.Warnf("Failed to abort the rebase in the temporary worktree: %v", abortErr)
+		}
+		return errors.Errorf("%s\n\n%s", self.Tr.RebaseInWorktreeStopped, err.Error())
+	}
+
+	headArgs := NewGitCmd("rev-parse").Arg("--verify", "HEAD").Dir(worktreePath).ToArgv()
+	output, err := self.cmd.New(headArgs).DontLog().RunWithOutput()
+	if err != nil {
+		return err
+	}
+	rebasedSha := strings.TrimSpace(output)
+
+	// A two-tree read-tree refuses to touch files with uncommitted changes, so
+	// if this fails nothing has changed yet
+	readTreeArgs := NewGitCmd("read-tree").Arg("-m", "-u", originalSha, rebasedSha).ToArgv()
+	if err := self.cmd.New(readTreeArgs).Run(); err != nil {
+		return errors.Errorf("could not move the branch to the rebased commits (%s), probably because of uncommitted changes to files that the rebase changed: %s",
+			utils.ShortSha(rebasedSha), err.Error())
+	}
+
+	updateRefArgs := NewGitCmd("update-ref").
+		Arg("-m", "rebase (finish): onto "+branchName, "HEAD", rebasedSha, originalSha).
+		ToArgv()
+	return self.cmd.New(updateRefArgs).Run()
+}
+
+// RebaseIgnoringWhitespace rebases onto the given base like RebaseBranch, but
+// has git ignore whitespace when merging, so that rebasing across a change
+// that only reformatted the code doesn't stop at conflicts. If it still stops
+// at conflicts where our and their version of each conflicted file only
+// differ in whitespace, we resolve these by keeping our version (the one from
+// the base, where a formatter would have run) and continue. Any other
+// conflict is left for the user to resolve.
+func (self *RebaseCommands) RebaseIgnoringWhitespace(base string) error {
+	if err := self.checkCanRebaseWithoutAutostash(); err != nil {
+		return err
+	}
+
+	err := self.runInteractiveRebase(PrepareInteractiveRebaseCommandOpts{
+		baseShaOrRoot:   base,
+		strategyOptions: []string{"ignore-all-space"},
+	})
+
+	for err != nil {
+		resolved, resolveErr := self.resolveWhitespaceOnlyConflicts()
+		if resolveErr != nil {
+			return resolveErr
+		}
+		if !resolved {
+			return err
+		}
+
+		err = self.ContinueRebase()
+	}
+
+	return nil
+}
+
+// Resolves the current conflicts by taking our version of each conflicted
+// file, but only if that differs from their version in nothing but
+// whitespace; otherwise nothing is touched and we return false. A file that
+// was deleted on one side has no version to compare, so it never counts as a
+// whitespace-only conflict.
+func (self *RebaseCommands) resolveWhitespaceOnlyConflicts() (bool, error) {
+	unmergedFiles, err := self.unmergedFiles()
+	if err != nil {
+		return false, err
+	}
+	if len(unmergedFiles) == 0 {
+		return false, nil
+	}
+
+	for _, file := range unmergedFiles {
+		cmdArgs := NewGitCmd("diff").Arg("-w", "--quiet", ":2:"+file, ":3:"+file).ToArgv()
+		if err := self.cmd.New(cmdArgs).DontLog().Run(); err != nil {
+			return false, nil
+		}
+	}
+
+	for _, file := range unmergedFiles {
+		if err := self.cmd.New(NewGitCmd("checkout").Arg("--ours", "--", file).ToArgv()).Run(); err != nil {
+			return false, err
+		}
+		if err := self.cmd.New(NewGitCmd("add").Arg("--", file).ToArgv()).Run(); err != nil {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// The apply backend can't be combined with --interactive, nor with most of
+// the options we normally pass (--rebase-merges, --update-refs, --empty), so
+// this is a plain rebase that only keeps what the backend supports
+func (self *RebaseCommands) rebaseBranchWithApplyBackend(branchName string) error {
+	if self.UserConfig.Git.Rebase.UpdateRefs {
+		self.Log.Warn("Not updating refs during the rebase because the apply backend doesn't support it")
+	}
+
+	conflictStyle := self.conflictStyle()
+	cmdArgs := NewGitCmd("rebase").
+		ConfigIf(conflictStyle != "", "merge.conflictStyle="+conflictStyle).
+		Arg("--apply").
+		ArgIfElse(self.UserConfig.Git.Rebase.AutoStash, "--autostash", "--no-autostash").
+		ArgIf(self.UserConfig.Git.Rebase.SignOff && self.version.IsAtLeast(2, 34, 0), "--signoff").
+		Arg(branchName).
+		ToArgv()
+
+	return self.cmd.New(cmdArgs).AddEnvVars(
+		"LANG=en_US.UTF-8",   // Force using EN as language
+		"LC_ALL=en_US.UTF-8", // Force using EN as language
+	).Run()
+}
+
+// How RebaseBranchWithDates sets the dates of the commits that it rebuilds
+type RebaseDates struct {
+	// Give every rebuilt commit the current time as its author and committer
+	// date (git's --ignore-date), e.g. when a long-lived branch is squashed into
+	// fresh commits and its old dates would only be misleading
+	IgnoreDate bool
+	// Give every rebuilt commit its author date as its committer date (git's
+	// --committer-date-is-author-date)
+	CommitterDateIsAuthorDate bool
+}
+
+// RebaseBranchWithDates rebases onto a branch like RebaseBranch, but sets the
+// dates of the rebased commits as dates asks. Unlike a normal rebase, this also
+// rewrites commits that are already on top of the branch. Both options need git
+// 2.29, and they can't be combined.
+func (self *RebaseCommands) RebaseBranchWithDates(branchName string, dates RebaseDates) error {
+	opts := PrepareInteractiveRebaseCommandOpts{
+		baseShaOrRoot:             branchName,
+		ignoreDate:                dates.IgnoreDate,
+		committerDateIsAuthorDate: dates.CommitterDateIsAuthorDate,
+	}
+	if err := self.checkDateOpts(opts); err != nil {
+		return err
+	}
+
+	if err := self.checkCanRebaseWithoutAutostash(); err != nil {
+		return err
+	}
+
+	return self.runInteractiveRebase(opts)
+}
+
+// RebaseBranchDroppingEmptied rebases onto a branch like RebaseBranch, but
+// drops the commits whose changes the branch already has instead of stopping
+// at them. Commits that were empty to begin with are kept. Git older than 2.26
+// can't be told to do this, so there we skip each such commit when the rebase
+// stops at it.
+func (self *RebaseCommands) RebaseBranchDroppingEmptied(branchName string) error {
+	if err := self.checkCanRebaseWithoutAutostash(); err != nil {
+		return err
+	}
+
+	err := self.runInteractiveRebase(PrepareInteractiveRebaseCommandOpts{
+		baseShaOrRoot: branchName,
+		emptyCommits:  EmptyCommitsDrop,
+	})
+	return self.skipEmptiedPicks(err)
+}
+
+// To be called with the result of a rebase. For as long as the rebase is
+// stopped at a pick that has become empty because the new base already has
+// its changes, we skip that pick. Any other stop, including one at a commit
+// that was empty to begin with, is returned as usual.
+func (self *RebaseCommands) skipEmptiedPicks(err error) error {
+	for err != nil {
+		emptied, checkErr := self.stoppedAtEmptiedPick()
+		if checkErr != nil {
+			return checkErr
+		}
+		if !emptied {
+			return err
+		}
+
+		err = self.GenericMergeOrRebaseAction("rebase", "skip")
+	}
+
+	return nil
+}
+
+func (self *RebaseCommands) stoppedAtEmptiedPick() (bool, error) {
+	done, err := self.RebaseDoneSteps()
+	if err != nil || len(done) == 0 || done[len(done)-1].Command != todo.Pick {
+		return false, err
+	}
+
+	empty, err := self.ContinueWouldBeEmpty()
+	if err != nil || !empty {
+		return false, err
+	}
+
+	// diff-tree fails with --quiet if the commit changes anything
+	cmdArgs := NewGitCmd("diff-tree").Arg("--quiet", "--root", done[len(done)-1].Commit).ToArgv()
+	return self.cmd.New(cmdArgs).DontLog().Run() != nil, nil
+}
+
+// RebaseOntoRef rebases the checked-out branch onto any ref that git can
+// resolve to a commit, e.g. a remote branch like origin/main, a tag, or a
+// fully-qualified ref
+func (self *RebaseCommands) RebaseOntoRef(ref string) error {
+	if _, err := self.ResolveSha(ref); err != nil {
+		return err
+	}
+
+	if err := self.checkCanRebaseWithoutAutostash(); err != nil {
+		return err
+	}
+
+	return self.runInteractiveRebase(PrepareInteractiveRebaseCommandOpts{baseShaOrRoot: ref})
+}
+
+// With autostash turned off, git refuses to rebase over uncommitted changes
+// to tracked files, but only once it has started setting up the rebase.
+// Checking first lets us refuse with a message that says what to do about it.
+func (self *RebaseCommands) checkCanRebaseWithoutAutostash() error {
+	if self.UserConfig.Git.Rebase.AutoStash {
+		return nil
+	}
+
+	clean, err := self.workingTree.WorkingTreeClean()
+	if err != nil {
+		return err
+	}
+	if !clean {
+		return errors.New(self.Tr.RebaseNeedsCleanWorkingTree)
+	}
+
+	return nil
+}
+
+// ResolveSha returns the full sha of the commit that the given ref points to.
+// The ref can be anything git understands, e.g. an abbreviated sha, a branch
+// name or HEAD~2. If git doesn't know the ref (e.g. a remote branch that
+// hasn't been fetched), or the ref is a short sha that matches more than one
+// object, we return a clearer error than git's own "fatal: Needed a single
+// revision".
+func (self *RebaseCommands) ResolveSha(ref string) (string, error) {
+	cmdArgs := NewGitCmd("rev-parse").Arg("--verify", ref+"^{commit}").ToArgv()
+	output, err := self.cmd.New(cmdArgs).DontLog().RunWithOutput()
+	if err != nil {
+		if strings.Contains(err.Error(), "is ambiguous") {
+			return "", errors.Errorf("ambiguous ref '%s'; use more characters of the sha", ref)
+		}
+		return "", errors.Errorf("unknown ref '%s'", ref)
+	}
+
+	return strings.TrimSpace(output), nil
+}
+
+// IsAncestor tells whether maybeAncestor is reachable from descendant. A commit
+// counts as its own ancestor. merge-base exits with status 1 when it isn't an
+// ancestor; any other failure (e.g. an unknown ref) is returned as an error.
+func (self *RebaseCommands) IsAncestor(maybeAncestor string, descendant string) (bool, error) {
+	cmdArgs := NewGitCmd("merge-base").Arg("--is-ancestor", maybeAncestor, descendant).ToArgv()
+
+	err := self.cmd.New(cmdArgs).DontLog().Run()
+	if err == nil {
+		return true, nil
+	}
+
+	var exitErr interface{ ExitCode() int }
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		return false, nil
+	}
+
+	return false, err
+}
+
+// Rebasing from a base that isn't an ancestor of HEAD would pick up commits
+// from the base's own history too, which git reports in a confusing way (if
+// at all), so we check it up front.
+func (self *RebaseCommands) checkBaseIsAncestorOfHead(baseCommit string) error {
+	isAncestor, err := self.IsAncestor(baseCommit, "HEAD")
+	if err != nil {
+		return err
+	}
+	if !isAncestor {
+		return errors.Errorf(self.Tr.RebaseBaseNotAncestor, baseCommit)
+	}
+
+	return nil
+}
+
+// Todos are matched by sha, so the shas we put in an instruction must be full
+// ones; abbreviated shas or refs typed by the user are resolved here. Shas of
+// the given commits are already full, so we only ask git about the others.
+func (self *RebaseCommands) resolveShas(commits []*models.Commit, shas []string) ([]string, error) {
+	knownShas := lo.SliceToMap(commits, func(commit *models.Commit) (string, bool) { return commit.Sha, true })
+
+	result := make([]string, 0, len(shas))
+	for _, sha := range shas {
+		if !knownShas[sha] {
+			resolvedSha, err := self.ResolveSha(sha)
+			if err != nil {
+				return nil, err
+			}
+			sha = resolvedSha
+		}
+		result = append(result, sha)
+	}
+
+	return result, nil
+}
+
+// BranchesInRange returns the local branches that a rebase of the commits
+// above baseSha would affect, i.e. those whose tip is a descendant of baseSha
+// and an ancestor of HEAD. A branch pointing at baseSha itself isn't affected,
+// and the checked-out branch is left out since it's the one being rebased.
+func (self *RebaseCommands) BranchesInRange(baseSha string) ([]string, error) {
+	resolvedBaseSha, err := self.ResolveSha(baseSha)
+	if err != nil {
+		return nil, err
+	}
+
+	cmdArgs := NewGitCmd("for-each-ref").
+		Arg("--format=%(HEAD)%(objectname) %(refname:short)").
+		Arg("--contains", resolvedBaseSha).
+		Arg("--merged", "HEAD").
+		Arg("refs/heads").
+		ToArgv()
+	output, err := self.cmd.New(cmdArgs).DontLog().RunWithOutput()
+	if err != nil {
+		return nil, err
+	}
+
+	result := []string{}
+	for _, line := range utils.SplitLines(output) {
+		isHead := strings.HasPrefix(line, "*")
+		sha, name, found := strings.Cut(line[1:], " ")
+		if !found || isHead || sha == resolvedBaseSha {
+			continue
+		}
+		result = append(result, name)
+	}
+
+	return result, nil
+}
+
+// RangeTouchesSubmodules tells whether any of the commits between baseRef and
+// HEAD (or any of HEAD's commits if baseRef is empty) change .gitmodules or the
+// commit that a submodule points at. Rebasing such commits doesn't check the
+// submodules out again, so they can end up showing as modified afterwards.
+func (self *RebaseCommands) RangeTouchesSubmodules(baseRef string) (bool, error) {
+	cmdArgs := NewGitCmd("log").
+		Arg("--format=", "--raw", "--no-renames").
+		ArgIfElse(baseRef == "", "HEAD", baseRef+"..HEAD").
+		ToArgv()
+	output, err := self.cmd.New(cmdArgs).DontLog().RunWithOutput()
+	if err != nil {
+		return false, err
+	}
+
+	// Lines look like ":160000 160000 <old sha> <new sha> M\t<path>"
+	return lo.SomeBy(utils.SplitLines(output), func(line string) bool {
+		status, path, found := strings.Cut(line, "\t")
+		if !found {
+			return false
+		}
+		fields := strings.Fields(strings.TrimPrefix(status, ":"))
+		isGitlink := len(fields) >= 2 && (fields[0] == "160000" || fields[1] == "160000")
+		return isGitlink || path == ".gitmodules"
+	}), nil
+}
+
+// RebaseRangeStats returns how many commits a rebase onto base would rewrite,
+// i.e. the number of commits between base and HEAD, so that the UI can ask
+// before rewriting an unexpectedly long range (e.g. when the wrong base was
+// picked).
+func (self *RebaseCommands) RebaseRangeStats(base string) (int, error) {
+	cmdArgs := NewGitCmd("rev-list").Arg("--count", base+"..HEAD").ToArgv()
+	output, err := self.cmd.New(cmdArgs).DontLog().RunWithOutput()
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.Atoi(strings.TrimSpace(output))
+}
+
+// RangeContainsMerges tells whether any of the commits between base and HEAD
+// is a merge, so that the UI can warn before a rebase of that range that
+// --rebase-merges will try to recreate the merges, which may change the shape
+// of the history in ways the user doesn't expect.
+func (self *RebaseCommands) RangeContainsMerges(base string) (bool, error) {
+	cmdArgs := NewGitCmd("rev-list").Arg("--merges", "--max-count=1", base+"..HEAD").ToArgv()
+	output, err := self.cmd.New(cmdArgs).DontLog().RunWithOutput()
+	if err != nil {
+		return false, err
+	}
+
+	return strings.TrimSpace(output) != "", nil
+}
+
+// ExportRebasePlan returns the todo list that an interactive rebase onto base
+// would start out with, before anyone edits it: a pick for each commit in the
+// range, oldest first, with its full sha and subject. This is meant to be
+// shared for review before rewriting history. Rather than running a rebase
+// and capturing its todo, which would need a clean working tree and run the
+// pre-rebase hook, we ask for the commits the same way git does when it
+// builds the todo: commits whose changes are already in base are left out, as
+// are merge commits.
+func (self *RebaseCommands) ExportRebasePlan(base string) (string, error) {
+	cmdArgs := NewGitCmd("log").
+		Arg("--reverse", "--topo-order", "--no-merges", "--right-only", "--cherry-pick").
+		Arg("--format=pick %H %s").
+		Arg(base + "...HEAD").
+		ToArgv()
+
+	return self.cmd.New(cmdArgs).DontLog().RunWithOutput()
+}
+
+// RebaseOnto transplants the commits of the checked-out branch above baseCommit
+// onto the target branch (git rebase --onto). emptyCommits says what to do with
+// commits that become empty because the target already has their changes, like
+// in the other rebase flows; commits that were empty to begin with, e.g.
+// deliberate marker commits, are moved along with the rest. If onProgress isn't
+// nil, it's told about each step of the rebase as git gets to it.
+func (self *RebaseCommands) RebaseOnto(targetBranchName string, baseCommit string, emptyCommits EmptyCommitsMode, onProgress func(RebaseProgress)) error {
+	if err := self.checkCanRebaseWithoutAutostash(); err != nil {
+		return err
+	}
+
+	if err := self.checkBaseIsAncestorOfHead(baseCommit); err != nil {
+		return err
+	}
+
+	return self.runInteractiveRebase(PrepareInteractiveRebaseCommandOpts{
+		baseShaOrRoot: baseCommit,
+		onto:          targetBranchName,
+		emptyCommits:  emptyCommits,
+		onProgress:    onProgress,
+	})
+}
+
+func (self *RebaseCommands) GenericMergeOrRebaseActionCmdObj(commandType string, command string) oscommands.ICmdObj {
+	return self.mergeOrRebaseActionCmdObj(commandType, command, "")
+}
+
+// Like GenericMergeOrRebaseActionCmdObj, but runs git with the given hooks
+// directory if it isn't "" (see installRebaseResultHook)
+func (self *RebaseCommands) mergeOrRebaseActionCmdObj(commandType string, command string, hooksPath string) oscommands.ICmdObj {
+	// Git doesn't remember the conflict style for the rest of the rebase, so
+	// the todos that a continue or skip gets to need it again
+	conflictStyle := ""
+	if commandType == "rebase" {
+		conflictStyle = self.conflictStyle()
+	}
+	cmdArgs := NewGitCmd(commandType).
+		ConfigIf(hooksPath != "", "core.hooksPath="+hooksPath).
+		ConfigIf(conflictStyle != "", "merge.conflictStyle="+conflictStyle).
+		Arg("--" + command).
+		ToArgv()
+
+	return self.cmd.New(cmdArgs)
+}
+
+// Returns the merge.conflictStyle to run rebases with, going by the
+// git.rebase.conflictStyle config, or "" to leave it to git's own config
+func (self *RebaseCommands) conflictStyle() string {
+	conflictStyle := self.UserConfig.Git.Rebase.ConflictStyle
+	if conflictStyle == "zdiff3" && !self.version.IsAtLeast(2, 35, 0) {
+		self.Log.Warn("Using the diff3 conflict style instead of zdiff3 because zdiff3 requires git 2.35 or later")
+		return "diff3"
+	}
+
+	return conflictStyle
+}
+
+// ContinueRebase is ContinueRebaseWithResult for callers that only care
+// whether continuing failed
+func (self *RebaseCommands) ContinueRebase() error {
+	_, err := self.ContinueRebaseWithResult()
+	return err
+}
+
+// Where a rebase got to after being continued
+type RebaseResult int
+
+const (
+	// The rebase ran to the end
+	RebaseResultCompleted RebaseResult = iota
+	// The rebase stopped at conflicts that the user needs to resolve
+	RebaseResultConflicts
+	// The rebase stopped without conflicts, at an edit todo or because an
+	// exec failed
+	RebaseResultStopped
+	// The rebase stopped at a break todo, e.g. one that EditRebase inserted.
+	// Nothing went wrong; the user can make changes and then continue.
+	RebaseResultStoppedAtBreak
+)
+
+// ContinueRebaseWithResult continues the rebase and tells us where it got to,
+// so that callers don't need to query the repo state themselves. Like
+// ContinueRebase, it returns git's error if continuing failed; the result is
+// only worked out when git succeeded or stopped at conflicts, and is
+// RebaseResultStopped for any other failure.
+func (self *RebaseCommands) ContinueRebaseWithResult() (RebaseResult, error) {
+	err := self.GenericMergeOrRebaseAction("rebase", "continue")
+	if err != nil {
+		// git's output may be translated, so we can only tell that it
+		// stopped at conflicts by looking for unmerged files. If we can't do
+		// that, git's error is still the one the user needs to see.
+		unmergedFiles, stateErr := self.unmergedFiles()
+		if stateErr != nil || len(unmergedFiles) == 0 {
+			return RebaseResultStopped, err
+		}
+		return RebaseResultConflicts, err
+	}
+
+	operation, err := self.status.CurrentOperation()
+	if err != nil {
+		return RebaseResultStopped, err
+	}
+	if operation != enums.OPERATION_REBASE {
+		return RebaseResultCompleted, nil
+	}
+
+	done, err := self.RebaseDoneSteps()
+	if err != nil {
+		return RebaseResultStopped, err
+	}
+	if lastTodoIsBreak(done) {
+		return RebaseResultStoppedAtBreak, nil
+	}
+
+	return RebaseResultStopped, nil
+}
+
+// The commit that a continued rebase couldn't apply, and the files that have
+// conflicts because of it
+type RebaseConflict struct {
+	Sha   string
+	Files []string
+}
+
+// Git says which commit it couldn't apply in a line like "error: could not
+// apply 1234567... subject"
+var couldNotApplyRegexp = regexp.MustCompile(`could not apply ([0-9a-f]{4,})`)
+
+// ContinueRebaseWithConflicts is like ContinueRebaseWithResult, but when the
+// rebase stops at conflicts it also returns the commit that couldn't be applied
+// and the conflicted files, so that the UI can take the user straight to them.
+func (self *RebaseCommands) ContinueRebaseWithConflicts() (RebaseResult, *RebaseConflict, error) {
+	result, err := self.ContinueRebaseWithResult()
+	if result != RebaseResultConflicts {
+		return result, nil, err
+	}
+
+	files, filesErr := self.unmergedFiles()
+	if filesErr != nil {
+		return result, nil, err
+	}
+
+	sha, shaErr := self.couldNotApplySha(err)
+	if shaErr != nil {
+		return result, nil, err
+	}
+
+	return result, &RebaseConflict{Sha: sha, Files: files}, err
+}
+
+// Returns the full sha of the commit that git couldn't apply. Git's output is
+// only in English if the user's locale is, so when we can't find the sha in
+// it, we take it from the todo that git was carrying out when it stopped.
+func (self *RebaseCommands) couldNotApplySha(rebaseErr error) (string, error) {
+	if rebaseErr != nil {
+		if match := couldNotApplyRegexp.FindStringSubmatch(rebaseErr.Error()); match != nil {
+			return self.ResolveSha(match[1])
+		}
+	}
+
+	done, err := self.RebaseDoneSteps()
+	if err != nil {
+		return "", err
+	}
+	if len(done) == 0 || done[len(done)-1].Commit == "" {
+		return "", errors.New("the rebase isn't stopped at a commit")
+	}
+
+	return self.ResolveSha(done[len(done)-1].Commit)
+}
+
+// StoppedAtBreak tells whether the paused rebase stopped at a break todo,
+// rather than at conflicts, an edit or a failed exec, so that the UI can tell
+// the user they're free to make changes before continuing.
+func (self *RebaseCommands) StoppedAtBreak() (bool, error) {
+	done, err := self.RebaseDoneSteps()
+	if err != nil {
+		return false, err
+	}
+	if !lastTodoIsBreak(done) {
+		return false, nil
+	}
+
+	unmergedFiles, err := self.unmergedFiles()
+	if err != nil {
+		return false, err
+	}
+
+	return len(unmergedFiles) == 0, nil
+}
+
+// Git appends each todo to the done file as it starts carrying it out, so
+// when it has stopped at a break, that's the last thing in there
+func lastTodoIsBreak(done []todo.Todo) bool {
+	return len(done) > 0 && done[len(done)-1].Command == todo.Break
+}
+
+// ContinueNeedsMessageEditor tells us whether continuing the paused rebase
+// will get to a reword or squash, where git asks for a commit message that the
+// user should get to edit. When it doesn't, ContinueRebase can skip the editor
+// altogether.
+func (self *RebaseCommands) ContinueNeedsMessageEditor() (bool, error) {
+	done, err := self.RebaseDoneSteps()
+	if err != nil {
+		return false, err
+	}
+	if utils.DoneTodosNeedMessageEditor(done) {
+		return true, nil
+	}
+	if givenUpOn, err := self.rewordGivenUpOn(done); err != nil || givenUpOn != "" {
+		return givenUpOn != "", err
+	}
+
+	todoPath := filepath.Join(self.repoPaths.WorktreeGitDirPath(), "rebase-merge/git-rebase-todo")
+	remaining, err := utils.ReadRebaseTodoFile(todoPath, self.config.GetCoreCommentChar())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return lo.SomeBy(remaining, func(t todo.Todo) bool {
+		return t.Command == todo.Reword || t.Command == todo.Squash
+	}), nil
+}
+
+// ContinueRebaseWithEditorForRewords continues the rebase, opening the user's
+// editor only for the reword and squash todos and keeping the message as is
+// for everything else (e.g. the pick that stopped at conflicts). Because of the
+// editor, git needs to be run as a subprocess, which runSubprocess takes care
+// of; it returns what git printed. Once git is done we carry on like
+// ContinueRebase does, so any step queued up with onSuccessfulContinue runs.
+func (self *RebaseCommands) ContinueRebaseWithEditorForRewords(runSubprocess func(oscommands.ICmdObj) (string, error)) error {
+	self.dropContinuationOfOtherRebase()
+
+	if err := self.rewordCommitGivenUpOn(runSubprocess); err != nil {
+		return err
+	}
+
+	hooksPath := self.installRebaseResultHook()
+	continueCmdObj := func() (oscommands.ICmdObj, error) {
+		return self.continueRebaseWithEditorForRewordsCmdObj(hooksPath)
+	}
+	cmdObj, err := continueCmdObj()
+	if err != nil {
+		return err
+	}
+
+	err = self.continueWithCommitterDates(cmdObj, continueCmdObj, func(cmdObj oscommands.ICmdObj) error {
+		output, err := runSubprocess(cmdObj)
+		if err != nil && strings.TrimSpace(output) != "" {
+			// like the errors of the commands we run ourselves, so that callers
+			// can tell e.g. conflicts from other failures
+			return errors.New(output)
+		}
+		return err
+	})
+
+	return self.afterMergeOrRebaseAction("rebase", "continue", err)
+}
+
+// If git gave up on one of our queued rewords, we open the editor for it
+// again by amending the commit, before the rebase moves on. If the editor
+// fails again, we stay stopped at the commit.
+func (self *RebaseCommands) rewordCommitGivenUpOn(runSubprocess func(oscommands.ICmdObj) (string, error)) error {
+	done, err := self.RebaseDoneSteps()
+	if err != nil {
+		return err
+	}
+	givenUpOn, err := self.rewordGivenUpOn(done)
+	if err != nil || givenUpOn == "" {
+		return err
+	}
+
+	cmdArgs := NewGitCmd("commit").Arg("--amend", "--only", "--allow-empty").ToArgv()
+	if output, err := runSubprocess(self.cmd.New(cmdArgs)); err != nil {
+		if strings.TrimSpace(output) != "" {
+			return errors.New(output)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Builds the command for ContinueRebaseWithEditorForRewords. It commits the
+// rest of a restaged commit right away though, because git won't continue
+// otherwise.
+func (self *RebaseCommands) continueRebaseWithEditorForRewordsCmdObj(hooksPath string) (oscommands.ICmdObj, error) {
+	if err := self.finishRestage("continue"); err != nil {
+		return nil, err
+	}
+
+	// Ask git which editor it would use, so that we respect core.editor and
+	// the environment the same way it does
+	cmdArgs := NewGitCmd("var").Arg("GIT_EDITOR").ToArgv()
+	editor, err := self.cmd.New(cmdArgs).DontLog().RunWithOutput()
+	if err != nil {
+		return nil, err
+	}
+
+	// Without lazygit in between, git opens the editor for every commit it
+	// wants a message for
+	if !self.UserConfig.Git.Rebase.UseDaemon {
+		return self.mergeOrRebaseActionCmdObj("rebase", "continue", hooksPath), nil
+	}
+
+	instruction := daemon.NewEditorForRewordsInstruction(strings.TrimSpace(editor))
+	return self.mergeOrRebaseActionCmdObj("rebase", "continue", hooksPath).
+		AddEnvVars("GIT_EDITOR=" + oscommands.GetLazygitPath()).
+		AddEnvVars(daemon.ToEnvVars(instruction)...), nil
+}
+
+// Returns the paths of the files that still have unresolved conflicts
+func (self *RebaseCommands) unmergedFiles() ([]string, error) {
+	cmdArgs := NewGitCmd("diff").Arg("--name-only", "--diff-filter=U", "-z").ToArgv()
+	output, err := self.cmd.New(cmdArgs).DontLog().RunWithOutput()
+	if err != nil {
+		return nil, err
+	}
+
+	return lo.Compact(strings.Split(output, "\x00")), nil
+}
+
+// ContinueWouldBeEmpty tells us whether continuing the paused rebase would
+// create an empty commit, e.g. because the user resolved the conflicts by
+// taking the parent's version of every file. Git refuses to continue in that
+// case and asks the user to skip the commit instead, so the UI can offer that
+// up front. It's false if git isn't about to create a commit, e.g. because
+// we're stopped at an edit (where the commit has already been made) or at a
+// break.
+func (self *RebaseCommands) ContinueWouldBeEmpty() (bool, error) {
+	rebaseMergeDir := filepath.Join(self.repoPaths.WorktreeGitDirPath(), "rebase-merge")
+
+	// git keeps the message of the commit it's going to create in this file,
+	// and writes the amend file when it stops at an edit
+	if _, err := os.Stat(filepath.Join(rebaseMergeDir, "message")); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	if _, err := os.Stat(filepath.Join(rebaseMergeDir, "amend")); err == nil {
+		return false, nil
+	}
+
+	// While paused, HEAD is the parent of the commit that git is about to
+	// create, so comparing the index against it tells us what that commit
+	// would contain. Unmerged files show up here too, which is what we want
+	// because they mean the commit isn't ready yet.
+	cmdArgs := NewGitCmd("diff").Arg("--cached", "--name-only", "HEAD").ToArgv()
+	output, err := self.cmd.New(cmdArgs).DontLog().RunWithOutput()
+	if err != nil {
+		return false, err
+	}
+
+	return strings.TrimSpace(output) == "", nil
+}
+
+// ContinueRebaseAutoStage stages all files that still have unmerged status but
+// whose conflicts the user has since resolved, then continues the rebase. If
+// any of those files still contain conflict markers we stage nothing and
+// return an error listing them instead.
+func (self *RebaseCommands) ContinueRebaseAutoStage() error {
+	unmergedFiles, err := self.unmergedFiles()
+	if err != nil {
+		return err
+	}
+
+	if len(unmergedFiles) > 0 {
+		filesWithMarkers := []string{}
+		for _, fileName := range unmergedFiles {
+			// git lists the files relative to the top of the worktree
+			hasMarkers, err := utils.FileHasConflictMarkers(filepath.Join(self.repoPaths.WorktreePath(), fileName))
+			// a file that was deleted on one side of the conflict may not exist
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			if hasMarkers {
+				filesWithMarkers = append(filesWithMarkers, fileName)
+			}
+		}
+
+		if len(filesWithMarkers) > 0 {
+			return errors.Errorf("the following files still contain conflict markers:\n%s",
+				strings.Join(filesWithMarkers, "\n"))
+		}
+
+		if err := self.workingTree.StageFiles(unmergedFiles); err != nil {
+			return err
+		}
+	}
+
+	return self.ContinueRebase()
+}
+
+func (self *RebaseCommands) AbortRebase() error {
+	return self.GenericMergeOrRebaseAction("rebase", "abort")
+}
+
+// AbortRebaseAndCheckout aborts the rebase and then checks out the given
+// branch, for when the rebase was part of a larger operation and the user
+// wants to get back to where they started. Aborting also reapplies any changes
+// that git autostashed, and those are carried over to the branch as long as
+// they don't conflict with it. We only check out the branch if the abort
+// succeeded.
+func (self *RebaseCommands) AbortRebaseAndCheckout(branchName string) error {
+	if err := self.AbortRebase(); err != nil {
+		return err
+	}
+
+	return self.branch.Checkout(branchName, CheckoutOptions{})
+}
+
+// AbortCurrentOperation aborts whichever of a rebase, merge, cherry-pick or
+// revert is in progress, so that the user doesn't need to know which state
+// they're in to get out of it
+func (self *RebaseCommands) AbortCurrentOperation() error {
+	// whatever we were going to do after the operation, we're not doing it now
+	self.clearOnSuccessfulContinue()
+
+	operation, err := self.status.CurrentOperation()
+	if err != nil {
+		return err
+	}
+
+	switch operation {
+	case enums.OPERATION_REBASE:
+		return self.AbortRebase()
+	case enums.OPERATION_MERGE:
+		return self.GenericMergeOrRebaseAction("merge", "abort")
+	case enums.OPERATION_CHERRY_PICK:
+		return self.GenericMergeOrRebaseAction("cherry-pick", "abort")
+	case enums.OPERATION_REVERT:
+		return self.GenericMergeOrRebaseAction("revert", "abort")
+	}
+
+	return nil
+}
+
+// QuitRebase removes the rebase state without touching HEAD, the index, or
+// the working tree. This is the recovery path for a rebase-merge directory
+// that is too broken to continue or abort.
+func (self *RebaseCommands) QuitRebase() error {
+	self.clearOnSuccessfulContinue()
+
+	cmdArgs := NewGitCmd("rebase").Arg("--quit").ToArgv()
+
+	return self.cmd.New(cmdArgs).Run()
+}
+
+// The files git needs in order to resume an interactive rebase
+var rebaseMergeStateFiles = []string{"git-rebase-todo", "done", "msgnum", "end", "onto"}
+
+// IsRebaseStateHealthy checks that the rebase-merge directory contains the
+// files git needs to resume the rebase, and returns the names of any that are
+// missing or malformed. If lazygit or git was killed mid-rebase, some of these
+// may not have been written. When no interactive rebase is in progress we
+// report the state as healthy.
+func (self *RebaseCommands) IsRebaseStateHealthy() (bool, []string, error) {
+	dir := filepath.Join(self.repoPaths.WorktreeGitDirPath(), "rebase-merge")
+	exists, err := self.os.FileExists(dir)
+	if err != nil || !exists {
+		return true, nil, err
+	}
+
+	problems := []string{}
+	for _, name := range rebaseMergeStateFiles {
+		content, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			if os.IsNotExist(err) {
+				problems = append(problems, name)
+				continue
+			}
+			return false, nil, err
+		}
+
+		value := strings.TrimSpace(string(content))
+		switch name {
+		case "msgnum", "end":
+			if _, err := strconv.Atoi(value); err != nil {
+				problems = append(problems, name)
+			}
+		case "onto":
+			if value == "" {
+				problems = append(problems, name)
+			}
+		}
+	}
+
+	return len(problems) == 0, problems, nil
+}
+
+// The todos that a rebase can contain without the user having edited the todo
+// by hand: git generates these itself (e.g. with --rebase-merges)
+var generatedTodoCommands = []todo.TodoCommand{
+	todo.Pick, todo.Label, todo.Reset, todo.Merge, todo.UpdateRef, todo.NoOp, todo.Comment,
+}
+
+// CanResumeRebase tells us whether lazygit can safely continue, skip or abort
+// the rebase that's in progress. If it can't, the reason explains why, so that
+// the user can be told to deal with the rebase themselves. This only reads a
+// few small files, so it's fine to call on every refresh.
+func (self *RebaseCommands) CanResumeRebase() (bool, string) {
+	gitDir := self.repoPaths.WorktreeGitDirPath()
+	rebaseMergeDir := filepath.Join(gitDir, "rebase-merge")
+
+	if exists, _ := self.os.FileExists(rebaseMergeDir); !exists {
+		if exists, _ := self.os.FileExists(filepath.Join(gitDir, "rebase-apply")); exists {
+			return true, ""
+		}
+		return false, "no rebase is in progress"
+	}
+
+	healthy, problems, err := self.IsRebaseStateHealthy()
+	if err != nil {
+		return false, err.Error()
+	}
+	if !healthy {
+		return false, fmt.Sprintf("the rebase state is corrupt (bad or missing: %s)", strings.Join(problems, ", "))
+	}
+
+	if exists, _ := self.os.FileExists(filepath.Join(rebaseMergeDir, daemon.StartedByLazygitMarkerFile)); exists {
+		return true, ""
+	}
+
+	commentChar := self.config.GetCoreCommentChar()
+	for _, name := range []string{"done", "git-rebase-todo"} {
+		todos, err := utils.ReadRebaseTodoFile(filepath.Join(rebaseMergeDir, name), commentChar)
+		if err != nil {
+			return false, err.Error()
+		}
+		if lo.SomeBy(todos, func(t todo.Todo) bool { return !lo.Contains(generatedTodoCommands, t.Command) }) {
+			return false, "the rebase was started outside of lazygit with a custom todo"
+		}
+	}
+
+	return true, ""
+}
+
+// GenericMerge takes a commandType of "merge" or "rebase" and a command of "abort", "skip" or "continue"
+// By default we skip the editor in the case where a commit will be made
+func (self *RebaseCommands) GenericMergeOrRebaseAction(commandType string, command string) error {
+	if commandType == "rebase" && command == "continue" {
+		self.dropContinuationOfOtherRebase()
+	}
+	if commandType == "rebase" && (command == "continue" || command == "skip") {
+		if err := self.finishRestage(command); err != nil {
+			return err
+		}
+	}
+	if commandType == "rebase" && command == "continue" {
+		if err := self.finishExecCherryPick(); err != nil {
+			return err
+		}
+	}
+
+	var err error
+	if commandType == "rebase" && (command == "continue" || command == "skip") {
+		hooksPath := self.installRebaseResultHook()
+		cmdObj := self.mergeOrRebaseActionCmdObj(commandType, command, hooksPath)
+		continueCmdObj := func() (oscommands.ICmdObj, error) {
+			return self.mergeOrRebaseActionCmdObj("rebase", "continue", hooksPath), nil
+		}
+		err = self.continueWithCommitterDates(cmdObj, continueCmdObj, self.runSkipEditorCommand)
+	} else {
+		err = self.runSkipEditorCommand(self.GenericMergeOrRebaseActionCmdObj(commandType, command))
+	}
+	return self.afterMergeOrRebaseAction(commandType, command, err)
+}
+
+// Everything that needs doing once git has carried out a merge or rebase
+// action, whether we ran it ourselves or in a subprocess
+func (self *RebaseCommands) afterMergeOrRebaseAction(commandType string, command string, err error) error {
+	if commandType == "rebase" {
+		self.recordFailedExec(err)
+	}
+	if err != nil {
+		if !strings.Contains(err.Error(), "no rebase in progress") {
+			return err
+		}
+		self.Log.Warn(err)
+	}
+
+	if commandType == "rebase" && (command == "continue" || command == "skip") {
+		self.noteRebuiltCommits()
+	}
+
+	// sometimes we need to do a sequence of things in a rebase but the user needs to
+	// fix merge conflicts along the way. When this happens we queue up the next step
+	// so that after the next successful rebase continue we can continue from where we left off
+	if commandType == "rebase" && command == "continue" && self.onSuccessfulContinue != nil {
+		f := self.onSuccessfulContinue
+		self.clearOnSuccessfulContinue()
+		return f()
+	}
+	if command == "abort" {
+		self.clearOnSuccessfulContinue()
+	}
+	return nil
+}
+
+// The name of a file in the git dir that describes the step queued up with
+// setOnSuccessfulContinue. The step itself only lives in memory, so if lazygit
+// is closed before the rebase is continued, the file is how the next instance
+// finds out that an operation was left half done.
+const pendingContinuationFile = "lazygit-pending-continuation"
+
+func (self *RebaseCommands) pendingContinuationPath() string {
+	return filepath.Join(self.repoPaths.WorktreeGitDirPath(), pendingContinuationFile)
+}
+
+// Queues up f to be run after the next successful continue. description says
+// which operation it belongs to, for DanglingContinuation to report.
+func (self *RebaseCommands) setOnSuccessfulContinue(description string, f func() error) {
+	self.onSuccessfulContinue = f
+	self.onSuccessfulContinueRebase, _ = self.currentRebaseIdentity()
+	if err := os.WriteFile(self.pendingContinuationPath(), []byte(description), 0o644); err != nil {
+		self.Log.Warnf("Failed to save the pending continuation: %v", err)
+	}
+}
+
+func (self *RebaseCommands) clearOnSuccessfulContinue() {
+	self.onSuccessfulContinue = nil
+	self.onSuccessfulContinueRebase = rebaseIdentity{}
+	if err := os.Remove(self.pendingContinuationPath()); err != nil && !os.IsNotExist(err) {
+		self.Log.Warnf("Failed to remove the pending continuation: %v", err)
+	}
+}
+
+// What tells one rebase apart from another: the branch being rebased and the
+// commit it's being rebased onto
+type rebaseIdentity struct {
+	headName string
+	onto     string
+}
+
+// Returns the identity of the rebase in progress, if there is one
+func (self *RebaseCommands) currentRebaseIdentity() (rebaseIdentity, bool) {
+	for _, dir := range []string{"rebase-merge", "rebase-apply"} {
+		rebaseDir := filepath.Join(self.repoPaths.WorktreeGitDirPath(), dir)
+		headName, err := os.ReadFile(filepath.Join(rebaseDir, "head-name"))
+		if err != nil {
+			continue
+		}
+		onto, err := os.ReadFile(filepath.Join(rebaseDir, "onto"))
+		if err != nil {
+			continue
+		}
+		return rebaseIdentity{
+			headName: strings.TrimSpace(string(headName)),
+			onto:     strings.TrimSpace(string(onto)),
+		}, true
+	}
+
+	return rebaseIdentity{}, false
+}
+
+// A queued up step belongs to the rebase it was queued up in. If that rebase
+// went away without us noticing (e.g. it was aborted from the command line)
+// and the user is now continuing a different one, the step must not run at
+// the end of it, so we drop it.
+func (self *RebaseCommands) dropContinuationOfOtherRebase() {
+	if self.onSuccessfulContinue == nil {
+		return
+	}
+
+	identity, ok := self.currentRebaseIdentity()
+	if ok && identity != self.onSuccessfulContinueRebase {
+		self.Log.Warnf("Dropping the step queued up for the rebase of %s onto %s, which isn't the one being continued",
+			self.onSuccessfulContinueRebase.headName, self.onSuccessfulContinueRebase.onto)
+		self.clearOnSuccessfulContinue()
+	}
+}
+
+// DanglingContinuation tells whether an earlier lazygit instance queued up a
+// step to run after the rebase was continued, but went away before it could
+// run it (e.g. because the user quit while resolving conflicts). It returns
+// the description of the operation that was left unfinished, so that the user
+// can be warned to check the result.
+func (self *RebaseCommands) DanglingContinuation() (string, bool) {
+	if self.onSuccessfulContinue != nil {
+		return "", false
+	}
+
+	description, err := os.ReadFile(self.pendingContinuationPath())
+	if err != nil {
+		return "", false
+	}
+
+	return string(description), true
+}
+
+// DiscardDanglingContinuation forgets about the step that DanglingContinuation
+// reported, once the user has been told about it
+func (self *RebaseCommands) DiscardDanglingContinuation() {
+	self.clearOnSuccessfulContinue()
+}
+
+func (self *RebaseCommands) runSkipEditorCommand(cmdObj oscommands.ICmdObj) error {
+	instruction := daemon.NewExitImmediatelyInstruction()
+	editor := self.skipEditor()
+	return cmdObj.
+		AddEnvVars(
+			"GIT_EDITOR="+editor,
+			"GIT_SEQUENCE_EDITOR="+editor,
+			"EDITOR="+editor,
+			"VISUAL="+editor,
+		).
+		AddEnvVars(daemon.ToEnvVars(instruction)...).
+		Run()
+}
+
+// DiscardOldFileChanges discards changes to a file from an old commit
+func (self *RebaseCommands) DiscardOldFileChanges(commits []*models.Commit, commitIndex int, fileName string) error {
+	if err := self.BeginInteractiveRebaseForCommit(commits, commitIndex, false); err != nil {
+		return err
+	}
+
+	// check if file exists in previous commit (this command returns an error if the file doesn't exist)
+	cmdArgs := NewGitCmd("cat-file").Arg("-e", "HEAD^:"+fileName).ToArgv()
+
+	if err := self.cmd.New(cmdArgs).Run(); err != nil {
+		if err := self.os.Remove(fileName); err != nil {
+			return err
+		}
+		if err := self.workingTree.StageFile(fileName); err != nil {
+			return err
+		}
+	} else if err := self.workingTree.CheckoutFile("HEAD^", fileName); err != nil {
+		return err
+	}
+
+	// amend the commit
+	err := self.commit.amendHeadInRebase()
+	if err != nil {
+		return err
+	}
+
+	// continue
+	return self.ContinueRebase()
+}
+
+// RemoveFileFromHistory removes the given file from every commit that is only
+// on the checked-out branch, e.g. to get rid of an accidentally committed
+// secret. Commits that are also on another local branch are left alone, as
+// rewriting them would fork the history of that branch. This rewrites history
+// (which is a problem if the branch has been pushed already) and removes the
+// file from the working tree, so callers must get the user's explicit
+// confirmation first. Commits that only touched the file are kept as empty
+// commits rather than pruned, because git can't tell them apart from commits
+// that were empty on purpose.
+//
+// We do this with an interactive rebase that stops at each commit that has the
+// file to remove it and amend the commit. Once the file is gone, picking a
+// later commit that changes it conflicts, so we remove it there too and commit
+// the result ourselves. If anything else goes wrong, the rebase is aborted so
+// that the branch is left as it was.
+func (self *RebaseCommands) RemoveFileFromHistory(fileName string) error {
+	branchName, err := self.cmd.New(
+		NewGitCmd("symbolic-ref").Arg("--quiet", "--short", "HEAD").ToArgv(),
+	).DontLog().RunWithOutput()
+	if err != nil {
+		return errors.New("a file can only be removed from history when a branch is checked out")
+	}
+	branchName = strings.TrimSpace(branchName)
+
+	msg := utils.ResolvePlaceholderString(
+		self.Tr.Log.RemoveFileFromHistory,
+		map[string]string{
+			"fileName":   fileName,
+			"branchName": branchName,
+		},
+	)
+	self.os.LogCommand(msg, false)
+
+	baseShaOrRoot, changes, err := self.commitsWithFile(fileName, branchName)
+	if err != nil {
+		return err
+	}
+
+	err = self.runInteractiveRebase(PrepareInteractiveRebaseCommandOpts{
+		baseShaOrRoot:  baseShaOrRoot,
+		overrideEditor: true,
+		instruction:    daemon.NewChangeTodoActionsInstruction(changes),
+	})
+	for self.status.WorkingTreeState() == enums.REBASE_MODE_REBASING {
+		if err := self.removeFileFromPausedCommit(fileName, err); err != nil {
+			if abortErr := self.AbortRebase(); abortErr != nil {
+				self.Log.Warnf("Failed to abort the rebase: %v", abortErr)
+			}
+			return err
+		}
+		err = self.ContinueRebase()
+	}
+
+	return err
+}
+
+// Returns the base of the rebase that removes the file from the commits that
+// are only on the given branch, and the todo changes that make it stop at each
+// commit that the file needs to be removed from: the ones that change it, and
+// the oldest of them all if it inherits the file from a shared commit.
+func (self *RebaseCommands) commitsWithFile(fileName string, branchName string) (string, []daemon.ChangeTodoAction, error) {
+	// --exclude takes a glob, but branch names can't contain glob characters
+	// anyway
+	notOnOtherBranches := []string{"--not", "--exclude=" + branchName, "--branches"}
+
+	// Each line is a commit followed by its parents, oldest first
+	cmdArgs := NewGitCmd("rev-list").Arg("--reverse", "--parents", "HEAD").Arg(notOnOtherBranches...).ToArgv()
+	output, err := self.cmd.New(cmdArgs).DontLog().RunWithOutput()
+	if err != nil {
+		return "", nil, err
+	}
+	commits := lo.Map(utils.SplitLines(output), func(line string, _ int) []string { return strings.Fields(line) })
+	if lo.SomeBy(commits, func(fields []string) bool { return len(fields) > 2 }) {
+		return "", nil, errors.New("a file can't be removed from history that contains merge commits")
+	}
+
+	cmdArgs = NewGitCmd("rev-list").Arg("HEAD").Arg(notOnOtherBranches...).Arg("--", fileName).ToArgv()
+	output, err = self.cmd.New(cmdArgs).DontLog().RunWithOutput()
+	if err != nil {
+		return "", nil, err
+	}
+	changingShas := utils.SplitLines(output)
+
+	inheritsFile := false
+	if len(commits) > 0 && len(commits[0]) == 2 {
+		cmdArgs := NewGitCmd("ls-tree").Arg("--name-only", commits[0][1], "--", fileName).ToArgv()
+		output, err := self.cmd.New(cmdArgs).DontLog().RunWithOutput()
+		if err != nil {
+			return "", nil, err
+		}
+		inheritsFile = strings.TrimSpace(output) != ""
+	}
+
+	baseShaOrRoot := ""
+	changes := []daemon.ChangeTodoAction{}
+	for i, fields := range commits {
+		if !(i == 0 && inheritsFile) && !lo.Contains(changingShas, fields[0]) {
+			continue
+		}
+		if len(changes) == 0 {
+			baseShaOrRoot = "--root"
+			if len(fields) == 2 {
+				baseShaOrRoot = fields[1]
+			}
+		}
+		changes = append(changes, daemon.ChangeTodoAction{Sha: fields[0], NewAction: todo.Edit})
+	}
+	if len(changes) == 0 {
+		return "", nil, errors.Errorf("'%s' isn't in any commit that is only on branch '%s'", fileName, branchName)
+	}
+
+	return baseShaOrRoot, changes, nil
+}
+
+// Removes the file from the commit that the rebase is paused at: either an
+// edit, where we amend the commit, or a commit that changes the file and so
+// conflicted with its removal, which we commit ourselves. rebaseErr is what
+// git said when it paused, and is returned if the rebase paused for any other
+// reason.
+func (self *RebaseCommands) removeFileFromPausedCommit(fileName string, rebaseErr error) error {
+	unmergedFiles, err := self.unmergedFiles()
+	if err != nil {
+		return err
+	}
+	if lo.SomeBy(unmergedFiles, func(file string) bool { return file != fileName }) {
+		if rebaseErr == nil {
+			return errors.New("the rebase stopped at conflicts")
+		}
+		return rebaseErr
+	}
+
+	if len(unmergedFiles) == 0 && rebaseErr != nil {
+		return rebaseErr
+	}
+
+	cmdArgs := NewGitCmd("rm").Arg("--ignore-unmatch", "--quiet", "--", fileName).ToArgv()
+	if err := self.cmd.New(cmdArgs).Run(); err != nil {
+		return err
+	}
+
+	if len(unmergedFiles) == 0 {
+		return self.commit.amendHeadInRebase()
+	}
+
+	sha, err := self.couldNotApplySha(rebaseErr)
+	if err != nil {
+		return err
+	}
+	signoffFlag := self.commit.rebaseSignoffFlag()
+	// -C reuses the original commit's message and author
+	cmdArgs = NewGitCmd("commit").
+		Arg("--allow-empty", "--no-verify", "-C", sha).
+		ArgIf(signoffFlag != "", signoffFlag).
+		ToArgv()
+	return self.cmd.New(cmdArgs).Run()
+}
+
+type TreeChangeKind int
+
+const (
+	TreeChangeAdd TreeChangeKind = iota
+	TreeChangeModify
+	TreeChangeDelete
+)
+
+// A single edit to the files of a commit
+type TreeChange struct {
+	Kind TreeChangeKind
+	Path string
+	// The new content of the file. Not used for deletions
+	Content string
+}
+
+type TreeChanges []TreeChange
+
+// AmendCommitTree edits the files of an old commit directly, without going
+// through the working tree's staging area: it stops the rebase at the commit,
+// adds, modifies or deletes the given files, amends the commit and continues.
+// This is the multi-file generalisation of DiscardOldFileChanges.
+func (self *RebaseCommands) AmendCommitTree(commits []*models.Commit, index int, changes TreeChanges) error {
+	if index < 0 || index >= len(commits) {
+		return errors.New("index outside of range of commits")
+	}
+
+	if len(changes) == 0 {
+		return errors.New("no changes to make to the commit")
+	}
+
+	if err := self.validateTreeChanges(commits[index].Sha, changes); err != nil {
+		return err
+	}
+
+	if err := self.BeginInteractiveRebaseForCommit(commits, index, false); err != nil {
+		return err
+	}
+
+	for _, change := range changes {
+		var err error
+		if change.Kind == TreeChangeDelete {
+			err = self.os.Remove(change.Path)
+		} else {
+			err = self.os.CreateFileWithContent(change.Path, change.Content)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	paths := lo.Map(changes, func(change TreeChange, _ int) string { return change.Path })
+	if err := self.workingTree.StageFiles(paths); err != nil {
+		return err
+	}
+
+	if err := self.commit.amendHeadInRebase(); err != nil {
+		return err
+	}
+
+	return self.ContinueRebase()
+}
+
+// Checks that the files to modify or delete exist in the commit, and that the
+// files to add don't, so that we don't get halfway through the rebase before
+// finding out that the changes don't make sense
+func (self *RebaseCommands) validateTreeChanges(sha string, changes TreeChanges) error {
+	paths := lo.Map(changes, func(change TreeChange, _ int) string { return change.Path })
+	cmdArgs := NewGitCmd("ls-tree").Arg("-r", "--name-only", "-z", sha, "--").Arg(paths...).ToArgv()
+	output, err := self.cmd.New(cmdArgs).DontLog().RunWithOutput()
+	if err != nil {
+		return err
+	}
+	existingPaths := lo.Compact(strings.Split(output, "\x00"))
+
+	problems := []string{}
+	for _, change := range changes {
+		exists := lo.Contains(existingPaths, change.Path)
+		if change.Kind == TreeChangeAdd && exists {
+			problems = append(problems, fmt.Sprintf("'%s' already exists", change.Path))
+		} else if change.Kind != TreeChangeAdd && !exists {
+			problems = append(problems, fmt.Sprintf("'%s' does not exist", change.Path))
+		}
+	}
+
+	if len(problems) > 0 {
+		return errors.Errorf("cannot change the files of commit %s:\n%s", utils.ShortSha(sha), strings.Join(problems, "\n"))
+	}
+
+	return nil
+}
+
+// CherryPickCommits begins an interactive rebase with the given shas being cherry picked onto HEAD
+func (self *RebaseCommands) CherryPickCommits(commits []*models.Commit) error {
+	return self.CherryPickCommitsWithContext(context.Background(), commits)
+}
+
+// CherryPickCommitsWithContext is like CherryPickCommits, but gives up when
+// ctx is cancelled. Git may have applied some of the commits by then, so the
+// rebase is aborted, which puts HEAD and any autostashed changes back the way
+// they were before; the returned error is then ctx.Err().
+func (self *RebaseCommands) CherryPickCommitsWithContext(ctx context.Context, commits []*models.Commit) error {
+	commitLines := lo.Map(commits, func(commit *models.Commit, _ int) string {
+		return fmt.Sprintf("%s %s", utils.ShortSha(commit.Sha), commit.Name)
+	})
+	msg := utils.ResolvePlaceholderString(
+		self.Tr.Log.CherryPickCommits,
+		map[string]string{
+			"commitLines": strings.Join(commitLines, "\n"),
+		},
+	)
+	self.os.LogCommand(msg, false)
+
+	return self.runInteractiveRebase(PrepareInteractiveRebaseCommandOpts{
+		baseShaOrRoot: "HEAD",
+		instruction:   daemon.NewCherryPickCommitsInstruction(commits),
+		ctx:           ctx,
+	})
+}
+
+// CherryPickWithReword cherry-picks the given commit onto HEAD and gives the
+// copy the given message, keeping the original author. Like CherryPickCommits,
+// the commit is picked by a rebase; if that stops at conflicts, the reword is
+// queued up until the user has resolved them and continued.
+func (self *RebaseCommands) CherryPickWithReword(sha string, message string) error {
+	fullSha, err := self.ResolveSha(sha)
+	if err != nil {
+		return err
+	}
+	subject, err := self.commit.GetCommitSubject(fullSha)
+	if err != nil {
+		return err
+	}
+
+	reword := func() error {
+		return self.commit.RewordHeadNonInteractive(message)
+	}
+
+	err = self.CherryPickCommits([]*models.Commit{{Sha: fullSha, Name: subject}})
+	if err != nil {
+		if self.status.WorkingTreeState() == enums.REBASE_MODE_REBASING {
+			self.setOnSuccessfulContinue(self.Tr.Actions.CherryPickWithReword, reword)
+		}
+		return err
+	}
+
+	return reword()
+}
+
+// Returns the context to run git with, which is cancelled (killing git) once
+// ctx is done, but only after we've taken a look at the index lock, so that
+// abortIfCancelled can tell whether a lock is the one that the killed git
+// left behind. The returned function must be called once git has exited; it
+// returns the index lock as it was right before git was killed, or nil if
+// there was none or git wasn't killed.
+func (self *RebaseCommands) watchIndexLock(ctx context.Context) (context.Context, func() os.FileInfo) {
+	gitCtx, cancelGit := context.WithCancel(context.Background())
+	gitExited := make(chan struct{})
+	watchingDone := make(chan struct{})
+	var lockAtKill os.FileInfo
+
+	go func() {
+		defer close(watchingDone)
+		select {
+		case <-ctx.Done():
+			if info, err := os.Stat(self.indexLockPath()); err == nil {
+				lockAtKill = info
+			}
+			cancelGit()
+		case <-gitExited:
+		}
+	}()
+
+	return gitCtx, func() os.FileInfo {
+		close(gitExited)
+		<-watchingDone
+		cancelGit()
+		return lockAtKill
+	}
+}
+
+func (self *RebaseCommands) indexLockPath() string {
+	return filepath.Join(self.repoPaths.WorktreeGitDirPath(), "index.lock")
+}
+
+// To be called with the result of a rebase that was run with the given
+// context, the time at which it was started, and the index lock as it was
+// right before git was killed (see watchIndexLock). If the context was
+// cancelled before the rebase finished, git was killed partway through, so we
+// abort whatever it left behind rather than leaving the user in a half-applied
+// rebase.
+func (self *RebaseCommands) abortIfCancelled(ctx context.Context, err error, startedAt time.Time, lockAtKill os.FileInfo) error {
+	if err == nil || ctx.Err() == nil {
+		return err
+	}
+
+	// git may have been killed while it held the index lock, which would make
+	// the abort fail. We only remove the lock if it's provably the killed
+	// git's: the very file that was there when git was killed, untouched since
+	// (no live process is writing to it), and made after the rebase started
+	// (so it's not a lock that something else, e.g. a 'git commit' waiting for
+	// its editor, had been holding all along). Any other lock is left alone.
+	// The mtime may only have a resolution of a second.
+	lockPath := self.indexLockPath()
+	if info, statErr := os.Stat(lockPath); statErr == nil {
+		isKilledGitsLock := lockAtKill != nil &&
+			os.SameFile(info, lockAtKill) &&
+			info.ModTime().Equal(lockAtKill.ModTime()) &&
+			info.Size() == lockAtKill.Size() &&
+			!info.ModTime().Before(startedAt.Truncate(time.Second))
+		if !isKilledGitsLock {
+			return errors.Errorf("the cancelled rebase could not be aborted because %s may belong to another git process; remove it and abort the rebase once no other git process is running", lockPath)
+		}
+		if removeErr := os.Remove(lockPath); removeErr != nil {
+			return removeErr
+		}
+	}
+
+	if self.status.WorkingTreeState() == enums.REBASE_MODE_REBASING {
+		cmdArgs := NewGitCmd("rebase").Arg("--abort").ToArgv()
+		if abortErr := self.cmd.New(cmdArgs).Run(); abortErr != nil {
+			return abortErr
+		}
+	}
+
+	return ctx.Err()
+}
+
+// CherryPickCommitsWithMainline is like CherryPickCommits, but can also
+// cherry-pick merge commits. For those, git needs to be told which parent to
+// take the changes relative to; mainlineBySha maps the sha of each merge commit
+// to that parent's number, starting at 1 like git's -m option. The merges are
+// cherry-picked by exec todos in the same rebase as the other commits (see
+// NewCherryPickCommitsWithMainlineInstruction), so if one of them stops at
+// conflicts, continuing the rebase picks up the rest.
+func (self *RebaseCommands) CherryPickCommitsWithMainline(commits []*models.Commit, mainlineBySha map[string]int) error {
+	for _, commit := range commits {
+		if !commit.IsMerge() {
+			continue
+		}
+		mainline, ok := mainlineBySha[commit.Sha]
+		if !ok || mainline < 1 || mainline > len(commit.Parents) {
+			return errors.Errorf("no valid mainline parent given for merge commit %s", commit.ShortSha())
+		}
+	}
+
+	commitLines := lo.Map(commits, func(commit *models.Commit, _ int) string {
+		return fmt.Sprintf("%s %s", utils.ShortSha(commit.Sha), commit.Name)
+	})
+	msg := utils.ResolvePlaceholderString(
+		self.Tr.Log.CherryPickCommits,
+		map[string]string{
+			"commitLines": strings.Join(commitLines, "\n"),
+		},
+	)
+	self.os.LogCommand(msg, false)
+
+	return self.runInteractiveRebase(PrepareInteractiveRebaseCommandOpts{
+		baseShaOrRoot: "HEAD",
+		instruction:   daemon.NewCherryPickCommitsWithMainlineInstruction(commits, mainlineBySha),
+	})
+}
+
+// CherryPickCommitsDuringRebase simply prepends the given commits to the existing git-rebase-todo file
+func (self *RebaseCommands) CherryPickCommitsDuringRebase(commits []*models.Commit) error {
+	todoLines := lo.Map(commits, func(commit *models.Commit, _ int) daemon.TodoLine {
+		return daemon.TodoLine{
+			Action: "pick",
+			Commit: commit,
+		}
+	})
+
+	todo := daemon.TodoLinesToString(todoLines)
+	filePath := filepath.Join(self.repoPaths.worktreeGitDirPath, "rebase-merge/git-rebase-todo")
+	return utils.PrependStrToTodoFile(filePath, []byte(todo))
+}
+
+// InsertMergeDuringRebase makes the paused rebase turn the commits it has done
+// so far into a branch that gets merged: it labels HEAD with the given label,
+// resets to mergeRef, and merges the labelled commits into that, so that the
+// remaining todos go on top of the merge. This is how a merge that was
+// flattened gets recreated. mergeRef can be any commit, or a label set earlier
+// in the rebase.
+func (self *RebaseCommands) InsertMergeDuringRebase(label string, mergeRef string) error {
+	if label == "" || strings.ContainsAny(label, " \t\n") {
+		return errors.Errorf("invalid label '%s'", label)
+	}
+
+	// Labels live in refs/rewritten while the rebase is going on
+	if _, err := self.ResolveSha("refs/rewritten/" + mergeRef); err != nil {
+		if _, err := self.ResolveSha(mergeRef); err != nil {
+			return err
+		}
+	}
+
+	// newest first, like the commits we usually build todo lines from
+	todoLines := []daemon.TodoLine{
+		{Action: "merge", Label: label},
+		{Action: "reset", Label: mergeRef},
+		{Action: "label", Label: label},
+	}
+
+	todo := daemon.TodoLinesToString(todoLines)
+	self.os.LogCommand(strings.TrimSpace(todo), false)
+	filePath := filepath.Join(self.repoPaths.worktreeGitDirPath, "rebase-merge/git-rebase-todo")
+	return utils.PrependStrToTodoFile(filePath, []byte(todo))
+}
+
+// DropPausedCommit drops the commit that the rebase is currently stopped at for
+// editing, and continues the rebase. We refuse if the working tree has
+// changes, since these would be lost by the reset, and if HEAD is no longer
+// the commit that the rebase stopped at (e.g. because it was amended, or
+// commits were added on top of it), since then it's not clear what to drop.
+func (self *RebaseCommands) DropPausedCommit() error {
+	if _, err := self.checkStoppedAtEdit(); err != nil {
+		return err
+	}
+
+	clean, err := self.workingTree.WorkingTreeClean()
+	if err != nil {
+		return err
+	}
+	if !clean {
+		return errors.New("cannot drop the commit while there are uncommitted changes, as they would be lost")
+	}
+
+	if _, err := self.ResolveSha("HEAD~1"); err != nil {
+		return errors.New("cannot drop the initial commit this way")
+	}
+
+	cmdArgs := NewGitCmd("reset").Arg("--hard", "HEAD~1").ToArgv()
+	if err := self.cmd.New(cmdArgs).Run(); err != nil {
+		return err
+	}
+
+	return self.ContinueRebase()
+}
+
+// RewordPausedCommit gives the commit that the rebase is currently stopped at
+// for editing the given message, without continuing the rebase, so the user
+// can go on changing its content afterwards. Staged changes are left alone;
+// they are amended into the commit when the rebase continues, as usual.
+func (self *RebaseCommands) RewordPausedCommit(message string) error {
+	amendFilePath, err := self.checkStoppedAtEdit()
+	if err != nil {
+		return err
+	}
+
+	if err := self.commit.amendMessageCmdObj([]string{"-m", message}, self.commit.rebaseSignoffFlag()).Run(); err != nil {
+		return err
+	}
+
+	// On continue, git only amends staged changes into HEAD if HEAD is still
+	// the commit it stopped at, so we tell it about the reworded one. It also
+	// takes the message for that amend from the message file it wrote when it
+	// stopped, which still has the old message.
+	headSha, err := self.headSha()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(amendFilePath, []byte(headSha+"\n"), 0o644); err != nil {
+		return err
+	}
+
+	newMessage, err := self.commit.GetCommitMessage(headSha)
+	if err != nil {
+		return err
+	}
+	messageFilePath := filepath.Join(filepath.Dir(amendFilePath), "message")
+	return os.WriteFile(messageFilePath, []byte(newMessage+"\n"), 0o644)
+}
+
+// checkStoppedAtEdit checks that the rebase is stopped at an edit todo with HEAD
+// still at the commit it stopped at, and returns the path of git's amend file.
+// The sha in the done file is that of the original commit, but git may have
+// rebuilt it before stopping (e.g. because an earlier commit was dropped). Git
+// writes the commit it actually stopped at to the amend file, and checks HEAD
+// against that on continue, so we do the same.
+func (self *RebaseCommands) checkStoppedAtEdit() (string, error) {
+	doneTodos, err := self.RebaseDoneSteps()
+	if err != nil {
+		return "", err
+	}
+	if len(doneTodos) == 0 || doneTodos[len(doneTodos)-1].Command != todo.Edit {
+		return "", errors.New("the rebase is not stopped at a commit for editing")
+	}
+
+	amendFilePath := filepath.Join(self.repoPaths.WorktreeGitDirPath(), "rebase-merge", "amend")
+	content, err := os.ReadFile(amendFilePath)
+	if err != nil {
+		return "", errors.New("the rebase is not stopped at a commit for editing")
+	}
+	pausedSha := strings.TrimSpace(string(content))
+
+	headSha, err := self.headSha()
+	if err != nil {
+		return "", err
+	}
+	if headSha != pausedSha {
+		return "", errors.Errorf("HEAD has moved since the rebase stopped at commit %s", utils.ShortSha(pausedSha))
+	}
+
+	return amendFilePath, nil
+}
+
+// we can't start an interactive rebase from the first commit without passing the
+// '--root' arg
+func getBaseShaOrRoot(commits []*models.Commit, index int) string {
+	if index < len(commits) {
+		return commits[index].Sha
+	}
+
+	// The commits slice may have been cut short by git.log.commitLimit, in
+	// which case its oldest commit isn't the initial commit of the repo, and
+	// the base is one of that commit's ancestors that we haven't loaded.
+	if len(commits) > 0 {
+		oldest := commits[len(commits)-1]
+		if oldest.Truncated && len(oldest.Parents) > 0 {
+			if index == len(commits) {
+				return oldest.Parents[0]
+			}
+			return fmt.Sprintf("%s~%d", oldest.Parents[0], index-len(commits))
+		}
+	}
+
+	return "--root"
 }
@@ -1,15 +1,25 @@
 package git_commands
 
 import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
 	"regexp"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/fsmiamoto/git-todo-parser/todo"
 	"github.com/go-errors/errors"
+	"github.com/jesseduffield/gocui"
 	"github.com/jesseduffield/lazygit/pkg/app/daemon"
 	"github.com/jesseduffield/lazygit/pkg/commands/git_config"
 	"github.com/jesseduffield/lazygit/pkg/commands/models"
 	"github.com/jesseduffield/lazygit/pkg/commands/oscommands"
+	"github.com/jesseduffield/lazygit/pkg/config"
+	"github.com/jesseduffield/lazygit/pkg/utils"
 	"github.com/samber/lo"
 	"github.com/stretchr/testify/assert"
 )
@@ -70,11 +80,128 @@ func TestRebaseRebaseBranch(t *testing.T) {
 		s := s
 		t.Run(s.testName, func(t *testing.T) {
 			instance := buildRebaseCommands(commonDeps{runner: s.runner, gitVersion: s.gitVersion})
-			s.test(instance.RebaseBranch(s.arg))
+			s.test(instance.RebaseBranch(s.arg, nil))
 		})
 	}
 }
 
+func TestRebaseRebaseOntoRef(t *testing.T) {
+	type scenario struct {
+		testName    string
+		ref         string
+		runner      *oscommands.FakeCmdObjRunner
+		expectedErr string
+	}
+
+	scenarios := []scenario{
+		{
+			testName: "remote branch",
+			ref:      "origin/main",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"rev-parse", "--verify", "origin/main^{commit}"}, "abcdef\n", nil).
+				ExpectGitArgs([]string{"rebase", "--interactive", "--autostash", "--keep-empty", "--no-autosquash", "--rebase-merges", "origin/main"}, "", nil),
+		},
+		{
+			testName: "fully-qualified tag",
+			ref:      "refs/tags/v1.2.3",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"rev-parse", "--verify", "refs/tags/v1.2.3^{commit}"}, "abcdef\n", nil).
+				ExpectGitArgs([]string{"rebase", "--interactive", "--autostash", "--keep-empty", "--no-autosquash", "--rebase-merges", "refs/tags/v1.2.3"}, "", nil),
+		},
+		{
+			testName: "remote branch that hasn't been fetched",
+			ref:      "upstream/main",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"rev-parse", "--verify", "upstream/main^{commit}"}, "", errors.New("exit status 1")),
+			expectedErr: "unknown ref 'upstream/main'",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			instance := buildRebaseCommands(commonDeps{runner: s.runner, gitVersion: &GitVersion{2, 26, 0, ""}})
+
+			err := instance.RebaseOntoRef(s.ref)
+			if s.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, s.expectedErr)
+			}
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestRebaseRebaseOnto(t *testing.T) {
+	type scenario struct {
+		testName     string
+		emptyCommits EmptyCommitsMode
+		gitVersion   *GitVersion
+		runner       *oscommands.FakeCmdObjRunner
+	}
+
+	scenarios := []scenario{
+		{
+			testName:     "keep commits that become empty",
+			emptyCommits: EmptyCommitsKeep,
+			gitVersion:   &GitVersion{2, 26, 0, ""},
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"merge-base", "--is-ancestor", "abcdef", "HEAD"}, "", nil).
+				ExpectGitArgs([]string{"rebase", "--interactive", "--autostash", "--keep-empty", "--empty=keep", "--no-autosquash", "--rebase-merges", "--onto", "target", "abcdef"}, "", nil),
+		},
+		{
+			testName:     "drop commits that become empty",
+			emptyCommits: EmptyCommitsDrop,
+			gitVersion:   &GitVersion{2, 26, 0, ""},
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"merge-base", "--is-ancestor", "abcdef", "HEAD"}, "", nil).
+				ExpectGitArgs([]string{"rebase", "--interactive", "--autostash", "--keep-empty", "--empty=drop", "--no-autosquash", "--rebase-merges", "--onto", "target", "abcdef"}, "", nil),
+		},
+		{
+			testName:     "leave it to git",
+			emptyCommits: EmptyCommitsDefault,
+			gitVersion:   &GitVersion{2, 26, 0, ""},
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"merge-base", "--is-ancestor", "abcdef", "HEAD"}, "", nil).
+				ExpectGitArgs([]string{"rebase", "--interactive", "--autostash", "--keep-empty", "--no-autosquash", "--rebase-merges", "--onto", "target", "abcdef"}, "", nil),
+		},
+		{
+			testName:     "git too old for --empty",
+			emptyCommits: EmptyCommitsDrop,
+			gitVersion:   &GitVersion{2, 25, 5, ""},
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"merge-base", "--is-ancestor", "abcdef", "HEAD"}, "", nil).
+				ExpectGitArgs([]string{"rebase", "--interactive", "--autostash", "--keep-empty", "--no-autosquash", "--rebase-merges", "--onto", "target", "abcdef"}, "", nil),
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			instance := buildRebaseCommands(commonDeps{runner: s.runner, gitVersion: s.gitVersion})
+			assert.NoError(t, instance.RebaseOnto("target", "abcdef", s.emptyCommits, nil))
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestRebaseEditRebase(t *testing.T) {
+	runner := oscommands.NewFakeRunner(t).
+		ExpectFunc("interactive rebase with a break", func(cmdObj oscommands.ICmdObj) bool {
+			return assert.ObjectsAreEqual(
+				[]string{"git", "rebase", "--interactive", "--autostash", "--keep-empty", "--no-autosquash", "--rebase-merges", "master"},
+				cmdObj.Args(),
+			) &&
+				lo.Contains(cmdObj.GetEnvVars(), daemon.DaemonKindEnvKey+"="+strconv.Itoa(int(daemon.DaemonKindInsertBreak))) &&
+				!lo.Contains(cmdObj.GetEnvVars(), "GIT_SEQUENCE_EDITOR=true")
+		}, "", nil)
+
+	instance := buildRebaseCommands(commonDeps{runner: runner, gitVersion: &GitVersion{2, 26, 0, ""}})
+	assert.NoError(t, instance.EditRebase("master"))
+	runner.CheckForMissingCalls()
+}
+
 // TestRebaseSkipEditorCommand confirms that SkipEditorCommand injects
 // environment variables that suppress an interactive editor
 func TestRebaseSkipEditorCommand(t *testing.T) {
@@ -176,3 +303,5245 @@ func TestRebaseDiscardOldFileChanges(t *testing.T) {
 		})
 	}
 }
+
+func TestRebaseAmendCommitTree(t *testing.T) {
+	commits := []*models.Commit{
+		{Name: "commit", Sha: "123456"},
+		{Name: "commit2", Sha: "abcdef"},
+	}
+
+	for _, signOff := range []bool{false, true} {
+		signOff := signOff
+		t.Run(fmt.Sprintf("applies the changes to the commit (signOff: %v)", signOff), func(t *testing.T) {
+			dir := t.TempDir()
+			addedFile := filepath.Join(dir, "new", "added.txt")
+			modifiedFile := filepath.Join(dir, "modified.txt")
+			deletedFile := filepath.Join(dir, "deleted.txt")
+			assert.NoError(t, os.WriteFile(modifiedFile, []byte("old\n"), 0o644))
+			assert.NoError(t, os.WriteFile(deletedFile, []byte("old\n"), 0o644))
+
+			runner := oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"ls-tree", "-r", "--name-only", "-z", "abcdef", "--", addedFile, modifiedFile, deletedFile},
+					modifiedFile+"\x00"+deletedFile+"\x00", nil).
+				ExpectGitArgs(append(append([]string{"rebase", "--interactive", "--autostash", "--keep-empty", "--no-autosquash", "--rebase-merges"}, lo.Ternary(signOff, []string{"--signoff"}, []string{})...), "--root"), "", nil).
+				ExpectGitArgs([]string{"add", "--", addedFile, modifiedFile, deletedFile}, "", nil).
+				ExpectGitArgs(append([]string{"commit", "--amend", "--no-edit", "--allow-empty"}, lo.Ternary(signOff, []string{"--signoff"}, []string{})...), "", nil).
+				ExpectGitArgs([]string{"rebase", "--continue"}, "", nil)
+
+			userConfig := config.GetDefaultConfig()
+			userConfig.Git.Rebase.SignOff = signOff
+			instance := buildRebaseCommands(commonDeps{runner: runner, gitVersion: &GitVersion{2, 34, 0, ""}, userConfig: userConfig})
+			assert.NoError(t, instance.AmendCommitTree(commits, 1, TreeChanges{
+				{Kind: TreeChangeAdd, Path: addedFile, Content: "added\n"},
+				{Kind: TreeChangeModify, Path: modifiedFile, Content: "new\n"},
+				{Kind: TreeChangeDelete, Path: deletedFile},
+			}))
+			runner.CheckForMissingCalls()
+
+			content, err := os.ReadFile(addedFile)
+			assert.NoError(t, err)
+			assert.Equal(t, "added\n", string(content))
+			content, err = os.ReadFile(modifiedFile)
+			assert.NoError(t, err)
+			assert.Equal(t, "new\n", string(content))
+			assert.NoFileExists(t, deletedFile)
+		})
+	}
+
+	t.Run("reports changes that don't fit the commit's tree", func(t *testing.T) {
+		runner := oscommands.NewFakeRunner(t).
+			ExpectGitArgs([]string{"ls-tree", "-r", "--name-only", "-z", "123456", "--", "a.txt", "b.txt", "c.txt"},
+				"a.txt\x00", nil)
+
+		instance := buildRebaseCommands(commonDeps{runner: runner})
+		err := instance.AmendCommitTree(commits, 0, TreeChanges{
+			{Kind: TreeChangeAdd, Path: "a.txt", Content: "a\n"},
+			{Kind: TreeChangeModify, Path: "b.txt", Content: "b\n"},
+			{Kind: TreeChangeDelete, Path: "c.txt"},
+		})
+		assert.EqualError(t, err, "cannot change the files of commit 123456:\n"+
+			"'a.txt' already exists\n"+
+			"'b.txt' does not exist\n"+
+			"'c.txt' does not exist")
+		runner.CheckForMissingCalls()
+	})
+}
+
+func TestRebaseIsRebaseStateHealthy(t *testing.T) {
+	type scenario struct {
+		testName         string
+		files            map[string]string
+		noRebaseDir      bool
+		expectedHealthy  bool
+		expectedProblems []string
+	}
+
+	allFiles := func() map[string]string {
+		return map[string]string{
+			"git-rebase-todo": "pick 123456 commit\n",
+			"done":            "pick abcdef commit2\n",
+			"msgnum":          "1\n",
+			"end":             "2\n",
+			"onto":            "a1b2c3\n",
+		}
+	}
+
+	without := func(name string) map[string]string {
+		files := allFiles()
+		delete(files, name)
+		return files
+	}
+
+	with := func(name string, content string) map[string]string {
+		files := allFiles()
+		files[name] = content
+		return files
+	}
+
+	scenarios := []scenario{
+		{
+			testName:         "not rebasing",
+			noRebaseDir:      true,
+			expectedHealthy:  true,
+			expectedProblems: nil,
+		},
+		{
+			testName:         "all files present",
+			files:            allFiles(),
+			expectedHealthy:  true,
+			expectedProblems: []string{},
+		},
+		{
+			testName:         "missing git-rebase-todo",
+			files:            without("git-rebase-todo"),
+			expectedHealthy:  false,
+			expectedProblems: []string{"git-rebase-todo"},
+		},
+		{
+			testName:         "missing done",
+			files:            without("done"),
+			expectedHealthy:  false,
+			expectedProblems: []string{"done"},
+		},
+		{
+			testName:         "missing msgnum",
+			files:            without("msgnum"),
+			expectedHealthy:  false,
+			expectedProblems: []string{"msgnum"},
+		},
+		{
+			testName:         "missing end",
+			files:            without("end"),
+			expectedHealthy:  false,
+			expectedProblems: []string{"end"},
+		},
+		{
+			testName:         "missing onto",
+			files:            without("onto"),
+			expectedHealthy:  false,
+			expectedProblems: []string{"onto"},
+		},
+		{
+			testName:         "truncated msgnum",
+			files:            with("msgnum", ""),
+			expectedHealthy:  false,
+			expectedProblems: []string{"msgnum"},
+		},
+		{
+			testName:         "empty rebase-merge dir",
+			files:            map[string]string{},
+			expectedHealthy:  false,
+			expectedProblems: []string{"git-rebase-todo", "done", "msgnum", "end", "onto"},
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			repoDir := t.TempDir()
+			rebaseDir := filepath.Join(repoDir, ".git", "rebase-merge")
+			if !s.noRebaseDir {
+				assert.NoError(t, os.MkdirAll(rebaseDir, 0o755))
+				for name, content := range s.files {
+					assert.NoError(t, os.WriteFile(filepath.Join(rebaseDir, name), []byte(content), 0o644))
+				}
+			}
+
+			instance := buildRebaseCommands(commonDeps{repoPaths: MockRepoPaths(repoDir)})
+			healthy, problems, err := instance.IsRebaseStateHealthy()
+			assert.NoError(t, err)
+			assert.Equal(t, s.expectedHealthy, healthy)
+			assert.Equal(t, s.expectedProblems, problems)
+		})
+	}
+}
+
+func TestRebasePreserveCommitterDates(t *testing.T) {
+	userConfig := config.GetDefaultConfig()
+	userConfig.Git.Rebase.PreserveCommitterDates = true
+
+	commits := []*models.Commit{
+		{Name: "commit", Sha: "123456"},
+		{Name: "commit2", Sha: "abcdef"},
+		{Name: "commit3", Sha: "a1b2c3"},
+	}
+
+	runner := oscommands.NewFakeRunner(t).
+		ExpectGitArgs([]string{"log", "--format=%H %cd", "--date=raw", "a1b2c3..HEAD"},
+			"123456 1700000100 +0100\nabcdef 1700000000 +0100\n", nil).
+		ExpectFunc("rebase with committer dates", func(cmdObj oscommands.ICmdObj) bool {
+			return lo.Contains(cmdObj.GetEnvVars(),
+				daemon.DaemonCommitterDatesEnvKey+`={"123456":"1700000100 +0100","abcdef":"1700000000 +0100"}`)
+		}, "", nil)
+
+	instance := buildRebaseCommands(commonDeps{runner: runner, userConfig: userConfig})
+	assert.NoError(t, instance.MoveCommitDown(commits, 0))
+	runner.CheckForMissingCalls()
+}
+
+func TestRebaseContinuePreservingCommitterDates(t *testing.T) {
+	repoDir := t.TempDir()
+	rebaseDir := filepath.Join(repoDir, ".git", "rebase-merge")
+	writeRebaseFile := func(name string, content string) {
+		assert.NoError(t, os.WriteFile(filepath.Join(rebaseDir, name), []byte(content), 0o644))
+	}
+	readRebaseFile := func(name string) string {
+		content, err := os.ReadFile(filepath.Join(rebaseDir, name))
+		assert.NoError(t, err)
+		return string(content)
+	}
+	assert.NoError(t, os.MkdirAll(rebaseDir, 0o755))
+	// stopped at conflicts in the first commit, with the break before the
+	// second one taken out
+	writeRebaseFile("done", "break\npick 111111 commit1\n")
+	writeRebaseFile("git-rebase-todo", "pick 222222 commit2\nexec make test\npick 333333 commit3\n")
+	writeRebaseFile(utils.CommitterDatesFile, "111111 1700000000 +0000\n222222 1700000100 +0000\n333333 1700000200 +0000\n")
+
+	continueWithDate := func(date string, simulateGit func()) func(cmdObj oscommands.ICmdObj) bool {
+		return func(cmdObj oscommands.ICmdObj) bool {
+			matched := strings.Join(cmdObj.Args()[1:], " ") == strings.Join(append(rebaseHooksArgs(repoDir), "rebase", "--continue"), " ") &&
+				lo.Contains(cmdObj.GetEnvVars(), "GIT_COMMITTER_DATE="+date)
+			if matched {
+				simulateGit()
+			}
+			return matched
+		}
+	}
+	runner := oscommands.NewFakeRunner(t).
+		ExpectFunc("continue with the date of the commit with conflicts", continueWithDate("1700000000 +0000", func() {
+			// the break is back, so git stops before the second commit
+			assert.Equal(t, "break\npick 222222 commit2\nexec make test\nbreak\npick 333333 commit3\n", readRebaseFile("git-rebase-todo"))
+			writeRebaseFile("done", "break\npick 111111 commit1\nbreak\n")
+			writeRebaseFile("git-rebase-todo", "pick 222222 commit2\nexec make test\nbreak\npick 333333 commit3\n")
+		}), "", nil).
+		ExpectFunc("continue with the date of the next commit", continueWithDate("1700000100 +0000", func() {
+			writeRebaseFile("done", "break\npick 111111 commit1\nbreak\npick 222222 commit2\nexec make test\n")
+			writeRebaseFile("git-rebase-todo", "break\npick 333333 commit3\n")
+		}), "", errors.New("Execution failed: make test"))
+	instance := buildRebaseCommands(commonDeps{runner: runner, repoPaths: MockRepoPaths(repoDir)})
+
+	assert.Error(t, instance.GenericMergeOrRebaseAction("rebase", "continue"))
+	runner.CheckForMissingCalls()
+	// the break is taken out again while the rebase is stopped at the exec
+	assert.Equal(t, "pick 333333 commit3\n", readRebaseFile("git-rebase-todo"))
+}
+
+// The args that the rebase commands we run start with once the repo in
+// repoDir has a git dir for installRebaseResultHook to set up its hooks in
+func rebaseHooksArgs(repoDir string) []string {
+	return []string{"-c", "core.hooksPath=" + filepath.Join(repoDir, ".git", rebaseHooksDirName)}
+}
+
+func TestRebaseAbortCurrentOperation(t *testing.T) {
+	type scenario struct {
+		testName    string
+		markerFiles map[string]string
+		runner      *oscommands.FakeCmdObjRunner
+	}
+
+	scenarios := []scenario{
+		{
+			testName:    "nothing in progress",
+			markerFiles: map[string]string{},
+			runner:      oscommands.NewFakeRunner(t),
+		},
+		{
+			testName:    "interactive rebase",
+			markerFiles: map[string]string{"rebase-merge/git-rebase-todo": ""},
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"rebase", "--abort"}, "", nil),
+		},
+		{
+			testName:    "normal rebase",
+			markerFiles: map[string]string{"rebase-apply/next": "1"},
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"rebase", "--abort"}, "", nil),
+		},
+		{
+			testName:    "merge",
+			markerFiles: map[string]string{"MERGE_HEAD": "abcdef"},
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"merge", "--abort"}, "", nil),
+		},
+		{
+			testName:    "cherry-pick",
+			markerFiles: map[string]string{"CHERRY_PICK_HEAD": "abcdef"},
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"cherry-pick", "--abort"}, "", nil),
+		},
+		{
+			testName:    "revert",
+			markerFiles: map[string]string{"REVERT_HEAD": "abcdef"},
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"revert", "--abort"}, "", nil),
+		},
+		{
+			testName:    "cherry-pick sequence after committing a conflict resolution",
+			markerFiles: map[string]string{"sequencer/todo": "pick abcdef commit\npick 123456 commit2\n"},
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"cherry-pick", "--abort"}, "", nil),
+		},
+		{
+			testName:    "revert sequence after committing a conflict resolution",
+			markerFiles: map[string]string{"sequencer/todo": "revert abcdef commit\n"},
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"revert", "--abort"}, "", nil),
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			repoDir := t.TempDir()
+			for name, content := range s.markerFiles {
+				path := filepath.Join(repoDir, ".git", name)
+				assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+				assert.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+			}
+
+			instance := buildRebaseCommands(commonDeps{runner: s.runner, repoPaths: MockRepoPaths(repoDir)})
+			instance.onSuccessfulContinue = func() error { return errors.New("should have been cleared") }
+
+			assert.NoError(t, instance.AbortCurrentOperation())
+			assert.Nil(t, instance.onSuccessfulContinue)
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestRebaseRewordAndFixup(t *testing.T) {
+	commits := []*models.Commit{
+		{Name: "commit5", Sha: "555555"},
+		{Name: "commit4", Sha: "444444"},
+		{Name: "commit3", Sha: "333333"},
+		{Name: "commit2", Sha: "222222"},
+		{Name: "commit1", Sha: "111111"},
+	}
+
+	type scenario struct {
+		testName     string
+		targetIndex  int
+		fixupIndices []int
+		runner       *oscommands.FakeCmdObjRunner
+		expectedErr  string
+	}
+
+	scenarios := []scenario{
+		{
+			testName:     "fixups above the target",
+			targetIndex:  3,
+			fixupIndices: []int{0, 2},
+			runner: oscommands.NewFakeRunner(t).
+				ExpectFunc("rebase from below target", func(cmdObj oscommands.ICmdObj) bool {
+					return cmdObj.Args()[len(cmdObj.Args())-1] == "111111" &&
+						lo.Contains(cmdObj.Args(), "commit.cleanup=whitespace") &&
+						lo.Contains(cmdObj.GetEnvVars(), daemon.DaemonInstructionEnvKey+`={"Changes":[`+
+							`{"Sha":"222222","NewAction":4,"NewMessage":"new message"},`+
+							`{"Sha":"333333","NewAction":5,"MoveAfterSha":"222222"},`+
+							`{"Sha":"555555","NewAction":5,"MoveAfterSha":"222222"}]}`)
+				}, "", nil),
+		},
+		{
+			testName:     "fixup below the target",
+			targetIndex:  1,
+			fixupIndices: []int{3},
+			runner: oscommands.NewFakeRunner(t).
+				ExpectFunc("rebase from below fixup", func(cmdObj oscommands.ICmdObj) bool {
+					return cmdObj.Args()[len(cmdObj.Args())-1] == "111111"
+				}, "", nil),
+		},
+		{
+			testName:     "target is the root commit",
+			targetIndex:  4,
+			fixupIndices: []int{0},
+			runner: oscommands.NewFakeRunner(t).
+				ExpectFunc("rebase from root", func(cmdObj oscommands.ICmdObj) bool {
+					return cmdObj.Args()[len(cmdObj.Args())-1] == "--root"
+				}, "", nil),
+		},
+		{
+			testName:     "fixup into itself",
+			targetIndex:  1,
+			fixupIndices: []int{1},
+			runner:       oscommands.NewFakeRunner(t),
+			expectedErr:  "cannot fixup a commit into itself",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			instance := buildRebaseCommands(commonDeps{runner: s.runner})
+			err := instance.RewordAndFixup(commits, s.targetIndex, "new message", s.fixupIndices)
+			if s.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, s.expectedErr)
+			}
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestRebasePreviewSquashMessage(t *testing.T) {
+	scenarios := []struct {
+		testName     string
+		childMessage string
+		expected     string
+	}{
+		{
+			testName:     "messages are separated by a blank line",
+			childMessage: "Child\n\nchild body",
+			expected:     "Parent\n\nparent body\n\nChild\n\nchild body",
+		},
+		{
+			testName:     "subject of a squash! commit is left out",
+			childMessage: "squash! Parent\n\nsquash body",
+			expected:     "Parent\n\nparent body\n\nsquash body",
+		},
+		{
+			testName:     "fixup! commit without a body adds nothing",
+			childMessage: "fixup! Parent",
+			expected:     "Parent\n\nparent body",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			runner := oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"show", "-s", "--format=%H%x00%B", "111111", "222222"},
+					"111111\x00Parent\n\nparent body\n\n222222\x00"+s.childMessage+"\n\n", nil)
+			instance := buildRebaseCommands(commonDeps{runner: runner})
+
+			message, err := instance.PreviewSquashMessage(&models.Commit{Sha: "111111"}, &models.Commit{Sha: "222222"})
+			assert.NoError(t, err)
+			assert.Equal(t, s.expected, message)
+			runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestRebaseEditCommits(t *testing.T) {
+	commits := []*models.Commit{
+		{Name: "commit6", Sha: "666666"},
+		{Name: "commit5", Sha: "555555"},
+		{Name: "commit4", Sha: "444444"},
+		{Name: "commit3", Sha: "333333"},
+		{Name: "commit2", Sha: "222222"},
+		{Name: "commit1", Sha: "111111"},
+	}
+
+	runner := oscommands.NewFakeRunner(t).
+		ExpectFunc("rebase from below the oldest edited commit", func(cmdObj oscommands.ICmdObj) bool {
+			return cmdObj.Args()[len(cmdObj.Args())-1] == "111111" &&
+				lo.Contains(cmdObj.GetEnvVars(), daemon.DaemonInstructionEnvKey+`={"Changes":[`+
+					`{"Sha":"555555","NewAction":3},`+
+					`{"Sha":"333333","NewAction":3},`+
+					`{"Sha":"222222","NewAction":3}]}`)
+		}, "", nil)
+
+	repoDir := t.TempDir()
+	instance := buildRebaseCommands(commonDeps{runner: runner, repoPaths: MockRepoPaths(repoDir)})
+	assert.NoError(t, instance.EditCommits(commits, []int{1, 3, 4}))
+	runner.CheckForMissingCalls()
+
+	stops, err := instance.RemainingEditStops()
+	assert.NoError(t, err)
+	assert.Empty(t, stops)
+
+	// simulate being paused at the first of the three stops
+	rebaseDir := filepath.Join(repoDir, ".git", "rebase-merge")
+	assert.NoError(t, os.MkdirAll(rebaseDir, 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(rebaseDir, "git-rebase-todo"), []byte(
+		"edit 333333 commit3\npick 444444 commit4\nedit 555555 commit5\npick 666666 commit6\n"), 0o644))
+
+	stops, err = instance.RemainingEditStops()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"333333", "555555"}, stops)
+}
+
+func TestRebaseRewordCommit(t *testing.T) {
+	isRebaseFrom := func(base string) func(cmdObj oscommands.ICmdObj) bool {
+		return func(cmdObj oscommands.ICmdObj) bool {
+			return cmdObj.Args()[1] == "rebase" && cmdObj.Args()[len(cmdObj.Args())-1] == base
+		}
+	}
+	amendArgs := []string{"commit", "--allow-empty", "--amend", "--only", "--cleanup=whitespace", "-m", "renamed"}
+
+	type scenario struct {
+		testName string
+		commits  []*models.Commit
+		index    int
+		runner   *oscommands.FakeCmdObjRunner
+	}
+
+	scenarios := []scenario{
+		{
+			testName: "only commit of the repo is amended without a rebase",
+			commits:  []*models.Commit{{Name: "commit1", Sha: "111111"}},
+			index:    0,
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs(amendArgs, "", nil),
+		},
+		{
+			testName: "initial commit is reworded with a rebase from the root",
+			commits: []*models.Commit{
+				{Name: "commit2", Sha: "222222", Parents: []string{"111111"}},
+				{Name: "commit1", Sha: "111111"},
+			},
+			index: 1,
+			runner: oscommands.NewFakeRunner(t).
+				ExpectFunc("rebase from the root", isRebaseFrom("--root"), "", nil).
+				ExpectGitArgs(amendArgs, "", nil).
+				ExpectGitArgs([]string{"rebase", "--continue"}, "", nil),
+		},
+		{
+			testName: "oldest loaded commit that isn't the initial commit is reworded with a rebase from its parent",
+			commits: []*models.Commit{
+				{Name: "commit3", Sha: "333333", Parents: []string{"222222"}},
+				{Name: "commit2", Sha: "222222", Parents: []string{"111111"}, Truncated: true},
+			},
+			index: 1,
+			runner: oscommands.NewFakeRunner(t).
+				ExpectFunc("rebase from the parent", isRebaseFrom("111111"), "", nil).
+				ExpectGitArgs(amendArgs, "", nil).
+				ExpectGitArgs([]string{"rebase", "--continue"}, "", nil),
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			instance := buildRebaseCommands(commonDeps{runner: s.runner, repoPaths: MockRepoPaths(t.TempDir())})
+
+			assert.NoError(t, instance.RewordCommit(s.commits, s.index, "renamed", ""))
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestRebaseRewordCommitsInteractive(t *testing.T) {
+	commits := []*models.Commit{
+		{Name: "commit6", Sha: "666666"},
+		{Name: "commit5", Sha: "555555"},
+		{Name: "commit4", Sha: "444444", Parents: []string{"333333", "aaaaaa"}},
+		{Name: "commit3", Sha: "333333"},
+		{Name: "commit2", Sha: "222222"},
+		{Name: "commit1", Sha: "111111"},
+	}
+
+	type scenario struct {
+		testName            string
+		indices             []int
+		expectedBase        string
+		expectedInstruction string
+		expectedErr         string
+	}
+
+	scenarios := []scenario{
+		{
+			testName:     "stops at three commits",
+			indices:      []int{0, 1, 3},
+			expectedBase: "222222",
+			expectedInstruction: `{"Changes":[` +
+				`{"Sha":"666666","NewAction":4},` +
+				`{"Sha":"555555","NewAction":4},` +
+				`{"Sha":"333333","NewAction":4}]}`,
+		},
+		{
+			testName:            "duplicate indices are only reworded once",
+			indices:             []int{4, 4},
+			expectedBase:        "111111",
+			expectedInstruction: `{"Changes":[{"Sha":"222222","NewAction":4}]}`,
+		},
+		{
+			testName:    "no commits",
+			indices:     []int{},
+			expectedErr: "no commits to reword",
+		},
+		{
+			testName:    "merge commit",
+			indices:     []int{0, 2},
+			expectedErr: "cannot reword a merge commit this way",
+		},
+		{
+			testName:    "index out of range",
+			indices:     []int{6},
+			expectedErr: "index outside of range of commits",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			runner := oscommands.NewFakeRunner(t)
+			instance := buildRebaseCommands(commonDeps{runner: runner})
+
+			cmdObj, err := instance.RewordCommitsInteractive(commits, s.indices)
+			if s.expectedErr != "" {
+				assert.EqualError(t, err, s.expectedErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, s.expectedBase, cmdObj.Args()[len(cmdObj.Args())-1])
+			assert.Contains(t, cmdObj.GetEnvVars(), daemon.DaemonInstructionEnvKey+"="+s.expectedInstruction)
+			// git opens the user's own editor for each reword
+			assert.NotContains(t, cmdObj.GetEnvVars(), "GIT_EDITOR="+oscommands.GetLazygitPath())
+			runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestRebasePendingRewords(t *testing.T) {
+	logArgs := func(ref string) []string {
+		return []string{"log", "-1", "--format=%an%n%ae%n%ad%n%B", "--date=raw", ref}
+	}
+
+	type scenario struct {
+		testName        string
+		done            string
+		runner          *oscommands.FakeCmdObjRunner
+		expectedPending []string
+		expectedQueue   []string
+	}
+
+	scenarios := []scenario{
+		{
+			testName:        "rebase is over",
+			runner:          oscommands.NewFakeRunner(t),
+			expectedPending: nil,
+			expectedQueue:   nil,
+		},
+		{
+			testName:        "first commit is reworded and the rebase stopped later on",
+			done:            "reword 222222 commit2\npick 333333 commit3\n",
+			runner:          oscommands.NewFakeRunner(t),
+			expectedPending: []string{"444444", "555555"},
+			expectedQueue:   []string{"222222", "444444", "555555"},
+		},
+		{
+			testName: "rebase stopped at conflicts in the second commit after rewording it",
+			done:     "reword 222222 commit2\npick 333333 commit3\nreword 444444 commit4\n",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs(logArgs("444444"), "Author\nauthor@example.com\n1700000000 +0000\ncommit4\n", nil).
+				ExpectGitArgs(logArgs("HEAD"), "Author\nauthor@example.com\n1700000000 +0000\nnew message\n", nil),
+			expectedPending: []string{"555555"},
+			expectedQueue:   []string{"222222", "444444", "555555"},
+		},
+		{
+			testName: "editor failed for the second commit, so git kept its message",
+			done:     "reword 222222 commit2\npick 333333 commit3\nreword 444444 commit4\n",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs(logArgs("444444"), "Author\nauthor@example.com\n1700000000 +0000\ncommit4\n", nil).
+				ExpectGitArgs(logArgs("HEAD"), "Author\nauthor@example.com\n1700000000 +0000\ncommit4\n", nil),
+			expectedPending: []string{"444444", "555555"},
+			expectedQueue:   []string{"222222", "444444", "555555"},
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			repoDir := t.TempDir()
+			if s.done != "" {
+				donePath := filepath.Join(repoDir, ".git", "rebase-merge", "done")
+				assert.NoError(t, os.MkdirAll(filepath.Dir(donePath), 0o755))
+				assert.NoError(t, os.WriteFile(donePath, []byte(s.done), 0o644))
+			}
+			instance := buildRebaseCommands(commonDeps{runner: s.runner, repoPaths: MockRepoPaths(repoDir)})
+			instance.rewordQueue = []string{"222222", "444444", "555555"}
+
+			pending, err := instance.PendingRewords()
+			assert.NoError(t, err)
+			assert.Equal(t, s.expectedPending, pending)
+			assert.Equal(t, s.expectedQueue, instance.rewordQueue)
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestRebaseRebasingBranchName(t *testing.T) {
+	scenarios := []struct {
+		testName string
+		// the rebase state dir to create, if any, and its head-name file
+		rebaseDir string
+		headName  string
+		expected  string
+	}{
+		{
+			testName: "not rebasing",
+			expected: "",
+		},
+		{
+			testName:  "rebasing a branch",
+			rebaseDir: "rebase-merge",
+			headName:  "refs/heads/feature/thing\n",
+			expected:  "feature/thing",
+		},
+		{
+			testName:  "rebasing a branch with the apply backend",
+			rebaseDir: "rebase-apply",
+			headName:  "refs/heads/feature\n",
+			expected:  "feature",
+		},
+		{
+			testName:  "rebasing a detached head",
+			rebaseDir: "rebase-merge",
+			headName:  "detached HEAD\n",
+			expected:  "",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			repoDir := t.TempDir()
+			assert.NoError(t, os.MkdirAll(filepath.Join(repoDir, ".git"), 0o755))
+			if s.rebaseDir != "" {
+				assert.NoError(t, os.MkdirAll(filepath.Join(repoDir, ".git", s.rebaseDir), 0o755))
+				assert.NoError(t, os.WriteFile(filepath.Join(repoDir, ".git", s.rebaseDir, "head-name"), []byte(s.headName), 0o644))
+			}
+
+			instance := buildRebaseCommands(commonDeps{repoPaths: MockRepoPaths(repoDir)})
+			branchName, err := instance.RebasingBranchName()
+			assert.NoError(t, err)
+			assert.Equal(t, s.expected, branchName)
+		})
+	}
+}
+
+func TestRebaseDanglingContinuation(t *testing.T) {
+	repoDir := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(repoDir, ".git"), 0o755))
+
+	runner := oscommands.NewFakeRunner(t)
+	instance := buildRebaseCommands(commonDeps{runner: runner, repoPaths: MockRepoPaths(repoDir)})
+	instance.setOnSuccessfulContinue("Uncommit commits", func() error { return nil })
+
+	// the instance that queued up the continuation still knows about it, so
+	// it isn't dangling
+	_, found := instance.DanglingContinuation()
+	assert.False(t, found)
+
+	// but after a restart it is
+	restarted := buildRebaseCommands(commonDeps{runner: runner, repoPaths: MockRepoPaths(repoDir)})
+	operation, found := restarted.DanglingContinuation()
+	assert.True(t, found)
+	assert.Equal(t, "Uncommit commits", operation)
+
+	restarted.DiscardDanglingContinuation()
+	_, found = restarted.DanglingContinuation()
+	assert.False(t, found)
+
+	// running the continuation forgets about it too
+	instance.setOnSuccessfulContinue("Uncommit commits", func() error { return nil })
+	runner.ExpectFunc("rebase --continue", func(cmdObj oscommands.ICmdObj) bool {
+		return lo.Contains(cmdObj.Args(), "--continue")
+	}, "", nil)
+	assert.NoError(t, instance.GenericMergeOrRebaseAction("rebase", "continue"))
+	_, found = buildRebaseCommands(commonDeps{runner: runner, repoPaths: MockRepoPaths(repoDir)}).DanglingContinuation()
+	assert.False(t, found)
+	runner.CheckForMissingCalls()
+}
+
+func TestRebaseRebaseThenPushWithLease(t *testing.T) {
+	rebaseArgs := func(hooksArgs []string) []string {
+		return append(hooksArgs, "rebase", "--interactive", "--autostash", "--keep-empty", "--no-autosquash", "--rebase-merges", "origin/master")
+	}
+	continueArgs := func(hooksArgs []string) []string {
+		return append(hooksArgs, "rebase", "--continue")
+	}
+	// The branch tracks one with a different name, which a plain push with the
+	// default push.default would refuse to push to
+	pushArgs := []string{"push", "--force-with-lease=refs/heads/other-name:abc123", "origin", "HEAD:refs/heads/other-name"}
+	lookUpUpstream := func() *oscommands.FakeCmdObjRunner {
+		return oscommands.NewFakeRunner(t).
+			ExpectGitArgs([]string{"symbolic-ref", "--quiet", "--short", "HEAD"}, "feature\n", nil).
+			ExpectGitArgs([]string{"for-each-ref", "--format=%(upstream:remotename)%00%(upstream:remoteref)", "refs/heads/feature"}, "origin\x00refs/heads/other-name\n", nil).
+			ExpectGitArgs([]string{"rev-parse", "--verify", "--quiet", "feature@{upstream}"}, "abc123\n", nil)
+	}
+
+	type scenario struct {
+		testName        string
+		pushAfterRebase bool
+		// whether the rebase stops at conflicts, leaving a rebase in progress
+		stops bool
+		// gets the args that our rebase commands start with
+		runner func(hooksArgs []string) *oscommands.FakeCmdObjRunner
+		// what the user does once the rebase has stopped
+		afterStop   string
+		expectedErr string
+	}
+
+	conflictErr := errors.New("could not apply 123456... commit")
+
+	scenarios := []scenario{
+		{
+			testName:        "rebase succeeds",
+			pushAfterRebase: true,
+			runner: func(hooksArgs []string) *oscommands.FakeCmdObjRunner {
+				return lookUpUpstream().
+					ExpectGitArgs(rebaseArgs(hooksArgs), "", nil).
+					ExpectGitArgs(pushArgs, "", nil)
+			},
+		},
+		{
+			testName:        "branch doesn't track a remote branch",
+			pushAfterRebase: true,
+			runner: func(hooksArgs []string) *oscommands.FakeCmdObjRunner {
+				return oscommands.NewFakeRunner(t).
+					ExpectGitArgs([]string{"symbolic-ref", "--quiet", "--short", "HEAD"}, "feature\n", nil).
+					ExpectGitArgs([]string{"for-each-ref", "--format=%(upstream:remotename)%00%(upstream:remoteref)", "refs/heads/feature"}, ".\x00refs/heads/master\n", nil)
+			},
+			expectedErr: "The checked-out branch doesn't track a branch on a remote, so there's nowhere to push it to after the rebase",
+		},
+		{
+			testName:        "push turned off",
+			pushAfterRebase: false,
+			runner: func(hooksArgs []string) *oscommands.FakeCmdObjRunner {
+				return oscommands.NewFakeRunner(t).
+					ExpectGitArgs(rebaseArgs(hooksArgs), "", nil)
+			},
+		},
+		{
+			testName:        "rebase fails to start",
+			pushAfterRebase: true,
+			runner: func(hooksArgs []string) *oscommands.FakeCmdObjRunner {
+				return lookUpUpstream().
+					ExpectGitArgs(rebaseArgs(hooksArgs), "", errors.New("error"))
+			},
+			expectedErr: "error",
+		},
+		{
+			testName:        "rebase stops at conflicts and is continued",
+			pushAfterRebase: true,
+			stops:           true,
+			runner: func(hooksArgs []string) *oscommands.FakeCmdObjRunner {
+				return lookUpUpstream().
+					ExpectGitArgs(rebaseArgs(hooksArgs), "", conflictErr).
+					ExpectGitArgs(continueArgs(hooksArgs), "", nil).
+					ExpectGitArgs(pushArgs, "", nil)
+			},
+			afterStop:   "continue",
+			expectedErr: conflictErr.Error(),
+		},
+		{
+			testName:        "rebase stops at conflicts and is aborted",
+			pushAfterRebase: true,
+			stops:           true,
+			runner: func(hooksArgs []string) *oscommands.FakeCmdObjRunner {
+				return lookUpUpstream().
+					ExpectGitArgs(rebaseArgs(hooksArgs), "", conflictErr).
+					ExpectGitArgs([]string{"rebase", "--abort"}, "", nil).
+					// continuing after the abort doesn't push either
+					ExpectGitArgs(continueArgs(hooksArgs), "", errors.New("fatal: no rebase in progress"))
+			},
+			afterStop:   "abort",
+			expectedErr: conflictErr.Error(),
+		},
+		{
+			testName:        "rebase is aborted outside of lazygit and a different one is continued",
+			pushAfterRebase: true,
+			stops:           true,
+			runner: func(hooksArgs []string) *oscommands.FakeCmdObjRunner {
+				return lookUpUpstream().
+					ExpectGitArgs(rebaseArgs(hooksArgs), "", conflictErr).
+					ExpectGitArgs(continueArgs(hooksArgs), "", nil)
+			},
+			afterStop:   "continue other rebase",
+			expectedErr: conflictErr.Error(),
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			repoDir := t.TempDir()
+			rebaseDir := filepath.Join(repoDir, ".git", "rebase-merge")
+			assert.NoError(t, os.MkdirAll(filepath.Join(repoDir, ".git"), 0o755))
+			if s.stops {
+				assert.NoError(t, os.MkdirAll(rebaseDir, 0o755))
+				assert.NoError(t, os.WriteFile(filepath.Join(rebaseDir, "head-name"), []byte("refs/heads/feature\n"), 0o644))
+				assert.NoError(t, os.WriteFile(filepath.Join(rebaseDir, "onto"), []byte("aaaaaa\n"), 0o644))
+			}
+
+			userConfig := config.GetDefaultConfig()
+			userConfig.Git.Rebase.PushAfterRebase = s.pushAfterRebase
+			runner := s.runner(rebaseHooksArgs(repoDir))
+			instance := buildRebaseCommands(commonDeps{runner: runner, gitVersion: &GitVersion{2, 26, 0, ""}, userConfig: userConfig, repoPaths: MockRepoPaths(repoDir)})
+
+			err := instance.RebaseThenPushWithLease(gocui.NewFakeTask(), "origin/master")
+			if s.expectedErr != "" {
+				assert.EqualError(t, err, s.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			switch s.afterStop {
+			case "continue":
+				assert.NoError(t, instance.GenericMergeOrRebaseAction("rebase", "continue"))
+			case "abort":
+				assert.NoError(t, instance.GenericMergeOrRebaseAction("rebase", "abort"))
+				assert.NoError(t, instance.GenericMergeOrRebaseAction("rebase", "continue"))
+			case "continue other rebase":
+				assert.NoError(t, os.WriteFile(filepath.Join(rebaseDir, "onto"), []byte("bbbbbb\n"), 0o644))
+				assert.NoError(t, instance.GenericMergeOrRebaseAction("rebase", "continue"))
+			}
+
+			runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestRebaseInsertCommitBefore(t *testing.T) {
+	commits := []*models.Commit{
+		{Name: "commit3", Sha: "333333"},
+		{Name: "commit2", Sha: "222222"},
+		{Name: "commit1", Sha: "111111"},
+	}
+
+	type scenario struct {
+		testName    string
+		index       int
+		stageAll    bool
+		allowEmpty  bool
+		runner      *oscommands.FakeCmdObjRunner
+		expectedErr string
+	}
+
+	scenarios := []scenario{
+		{
+			testName: "insert before a commit in the middle",
+			index:    1,
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"diff", "--cached", "--name-only"}, "file.txt\n", nil).
+				ExpectGitArgs([]string{"commit", "-m", "new commit"}, "", nil).
+				ExpectGitArgs([]string{"rev-parse", "--verify", "HEAD"}, "abcdef\n", nil).
+				ExpectFunc("rebase from below the target", func(cmdObj oscommands.ICmdObj) bool {
+					return cmdObj.Args()[len(cmdObj.Args())-1] == "111111" &&
+						lo.Contains(cmdObj.GetEnvVars(), daemon.DaemonInstructionEnvKey+`={"Changes":[`+
+							`{"Sha":"abcdef","NewAction":1,"MoveBeforeSha":"222222"}]}`)
+				}, "", nil),
+		},
+		{
+			testName: "insert before the root commit, staging everything",
+			index:    2,
+			stageAll: true,
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"status", "--porcelain"}, "?? file.txt\n", nil).
+				ExpectGitArgs([]string{"add", "-A"}, "", nil).
+				ExpectGitArgs([]string{"commit", "-m", "new commit"}, "", nil).
+				ExpectGitArgs([]string{"rev-parse", "--verify", "HEAD"}, "abcdef\n", nil).
+				ExpectFunc("rebase from root", func(cmdObj oscommands.ICmdObj) bool {
+					return cmdObj.Args()[len(cmdObj.Args())-1] == "--root" &&
+						lo.Contains(cmdObj.GetEnvVars(), daemon.DaemonInstructionEnvKey+`={"Changes":[`+
+							`{"Sha":"abcdef","NewAction":1,"MoveBeforeSha":"111111"}]}`)
+				}, "", nil),
+		},
+		{
+			testName: "nothing staged",
+			index:    1,
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"diff", "--cached", "--name-only"}, "", nil),
+			expectedErr: "there are no staged changes to create the commit from",
+		},
+		{
+			testName: "nothing to stage",
+			index:    1,
+			stageAll: true,
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"status", "--porcelain"}, "", nil),
+			expectedErr: "there are no changes to create the commit from",
+		},
+		{
+			testName:   "nothing staged, but empty commits allowed",
+			index:      1,
+			allowEmpty: true,
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"commit", "--allow-empty", "-m", "new commit"}, "", nil).
+				ExpectGitArgs([]string{"rev-parse", "--verify", "HEAD"}, "abcdef\n", nil).
+				ExpectFunc("rebase from below the target", func(cmdObj oscommands.ICmdObj) bool {
+					return cmdObj.Args()[len(cmdObj.Args())-1] == "111111"
+				}, "", nil),
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			instance := buildRebaseCommands(commonDeps{runner: s.runner})
+
+			err := instance.InsertCommitBefore(commits, s.index, "new commit", s.stageAll, s.allowEmpty)
+			if s.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, s.expectedErr)
+			}
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestRebaseRebaseDoneSteps(t *testing.T) {
+	scenarios := []struct {
+		testName      string
+		files         map[string]string
+		expectedTodos []todo.Todo
+	}{
+		{
+			testName:      "not rebasing",
+			files:         map[string]string{},
+			expectedTodos: nil,
+		},
+		{
+			testName: "rebase-apply has no done file",
+			files: map[string]string{
+				"rebase-apply/next": "2\n",
+			},
+			expectedTodos: nil,
+		},
+		{
+			testName: "steps done so far",
+			files: map[string]string{
+				"rebase-merge/done":            "pick 111111 commit1\nexec make test\nedit 222222 commit2\n",
+				"rebase-merge/git-rebase-todo": "pick 333333 commit3\n",
+			},
+			expectedTodos: []todo.Todo{
+				{Command: todo.Pick, Commit: "111111", Msg: "commit1"},
+				{Command: todo.Exec, ExecCommand: "make test"},
+				{Command: todo.Edit, Commit: "222222", Msg: "commit2"},
+			},
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			repoDir := t.TempDir()
+			for name, content := range s.files {
+				path := filepath.Join(repoDir, ".git", name)
+				assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+				assert.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+			}
+
+			instance := buildRebaseCommands(commonDeps{repoPaths: MockRepoPaths(repoDir)})
+			todos, err := instance.RebaseDoneSteps()
+			assert.NoError(t, err)
+			assert.Equal(t, s.expectedTodos, todos)
+		})
+	}
+}
+
+func TestRebasePendingCommitDiff(t *testing.T) {
+	runner := oscommands.NewFakeRunner(t).
+		ExpectGitArgs([]string{"show", "--no-ext-diff", "--submodule", "--color=always", "--unified=3", "--stat", "--decorate", "-p", "123456"},
+			"diff --git a/image.png b/image.png\nBinary files differ\n", nil)
+	instance := buildRebaseCommands(commonDeps{runner: runner, appState: &config.AppState{DiffContextSize: 3}})
+
+	diff, err := instance.PendingCommitDiff("123456")
+	assert.NoError(t, err)
+	assert.Equal(t, "diff --git a/image.png b/image.png\nBinary files differ\n", diff)
+	runner.CheckForMissingCalls()
+}
+
+func TestRebaseRemoveFileFromHistory(t *testing.T) {
+	type scenario struct {
+		testName    string
+		fileName    string
+		runner      *oscommands.FakeCmdObjRunner
+		expectedErr string
+	}
+
+	onlyOnFeature := []string{"--not", "--exclude=feature", "--branches"}
+	revList := func(args ...string) []string {
+		return append(append([]string{"rev-list"}, args...), onlyOnFeature...)
+	}
+	changingCommits := func(fileName string) []string {
+		return append(revList("HEAD"), "--", fileName)
+	}
+
+	scenarios := []scenario{
+		{
+			testName: "file inherited from a shared commit",
+			fileName: "it's a secret.txt",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"symbolic-ref", "--quiet", "--short", "HEAD"}, "feature\n", nil).
+				ExpectGitArgs(revList("--reverse", "--parents", "HEAD"), "bbbbbb aaaaaa\ncccccc bbbbbb\ndddddd cccccc\n", nil).
+				ExpectGitArgs(changingCommits("it's a secret.txt"), "cccccc\n", nil).
+				ExpectGitArgs([]string{"ls-tree", "--name-only", "aaaaaa", "--", "it's a secret.txt"}, "it's a secret.txt\n", nil).
+				ExpectGitArgs([]string{"rebase", "--interactive", "--autostash", "--keep-empty", "--no-autosquash", "aaaaaa"}, "", nil),
+		},
+		{
+			testName: "file added on the branch",
+			fileName: "secret.txt",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"symbolic-ref", "--quiet", "--short", "HEAD"}, "feature\n", nil).
+				ExpectGitArgs(revList("--reverse", "--parents", "HEAD"), "bbbbbb aaaaaa\ncccccc bbbbbb\ndddddd cccccc\n", nil).
+				ExpectGitArgs(changingCommits("secret.txt"), "dddddd\ncccccc\n", nil).
+				ExpectGitArgs([]string{"ls-tree", "--name-only", "aaaaaa", "--", "secret.txt"}, "", nil).
+				ExpectGitArgs([]string{"rebase", "--interactive", "--autostash", "--keep-empty", "--no-autosquash", "bbbbbb"}, "", nil),
+		},
+		{
+			testName: "file added in the root commit",
+			fileName: "secret.txt",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"symbolic-ref", "--quiet", "--short", "HEAD"}, "feature\n", nil).
+				ExpectGitArgs(revList("--reverse", "--parents", "HEAD"), "aaaaaa\nbbbbbb aaaaaa\n", nil).
+				ExpectGitArgs(changingCommits("secret.txt"), "aaaaaa\n", nil).
+				ExpectGitArgs([]string{"rebase", "--interactive", "--autostash", "--keep-empty", "--no-autosquash", "--root"}, "", nil),
+		},
+		{
+			testName: "nothing to remove",
+			fileName: "secret.txt",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"symbolic-ref", "--quiet", "--short", "HEAD"}, "feature\n", nil).
+				ExpectGitArgs(revList("--reverse", "--parents", "HEAD"), "bbbbbb aaaaaa\n", nil).
+				ExpectGitArgs(changingCommits("secret.txt"), "", nil).
+				ExpectGitArgs([]string{"ls-tree", "--name-only", "aaaaaa", "--", "secret.txt"}, "", nil),
+			expectedErr: "'secret.txt' isn't in any commit that is only on branch 'feature'",
+		},
+		{
+			testName: "merge commits",
+			fileName: "secret.txt",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"symbolic-ref", "--quiet", "--short", "HEAD"}, "feature\n", nil).
+				ExpectGitArgs(revList("--reverse", "--parents", "HEAD"), "bbbbbb aaaaaa\ncccccc bbbbbb eeeeee\n", nil),
+			expectedErr: "a file can't be removed from history that contains merge commits",
+		},
+		{
+			testName: "detached head",
+			fileName: "secret.txt",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"symbolic-ref", "--quiet", "--short", "HEAD"}, "", errors.New("error")),
+			expectedErr: "a file can only be removed from history when a branch is checked out",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			instance := buildRebaseCommands(commonDeps{runner: s.runner})
+
+			err := instance.RemoveFileFromHistory(s.fileName)
+			if s.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, s.expectedErr)
+			}
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestRebaseRemoveFileFromPausedCommit(t *testing.T) {
+	type scenario struct {
+		testName    string
+		rebaseErr   error
+		runner      *oscommands.FakeCmdObjRunner
+		expectedErr string
+	}
+
+	scenarios := []scenario{
+		{
+			testName: "stopped at an edit",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"diff", "--name-only", "--diff-filter=U", "-z"}, "", nil).
+				ExpectGitArgs([]string{"rm", "--ignore-unmatch", "--quiet", "--", "secret.txt"}, "", nil).
+				ExpectGitArgs([]string{"commit", "--amend", "--no-edit", "--allow-empty"}, "", nil),
+		},
+		{
+			testName:  "a commit that changes the file conflicted with its removal",
+			rebaseErr: errors.New("error: could not apply cccccc... change secret"),
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"diff", "--name-only", "--diff-filter=U", "-z"}, "secret.txt\x00", nil).
+				ExpectGitArgs([]string{"rm", "--ignore-unmatch", "--quiet", "--", "secret.txt"}, "", nil).
+				ExpectGitArgs([]string{"rev-parse", "--verify", "cccccc^{commit}"}, "cccccccccc\n", nil).
+				ExpectGitArgs([]string{"commit", "--allow-empty", "--no-verify", "-C", "cccccccccc"}, "", nil),
+		},
+		{
+			testName:  "conflicts in other files",
+			rebaseErr: errors.New("error: could not apply cccccc... change secret"),
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"diff", "--name-only", "--diff-filter=U", "-z"}, "other.txt\x00secret.txt\x00", nil),
+			expectedErr: "error: could not apply cccccc... change secret",
+		},
+		{
+			testName:  "stopped for another reason",
+			rebaseErr: errors.New("error: cannot rebase"),
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"diff", "--name-only", "--diff-filter=U", "-z"}, "", nil),
+			expectedErr: "error: cannot rebase",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			instance := buildRebaseCommands(commonDeps{runner: s.runner})
+
+			err := instance.removeFileFromPausedCommit("secret.txt", s.rebaseErr)
+			if s.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, s.expectedErr)
+			}
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestRebaseContinueRebaseAutoStage(t *testing.T) {
+	dir := t.TempDir()
+	resolvedFile := "resolved.txt"
+	conflictedFile := "sub/conflicted.txt"
+	deletedFile := "deleted.txt"
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, resolvedFile), []byte("ours\ntheirs\n"), 0o644))
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "sub"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, conflictedFile), []byte("<<<<<<< HEAD\nours\n=======\ntheirs\n>>>>>>> 123456 (commit)\n"), 0o644))
+
+	type scenario struct {
+		testName    string
+		runner      *oscommands.FakeCmdObjRunner
+		expectedErr string
+	}
+
+	scenarios := []scenario{
+		{
+			testName: "all conflicts resolved",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"diff", "--name-only", "--diff-filter=U", "-z"}, resolvedFile+"\x00"+deletedFile+"\x00", nil).
+				ExpectGitArgs([]string{"add", "--", resolvedFile, deletedFile}, "", nil).
+				ExpectGitArgs([]string{"rebase", "--continue"}, "", nil),
+		},
+		{
+			testName: "nothing to stage",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"diff", "--name-only", "--diff-filter=U", "-z"}, "", nil).
+				ExpectGitArgs([]string{"rebase", "--continue"}, "", nil),
+		},
+		{
+			testName: "a file still has conflict markers",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"diff", "--name-only", "--diff-filter=U", "-z"}, resolvedFile+"\x00"+conflictedFile+"\x00", nil),
+			expectedErr: "the following files still contain conflict markers:\n" + conflictedFile,
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			instance := buildRebaseCommands(commonDeps{runner: s.runner, repoPaths: MockRepoPaths(dir)})
+
+			err := instance.ContinueRebaseAutoStage()
+			if s.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, s.expectedErr)
+			}
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestRebaseCherryPickCommitsWithContext(t *testing.T) {
+	commits := []*models.Commit{
+		{Name: "commit3", Sha: "333333"},
+		{Name: "commit2", Sha: "222222"},
+		{Name: "commit1", Sha: "111111"},
+	}
+
+	type scenario struct {
+		testName string
+		// simulates what git has done by the time it is killed
+		stopMidRebase bool
+		// simulates another git process holding the index lock since before
+		// the rebase started
+		lockHeldBefore bool
+		// simulates another git process taking the index lock after the
+		// killed one exited
+		lockTakenAfterwards bool
+		expectAbort         bool
+		expectedErr         string
+	}
+
+	scenarios := []scenario{
+		{
+			testName:      "cancelled after some commits were applied",
+			stopMidRebase: true,
+			expectAbort:   true,
+		},
+		{
+			testName:      "cancelled before git got going",
+			stopMidRebase: false,
+			expectAbort:   false,
+		},
+		{
+			testName:            "index lock taken by another git process afterwards is left alone",
+			stopMidRebase:       true,
+			lockTakenAfterwards: true,
+			expectAbort:         false,
+			expectedErr:         "may belong to another git process",
+		},
+		{
+			testName:       "index lock held by another git process all along is left alone",
+			stopMidRebase:  true,
+			lockHeldBefore: true,
+			expectAbort:    false,
+			expectedErr:    "may belong to another git process",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			dir := t.TempDir()
+			gitDir := filepath.Join(dir, ".git")
+			lockPath := filepath.Join(gitDir, "index.lock")
+			assert.NoError(t, os.MkdirAll(gitDir, 0o755))
+			if s.lockHeldBefore {
+				assert.NoError(t, os.WriteFile(lockPath, nil, 0o644))
+				earlier := time.Now().Add(-time.Hour)
+				assert.NoError(t, os.Chtimes(lockPath, earlier, earlier))
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			runner := oscommands.NewFakeRunner(t).
+				ExpectFunc("cherry-pick rebase", func(cmdObj oscommands.ICmdObj) bool {
+					if !lo.Contains(cmdObj.GetEnvVars(), daemon.DaemonKindEnvKey+"="+strconv.Itoa(int(daemon.DaemonKindCherryPick))) {
+						return false
+					}
+					if s.stopMidRebase {
+						assert.NoError(t, os.MkdirAll(filepath.Join(gitDir, "rebase-merge"), 0o755))
+						if !s.lockHeldBefore {
+							assert.NoError(t, os.WriteFile(lockPath, nil, 0o644))
+						}
+					}
+					cancel()
+					// git is killed
+					<-cmdObj.GetContext().Done()
+					if s.lockTakenAfterwards {
+						assert.NoError(t, os.Remove(lockPath))
+						assert.NoError(t, os.WriteFile(lockPath, []byte("index"), 0o644))
+					}
+					return true
+				}, "", errors.New("signal: killed"))
+			if s.expectAbort {
+				runner.ExpectGitArgs([]string{"rebase", "--abort"}, "", nil)
+			}
+			instance := buildRebaseCommands(commonDeps{runner: runner, repoPaths: MockRepoPaths(dir)})
+
+			err := instance.CherryPickCommitsWithContext(ctx, commits)
+			if s.expectedErr == "" {
+				assert.ErrorIs(t, err, context.Canceled)
+				assert.NoFileExists(t, lockPath)
+			} else {
+				assert.ErrorContains(t, err, s.expectedErr)
+				assert.FileExists(t, lockPath)
+			}
+			runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestRebaseCherryPickCommitsWithContextNotCancelled(t *testing.T) {
+	commits := []*models.Commit{{Name: "commit1", Sha: "111111"}}
+
+	// A conflict is reported as usual rather than aborting the rebase
+	runner := oscommands.NewFakeRunner(t).
+		ExpectFunc("cherry-pick rebase", func(cmdObj oscommands.ICmdObj) bool {
+			return lo.Contains(cmdObj.Args(), "--interactive")
+		}, "", errors.New("CONFLICT"))
+	instance := buildRebaseCommands(commonDeps{runner: runner})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	assert.EqualError(t, instance.CherryPickCommitsWithContext(ctx, commits), "CONFLICT")
+	runner.CheckForMissingCalls()
+}
+
+func TestRebaseRewriteAuthorEmail(t *testing.T) {
+	commits := []*models.Commit{
+		{Name: "commit5", Sha: "555555", AuthorEmail: "old@example.com"},
+		{Name: "commit4", Sha: "444444", AuthorEmail: "other@example.com"},
+		{Name: "commit3", Sha: "333333", AuthorEmail: "Old@Example.com"},
+		{Name: "commit2", Sha: "222222", AuthorEmail: "old@example.com"},
+		{Name: "commit1", Sha: "111111", AuthorEmail: "other@example.com"},
+	}
+
+	repoDir := t.TempDir()
+	doneFile := filepath.Join(repoDir, ".git", "rebase-merge", "done")
+	// Matches a continue, and simulates git getting to the next stop of the
+	// rebase
+	stopAt := func(done string) func(oscommands.ICmdObj) bool {
+		return func(cmdObj oscommands.ICmdObj) bool {
+			if strings.Join(cmdObj.Args()[1:], " ") != strings.Join(append(rebaseHooksArgs(repoDir), "rebase", "--continue"), " ") {
+				return false
+			}
+			if done == "" {
+				_ = os.RemoveAll(filepath.Dir(doneFile))
+			} else {
+				_ = os.MkdirAll(filepath.Dir(doneFile), 0o755)
+				_ = os.WriteFile(doneFile, []byte(done), 0o644)
+			}
+			return true
+		}
+	}
+	setAuthorArgs := []string{"commit", "--allow-empty", "--only", "--no-edit", "--amend", "--author=New Name <new@example.com>"}
+
+	runner := oscommands.NewFakeRunner(t).
+		ExpectFunc("rebase stopping at the first commit by the old email", func(cmdObj oscommands.ICmdObj) bool {
+			return cmdObj.Args()[len(cmdObj.Args())-1] == "111111" &&
+				lo.Contains(cmdObj.GetEnvVars(), daemon.DaemonInstructionEnvKey+`={"Changes":[`+
+					`{"Sha":"555555","NewAction":3},{"Sha":"333333","NewAction":3},{"Sha":"222222","NewAction":3}]}`) &&
+				os.MkdirAll(filepath.Dir(doneFile), 0o755) == nil &&
+				os.WriteFile(doneFile, []byte("edit 222222 commit2\n"), 0o644) == nil
+		}, "", nil).
+		ExpectGitArgs(setAuthorArgs, "", nil).
+		ExpectFunc("continue to commit3", stopAt("edit 222222 commit2\nedit 333333 commit3\n"), "", nil).
+		ExpectGitArgs(setAuthorArgs, "", nil).
+		ExpectFunc("continue past commit4 to commit5", stopAt("edit 222222 commit2\nedit 333333 commit3\npick 444444 commit4\nedit 555555 commit5\n"), "", nil).
+		ExpectGitArgs(setAuthorArgs, "", nil).
+		ExpectFunc("continue to the end", stopAt(""), "", nil)
+	instance := buildRebaseCommands(commonDeps{runner: runner, repoPaths: MockRepoPaths(repoDir)})
+
+	err := instance.RewriteAuthorEmail(commits, "old@example.com", "New Name", "new@example.com")
+	assert.NoError(t, err)
+	runner.CheckForMissingCalls()
+}
+
+func TestRebaseSwapCommits(t *testing.T) {
+	commits := []*models.Commit{
+		{Name: "commit3", Sha: "333333", Parents: []string{"222222"}},
+		{Name: "commit2", Sha: "222222", Parents: []string{"111111"}},
+		{Name: "commit1", Sha: "111111"},
+	}
+
+	expectMoveDown := func(sha string, base string) func(cmdObj oscommands.ICmdObj) bool {
+		return func(cmdObj oscommands.ICmdObj) bool {
+			return cmdObj.Args()[len(cmdObj.Args())-1] == base &&
+				lo.Contains(cmdObj.GetEnvVars(), daemon.DaemonKindEnvKey+"="+strconv.Itoa(int(daemon.DaemonKindMoveTodoDown))) &&
+				lo.Contains(cmdObj.GetEnvVars(), daemon.DaemonInstructionEnvKey+`={"Sha":"`+sha+`"}`)
+		}
+	}
+
+	type scenario struct {
+		testName    string
+		indexA      int
+		indexB      int
+		runner      *oscommands.FakeCmdObjRunner
+		expectedErr string
+	}
+
+	scenarios := []scenario{
+		{
+			testName: "top two commits",
+			indexA:   0,
+			indexB:   1,
+			runner: oscommands.NewFakeRunner(t).
+				ExpectFunc("move commit3 down", expectMoveDown("333333", "111111"), "", nil),
+		},
+		{
+			testName: "indices in either order",
+			indexA:   1,
+			indexB:   0,
+			runner: oscommands.NewFakeRunner(t).
+				ExpectFunc("move commit3 down", expectMoveDown("333333", "111111"), "", nil),
+		},
+		{
+			testName: "pair including the root commit",
+			indexA:   1,
+			indexB:   2,
+			runner: oscommands.NewFakeRunner(t).
+				ExpectFunc("move commit2 down", expectMoveDown("222222", "--root"), "", nil),
+		},
+		{
+			testName:    "commits that aren't adjacent",
+			indexA:      0,
+			indexB:      2,
+			runner:      oscommands.NewFakeRunner(t),
+			expectedErr: "only adjacent commits can be swapped",
+		},
+		{
+			testName:    "index out of range",
+			indexA:      2,
+			indexB:      3,
+			runner:      oscommands.NewFakeRunner(t),
+			expectedErr: "index outside of range of commits",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			instance := buildRebaseCommands(commonDeps{runner: s.runner})
+
+			err := instance.SwapCommits(commits, s.indexA, s.indexB)
+			if s.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, s.expectedErr)
+			}
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestRebaseReorderCommits(t *testing.T) {
+	commits := []*models.Commit{
+		{Name: "commit6", Sha: "666666"},
+		{Name: "commit5", Sha: "555555"},
+		{Name: "commit4", Sha: "444444"},
+		{Name: "commit3", Sha: "333333"},
+		{Name: "commit2", Sha: "222222"},
+		{Name: "commit1", Sha: "111111"},
+	}
+
+	type scenario struct {
+		testName    string
+		newOrder    []string
+		runner      *oscommands.FakeCmdObjRunner
+		expectedErr string
+	}
+
+	scenarios := []scenario{
+		{
+			testName: "reverse the top five commits",
+			newOrder: []string{"666666", "555555", "444444", "333333", "222222"},
+			runner: oscommands.NewFakeRunner(t).
+				ExpectFunc("rebase from below the oldest reordered commit", func(cmdObj oscommands.ICmdObj) bool {
+					return cmdObj.Args()[len(cmdObj.Args())-1] == "111111" &&
+						lo.Contains(cmdObj.GetEnvVars(), daemon.DaemonInstructionEnvKey+
+							`={"Shas":["666666","555555","444444","333333","222222"]}`)
+				}, "", nil),
+		},
+		{
+			testName: "reorder all commits",
+			newOrder: []string{"222222", "111111", "333333", "444444", "555555", "666666"},
+			runner: oscommands.NewFakeRunner(t).
+				ExpectFunc("rebase from root", func(cmdObj oscommands.ICmdObj) bool {
+					return cmdObj.Args()[len(cmdObj.Args())-1] == "--root"
+				}, "", nil),
+		},
+		{
+			testName: "abbreviated shas and refs are resolved",
+			newOrder: []string{"6666", "HEAD~1"},
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"rev-parse", "--verify", "6666^{commit}"}, "666666\n", nil).
+				ExpectGitArgs([]string{"rev-parse", "--verify", "HEAD~1^{commit}"}, "555555\n", nil).
+				ExpectFunc("rebase with full shas", func(cmdObj oscommands.ICmdObj) bool {
+					return cmdObj.Args()[len(cmdObj.Args())-1] == "444444" &&
+						lo.Contains(cmdObj.GetEnvVars(), daemon.DaemonInstructionEnvKey+`={"Shas":["666666","555555"]}`)
+				}, "", nil),
+		},
+		{
+			testName:    "commit outside of the reordered range",
+			newOrder:    []string{"111111", "555555", "666666"},
+			runner:      oscommands.NewFakeRunner(t),
+			expectedErr: "new commit order must contain each of the reordered commits exactly once",
+		},
+		{
+			testName:    "duplicate commit",
+			newOrder:    []string{"555555", "666666", "555555"},
+			runner:      oscommands.NewFakeRunner(t),
+			expectedErr: "new commit order must contain each of the reordered commits exactly once",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			instance := buildRebaseCommands(commonDeps{runner: s.runner})
+
+			err := instance.ReorderCommits(commits, s.newOrder)
+			if s.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, s.expectedErr)
+			}
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestRebaseCanResumeRebase(t *testing.T) {
+	healthyState := map[string]string{
+		"rebase-merge/done":   "pick 111111 commit1\n",
+		"rebase-merge/msgnum": "1\n",
+		"rebase-merge/end":    "2\n",
+		"rebase-merge/onto":   "000000\n",
+	}
+	withFiles := func(files map[string]string) map[string]string {
+		return lo.Assign(healthyState, files)
+	}
+
+	scenarios := []struct {
+		testName       string
+		files          map[string]string
+		expectedResult bool
+		expectedReason string
+	}{
+		{
+			testName:       "not rebasing",
+			files:          map[string]string{},
+			expectedResult: false,
+			expectedReason: "no rebase is in progress",
+		},
+		{
+			testName:       "rebase using the apply backend",
+			files:          map[string]string{"rebase-apply/next": "1\n"},
+			expectedResult: true,
+		},
+		{
+			testName: "started by lazygit",
+			files: withFiles(map[string]string{
+				"rebase-merge/git-rebase-todo": "exec make test\npick 222222 commit2\n",
+				"rebase-merge/lazygit-started": "",
+			}),
+			expectedResult: true,
+		},
+		{
+			testName: "started outside of lazygit with a generated todo",
+			files: withFiles(map[string]string{
+				"rebase-merge/git-rebase-todo": "pick 222222 commit2\nupdate-ref refs/heads/branch\n",
+			}),
+			expectedResult: true,
+		},
+		{
+			testName: "started outside of lazygit with a custom todo",
+			files: withFiles(map[string]string{
+				"rebase-merge/git-rebase-todo": "exec make test\npick 222222 commit2\n",
+			}),
+			expectedResult: false,
+			expectedReason: "the rebase was started outside of lazygit with a custom todo",
+		},
+		{
+			testName: "corrupt state",
+			files: map[string]string{
+				"rebase-merge/git-rebase-todo": "pick 222222 commit2\n",
+				"rebase-merge/lazygit-started": "",
+				"rebase-merge/msgnum":          "1\n",
+			},
+			expectedResult: false,
+			expectedReason: "the rebase state is corrupt (bad or missing: done, end, onto)",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			repoDir := t.TempDir()
+			for name, content := range s.files {
+				path := filepath.Join(repoDir, ".git", name)
+				assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+				assert.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+			}
+
+			instance := buildRebaseCommands(commonDeps{repoPaths: MockRepoPaths(repoDir)})
+			result, reason := instance.CanResumeRebase()
+			assert.Equal(t, s.expectedResult, result)
+			assert.Equal(t, s.expectedReason, reason)
+		})
+	}
+}
+
+func TestRebaseSignoff(t *testing.T) {
+	type scenario struct {
+		testName      string
+		gitVersion    *GitVersion
+		rebaseSignOff bool
+		expectedArgs  []string
+	}
+
+	scenarios := []scenario{
+		{
+			testName:      "signoff disabled",
+			gitVersion:    &GitVersion{2, 34, 0, ""},
+			rebaseSignOff: false,
+			expectedArgs:  []string{"rebase", "--interactive", "--autostash", "--keep-empty", "--no-autosquash", "--rebase-merges", "master"},
+		},
+		{
+			testName:      "signoff enabled",
+			gitVersion:    &GitVersion{2, 34, 0, ""},
+			rebaseSignOff: true,
+			expectedArgs:  []string{"rebase", "--interactive", "--autostash", "--keep-empty", "--no-autosquash", "--rebase-merges", "--signoff", "master"},
+		},
+		{
+			testName:      "signoff enabled, with a git too old to sign off rebased commits itself",
+			gitVersion:    &GitVersion{2, 33, 0, ""},
+			rebaseSignOff: true,
+			expectedArgs:  []string{"rebase", "--interactive", "--autostash", "--keep-empty", "--no-autosquash", "--rebase-merges", "--exec", "git commit --amend --no-edit --allow-empty --no-verify --signoff", "master"},
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			userConfig := config.GetDefaultConfig()
+			userConfig.Git.Rebase.SignOff = s.rebaseSignOff
+			runner := oscommands.NewFakeRunner(t).ExpectGitArgs(s.expectedArgs, "", nil)
+			instance := buildRebaseCommands(commonDeps{runner: runner, gitVersion: s.gitVersion, userConfig: userConfig})
+
+			assert.NoError(t, instance.RebaseBranch("master", nil))
+			runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestRebaseUncommitRange(t *testing.T) {
+	commits := []*models.Commit{
+		{Name: "commit5", Sha: "555555", Parents: []string{"444444"}},
+		{Name: "commit4", Sha: "444444", Parents: []string{"333333"}},
+		{Name: "commit3", Sha: "333333", Parents: []string{"222222"}},
+		{Name: "commit2", Sha: "222222", Parents: []string{"111111"}},
+		{Name: "commit1", Sha: "111111"},
+	}
+
+	type scenario struct {
+		testName    string
+		startIndex  int
+		endIndex    int
+		runner      *oscommands.FakeCmdObjRunner
+		expectedErr string
+	}
+
+	scenarios := []scenario{
+		{
+			testName:   "range at the top of the branch",
+			startIndex: 0,
+			endIndex:   1,
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"reset", "--soft", "444444^"}, "", nil),
+		},
+		{
+			testName:   "range in the middle of the branch",
+			startIndex: 2,
+			endIndex:   3,
+			runner: oscommands.NewFakeRunner(t).
+				ExpectFunc("rebase that moves the range to the top", func(cmdObj oscommands.ICmdObj) bool {
+					return cmdObj.Args()[len(cmdObj.Args())-1] == "111111" &&
+						lo.Contains(cmdObj.GetEnvVars(), daemon.DaemonInstructionEnvKey+
+							`={"Shas":["444444","555555","222222","333333"]}`)
+				}, "", nil).
+				ExpectGitArgs([]string{"reset", "--soft", "HEAD~2"}, "", nil),
+		},
+		{
+			testName:    "range containing the first commit",
+			startIndex:  3,
+			endIndex:    4,
+			runner:      oscommands.NewFakeRunner(t),
+			expectedErr: "cannot uncommit the first commit",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			instance := buildRebaseCommands(commonDeps{runner: s.runner})
+
+			err := instance.UncommitRange(commits, s.startIndex, s.endIndex)
+			if s.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, s.expectedErr)
+			}
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestRebaseContinueRebaseWithResult(t *testing.T) {
+	type scenario struct {
+		testName      string
+		rebasingAfter bool
+		doneAfter     string
+		// gets the args of the continue that we run
+		runner         func(continueArgs []string) *oscommands.FakeCmdObjRunner
+		expectedResult RebaseResult
+		expectedErr    string
+	}
+
+	scenarios := []scenario{
+		{
+			testName:      "rebase completes",
+			rebasingAfter: false,
+			runner: func(continueArgs []string) *oscommands.FakeCmdObjRunner {
+				return oscommands.NewFakeRunner(t).
+					ExpectGitArgs(continueArgs, "", nil)
+			},
+			expectedResult: RebaseResultCompleted,
+		},
+		{
+			testName:      "rebase stops at conflicts",
+			rebasingAfter: true,
+			runner: func(continueArgs []string) *oscommands.FakeCmdObjRunner {
+				return oscommands.NewFakeRunner(t).
+					ExpectGitArgs(continueArgs, "", errors.New("error: could not apply 123456... commit")).
+					ExpectGitArgs([]string{"diff", "--name-only", "--diff-filter=U", "-z"}, "file.txt\x00", nil)
+			},
+			expectedResult: RebaseResultConflicts,
+			expectedErr:    "error: could not apply 123456... commit",
+		},
+		{
+			testName:      "rebase stops at an edit",
+			rebasingAfter: true,
+			runner: func(continueArgs []string) *oscommands.FakeCmdObjRunner {
+				return oscommands.NewFakeRunner(t).
+					ExpectGitArgs(continueArgs, "", nil)
+			},
+			expectedResult: RebaseResultStopped,
+		},
+		{
+			testName:      "rebase stops at a break",
+			rebasingAfter: true,
+			doneAfter:     "pick 123456 commit\nbreak\n",
+			runner: func(continueArgs []string) *oscommands.FakeCmdObjRunner {
+				return oscommands.NewFakeRunner(t).
+					ExpectGitArgs(continueArgs, "", nil)
+			},
+			expectedResult: RebaseResultStoppedAtBreak,
+		},
+		{
+			testName:      "continue fails for another reason after the rebase is gone",
+			rebasingAfter: false,
+			runner: func(continueArgs []string) *oscommands.FakeCmdObjRunner {
+				return oscommands.NewFakeRunner(t).
+					ExpectGitArgs(continueArgs, "", errors.New("error: could not write index")).
+					ExpectGitArgs([]string{"diff", "--name-only", "--diff-filter=U", "-z"}, "", nil)
+			},
+			expectedResult: RebaseResultStopped,
+			expectedErr:    "error: could not write index",
+		},
+		{
+			testName:      "git's error wins over a failure to look for conflicts",
+			rebasingAfter: true,
+			runner: func(continueArgs []string) *oscommands.FakeCmdObjRunner {
+				return oscommands.NewFakeRunner(t).
+					ExpectGitArgs(continueArgs, "", errors.New("error: could not apply 123456... commit")).
+					ExpectGitArgs([]string{"diff", "--name-only", "--diff-filter=U", "-z"}, "", errors.New("fatal: unable to read index"))
+			},
+			expectedResult: RebaseResultStopped,
+			expectedErr:    "error: could not apply 123456... commit",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			repoDir := t.TempDir()
+			assert.NoError(t, os.MkdirAll(filepath.Join(repoDir, ".git"), 0o755))
+			if s.rebasingAfter {
+				assert.NoError(t, os.MkdirAll(filepath.Join(repoDir, ".git", "rebase-merge"), 0o755))
+			}
+			if s.doneAfter != "" {
+				assert.NoError(t, os.WriteFile(filepath.Join(repoDir, ".git", "rebase-merge", "done"), []byte(s.doneAfter), 0o644))
+			}
+			runner := s.runner(append(rebaseHooksArgs(repoDir), "rebase", "--continue"))
+			instance := buildRebaseCommands(commonDeps{runner: runner, repoPaths: MockRepoPaths(repoDir)})
+
+			result, err := instance.ContinueRebaseWithResult()
+			assert.Equal(t, s.expectedResult, result)
+			if s.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, s.expectedErr)
+			}
+			runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestGetBaseShaOrRoot(t *testing.T) {
+	type scenario struct {
+		testName string
+		commits  []*models.Commit
+		index    int
+		expected string
+	}
+
+	scenarios := []scenario{
+		{
+			testName: "base is among the loaded commits",
+			commits: []*models.Commit{
+				{Sha: "333333", Parents: []string{"222222"}},
+				{Sha: "222222", Parents: []string{"111111"}},
+				{Sha: "111111"},
+			},
+			index:    2,
+			expected: "111111",
+		},
+		{
+			testName: "all commits loaded, rebasing from the root",
+			commits: []*models.Commit{
+				{Sha: "222222", Parents: []string{"111111"}},
+				{Sha: "111111"},
+			},
+			index:    2,
+			expected: "--root",
+		},
+		{
+			testName: "commit list was cut short by the commit limit",
+			commits: []*models.Commit{
+				{Sha: "333333", Parents: []string{"222222"}},
+				{Sha: "222222", Parents: []string{"111111"}, Truncated: true},
+			},
+			index:    2,
+			expected: "111111",
+		},
+		{
+			testName: "base is further beyond the commit limit",
+			commits: []*models.Commit{
+				{Sha: "333333", Parents: []string{"222222"}},
+				{Sha: "222222", Parents: []string{"111111"}, Truncated: true},
+			},
+			index:    4,
+			expected: "111111~2",
+		},
+		{
+			testName: "oldest commit has a parent but the list wasn't cut short",
+			commits: []*models.Commit{
+				{Sha: "333333", Parents: []string{"222222"}},
+				{Sha: "222222", Parents: []string{"111111"}},
+			},
+			index:    2,
+			expected: "--root",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			assert.Equal(t, s.expected, getBaseShaOrRoot(s.commits, s.index))
+		})
+	}
+}
+
+func TestRebaseRewordCommitsMatching(t *testing.T) {
+	commits := []*models.Commit{
+		{Name: "proj-1 fix", Sha: "555555"},
+		{Name: "PROJ-2 already right", Sha: "444444"},
+		{Name: "other", Sha: "333333"},
+		{Name: "Add thing", Sha: "222222"},
+		{Name: "unrelated", Sha: "111111"},
+	}
+	pattern := regexp.MustCompile(`(?i)proj-(\d+)`)
+
+	messagesOutput := "555555\x00proj-1 fix\n\n" +
+		"444444\x00PROJ-2 already right\n\n" +
+		"333333\x00other\n\n" +
+		"222222\x00Add thing\n\nSome details\nRefs proj-3\n\n" +
+		"111111\x00unrelated\n"
+
+	runner := oscommands.NewFakeRunner(t).
+		ExpectGitArgs([]string{"show", "-s", "--format=%H%x00%B", "555555", "444444", "333333", "222222", "111111"}, messagesOutput, nil).
+		ExpectFunc("rebase rewording the changed commits only", func(cmdObj oscommands.ICmdObj) bool {
+			return cmdObj.Args()[len(cmdObj.Args())-1] == "111111" &&
+				lo.Contains(cmdObj.GetEnvVars(), daemon.DaemonInstructionEnvKey+`={"Changes":[`+
+					`{"Sha":"555555","NewAction":4,"NewMessage":"PROJ-1 fix"},`+
+					`{"Sha":"222222","NewAction":4,"NewMessage":"Add thing\n\nSome details\nRefs PROJ-3"}]}`)
+		}, "", nil)
+	instance := buildRebaseCommands(commonDeps{runner: runner})
+
+	err := instance.RewordCommitsMatching(commits, pattern, "PROJ-$1")
+	assert.NoError(t, err)
+	runner.CheckForMissingCalls()
+}
+
+func TestRebaseRewordCommitsMatchingNothingChanges(t *testing.T) {
+	commits := []*models.Commit{
+		{Name: "PROJ-1 fix", Sha: "222222"},
+		{Name: "unrelated", Sha: "111111"},
+	}
+
+	runner := oscommands.NewFakeRunner(t).
+		ExpectGitArgs([]string{"show", "-s", "--format=%H%x00%B", "222222", "111111"},
+			"222222\x00PROJ-1 fix\n\n111111\x00unrelated\n", nil)
+	instance := buildRebaseCommands(commonDeps{runner: runner})
+
+	err := instance.RewordCommitsMatching(commits, regexp.MustCompile(`(?i)proj-(\d+)`), "PROJ-$1")
+	assert.EqualError(t, err, "no commit messages would change")
+	runner.CheckForMissingCalls()
+}
+
+func TestRebaseResolveRebaseTarget(t *testing.T) {
+	commits := []*models.Commit{
+		{Sha: "555555", Parents: []string{"444444"}},
+		{Sha: "444444", Parents: []string{"333333"}},
+		{Sha: "333333", Parents: []string{"222222"}},
+		{Sha: "222222", Parents: []string{"111111"}},
+		{Sha: "111111"},
+	}
+
+	type scenario struct {
+		testName             string
+		index                int
+		action               todo.TodoCommand
+		expectedBaseSha      string
+		expectedAffectedShas []string
+		expectedErr          string
+	}
+
+	scenarios := []scenario{
+		{
+			testName:             "reword",
+			index:                2,
+			action:               todo.Reword,
+			expectedBaseSha:      "222222",
+			expectedAffectedShas: []string{"555555", "444444", "333333"},
+		},
+		{
+			testName:             "squash also rewrites the commit below",
+			index:                2,
+			action:               todo.Squash,
+			expectedBaseSha:      "111111",
+			expectedAffectedShas: []string{"555555", "444444", "333333", "222222"},
+		},
+		{
+			testName:             "fixup also rewrites the commit below",
+			index:                2,
+			action:               todo.Fixup,
+			expectedBaseSha:      "111111",
+			expectedAffectedShas: []string{"555555", "444444", "333333", "222222"},
+		},
+		{
+			testName:             "squash into the root commit",
+			index:                3,
+			action:               todo.Squash,
+			expectedBaseSha:      "",
+			expectedAffectedShas: []string{"555555", "444444", "333333", "222222", "111111"},
+		},
+		{
+			testName:    "index out of range",
+			index:       5,
+			action:      todo.Reword,
+			expectedErr: "index outside of range of commits",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			instance := buildRebaseCommands(commonDeps{})
+
+			baseSha, affectedShas, err := instance.ResolveRebaseTarget(commits, s.index, s.action)
+			if s.expectedErr != "" {
+				assert.EqualError(t, err, s.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, s.expectedBaseSha, baseSha)
+			assert.Equal(t, s.expectedAffectedShas, affectedShas)
+		})
+	}
+}
+
+func TestRebaseRewordCommitBelowMerge(t *testing.T) {
+	commits := []*models.Commit{
+		{Name: "Merge branch 'feature'", Sha: "444444", Parents: []string{"333333", "222222"}},
+		{Name: "feature 01", Sha: "222222", Parents: []string{"111111"}},
+		{Name: "master 01", Sha: "333333", Parents: []string{"111111"}},
+		{Name: "initial commit", Sha: "111111"},
+	}
+
+	runner := oscommands.NewFakeRunner(t)
+	instance := buildRebaseCommands(commonDeps{runner: runner, gitVersion: &GitVersion{2, 21, 0, ""}})
+
+	err := instance.RewordCommit(commits, 1, "renamed feature 01", "")
+	assert.EqualError(t, err, "Rewording a commit below a merge commit requires git 2.22 or later, as older versions would flatten the merge")
+	runner.CheckForMissingCalls()
+}
+
+func TestRebaseContinueWouldBeEmpty(t *testing.T) {
+	scenarios := []struct {
+		testName       string
+		files          map[string]string
+		runner         *oscommands.FakeCmdObjRunner
+		expectedResult bool
+	}{
+		{
+			testName:       "not rebasing",
+			files:          map[string]string{},
+			runner:         oscommands.NewFakeRunner(t),
+			expectedResult: false,
+		},
+		{
+			testName:       "stopped at a break",
+			files:          map[string]string{"rebase-merge/done": "break\n"},
+			runner:         oscommands.NewFakeRunner(t),
+			expectedResult: false,
+		},
+		{
+			testName: "stopped at an edit",
+			files: map[string]string{
+				"rebase-merge/message": "commit2\n",
+				"rebase-merge/amend":   "222222\n",
+			},
+			runner:         oscommands.NewFakeRunner(t),
+			expectedResult: false,
+		},
+		{
+			testName: "conflict resolved to the parent's content",
+			files:    map[string]string{"rebase-merge/message": "commit2\n"},
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"diff", "--cached", "--name-only", "HEAD"}, "", nil),
+			expectedResult: true,
+		},
+		{
+			testName: "conflict resolved with changes",
+			files:    map[string]string{"rebase-merge/message": "commit2\n"},
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"diff", "--cached", "--name-only", "HEAD"}, "file.txt\n", nil),
+			expectedResult: false,
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			repoDir := t.TempDir()
+			for name, content := range s.files {
+				path := filepath.Join(repoDir, ".git", name)
+				assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+				assert.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+			}
+
+			instance := buildRebaseCommands(commonDeps{runner: s.runner, repoPaths: MockRepoPaths(repoDir)})
+			result, err := instance.ContinueWouldBeEmpty()
+			assert.NoError(t, err)
+			assert.Equal(t, s.expectedResult, result)
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestRebaseUpdateRefs(t *testing.T) {
+	type scenario struct {
+		testName         string
+		gitVersion       *GitVersion
+		rebaseUpdateRefs bool
+		expectedArgs     []string
+	}
+
+	scenarios := []scenario{
+		{
+			testName:         "update refs disabled",
+			gitVersion:       &GitVersion{2, 38, 0, ""},
+			rebaseUpdateRefs: false,
+			expectedArgs:     []string{"rebase", "--interactive", "--autostash", "--keep-empty", "--no-autosquash", "--rebase-merges", "master"},
+		},
+		{
+			testName:         "update refs enabled",
+			gitVersion:       &GitVersion{2, 38, 0, ""},
+			rebaseUpdateRefs: true,
+			expectedArgs:     []string{"rebase", "--interactive", "--autostash", "--keep-empty", "--no-autosquash", "--rebase-merges", "--update-refs", "master"},
+		},
+		{
+			testName:         "update refs enabled, but git too old to support it",
+			gitVersion:       &GitVersion{2, 37, 0, ""},
+			rebaseUpdateRefs: true,
+			expectedArgs:     []string{"rebase", "--interactive", "--autostash", "--keep-empty", "--no-autosquash", "--rebase-merges", "master"},
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			userConfig := config.GetDefaultConfig()
+			userConfig.Git.Rebase.UpdateRefs = s.rebaseUpdateRefs
+			runner := oscommands.NewFakeRunner(t).ExpectGitArgs(s.expectedArgs, "", nil)
+			instance := buildRebaseCommands(commonDeps{runner: runner, gitVersion: s.gitVersion, userConfig: userConfig})
+
+			assert.NoError(t, instance.RebaseBranch("master", nil))
+			runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestRebaseBackend(t *testing.T) {
+	type scenario struct {
+		testName      string
+		configBackend string
+		optsBackend   RebaseBackend
+		expectedArgs  []string
+	}
+
+	scenarios := []scenario{
+		{
+			testName:      "default backend",
+			configBackend: "",
+			expectedArgs:  []string{"rebase", "--interactive", "--autostash", "--keep-empty", "--no-autosquash", "--rebase-merges", "master"},
+		},
+		{
+			testName:      "merge backend from config",
+			configBackend: "merge",
+			expectedArgs:  []string{"rebase", "--interactive", "--merge", "--autostash", "--keep-empty", "--no-autosquash", "--rebase-merges", "master"},
+		},
+		{
+			testName:     "merge backend from opts",
+			optsBackend:  RebaseBackendMerge,
+			expectedArgs: []string{"rebase", "--interactive", "--merge", "--autostash", "--keep-empty", "--no-autosquash", "--rebase-merges", "master"},
+		},
+		{
+			testName:      "apply backend is ignored for an interactive rebase",
+			configBackend: "apply",
+			expectedArgs:  []string{"rebase", "--interactive", "--autostash", "--keep-empty", "--no-autosquash", "--rebase-merges", "master"},
+		},
+		{
+			testName:     "apply backend from opts is ignored too",
+			optsBackend:  RebaseBackendApply,
+			expectedArgs: []string{"rebase", "--interactive", "--autostash", "--keep-empty", "--no-autosquash", "--rebase-merges", "master"},
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			userConfig := config.GetDefaultConfig()
+			userConfig.Git.Rebase.Backend = s.configBackend
+			instance := buildRebaseCommands(commonDeps{gitVersion: &GitVersion{2, 38, 0, ""}, userConfig: userConfig})
+
+			cmdObj := instance.PrepareInteractiveRebaseCommand(PrepareInteractiveRebaseCommandOpts{
+				baseShaOrRoot: "master",
+				backend:       s.optsBackend,
+			})
+			assert.Equal(t, s.expectedArgs, cmdObj.Args()[1:])
+		})
+	}
+}
+
+func TestRebaseInteractiveRebaseWithApplyBackend(t *testing.T) {
+	t.Run("apply backend from config is replaced, and the user is told", func(t *testing.T) {
+		userConfig := config.GetDefaultConfig()
+		userConfig.Git.Rebase.Backend = "apply"
+		runner := oscommands.NewFakeRunner(t).
+			ExpectFunc("interactive rebase", func(cmdObj oscommands.ICmdObj) bool {
+				return assert.ObjectsAreEqual(
+					[]string{"git", "rebase", "--interactive", "--autostash", "--keep-empty", "--no-autosquash", "--rebase-merges", "master"},
+					cmdObj.Args(),
+				)
+			}, "", nil)
+		logged := []string{}
+		instance := buildRebaseCommands(commonDeps{
+			runner:     runner,
+			gitVersion: &GitVersion{2, 26, 0, ""},
+			userConfig: userConfig,
+			logCommand: func(str string, _ bool) { logged = append(logged, str) },
+		})
+
+		assert.NoError(t, instance.EditRebase("master"))
+		assert.Contains(t, logged, "git.rebase.backend is 'apply', but interactive rebases can only use the merge backend, so this rebase uses that")
+		runner.CheckForMissingCalls()
+	})
+
+	t.Run("apply backend from opts is refused", func(t *testing.T) {
+		runner := oscommands.NewFakeRunner(t)
+		instance := buildRebaseCommands(commonDeps{runner: runner, gitVersion: &GitVersion{2, 26, 0, ""}})
+
+		err := instance.runInteractiveRebase(PrepareInteractiveRebaseCommandOpts{baseShaOrRoot: "master", backend: RebaseBackendApply})
+		assert.EqualError(t, err, "Interactive rebases can only use the merge backend, not the apply backend")
+		runner.CheckForMissingCalls()
+	})
+}
+
+func TestRebaseBranchWithBackend(t *testing.T) {
+	type scenario struct {
+		testName      string
+		configBackend string
+		signOff       bool
+		expectedArgs  []string
+	}
+
+	scenarios := []scenario{
+		{
+			testName:      "merge backend goes through the interactive rebase",
+			configBackend: "merge",
+			expectedArgs:  []string{"rebase", "--interactive", "--merge", "--autostash", "--keep-empty", "--no-autosquash", "--rebase-merges", "master"},
+		},
+		{
+			testName:      "apply backend uses a plain rebase",
+			configBackend: "apply",
+			expectedArgs:  []string{"rebase", "--apply", "--autostash", "master"},
+		},
+		{
+			testName:      "apply backend with sign-off",
+			configBackend: "apply",
+			signOff:       true,
+			expectedArgs:  []string{"rebase", "--apply", "--autostash", "--signoff", "master"},
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			userConfig := config.GetDefaultConfig()
+			userConfig.Git.Rebase.Backend = s.configBackend
+			userConfig.Git.Rebase.SignOff = s.signOff
+			runner := oscommands.NewFakeRunner(t).ExpectGitArgs(s.expectedArgs, "", nil)
+			instance := buildRebaseCommands(commonDeps{runner: runner, gitVersion: &GitVersion{2, 38, 0, ""}, userConfig: userConfig})
+
+			assert.NoError(t, instance.RebaseBranch("master", nil))
+			runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestRebaseBranchWithoutAutostash(t *testing.T) {
+	type scenario struct {
+		testName    string
+		runner      *oscommands.FakeCmdObjRunner
+		expectedErr string
+	}
+
+	scenarios := []scenario{
+		{
+			testName: "untracked files don't get in the way",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"status", "--porcelain"}, "?? new.txt\n", nil).
+				ExpectGitArgs([]string{"rebase", "--interactive", "--no-autostash", "--keep-empty", "--no-autosquash", "--rebase-merges", "master"}, "", nil),
+		},
+		{
+			testName: "changes to tracked files are refused before starting",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"status", "--porcelain"}, " M file.txt\n", nil),
+			expectedErr: "You have uncommitted changes to tracked files. Commit or stash them before rebasing, or set git.rebase.autoStash to true",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			userConfig := config.GetDefaultConfig()
+			userConfig.Git.Rebase.AutoStash = false
+			instance := buildRebaseCommands(commonDeps{runner: s.runner, gitVersion: &GitVersion{2, 38, 0, ""}, userConfig: userConfig})
+
+			err := instance.RebaseBranch("master", nil)
+			if s.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, s.expectedErr)
+			}
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestRebaseBranchesInRange(t *testing.T) {
+	type scenario struct {
+		testName         string
+		runner           *oscommands.FakeCmdObjRunner
+		expectedBranches []string
+		expectedErr      string
+	}
+
+	forEachRefArgs := []string{
+		"for-each-ref", "--format=%(HEAD)%(objectname) %(refname:short)",
+		"--contains", "aaaaaa", "--merged", "HEAD", "refs/heads",
+	}
+
+	scenarios := []scenario{
+		{
+			// main <- stack1 <- stack2 <- stack3, with stack3 checked out
+			testName: "stacked branches",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"rev-parse", "--verify", "main^{commit}"}, "aaaaaa\n", nil).
+				ExpectGitArgs(forEachRefArgs, " aaaaaa main\n 111111 stack1\n 222222 stack2\n*333333 stack3\n", nil),
+			expectedBranches: []string{"stack1", "stack2"},
+		},
+		{
+			testName: "no other branches",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"rev-parse", "--verify", "main^{commit}"}, "aaaaaa\n", nil).
+				ExpectGitArgs(forEachRefArgs, "*333333 stack3\n", nil),
+			expectedBranches: []string{},
+		},
+		{
+			testName: "unknown base",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"rev-parse", "--verify", "main^{commit}"}, "", errors.New("error")),
+			expectedErr: "unknown ref 'main'",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			instance := buildRebaseCommands(commonDeps{runner: s.runner})
+
+			branches, err := instance.BranchesInRange("main")
+			if s.expectedErr == "" {
+				assert.NoError(t, err)
+				assert.Equal(t, s.expectedBranches, branches)
+			} else {
+				assert.EqualError(t, err, s.expectedErr)
+			}
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestRebaseRangeTouchesSubmodules(t *testing.T) {
+	scenarios := []struct {
+		testName string
+		output   string
+		expected bool
+	}{
+		{
+			testName: "no submodule changes",
+			output:   ":100644 100644 aaaaaa bbbbbb M\tfile1\n\n:000000 100644 000000 cccccc A\tfile2\n",
+			expected: false,
+		},
+		{
+			testName: "submodule pointer changed",
+			output:   ":100644 100644 aaaaaa bbbbbb M\tfile1\n\n:160000 160000 dddddd eeeeee M\tmy_submodule\n",
+			expected: true,
+		},
+		{
+			testName: "submodule added",
+			output:   ":000000 160000 000000 eeeeee A\tmy_submodule\n",
+			expected: true,
+		},
+		{
+			testName: ".gitmodules changed",
+			output:   ":100644 100644 aaaaaa bbbbbb M\t.gitmodules\n",
+			expected: true,
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			runner := oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"log", "--format=", "--raw", "--no-renames", "main..HEAD"}, s.output, nil)
+			instance := buildRebaseCommands(commonDeps{runner: runner})
+
+			touchesSubmodules, err := instance.RangeTouchesSubmodules("main")
+			assert.NoError(t, err)
+			assert.Equal(t, s.expected, touchesSubmodules)
+			runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestRebaseRangeTouchesSubmodulesFromRoot(t *testing.T) {
+	runner := oscommands.NewFakeRunner(t).
+		ExpectGitArgs([]string{"log", "--format=", "--raw", "--no-renames", "HEAD"}, ":000000 160000 000000 eeeeee A\tmy_submodule\n", nil)
+	instance := buildRebaseCommands(commonDeps{runner: runner})
+
+	touchesSubmodules, err := instance.RangeTouchesSubmodules("")
+	assert.NoError(t, err)
+	assert.True(t, touchesSubmodules)
+	runner.CheckForMissingCalls()
+}
+
+func TestRebaseRebaseRangeStats(t *testing.T) {
+	scenarios := []struct {
+		testName      string
+		output        string
+		err           error
+		expectedCount int
+		expectedErr   string
+	}{
+		{
+			testName:      "commits above the base",
+			output:        "3\n",
+			expectedCount: 3,
+		},
+		{
+			testName:      "empty range",
+			output:        "0\n",
+			expectedCount: 0,
+		},
+		{
+			testName:    "unknown base",
+			err:         errors.New("fatal: bad revision 'main..HEAD'"),
+			expectedErr: "fatal: bad revision 'main..HEAD'",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			runner := oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"rev-list", "--count", "main..HEAD"}, s.output, s.err)
+			instance := buildRebaseCommands(commonDeps{runner: runner})
+
+			count, err := instance.RebaseRangeStats("main")
+			if s.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, s.expectedErr)
+			}
+			assert.Equal(t, s.expectedCount, count)
+			runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestRebasePreviewAutosquash(t *testing.T) {
+	// newest first
+	commits := []*models.Commit{
+		{Sha: "d71c7c5aaaa", Name: "fixup! 6803559"},
+		{Sha: "acac306aaaa", Name: "Unrelated"},
+		{Sha: "c8d21a7aaaa", Name: "squash! Fix"},
+		{Sha: "9160aa9aaaa", Name: "amend! other"},
+		{Sha: "4af61cbaaaa", Name: "fixup! fixup! Fix bug"},
+		{Sha: "3fccb57aaaa", Name: "fixup! Fix bug"},
+		{Sha: "78e4f12aaaa", Name: "Fix bug"},
+		{Sha: "e64d272aaaa", Name: "other"},
+		{Sha: "6803559aaaa", Name: "Fix bug"},
+	}
+
+	scenarios := []struct {
+		testName   string
+		gitVersion *GitVersion
+		expected   []AutosquashPair
+	}{
+		{
+			testName:   "stacked fixups go into the oldest commit with the subject",
+			gitVersion: &GitVersion{2, 32, 0, ""},
+			expected: []AutosquashPair{
+				{MarkerSha: "d71c7c5aaaa", TargetSha: "6803559aaaa"},
+				{MarkerSha: "c8d21a7aaaa", TargetSha: "6803559aaaa"},
+				{MarkerSha: "9160aa9aaaa", TargetSha: "e64d272aaaa"},
+				{MarkerSha: "4af61cbaaaa", TargetSha: "6803559aaaa"},
+				{MarkerSha: "3fccb57aaaa", TargetSha: "6803559aaaa"},
+			},
+		},
+		{
+			testName:   "amend! is not understood by older git",
+			gitVersion: &GitVersion{2, 31, 0, ""},
+			expected: []AutosquashPair{
+				{MarkerSha: "d71c7c5aaaa", TargetSha: "6803559aaaa"},
+				{MarkerSha: "c8d21a7aaaa", TargetSha: "6803559aaaa"},
+				{MarkerSha: "4af61cbaaaa", TargetSha: "6803559aaaa"},
+				{MarkerSha: "3fccb57aaaa", TargetSha: "6803559aaaa"},
+			},
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			instance := buildRebaseCommands(commonDeps{gitVersion: s.gitVersion})
+
+			pairs, err := instance.PreviewAutosquash(commits)
+			assert.NoError(t, err)
+			assert.Equal(t, s.expected, pairs)
+		})
+	}
+
+	t.Run("marker without a target", func(t *testing.T) {
+		instance := buildRebaseCommands(commonDeps{})
+
+		pairs, err := instance.PreviewAutosquash([]*models.Commit{
+			{Sha: "bbbbbb", Name: "fixup! Gone"},
+			{Sha: "aaaaaa", Name: "Base"},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []AutosquashPair{}, pairs)
+	})
+}
+
+func TestRebaseCollapseFixups(t *testing.T) {
+	type scenario struct {
+		testName    string
+		gitVersion  *GitVersion
+		runner      *oscommands.FakeCmdObjRunner
+		expectedErr string
+	}
+
+	scenarios := []scenario{
+		{
+			testName:   "fixups and new message are squashed into the target",
+			gitVersion: &GitVersion{2, 32, 0, ""},
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"rev-parse", "--verify", "abc123^{commit}"}, "abc123def\n", nil).
+				ExpectGitArgs([]string{"rev-parse", "--verify", "abc123def^^{commit}"}, "0000aaaa\n", nil).
+				ExpectGitArgs([]string{"commit", "--allow-empty", "--only", "-m", "amend! abc123def", "-m", "Fresh subject\n\nFresh body"}, "", nil).
+				ExpectGitArgs([]string{"rebase", "--interactive", "--rebase-merges", "--autostash", "--autosquash", "abc123def^"}, "", nil),
+		},
+		{
+			testName:   "target is the initial commit",
+			gitVersion: &GitVersion{2, 32, 0, ""},
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"rev-parse", "--verify", "abc123^{commit}"}, "abc123def\n", nil).
+				ExpectGitArgs([]string{"rev-parse", "--verify", "abc123def^^{commit}"}, "", errors.New("fatal: Needed a single revision")).
+				ExpectGitArgs([]string{"commit", "--allow-empty", "--only", "-m", "amend! abc123def", "-m", "Fresh subject\n\nFresh body"}, "", nil).
+				ExpectGitArgs([]string{"rebase", "--interactive", "--rebase-merges", "--autostash", "--autosquash", "--root"}, "", nil),
+		},
+		{
+			testName:    "git is too old",
+			gitVersion:  &GitVersion{2, 31, 0, ""},
+			runner:      oscommands.NewFakeRunner(t),
+			expectedErr: "Creating an amend! commit requires git 2.32 or later, as older versions don't squash it when autosquashing",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			instance := buildRebaseCommands(commonDeps{runner: s.runner, gitVersion: s.gitVersion})
+
+			err := instance.CollapseFixups("abc123", "Fresh subject\n\nFresh body")
+			if s.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, s.expectedErr)
+			}
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestRebaseResolveSha(t *testing.T) {
+	scenarios := []struct {
+		testName    string
+		ref         string
+		runner      *oscommands.FakeCmdObjRunner
+		expectedSha string
+		expectedErr string
+	}{
+		{
+			testName: "abbreviated sha",
+			ref:      "1234abc",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"rev-parse", "--verify", "1234abc^{commit}"}, "1234abcdef\n", nil),
+			expectedSha: "1234abcdef",
+		},
+		{
+			testName: "branch name",
+			ref:      "feature",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"rev-parse", "--verify", "feature^{commit}"}, "abcdef1234\n", nil),
+			expectedSha: "abcdef1234",
+		},
+		{
+			testName: "relative ref",
+			ref:      "HEAD~2",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"rev-parse", "--verify", "HEAD~2^{commit}"}, "fedcba9876\n", nil),
+			expectedSha: "fedcba9876",
+		},
+		{
+			testName: "ambiguous sha",
+			ref:      "3d73",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"rev-parse", "--verify", "3d73^{commit}"}, "",
+					errors.New("error: short object ID 3d73 is ambiguous\nfatal: Needed a single revision")),
+			expectedErr: "ambiguous ref '3d73'; use more characters of the sha",
+		},
+		{
+			testName: "unknown ref",
+			ref:      "nope",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"rev-parse", "--verify", "nope^{commit}"}, "",
+					errors.New("fatal: Needed a single revision")),
+			expectedErr: "unknown ref 'nope'",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			instance := buildRebaseCommands(commonDeps{runner: s.runner})
+
+			sha, err := instance.ResolveSha(s.ref)
+			if s.expectedErr == "" {
+				assert.NoError(t, err)
+				assert.Equal(t, s.expectedSha, sha)
+			} else {
+				assert.EqualError(t, err, s.expectedErr)
+			}
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestRebaseStoppedAtBreak(t *testing.T) {
+	type scenario struct {
+		testName string
+		done     string
+		runner   *oscommands.FakeCmdObjRunner
+		expected bool
+	}
+
+	scenarios := []scenario{
+		{
+			testName: "stopped cleanly at a break",
+			done:     "pick 123456 commit\nbreak\n",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"diff", "--name-only", "--diff-filter=U", "-z"}, "", nil),
+			expected: true,
+		},
+		{
+			testName: "conflicts since the break",
+			done:     "break\n",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"diff", "--name-only", "--diff-filter=U", "-z"}, "file.txt\x00", nil),
+			expected: false,
+		},
+		{
+			testName: "stopped at an edit",
+			done:     "break\nedit 123456 commit\n",
+			runner:   oscommands.NewFakeRunner(t),
+			expected: false,
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			repoDir := t.TempDir()
+			assert.NoError(t, os.MkdirAll(filepath.Join(repoDir, ".git", "rebase-merge"), 0o755))
+			assert.NoError(t, os.WriteFile(filepath.Join(repoDir, ".git", "rebase-merge", "done"), []byte(s.done), 0o644))
+			instance := buildRebaseCommands(commonDeps{runner: s.runner, repoPaths: MockRepoPaths(repoDir)})
+
+			stopped, err := instance.StoppedAtBreak()
+			assert.NoError(t, err)
+			assert.Equal(t, s.expected, stopped)
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestRebaseCherryPickCommitsWithMainline(t *testing.T) {
+	merge := &models.Commit{Name: "Merge branch 'feature'", Sha: "333333", Parents: []string{"111111", "222222"}}
+	commit := &models.Commit{Name: "commit", Sha: "444444", Parents: []string{"333333"}}
+
+	type scenario struct {
+		testName      string
+		commits       []*models.Commit
+		mainlineBySha map[string]int
+		runner        *oscommands.FakeCmdObjRunner
+		expectedErr   string
+	}
+
+	scenarios := []scenario{
+		{
+			testName:      "no merges",
+			commits:       []*models.Commit{commit},
+			mainlineBySha: nil,
+			runner: oscommands.NewFakeRunner(t).
+				ExpectFunc("cherry-pick rebase", func(cmdObj oscommands.ICmdObj) bool {
+					return cmdObj.Args()[len(cmdObj.Args())-1] == "HEAD" &&
+						lo.Contains(cmdObj.GetEnvVars(), daemon.DaemonKindEnvKey+"="+strconv.Itoa(int(daemon.DaemonKindCherryPick)))
+				}, "", nil),
+		},
+		{
+			testName:      "merge commit onto HEAD",
+			commits:       []*models.Commit{commit, merge},
+			mainlineBySha: map[string]int{"333333": 2},
+			runner: oscommands.NewFakeRunner(t).
+				ExpectFunc("cherry-pick rebase with an exec for the merge", func(cmdObj oscommands.ICmdObj) bool {
+					return cmdObj.Args()[len(cmdObj.Args())-1] == "HEAD" &&
+						lo.Contains(cmdObj.GetEnvVars(), daemon.DaemonInstructionEnvKey+
+							`={"Todo":"exec git cherry-pick -m 2 333333\npick 444444 commit\n"}`)
+				}, "", nil),
+		},
+		{
+			testName:      "merge commit fails to apply",
+			commits:       []*models.Commit{commit, merge},
+			mainlineBySha: map[string]int{"333333": 1},
+			runner: oscommands.NewFakeRunner(t).
+				ExpectFunc("cherry-pick rebase", func(cmdObj oscommands.ICmdObj) bool {
+					return cmdObj.Args()[len(cmdObj.Args())-1] == "HEAD"
+				}, "", errors.New("error: could not apply 333333")),
+			expectedErr: "error: could not apply 333333",
+		},
+		{
+			testName:      "no mainline for merge commit",
+			commits:       []*models.Commit{merge},
+			mainlineBySha: map[string]int{},
+			runner:        oscommands.NewFakeRunner(t),
+			expectedErr:   "no valid mainline parent given for merge commit 333333",
+		},
+		{
+			testName:      "mainline out of range",
+			commits:       []*models.Commit{merge},
+			mainlineBySha: map[string]int{"333333": 3},
+			runner:        oscommands.NewFakeRunner(t),
+			expectedErr:   "no valid mainline parent given for merge commit 333333",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			instance := buildRebaseCommands(commonDeps{runner: s.runner})
+
+			err := instance.CherryPickCommitsWithMainline(s.commits, s.mainlineBySha)
+			if s.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, s.expectedErr)
+			}
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestRebaseAmendToByIndex(t *testing.T) {
+	commits := []*models.Commit{
+		{Name: "commit3", Sha: "333333"},
+		{Name: "commit2", Sha: "222222"},
+		{Name: "commit1", Sha: "111111"},
+	}
+
+	type scenario struct {
+		testName    string
+		index       int
+		runner      *oscommands.FakeCmdObjRunner
+		expectedErr string
+	}
+
+	scenarios := []scenario{
+		{
+			testName: "head commit is amended directly",
+			index:    0,
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"commit", "--amend", "--no-edit", "--allow-empty"}, "", nil),
+		},
+		{
+			testName: "older commit is amended through a fixup commit",
+			index:    1,
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"commit", "--fixup=222222"}, "", nil).
+				ExpectGitArgs([]string{"rev-parse", "--verify", "HEAD"}, "444444\n", nil).
+				ExpectFunc("rebase that squashes in the fixup", func(cmdObj oscommands.ICmdObj) bool {
+					return cmdObj.Args()[len(cmdObj.Args())-1] == "111111" &&
+						lo.Contains(cmdObj.GetEnvVars(), daemon.DaemonKindEnvKey+"="+strconv.Itoa(int(daemon.DaemonKindMoveFixupCommitDown)))
+				}, "", nil),
+		},
+		{
+			testName:    "index out of range",
+			index:       3,
+			runner:      oscommands.NewFakeRunner(t),
+			expectedErr: "index outside of range of commits",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			instance := buildRebaseCommands(commonDeps{runner: s.runner})
+
+			err := instance.AmendToByIndex(commits, s.index)
+			if s.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, s.expectedErr)
+			}
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestRebaseSquashStagedInto(t *testing.T) {
+	commit := &models.Commit{Name: "commit2", Sha: "222222", Parents: []string{"111111"}}
+
+	isRebaseWithInstruction := func(kind daemon.DaemonKind, instruction string) func(cmdObj oscommands.ICmdObj) bool {
+		return func(cmdObj oscommands.ICmdObj) bool {
+			return cmdObj.Args()[len(cmdObj.Args())-1] == "111111" &&
+				lo.Contains(cmdObj.GetEnvVars(), daemon.DaemonKindEnvKey+"="+strconv.Itoa(int(kind))) &&
+				lo.Contains(cmdObj.GetEnvVars(), daemon.DaemonInstructionEnvKey+"="+instruction)
+		}
+	}
+
+	type scenario struct {
+		testName   string
+		gitVersion *GitVersion
+		runner     *oscommands.FakeCmdObjRunner
+	}
+
+	scenarios := []scenario{
+		{
+			testName:   "amend commit is squashed in",
+			gitVersion: &GitVersion{2, 32, 0, ""},
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"commit", "-m", "amend! commit2", "-m", "new message"}, "", nil).
+				ExpectGitArgs([]string{"rev-parse", "--verify", "HEAD"}, "444444\n", nil).
+				ExpectFunc("rebase that squashes in the amend commit",
+					isRebaseWithInstruction(daemon.DaemonKindMoveFixupCommitDown,
+						`{"OriginalSha":"222222","FixupSha":"444444","UseFixupMessage":true}`), "", nil),
+		},
+		{
+			testName:   "older git rewords the target while squashing in a fixup commit",
+			gitVersion: &GitVersion{2, 31, 0, ""},
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"commit", "--fixup=222222"}, "", nil).
+				ExpectGitArgs([]string{"rev-parse", "--verify", "HEAD"}, "444444\n", nil).
+				ExpectFunc("rebase that rewords and squashes in the fixup commit",
+					isRebaseWithInstruction(daemon.DaemonKindChangeTodoActions, `{"Changes":[`+
+						`{"Sha":"222222","NewAction":4,"NewMessage":"new message"},`+
+						`{"Sha":"444444","NewAction":5,"MoveAfterSha":"222222"}]}`), "", nil),
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			instance := buildRebaseCommands(commonDeps{runner: s.runner, gitVersion: s.gitVersion})
+
+			assert.NoError(t, instance.SquashStagedInto(commit, "new message"))
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestRebaseSequenceEditorOverride(t *testing.T) {
+	type scenario struct {
+		testName               string
+		sequenceEditorOverride string
+		expectedEditor         string
+	}
+
+	scenarios := []scenario{
+		{
+			testName:               "no override",
+			sequenceEditorOverride: "",
+			expectedEditor:         "GIT_SEQUENCE_EDITOR=" + oscommands.GetLazygitPath(),
+		},
+		{
+			testName:               "override",
+			sequenceEditorOverride: "/usr/local/bin/rewrite-todo",
+			expectedEditor:         "GIT_SEQUENCE_EDITOR=/usr/local/bin/rewrite-todo",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			instance := buildRebaseCommands(commonDeps{})
+
+			cmdObj := instance.PrepareInteractiveRebaseCommand(PrepareInteractiveRebaseCommandOpts{
+				baseShaOrRoot:          "master",
+				instruction:            daemon.NewInsertBreakInstruction(),
+				sequenceEditorOverride: s.sequenceEditorOverride,
+			})
+
+			envVars := cmdObj.GetEnvVars()
+			assert.Contains(t, envVars, s.expectedEditor)
+			assert.Contains(t, envVars, daemon.DaemonKindEnvKey+"="+strconv.Itoa(int(daemon.DaemonKindInsertBreak)))
+		})
+	}
+}
+
+func TestRebaseAbortRebaseAndCheckout(t *testing.T) {
+	type scenario struct {
+		testName    string
+		runner      *oscommands.FakeCmdObjRunner
+		expectedErr string
+	}
+
+	scenarios := []scenario{
+		{
+			testName: "abort succeeds",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"rebase", "--abort"}, "", nil).
+				ExpectGitArgs([]string{"checkout", "main"}, "", nil),
+		},
+		{
+			testName: "abort fails",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"rebase", "--abort"}, "", errors.New("error: could not abort")),
+			expectedErr: "error: could not abort",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			instance := buildRebaseCommands(commonDeps{runner: s.runner})
+
+			err := instance.AbortRebaseAndCheckout("main")
+			if s.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, s.expectedErr)
+			}
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestRebaseProgressWriter(t *testing.T) {
+	scenarios := []struct {
+		testName         string
+		done             string
+		output           []string
+		expectedProgress []string
+	}{
+		{
+			testName:         "no progress messages",
+			done:             "pick 111111 commit1\n",
+			output:           []string{"Successfully rebased and updated refs/heads/master.\n"},
+			expectedProgress: nil,
+		},
+		{
+			testName: "steps as git gets to them",
+			done:     "pick 1111111111 commit1\n# a comment\nreword 2222222222 commit2\nexec make test\n",
+			output: []string{
+				"Rebasing (1/3)\rRebasing (2/3)\r",
+				"\r\x1b[KExecuting: make test\nRebasing (3/3)\r",
+				"\r\x1b[KSuccessfully rebased and updated refs/heads/master.\n",
+			},
+			expectedProgress: []string{"pick 11111111 (1/3)", "reword 22222222 (2/3)", "exec (3/3)"},
+		},
+		{
+			testName:         "message split across writes",
+			done:             "pick 1111111111 commit1\n",
+			output:           []string{"Rebas", "ing (1/", "1)\r"},
+			expectedProgress: []string{"pick 11111111 (1/1)"},
+		},
+		{
+			testName:         "step that isn't in the done file",
+			done:             "pick 1111111111 commit1\n",
+			output:           []string{"Rebasing (2/2)\r"},
+			expectedProgress: nil,
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			repoDir := t.TempDir()
+			donePath := filepath.Join(repoDir, ".git", "rebase-merge", "done")
+			assert.NoError(t, os.MkdirAll(filepath.Dir(donePath), 0o755))
+			assert.NoError(t, os.WriteFile(donePath, []byte(s.done), 0o644))
+
+			instance := buildRebaseCommands(commonDeps{repoPaths: MockRepoPaths(repoDir)})
+			var progress []string
+			writer := instance.newRebaseProgressWriter(PrepareInteractiveRebaseCommandOpts{
+				onProgress: func(p RebaseProgress) { progress = append(progress, p.String()) },
+			})
+			for _, output := range s.output {
+				n, err := writer.Write([]byte(output))
+				assert.NoError(t, err)
+				assert.Equal(t, len(output), n)
+			}
+
+			assert.Equal(t, s.expectedProgress, progress)
+		})
+	}
+}
+
+func TestRebaseContinueNeedsMessageEditor(t *testing.T) {
+	scenarios := []struct {
+		testName string
+		files    map[string]string
+		expected bool
+	}{
+		{
+			testName: "not rebasing",
+			files:    map[string]string{},
+			expected: false,
+		},
+		{
+			testName: "stopped at conflicts in a pick, with a reword still to come",
+			files: map[string]string{
+				"rebase-merge/done":            "pick 111111 commit1\n",
+				"rebase-merge/git-rebase-todo": "pick 222222 commit2\nreword 333333 commit3\n",
+			},
+			expected: true,
+		},
+		{
+			testName: "stopped at conflicts in a reword",
+			files: map[string]string{
+				"rebase-merge/done":            "pick 111111 commit1\nreword 222222 commit2\n",
+				"rebase-merge/git-rebase-todo": "pick 333333 commit3\n",
+			},
+			expected: true,
+		},
+		{
+			testName: "only picks and fixups left",
+			files: map[string]string{
+				"rebase-merge/done":            "reword 111111 commit1\npick 222222 commit2\n",
+				"rebase-merge/git-rebase-todo": "pick 333333 commit3\nfixup 444444 commit4\n",
+			},
+			expected: false,
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			repoDir := t.TempDir()
+			for name, content := range s.files {
+				path := filepath.Join(repoDir, ".git", name)
+				assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+				assert.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+			}
+
+			instance := buildRebaseCommands(commonDeps{repoPaths: MockRepoPaths(repoDir)})
+			needsEditor, err := instance.ContinueNeedsMessageEditor()
+			assert.NoError(t, err)
+			assert.Equal(t, s.expected, needsEditor)
+		})
+	}
+}
+
+func TestRebaseContinueRebaseWithEditorForRewords(t *testing.T) {
+	scenarios := []struct {
+		testName                 string
+		output                   string
+		subprocessErr            error
+		expectedErr              string
+		expectedContinuationRuns bool
+		// The editor failed for this commit when the rebase got to it, so git
+		// kept the old message and stopped there
+		givenUpOn string
+	}{
+		{
+			testName:                 "rebase completes",
+			expectedContinuationRuns: true,
+		},
+		{
+			testName:                 "editor is opened again for the commit it failed for",
+			givenUpOn:                "222222",
+			expectedContinuationRuns: true,
+		},
+		{
+			testName:      "rebase stops at conflicts",
+			output:        "CONFLICT (content): Merge conflict in file.txt\nerror: could not apply 123456... commit\n",
+			subprocessErr: errors.New("exit status 1"),
+			expectedErr:   "CONFLICT (content): Merge conflict in file.txt\nerror: could not apply 123456... commit\n",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			repoDir := t.TempDir()
+			assert.NoError(t, os.MkdirAll(filepath.Join(repoDir, ".git"), 0o755))
+			runner := oscommands.NewFakeRunner(t)
+			if s.givenUpOn != "" {
+				donePath := filepath.Join(repoDir, ".git", "rebase-merge", "done")
+				assert.NoError(t, os.MkdirAll(filepath.Dir(donePath), 0o755))
+				assert.NoError(t, os.WriteFile(donePath, []byte("reword "+s.givenUpOn+" commit2\n"), 0o644))
+				for _, ref := range []string{s.givenUpOn, "HEAD"} {
+					runner.ExpectGitArgs([]string{"log", "-1", "--format=%an%n%ae%n%ad%n%B", "--date=raw", ref}, "Author\nauthor@example.com\n1700000000 +0000\ncommit2\n", nil)
+				}
+			}
+			runner.ExpectGitArgs([]string{"var", "GIT_EDITOR"}, "vim\n", nil)
+			instance := buildRebaseCommands(commonDeps{runner: runner, repoPaths: MockRepoPaths(repoDir)})
+			if s.givenUpOn != "" {
+				instance.rewordQueue = []string{s.givenUpOn}
+			}
+
+			continuationRan := false
+			instance.onSuccessfulContinue = func() error {
+				continuationRan = true
+				return nil
+			}
+
+			subprocesses := []string{}
+			err := instance.ContinueRebaseWithEditorForRewords(func(cmdObj oscommands.ICmdObj) (string, error) {
+				subprocesses = append(subprocesses, cmdObj.ToString())
+				if cmdObj.Args()[1] == "commit" {
+					return "", nil
+				}
+				assert.Equal(t, append(rebaseHooksArgs(repoDir), "rebase", "--continue"), cmdObj.Args()[1:])
+				envVars := cmdObj.GetEnvVars()
+				assert.Contains(t, envVars, daemon.DaemonKindEnvKey+"="+strconv.Itoa(int(daemon.DaemonKindEditorForRewords)))
+				assert.Contains(t, envVars, daemon.DaemonInstructionEnvKey+`={"Editor":"vim"}`)
+				return s.output, s.subprocessErr
+			})
+			if s.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, s.expectedErr)
+			}
+			assert.Equal(t, s.expectedContinuationRuns, continuationRan)
+			// the user gets to reword the commit before the rebase moves on
+			assert.Equal(t, s.givenUpOn != "", len(subprocesses) == 2 && subprocesses[0] == "git commit --amend --only --allow-empty")
+			runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestRebaseContinueAfterExecCherryPickConflict(t *testing.T) {
+	scenarios := []struct {
+		testName     string
+		done         string
+		expectCommit bool
+	}{
+		{
+			testName:     "cherry-pick of a merge commit by lazygit's exec todo",
+			done:         "pick 111111 commit1\nexec git cherry-pick -m 1 333333\n",
+			expectCommit: true,
+		},
+		{
+			testName:     "exec todo that cherry-picked a different commit",
+			done:         "exec git cherry-pick -m 1 444444\n",
+			expectCommit: false,
+		},
+		{
+			testName:     "cherry-pick that the user ran while stopped at a pick",
+			done:         "exec git cherry-pick -m 1 333333\npick 111111 commit1\n",
+			expectCommit: false,
+		},
+		{
+			testName:     "user's own exec todo",
+			done:         "exec git cherry-pick 333333\n",
+			expectCommit: false,
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			repoDir := t.TempDir()
+			assert.NoError(t, os.MkdirAll(filepath.Join(repoDir, ".git", "rebase-merge"), 0o755))
+			assert.NoError(t, os.WriteFile(filepath.Join(repoDir, ".git", "rebase-merge", "done"), []byte(s.done), 0o644))
+			assert.NoError(t, os.WriteFile(filepath.Join(repoDir, ".git", "CHERRY_PICK_HEAD"), []byte("333333\n"), 0o644))
+
+			runner := oscommands.NewFakeRunner(t)
+			if s.expectCommit {
+				runner.ExpectGitArgs([]string{"commit", "--no-edit"}, "", nil)
+			}
+			runner.ExpectGitArgs(append(rebaseHooksArgs(repoDir), "rebase", "--continue"), "", nil)
+			instance := buildRebaseCommands(commonDeps{runner: runner, repoPaths: MockRepoPaths(repoDir)})
+
+			assert.NoError(t, instance.GenericMergeOrRebaseAction("rebase", "continue"))
+			runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestRebaseUntilFirstFailure(t *testing.T) {
+	scenarios := []struct {
+		testName    string
+		rebaseErr   error
+		doneFile    string
+		runner      func(*oscommands.FakeCmdObjRunner) *oscommands.FakeCmdObjRunner
+		expectedSha string
+		expectedErr string
+		// what's kept for FailedExec
+		expectedFailedExecLog string
+	}{
+		{
+			testName:    "every commit passes the check",
+			rebaseErr:   nil,
+			runner:      func(r *oscommands.FakeCmdObjRunner) *oscommands.FakeCmdObjRunner { return r },
+			expectedSha: "",
+		},
+		{
+			testName:  "third commit fails the check",
+			rebaseErr: errors.New("Executing: make check\nok\nExecuting: make check\nok\nExecuting: make check\nFAIL: TestFoo\nwarning: execution failed: make check\n"),
+			doneFile: "label onto\nreset onto\n" +
+				"pick 111111 commit1\nexec make check\n" +
+				"pick 222222 commit2\nexec make check\n" +
+				"pick 333333 commit3\nexec make check\n",
+			runner: func(r *oscommands.FakeCmdObjRunner) *oscommands.FakeCmdObjRunner {
+				return r.ExpectGitArgs([]string{"rev-parse", "HEAD"}, "abcdef\n", nil)
+			},
+			expectedSha:           "abcdef",
+			expectedFailedExecLog: "8\nFAIL: TestFoo",
+		},
+		{
+			testName:    "rebase stops at conflicts",
+			rebaseErr:   errors.New("could not apply 222222... commit2"),
+			doneFile:    "pick 111111 commit1\nexec make check\npick 222222 commit2\n",
+			runner:      func(r *oscommands.FakeCmdObjRunner) *oscommands.FakeCmdObjRunner { return r },
+			expectedErr: "could not apply 222222... commit2",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			repoDir := t.TempDir()
+			if s.doneFile != "" {
+				path := filepath.Join(repoDir, ".git", "rebase-merge", "done")
+				assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+				assert.NoError(t, os.WriteFile(path, []byte(s.doneFile), 0o644))
+			}
+
+			runner := s.runner(oscommands.NewFakeRunner(t).
+				ExpectFunc("rebase with an exec after each commit", func(cmdObj oscommands.ICmdObj) bool {
+					args := cmdObj.Args()
+					_, execIndex, found := lo.FindIndexOf(args, func(arg string) bool { return arg == "--exec" })
+					return found && args[execIndex+1] == "make check" && args[len(args)-1] == "master"
+				}, "", s.rebaseErr))
+			instance := buildRebaseCommands(commonDeps{runner: runner, repoPaths: MockRepoPaths(repoDir)})
+
+			sha, err := instance.RebaseUntilFirstFailure("master", "make check")
+			if s.expectedErr != "" {
+				assert.EqualError(t, err, s.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, s.expectedSha, sha)
+			runner.CheckForMissingCalls()
+
+			failedExecLog, err := os.ReadFile(filepath.Join(repoDir, ".git", "rebase-merge", failedExecFile))
+			if s.expectedFailedExecLog == "" {
+				assert.True(t, os.IsNotExist(err))
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, s.expectedFailedExecLog, string(failedExecLog))
+			}
+		})
+	}
+}
+
+func TestRebaseEditCommitForRestage(t *testing.T) {
+	commits := []*models.Commit{
+		{Name: "commit3", Sha: "333333", Parents: []string{"222222"}},
+		{Name: "commit2", Sha: "222222", Parents: []string{"111111"}},
+		{Name: "commit1", Sha: "111111"},
+	}
+
+	repoDir := t.TempDir()
+	runner := oscommands.NewFakeRunner(t).
+		ExpectFunc("rebase stopping at the commit", func(cmdObj oscommands.ICmdObj) bool {
+			// git creates the rebase-merge directory when it starts the rebase
+			_ = os.MkdirAll(filepath.Join(repoDir, ".git", "rebase-merge"), 0o755)
+			return cmdObj.Args()[len(cmdObj.Args())-1] == "111111"
+		}, "", nil).
+		ExpectGitArgs([]string{"reset", "--soft", "HEAD~1"}, "", nil)
+	instance := buildRebaseCommands(commonDeps{runner: runner, repoPaths: MockRepoPaths(repoDir)})
+
+	assert.NoError(t, instance.EditCommitForRestage(commits, 1))
+	runner.CheckForMissingCalls()
+
+	content, err := os.ReadFile(filepath.Join(repoDir, ".git", "rebase-merge", restageMarkerFile))
+	assert.NoError(t, err)
+	assert.Equal(t, "222222", string(content))
+
+	assert.EqualError(t, instance.EditCommitForRestage(commits, 2), "cannot restage the initial commit")
+}
+
+func TestRebaseContinueAfterRestage(t *testing.T) {
+	scenarios := []struct {
+		testName string
+		// gets the args of the continue that we run
+		runner func(continueArgs []string) *oscommands.FakeCmdObjRunner
+	}{
+		{
+			testName: "part of the commit is still staged",
+			runner: func(continueArgs []string) *oscommands.FakeCmdObjRunner {
+				return oscommands.NewFakeRunner(t).
+					ExpectGitArgs([]string{"diff", "--cached", "--name-only"}, "file2\n", nil).
+					ExpectGitArgs([]string{"commit", "-C", "222222"}, "", nil).
+					ExpectGitArgs(continueArgs, "", nil)
+			},
+		},
+		{
+			testName: "the user committed everything themselves",
+			runner: func(continueArgs []string) *oscommands.FakeCmdObjRunner {
+				return oscommands.NewFakeRunner(t).
+					ExpectGitArgs([]string{"diff", "--cached", "--name-only"}, "", nil).
+					ExpectGitArgs(continueArgs, "", nil)
+			},
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			repoDir := t.TempDir()
+			markerPath := filepath.Join(repoDir, ".git", "rebase-merge", restageMarkerFile)
+			assert.NoError(t, os.MkdirAll(filepath.Dir(markerPath), 0o755))
+			assert.NoError(t, os.WriteFile(markerPath, []byte("222222"), 0o644))
+
+			runner := s.runner(append(rebaseHooksArgs(repoDir), "rebase", "--continue"))
+			instance := buildRebaseCommands(commonDeps{runner: runner, repoPaths: MockRepoPaths(repoDir)})
+			assert.NoError(t, instance.GenericMergeOrRebaseAction("rebase", "continue"))
+			runner.CheckForMissingCalls()
+
+			// a later stop of the same rebase mustn't commit again
+			_, err := os.Stat(markerPath)
+			assert.True(t, os.IsNotExist(err))
+		})
+	}
+}
+
+func TestRebasePausedRebaseCommits(t *testing.T) {
+	repoDir := t.TempDir()
+	files := map[string]string{
+		"onto": "000000\n",
+		// commit1 and commit2 were squashed into aaaaaa, commit3 became bbbbbb
+		"rewritten-list": "111111 aaaaaa\n222222 aaaaaa\n333333 bbbbbb\n",
+		"done": "pick 111111 commit1\nsquash 222222 commit2\npick 333333 commit3\n" +
+			"pick 444444 commit4\nedit 555555 commit5\n",
+		// stopped at commit5 for editing, which git hasn't recorded yet
+		"amend":           "cccccc\n",
+		"stopped-sha":     "555555\n",
+		"git-rebase-todo": "# a comment\npick 666666 commit6\nfixup 777777 commit7\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(repoDir, ".git", "rebase-merge", name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+
+	runner := oscommands.NewFakeRunner(t).
+		ExpectGitArgs([]string{"log", "--first-parent", "--format=%H%x00%P%x00%s", "000000..HEAD"},
+			"dddddd\x00cccccc\x00made while stopped\n"+
+				"cccccc\x00444444\x00commit5\n"+
+				// commit4 could be fast-forwarded, so it kept its sha
+				"444444\x00bbbbbb\x00commit4\n"+
+				"bbbbbb\x00aaaaaa\x00commit3\n"+
+				"aaaaaa\x00000000\x00commit1\n", nil)
+	instance := buildRebaseCommands(commonDeps{runner: runner, repoPaths: MockRepoPaths(repoDir)})
+
+	commits, err := instance.PausedRebaseCommits()
+	assert.NoError(t, err)
+	runner.CheckForMissingCalls()
+
+	type appliedCommit struct {
+		sha          string
+		name         string
+		originalShas []string
+	}
+	assert.Equal(t,
+		[]appliedCommit{
+			{"dddddd", "made while stopped", nil},
+			{"cccccc", "commit5", []string{"555555"}},
+			{"444444", "commit4", []string{"444444"}},
+			{"bbbbbb", "commit3", []string{"333333"}},
+			{"aaaaaa", "commit1", []string{"111111", "222222"}},
+		},
+		lo.Map(commits.Applied, func(c *AppliedRebaseCommit, _ int) appliedCommit {
+			return appliedCommit{c.Sha, c.Name, c.OriginalShas}
+		}))
+	assert.Equal(t, []string{"cccccc"}, commits.Applied[0].Parents)
+	assert.Equal(t,
+		[]todo.Todo{
+			{Command: todo.Pick, Commit: "666666", Msg: "commit6"},
+			{Command: todo.Fixup, Commit: "777777", Msg: "commit7"},
+		},
+		commits.Pending)
+}
+
+func TestRebasePausedRebaseCommitsNotRebasing(t *testing.T) {
+	instance := buildRebaseCommands(commonDeps{repoPaths: MockRepoPaths(t.TempDir())})
+
+	commits, err := instance.PausedRebaseCommits()
+	assert.NoError(t, err)
+	assert.Nil(t, commits)
+}
+
+func TestRebaseInsertMergeDuringRebase(t *testing.T) {
+	type scenario struct {
+		testName     string
+		label        string
+		mergeRef     string
+		runner       *oscommands.FakeCmdObjRunner
+		expectedTodo string
+		expectedErr  string
+	}
+
+	scenarios := []scenario{
+		{
+			testName: "flattened commits are merged into the commit they were based on",
+			label:    "feature",
+			mergeRef: "aaaaaa",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"rev-parse", "--verify", "refs/rewritten/aaaaaa^{commit}"}, "", errors.New("fatal: Needed a single revision")).
+				ExpectGitArgs([]string{"rev-parse", "--verify", "aaaaaa^{commit}"}, "aaaaaa\n", nil),
+			expectedTodo: "label feature\nreset aaaaaa\nmerge feature\npick dddddd D\n",
+		},
+		{
+			testName: "merge onto a label from earlier in the rebase",
+			label:    "feature",
+			mergeRef: "onto",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"rev-parse", "--verify", "refs/rewritten/onto^{commit}"}, "bbbbbb\n", nil),
+			expectedTodo: "label feature\nreset onto\nmerge feature\npick dddddd D\n",
+		},
+		{
+			testName: "unknown ref",
+			label:    "feature",
+			mergeRef: "nope",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"rev-parse", "--verify", "refs/rewritten/nope^{commit}"}, "", errors.New("fatal: Needed a single revision")).
+				ExpectGitArgs([]string{"rev-parse", "--verify", "nope^{commit}"}, "", errors.New("fatal: Needed a single revision")),
+			expectedTodo: "pick dddddd D\n",
+			expectedErr:  "unknown ref 'nope'",
+		},
+		{
+			testName:     "invalid label",
+			label:        "my feature",
+			mergeRef:     "aaaaaa",
+			runner:       oscommands.NewFakeRunner(t),
+			expectedTodo: "pick dddddd D\n",
+			expectedErr:  "invalid label 'my feature'",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			repoDir := t.TempDir()
+			todoPath := filepath.Join(repoDir, ".git", "rebase-merge", "git-rebase-todo")
+			assert.NoError(t, os.MkdirAll(filepath.Dir(todoPath), 0o755))
+			assert.NoError(t, os.WriteFile(todoPath, []byte("pick dddddd D\n"), 0o644))
+
+			instance := buildRebaseCommands(commonDeps{runner: s.runner, repoPaths: MockRepoPaths(repoDir)})
+
+			err := instance.InsertMergeDuringRebase(s.label, s.mergeRef)
+			if s.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, s.expectedErr)
+			}
+			todo, err := os.ReadFile(todoPath)
+			assert.NoError(t, err)
+			assert.Equal(t, s.expectedTodo, string(todo))
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestTodoLinesToStringWithMerges(t *testing.T) {
+	merge := &models.Commit{Name: "Merge branch 'feature'", Sha: "333333"}
+	todoLines := []daemon.TodoLine{
+		{Action: "merge", Commit: merge, Label: "feature"},
+		{Action: "reset", Label: "onto"},
+		{Action: "label", Label: "feature"},
+		{Action: "pick", Commit: &models.Commit{Name: "commit", Sha: "222222"}},
+	}
+
+	assert.Equal(t,
+		"pick 222222 commit\nlabel feature\nreset onto\nmerge -C 333333 feature # Merge branch 'feature'\n",
+		daemon.TodoLinesToString(todoLines))
+}
+
+func TestRebaseDropPausedCommit(t *testing.T) {
+	type scenario struct {
+		testName string
+		done     string
+		amend    string
+		// gets the args of the continue that we run
+		runner      func(continueArgs []string) *oscommands.FakeCmdObjRunner
+		expectedErr string
+	}
+
+	scenarios := []scenario{
+		{
+			testName: "paused commit is dropped and the rebase continues",
+			done:     "pick aaaaaa A\nedit bbbbbb B\n",
+			amend:    "bbbbbb\n",
+			runner: func(continueArgs []string) *oscommands.FakeCmdObjRunner {
+				return oscommands.NewFakeRunner(t).
+					ExpectGitArgs([]string{"rev-parse", "--verify", "HEAD"}, "bbbbbb\n", nil).
+					ExpectGitArgs([]string{"status", "--porcelain"}, "?? untracked.txt\n", nil).
+					ExpectGitArgs([]string{"rev-parse", "--verify", "HEAD~1^{commit}"}, "aaaaaa\n", nil).
+					ExpectGitArgs([]string{"reset", "--hard", "HEAD~1"}, "", nil).
+					ExpectGitArgs(continueArgs, "", nil)
+			},
+		},
+		{
+			testName: "paused commit was rebuilt because an earlier commit changed",
+			done:     "drop aaaaaa A\nedit bbbbbb B\n",
+			amend:    "dddddd\n",
+			runner: func(continueArgs []string) *oscommands.FakeCmdObjRunner {
+				return oscommands.NewFakeRunner(t).
+					ExpectGitArgs([]string{"rev-parse", "--verify", "HEAD"}, "dddddd\n", nil).
+					ExpectGitArgs([]string{"status", "--porcelain"}, "", nil).
+					ExpectGitArgs([]string{"rev-parse", "--verify", "HEAD~1^{commit}"}, "eeeeee\n", nil).
+					ExpectGitArgs([]string{"reset", "--hard", "HEAD~1"}, "", nil).
+					ExpectGitArgs(continueArgs, "", nil)
+			},
+		},
+		{
+			testName: "not stopped at an edit",
+			done:     "pick aaaaaa A\nbreak\n",
+			runner: func(continueArgs []string) *oscommands.FakeCmdObjRunner {
+				return oscommands.NewFakeRunner(t)
+			},
+			expectedErr: "the rebase is not stopped at a commit for editing",
+		},
+		{
+			testName: "paused commit was amended",
+			done:     "pick aaaaaa A\nedit bbbbbb B\n",
+			amend:    "bbbbbb\n",
+			runner: func(continueArgs []string) *oscommands.FakeCmdObjRunner {
+				return oscommands.NewFakeRunner(t).
+					ExpectGitArgs([]string{"rev-parse", "--verify", "HEAD"}, "cccccc\n", nil)
+			},
+			expectedErr: "HEAD has moved since the rebase stopped at commit bbbbbb",
+		},
+		{
+			testName: "uncommitted changes would be lost",
+			done:     "pick aaaaaa A\nedit bbbbbb B\n",
+			amend:    "bbbbbb\n",
+			runner: func(continueArgs []string) *oscommands.FakeCmdObjRunner {
+				return oscommands.NewFakeRunner(t).
+					ExpectGitArgs([]string{"rev-parse", "--verify", "HEAD"}, "bbbbbb\n", nil).
+					ExpectGitArgs([]string{"status", "--porcelain"}, " M file.txt\n", nil)
+			},
+			expectedErr: "cannot drop the commit while there are uncommitted changes, as they would be lost",
+		},
+		{
+			testName: "paused at the initial commit",
+			done:     "edit aaaaaa A\n",
+			amend:    "aaaaaa\n",
+			runner: func(continueArgs []string) *oscommands.FakeCmdObjRunner {
+				return oscommands.NewFakeRunner(t).
+					ExpectGitArgs([]string{"rev-parse", "--verify", "HEAD"}, "aaaaaa\n", nil).
+					ExpectGitArgs([]string{"status", "--porcelain"}, "", nil).
+					ExpectGitArgs([]string{"rev-parse", "--verify", "HEAD~1^{commit}"}, "", errors.New("fatal: Needed a single revision"))
+			},
+			expectedErr: "cannot drop the initial commit this way",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			repoDir := t.TempDir()
+			donePath := filepath.Join(repoDir, ".git", "rebase-merge", "done")
+			assert.NoError(t, os.MkdirAll(filepath.Dir(donePath), 0o755))
+			assert.NoError(t, os.WriteFile(donePath, []byte(s.done), 0o644))
+			if s.amend != "" {
+				assert.NoError(t, os.WriteFile(filepath.Join(filepath.Dir(donePath), "amend"), []byte(s.amend), 0o644))
+			}
+
+			runner := s.runner(append(rebaseHooksArgs(repoDir), "rebase", "--continue"))
+			instance := buildRebaseCommands(commonDeps{runner: runner, repoPaths: MockRepoPaths(repoDir)})
+
+			err := instance.DropPausedCommit()
+			if s.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, s.expectedErr)
+			}
+			runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestRebaseRewordPausedCommit(t *testing.T) {
+	type scenario struct {
+		testName string
+		done     string
+		// the commit that git wrote to the amend file when it stopped
+		amend  string
+		runner *oscommands.FakeCmdObjRunner
+		// what git is told about the paused commit, so that continuing amends
+		// staged changes into the reworded commit and keeps its message
+		expectedAmend   string
+		expectedMessage string
+		expectedErr     string
+	}
+
+	scenarios := []scenario{
+		{
+			testName: "paused commit is reworded",
+			done:     "pick 111111 first\nedit 222222 second\n",
+			amend:    "222222\n",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"rev-parse", "--verify", "HEAD"}, "222222\n", nil).
+				ExpectGitArgs([]string{"commit", "--allow-empty", "--amend", "--only", "--cleanup=whitespace", "-m", "second, reworded"}, "", nil).
+				ExpectGitArgs([]string{"rev-parse", "--verify", "HEAD"}, "333333\n", nil).
+				ExpectGitArgs([]string{"log", "--format=%B", "--max-count=1", "333333"}, "second, reworded\n", nil),
+			expectedAmend:   "333333\n",
+			expectedMessage: "second, reworded\n",
+		},
+		{
+			testName: "HEAD has moved since the rebase stopped",
+			done:     "edit 222222 second\n",
+			amend:    "222222\n",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"rev-parse", "--verify", "HEAD"}, "444444\n", nil),
+			expectedAmend: "222222\n",
+			expectedErr:   "HEAD has moved since the rebase stopped at commit 222222",
+		},
+		{
+			testName:    "rebase isn't stopped at an edit",
+			done:        "pick 111111 first\n",
+			runner:      oscommands.NewFakeRunner(t),
+			expectedErr: "the rebase is not stopped at a commit for editing",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			repoDir := t.TempDir()
+			rebaseMergeDir := filepath.Join(repoDir, ".git", "rebase-merge")
+			assert.NoError(t, os.MkdirAll(rebaseMergeDir, 0o755))
+			assert.NoError(t, os.WriteFile(filepath.Join(rebaseMergeDir, "done"), []byte(s.done), 0o644))
+			if s.amend != "" {
+				assert.NoError(t, os.WriteFile(filepath.Join(rebaseMergeDir, "amend"), []byte(s.amend), 0o644))
+			}
+			assert.NoError(t, os.WriteFile(filepath.Join(rebaseMergeDir, "message"), []byte("second\n"), 0o644))
+			instance := buildRebaseCommands(commonDeps{runner: s.runner, repoPaths: MockRepoPaths(repoDir)})
+
+			err := instance.RewordPausedCommit("second, reworded")
+			if s.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, s.expectedErr)
+			}
+			s.runner.CheckForMissingCalls()
+
+			if s.expectedAmend != "" {
+				amend, err := os.ReadFile(filepath.Join(rebaseMergeDir, "amend"))
+				assert.NoError(t, err)
+				assert.Equal(t, s.expectedAmend, string(amend))
+			}
+			if s.expectedMessage != "" {
+				message, err := os.ReadFile(filepath.Join(rebaseMergeDir, "message"))
+				assert.NoError(t, err)
+				assert.Equal(t, s.expectedMessage, string(message))
+			}
+		})
+	}
+}
+
+func TestRebaseRebaseBranchInWorktree(t *testing.T) {
+	type scenario struct {
+		testName    string
+		runner      func(worktreePath string) *oscommands.FakeCmdObjRunner
+		expectedErr string
+	}
+
+	scenarios := []scenario{
+		{
+			testName: "branch is moved to the rebased commits",
+			runner: func(worktreePath string) *oscommands.FakeCmdObjRunner {
+				return oscommands.NewFakeRunner(t).
+					ExpectGitArgs([]string{"rev-parse", "--verify", "HEAD"}, "aaaaaa\n", nil).
+					ExpectGitArgs([]string{"worktree", "add", "--force", "--detach", worktreePath, "aaaaaa"}, "", nil).
+					ExpectGitArgs([]string{"-C", worktreePath, "rebase", "--interactive", "--autostash", "--keep-empty", "--no-autosquash", "--rebase-merges", "master"}, "", nil).
+					ExpectGitArgs([]string{"-C", worktreePath, "rev-parse", "--verify", "HEAD"}, "bbbbbb\n", nil).
+					ExpectGitArgs([]string{"worktree", "remove", "--force", worktreePath}, "", nil).
+					ExpectGitArgs([]string{"read-tree", "-m", "-u", "aaaaaa", "bbbbbb"}, "", nil).
+					ExpectGitArgs([]string{"update-ref", "-m", "rebase (finish): onto master", "HEAD", "bbbbbb", "aaaaaa"}, "", nil)
+			},
+		},
+		{
+			testName: "rebase stops at conflicts",
+			runner: func(worktreePath string) *oscommands.FakeCmdObjRunner {
+				return oscommands.NewFakeRunner(t).
+					ExpectGitArgs([]string{"rev-parse", "--verify", "HEAD"}, "aaaaaa\n", nil).
+					ExpectGitArgs([]string{"worktree", "add", "--force", "--detach", worktreePath, "aaaaaa"}, "", nil).
+					ExpectGitArgs([]string{"-C", worktreePath, "rebase", "--interactive", "--autostash", "--keep-empty", "--no-autosquash", "--rebase-merges", "master"}, "", errors.New("error: could not apply cccccc... commit")).
+					ExpectGitArgs([]string{"-C", worktreePath, "rebase", "--abort"}, "", nil).
+					ExpectGitArgs([]string{"worktree", "remove", "--force", worktreePath}, "", nil)
+			},
+			expectedErr: "The rebase stopped before it was done, so it was given up and your branch is unchanged. To resolve conflicts, rebase with git.rebase.useWorktree turned off\n\nerror: could not apply cccccc... commit",
+		},
+		{
+			testName: "uncommitted changes are in the way",
+			runner: func(worktreePath string) *oscommands.FakeCmdObjRunner {
+				return oscommands.NewFakeRunner(t).
+					ExpectGitArgs([]string{"rev-parse", "--verify", "HEAD"}, "aaaaaa\n", nil).
+					ExpectGitArgs([]string{"worktree", "add", "--force", "--detach", worktreePath, "aaaaaa"}, "", nil).
+					ExpectGitArgs([]string{"-C", worktreePath, "rebase", "--interactive", "--autostash", "--keep-empty", "--no-autosquash", "--rebase-merges", "master"}, "", nil).
+					ExpectGitArgs([]string{"-C", worktreePath, "rev-parse", "--verify", "HEAD"}, "bbbbbb\n", nil).
+					ExpectGitArgs([]string{"worktree", "remove", "--force", worktreePath}, "", nil).
+					ExpectGitArgs([]string{"read-tree", "-m", "-u", "aaaaaa", "bbbbbb"}, "", errors.New("error: Entry 'file' not uptodate. Cannot merge."))
+			},
+			expectedErr: "could not move the branch to the rebased commits (bbbbbb), probably because of uncommitted changes to files that the rebase changed: error: Entry 'file' not uptodate. Cannot merge.",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			repoDir := t.TempDir()
+			worktreePath := filepath.Join(repoDir, ".git", "lazygit-rebase-worktree")
+			runner := s.runner(worktreePath)
+			userConfig := config.GetDefaultConfig()
+			userConfig.Git.Rebase.UseWorktree = true
+			instance := buildRebaseCommands(commonDeps{runner: runner, userConfig: userConfig, gitVersion: &GitVersion{2, 26, 0, ""}, repoPaths: MockRepoPaths(repoDir)})
+
+			err := instance.RebaseBranch("master", nil)
+			if s.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, s.expectedErr)
+			}
+			runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestRebaseRebaseIgnoringWhitespace(t *testing.T) {
+	rebaseArgs := []string{"rebase", "--interactive", "--autostash", "--keep-empty", "--no-autosquash", "--rebase-merges", "--strategy-option=ignore-all-space", "master"}
+	conflictErr := errors.New("error: could not apply 123456... reindent")
+
+	type scenario struct {
+		testName    string
+		runner      *oscommands.FakeCmdObjRunner
+		expectedErr string
+	}
+
+	scenarios := []scenario{
+		{
+			testName: "no conflicts",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs(rebaseArgs, "", nil),
+		},
+		{
+			testName: "conflict caused purely by indentation is resolved",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs(rebaseArgs, "", conflictErr).
+				ExpectGitArgs([]string{"diff", "--name-only", "--diff-filter=U", "-z"}, "main.go\x00", nil).
+				ExpectGitArgs([]string{"diff", "-w", "--quiet", ":2:main.go", ":3:main.go"}, "", nil).
+				ExpectGitArgs([]string{"checkout", "--ours", "--", "main.go"}, "", nil).
+				ExpectGitArgs([]string{"add", "--", "main.go"}, "", nil).
+				ExpectGitArgs([]string{"rebase", "--continue"}, "", nil),
+		},
+		{
+			testName: "conflict that isn't only whitespace is left alone",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs(rebaseArgs, "", conflictErr).
+				ExpectGitArgs([]string{"diff", "--name-only", "--diff-filter=U", "-z"}, "main.go\x00util.go\x00", nil).
+				ExpectGitArgs([]string{"diff", "-w", "--quiet", ":2:main.go", ":3:main.go"}, "", nil).
+				ExpectGitArgs([]string{"diff", "-w", "--quiet", ":2:util.go", ":3:util.go"}, "", errors.New("exit status 1")),
+			expectedErr: "error: could not apply 123456... reindent",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			instance := buildRebaseCommands(commonDeps{runner: s.runner, gitVersion: &GitVersion{2, 26, 0, ""}, repoPaths: MockRepoPaths(t.TempDir())})
+
+			err := instance.RebaseIgnoringWhitespace("master")
+			if s.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, s.expectedErr)
+			}
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestRebaseRebaseBranchWithDates(t *testing.T) {
+	type scenario struct {
+		testName    string
+		gitVersion  *GitVersion
+		dates       RebaseDates
+		runner      *oscommands.FakeCmdObjRunner
+		expectedErr string
+	}
+
+	scenarios := []scenario{
+		{
+			testName:   "ignoring dates",
+			gitVersion: &GitVersion{2, 29, 0, ""},
+			dates:      RebaseDates{IgnoreDate: true},
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"rebase", "--interactive", "--autostash", "--keep-empty", "--no-autosquash", "--rebase-merges", "--ignore-date", "master"}, "", nil),
+		},
+		{
+			testName:   "using author dates as committer dates",
+			gitVersion: &GitVersion{2, 29, 0, ""},
+			dates:      RebaseDates{CommitterDateIsAuthorDate: true},
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"rebase", "--interactive", "--autostash", "--keep-empty", "--no-autosquash", "--rebase-merges", "--committer-date-is-author-date", "master"}, "", nil),
+		},
+		{
+			testName:    "both at once",
+			gitVersion:  &GitVersion{2, 29, 0, ""},
+			dates:       RebaseDates{IgnoreDate: true, CommitterDateIsAuthorDate: true},
+			runner:      oscommands.NewFakeRunner(t),
+			expectedErr: "cannot both ignore the author dates and use them as committer dates",
+		},
+		{
+			testName:    "git too old to ignore dates in an interactive rebase",
+			gitVersion:  &GitVersion{2, 28, 0, ""},
+			dates:       RebaseDates{IgnoreDate: true},
+			runner:      oscommands.NewFakeRunner(t),
+			expectedErr: "changing the dates of rebased commits requires git 2.29 or later",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			instance := buildRebaseCommands(commonDeps{runner: s.runner, gitVersion: s.gitVersion})
+
+			err := instance.RebaseBranchWithDates("master", s.dates)
+			if s.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, s.expectedErr)
+			}
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestRebasePreRebaseRef(t *testing.T) {
+	rebaseArgs := []string{"rebase", "--interactive", "--autostash", "--keep-empty", "--no-autosquash", "--rebase-merges", "master"}
+	checkedOutBranch := []string{"symbolic-ref", "--quiet", "--short", "HEAD"}
+	listRefs := []string{"for-each-ref", "--sort=-refname", "--format=%(refname)", "refs/lazygit/pre-rebase/feature/"}
+	isNewPreRebaseRef := func(cmdObj oscommands.ICmdObj) bool {
+		args := cmdObj.Args()
+		return len(args) == 7 &&
+			assert.ObjectsAreEqual([]string{"git", "update-ref", "-m", "lazygit: before rebase"}, args[:4]) &&
+			regexp.MustCompile(`^refs/lazygit/pre-rebase/feature/\d{10}\.\d{9}$`).MatchString(args[4]) &&
+			assert.ObjectsAreEqual([]string{"HEAD", ""}, args[5:])
+	}
+
+	type scenario struct {
+		testName      string
+		preRebaseRefs int
+		runner        *oscommands.FakeCmdObjRunner
+	}
+
+	scenarios := []scenario{
+		{
+			testName:      "ref is created and old ones are pruned",
+			preRebaseRefs: 2,
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs(checkedOutBranch, "feature\n", nil).
+				ExpectFunc("update-ref of a new pre-rebase ref", isNewPreRebaseRef, "", nil).
+				ExpectGitArgs(listRefs,
+					"refs/lazygit/pre-rebase/feature/1700000002.000000000\n"+
+						// belongs to a branch named feature/sub
+						"refs/lazygit/pre-rebase/feature/sub/1700000001\n"+
+						"refs/lazygit/pre-rebase/feature/1600000001\n"+
+						"refs/lazygit/pre-rebase/feature/1600000000\n", nil).
+				ExpectGitArgs([]string{"update-ref", "-d", "refs/lazygit/pre-rebase/feature/1600000000"}, "", nil).
+				ExpectGitArgs(rebaseArgs, "", nil),
+		},
+		{
+			testName:      "no ref when turned off",
+			preRebaseRefs: 0,
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs(rebaseArgs, "", nil),
+		},
+		{
+			testName:      "no ref with a detached HEAD",
+			preRebaseRefs: 2,
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs(checkedOutBranch, "", errors.New("exit status 1")).
+				ExpectGitArgs(rebaseArgs, "", nil),
+		},
+		{
+			testName:      "failing to create the ref doesn't stop the rebase",
+			preRebaseRefs: 2,
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs(checkedOutBranch, "feature\n", nil).
+				ExpectFunc("update-ref of a new pre-rebase ref", isNewPreRebaseRef, "", errors.New("fatal: cannot lock ref")).
+				ExpectGitArgs(rebaseArgs, "", nil),
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			userConfig := config.GetDefaultConfig()
+			userConfig.Git.Rebase.PreRebaseRefs = s.preRebaseRefs
+			instance := buildRebaseCommands(commonDeps{runner: s.runner, userConfig: userConfig, gitVersion: &GitVersion{2, 26, 0, ""}})
+
+			assert.NoError(t, instance.RebaseBranch("master", nil))
+			s.runner.CheckForMissingCalls()
+		})
+	}
+
+	t.Run("no ref for a command that isn't run", func(t *testing.T) {
+		runner := oscommands.NewFakeRunner(t)
+		userConfig := config.GetDefaultConfig()
+		userConfig.Git.Rebase.PreRebaseRefs = 2
+		instance := buildRebaseCommands(commonDeps{runner: runner, userConfig: userConfig, gitVersion: &GitVersion{2, 26, 0, ""}})
+
+		instance.PrepareInteractiveRebaseCommand(PrepareInteractiveRebaseCommandOpts{baseShaOrRoot: "master"})
+		runner.CheckForMissingCalls()
+	})
+}
+
+func TestRebaseUndoLastRebase(t *testing.T) {
+	checkedOutBranch := []string{"symbolic-ref", "--quiet", "--short", "HEAD"}
+	listRefs := []string{"for-each-ref", "--sort=-refname", "--format=%(refname)", "refs/lazygit/pre-rebase/feature/"}
+
+	type scenario struct {
+		testName    string
+		rebasing    bool
+		runner      *oscommands.FakeCmdObjRunner
+		expectedErr string
+	}
+
+	scenarios := []scenario{
+		{
+			testName: "branch is reset to the newest ref, which is deleted",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs(checkedOutBranch, "feature\n", nil).
+				ExpectGitArgs(listRefs, "refs/lazygit/pre-rebase/feature/1700000001.000000000\nrefs/lazygit/pre-rebase/feature/1700000000\n", nil).
+				ExpectGitArgs([]string{"reset", "--keep", "refs/lazygit/pre-rebase/feature/1700000001.000000000"}, "", nil).
+				ExpectGitArgs([]string{"update-ref", "-d", "refs/lazygit/pre-rebase/feature/1700000001.000000000"}, "", nil),
+		},
+		{
+			testName: "ref is kept if the reset fails",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs(checkedOutBranch, "feature\n", nil).
+				ExpectGitArgs(listRefs, "refs/lazygit/pre-rebase/feature/1700000000\n", nil).
+				ExpectGitArgs([]string{"reset", "--keep", "refs/lazygit/pre-rebase/feature/1700000000"}, "", errors.New("error: Entry 'file' not uptodate. Cannot merge.")),
+			expectedErr: "error: Entry 'file' not uptodate. Cannot merge.",
+		},
+		{
+			testName: "no refs left",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs(checkedOutBranch, "feature\n", nil).
+				ExpectGitArgs(listRefs, "refs/lazygit/pre-rebase/feature/sub/1700000000\n", nil),
+			expectedErr: "There's no ref marking where feature was before a rebase, so the rebase can't be undone (see git.rebase.preRebaseRefs)",
+		},
+		{
+			testName: "detached HEAD",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs(checkedOutBranch, "", errors.New("exit status 1")),
+			expectedErr: "A rebase can only be undone with a branch checked out",
+		},
+		{
+			testName:    "rebase in progress",
+			rebasing:    true,
+			runner:      oscommands.NewFakeRunner(t),
+			expectedErr: "A rebase can't be undone while another one is in progress",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			repoDir := t.TempDir()
+			if s.rebasing {
+				assert.NoError(t, os.MkdirAll(filepath.Join(repoDir, ".git", "rebase-merge"), 0o755))
+			}
+			instance := buildRebaseCommands(commonDeps{runner: s.runner, repoPaths: MockRepoPaths(repoDir)})
+
+			err := instance.UndoLastRebase()
+			if s.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, s.expectedErr)
+			}
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestRebaseFailedExecOutput(t *testing.T) {
+	type scenario struct {
+		testName       string
+		rebaseOutput   string
+		expectedOutput string
+		expectedFound  bool
+	}
+
+	scenarios := []scenario{
+		{
+			testName:      "no exec failed",
+			rebaseOutput:  "error: could not apply 123456... commit\nhint: Resolve all conflicts manually",
+			expectedFound: false,
+		},
+		{
+			testName: "only the output of the failed exec is kept",
+			rebaseOutput: "Rebasing (2/4)\r\x1b[KExecuting: make test\nok\nRebasing (4/4)\r\x1b[KExecuting: make test\nFAIL: TestFoo\nexit status 1\n" +
+				"warning: execution failed: make test\nYou can fix the problem, and then run\n\n  git rebase --continue\n",
+			expectedOutput: "FAIL: TestFoo\nexit status 1",
+			expectedFound:  true,
+		},
+		{
+			testName:       "exec that printed nothing",
+			rebaseOutput:   "Executing: false\nwarning: execution failed: false\n",
+			expectedOutput: "",
+			expectedFound:  true,
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			output, found := failedExecOutput(s.rebaseOutput)
+			assert.Equal(t, s.expectedOutput, output)
+			assert.Equal(t, s.expectedFound, found)
+		})
+	}
+}
+
+func TestRebaseFailedExec(t *testing.T) {
+	done := "pick 111111 first\nexec make test\n"
+
+	type scenario struct {
+		testName string
+		// what recordFailedExec wrote, if anything
+		failedExecLog string
+		runner        *oscommands.FakeCmdObjRunner
+		expected      *FailedRebaseExec
+	}
+
+	scenarios := []scenario{
+		{
+			testName: "rebase is paused at the failed exec",
+			// a new instance, as if lazygit had been restarted, still finds it
+			failedExecLog: "2\nFAIL: TestFoo\nexit status 1",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"rev-parse", "--verify", "HEAD"}, "abcdef\n", nil),
+			expected: &FailedRebaseExec{Command: "make test", Sha: "abcdef", Output: "FAIL: TestFoo\nexit status 1"},
+		},
+		{
+			testName:      "rebase has been continued since",
+			failedExecLog: "1\nFAIL: TestFoo",
+			runner:        oscommands.NewFakeRunner(t),
+			expected:      nil,
+		},
+		{
+			testName: "no exec has failed",
+			runner:   oscommands.NewFakeRunner(t),
+			expected: nil,
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			repoDir := t.TempDir()
+			rebaseMergeDir := filepath.Join(repoDir, ".git", "rebase-merge")
+			assert.NoError(t, os.MkdirAll(rebaseMergeDir, 0o755))
+			assert.NoError(t, os.WriteFile(filepath.Join(rebaseMergeDir, "done"), []byte(done), 0o644))
+			if s.failedExecLog != "" {
+				assert.NoError(t, os.WriteFile(filepath.Join(rebaseMergeDir, failedExecFile), []byte(s.failedExecLog), 0o644))
+			}
+			instance := buildRebaseCommands(commonDeps{runner: s.runner, repoPaths: MockRepoPaths(repoDir)})
+
+			failedExec, err := instance.FailedExec()
+			assert.NoError(t, err)
+			assert.Equal(t, s.expected, failedExec)
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestRebaseSquashCommitInto(t *testing.T) {
+	commits := []*models.Commit{
+		{Name: "commit3", Sha: "333333"},
+		{Name: "commit2", Sha: "222222"},
+		{Name: "commit1", Sha: "111111"},
+	}
+
+	type scenario struct {
+		testName    string
+		sourceIndex int
+		targetIndex int
+		action      todo.TodoCommand
+		// the todo changes that the rebase gets the daemon to make
+		expectedInstruction string
+		expectedErr         string
+	}
+
+	scenarios := []scenario{
+		{
+			testName:            "fixup into a commit further down",
+			sourceIndex:         0,
+			targetIndex:         1,
+			action:              todo.Fixup,
+			expectedInstruction: `{"Changes":[{"Sha":"333333","NewAction":5,"MoveAfterSha":"222222"}]}`,
+		},
+		{
+			testName:            "squash into a commit further down",
+			sourceIndex:         0,
+			targetIndex:         1,
+			action:              todo.Squash,
+			expectedInstruction: `{"Changes":[{"Sha":"333333","NewAction":6,"MoveAfterSha":"222222"}]}`,
+		},
+		{
+			testName:    "target is newer than source",
+			sourceIndex: 2,
+			targetIndex: 0,
+			action:      todo.Fixup,
+			expectedErr: "a commit can only be squashed into an older commit",
+		},
+		{
+			testName:    "target out of range",
+			sourceIndex: 0,
+			targetIndex: 3,
+			action:      todo.Fixup,
+			expectedErr: "index outside of range of commits",
+		},
+		{
+			testName:    "action other than squash or fixup",
+			sourceIndex: 0,
+			targetIndex: 2,
+			action:      todo.Drop,
+			expectedErr: "a commit can only be squashed or fixed up into another one",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			runner := oscommands.NewFakeRunner(t)
+			if s.expectedInstruction != "" {
+				runner.ExpectFunc("rebase that moves the commit onto the target", func(cmdObj oscommands.ICmdObj) bool {
+					return cmdObj.Args()[len(cmdObj.Args())-1] == "111111" &&
+						lo.Contains(cmdObj.GetEnvVars(), daemon.DaemonKindEnvKey+"="+strconv.Itoa(int(daemon.DaemonKindChangeTodoActions))) &&
+						lo.Contains(cmdObj.GetEnvVars(), daemon.DaemonInstructionEnvKey+"="+s.expectedInstruction)
+				}, "", nil)
+			}
+			instance := buildRebaseCommands(commonDeps{runner: runner})
+
+			err := instance.SquashCommitInto(commits, s.sourceIndex, s.targetIndex, s.action)
+			if s.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, s.expectedErr)
+			}
+			runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestRebaseResetAuthorToCommitter(t *testing.T) {
+	commits := []*models.Commit{
+		{Name: "commit2", Sha: "222222"},
+		{Name: "commit1", Sha: "111111"},
+	}
+	identArgs := []string{"var", "GIT_COMMITTER_IDENT"}
+	amendArgs := []string{"commit", "--allow-empty", "--only", "--no-edit", "--amend", "--author=CI <CI@example.com>"}
+
+	type scenario struct {
+		testName string
+		index    int
+		runner   *oscommands.FakeCmdObjRunner
+	}
+
+	scenarios := []scenario{
+		{
+			testName: "head commit is amended without a rebase",
+			index:    0,
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs(identArgs, "CI <CI@example.com> 1700000000 +0000\n", nil).
+				ExpectGitArgs(amendArgs, "", nil),
+		},
+		{
+			testName: "rebase stops at a commit further down to amend it",
+			index:    1,
+			runner: oscommands.NewFakeRunner(t).
+				ExpectFunc("rebase that stops at the commit", func(cmdObj oscommands.ICmdObj) bool {
+					return cmdObj.Args()[len(cmdObj.Args())-1] == "--root" &&
+						lo.Contains(cmdObj.GetEnvVars(), daemon.DaemonInstructionEnvKey+`={"Changes":[{"Sha":"111111","NewAction":3}]}`)
+				}, "", nil).
+				ExpectGitArgs(identArgs, "CI <CI@example.com> 1700000000 +0000\n", nil).
+				ExpectGitArgs(amendArgs, "", nil).
+				ExpectGitArgs([]string{"rebase", "--continue"}, "", nil),
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			instance := buildRebaseCommands(commonDeps{runner: s.runner})
+
+			assert.NoError(t, instance.ResetAuthorToCommitter(commits, s.index))
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestRebaseSquashByPrefix(t *testing.T) {
+	type scenario struct {
+		testName string
+		commits  []*models.Commit
+		// the todo changes that the rebase gets the daemon to make
+		expectedInstruction string
+		expectedBase        string
+		expectedErr         string
+	}
+
+	scenarios := []scenario{
+		{
+			testName: "each run of commits with the same prefix is squashed",
+			commits: []*models.Commit{
+				{Name: "[login] add f", Sha: "ffffff"},
+				{Name: "[api] add e", Sha: "eeeeee"},
+				{Name: "[api] add d", Sha: "dddddd"},
+				{Name: "add c", Sha: "cccccc"},
+				{Name: "[login] add b", Sha: "bbbbbb"},
+				{Name: "[login] add a", Sha: "aaaaaa"},
+				{Name: "base", Sha: "111111"},
+			},
+			expectedInstruction: `{"Changes":[{"Sha":"eeeeee","NewAction":6},{"Sha":"bbbbbb","NewAction":6}]}`,
+			expectedBase:        "111111",
+		},
+		{
+			testName: "merge commits end a run",
+			commits: []*models.Commit{
+				{Name: "[login] add c", Sha: "cccccc"},
+				{Name: "[login] merge", Sha: "bbbbbb", Parents: []string{"aaaaaa", "999999"}},
+				{Name: "[login] add a", Sha: "aaaaaa"},
+				{Name: "[login] base", Sha: "111111"},
+			},
+			expectedInstruction: `{"Changes":[{"Sha":"aaaaaa","NewAction":6}]}`,
+			expectedBase:        "--root",
+		},
+		{
+			testName: "no consecutive commits share a prefix",
+			commits: []*models.Commit{
+				{Name: "[login] commit3", Sha: "333333"},
+				{Name: "commit2", Sha: "222222"},
+				{Name: "[login] commit1", Sha: "111111"},
+			},
+			expectedErr: "no consecutive commits share a prefix",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			runner := oscommands.NewFakeRunner(t)
+			if s.expectedInstruction != "" {
+				runner.ExpectFunc("rebase that squashes the runs", func(cmdObj oscommands.ICmdObj) bool {
+					return cmdObj.Args()[len(cmdObj.Args())-1] == s.expectedBase &&
+						lo.Contains(cmdObj.GetEnvVars(), daemon.DaemonKindEnvKey+"="+strconv.Itoa(int(daemon.DaemonKindChangeTodoActions))) &&
+						lo.Contains(cmdObj.GetEnvVars(), daemon.DaemonInstructionEnvKey+"="+s.expectedInstruction)
+				}, "", nil)
+			}
+			instance := buildRebaseCommands(commonDeps{runner: runner})
+
+			err := instance.SquashByPrefix(s.commits, regexp.MustCompile(`^\[([^\]]+)\]`))
+			if s.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, s.expectedErr)
+			}
+			runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestRebaseTimeout(t *testing.T) {
+	type scenario struct {
+		testName       string
+		timeoutSeconds int
+		// whether git is still going when the timeout is up
+		hangs       bool
+		expectAbort bool
+		expectedErr string
+	}
+
+	scenarios := []scenario{
+		{
+			testName:       "rebase that hangs is given up",
+			timeoutSeconds: 1,
+			hangs:          true,
+			expectAbort:    true,
+			expectedErr:    "The rebase didn't finish within git.rebase.timeoutSeconds (1), so it was given up and your branch is unchanged",
+		},
+		{
+			testName:       "rebase that finishes in time",
+			timeoutSeconds: 1,
+		},
+		{
+			testName:       "no timeout",
+			timeoutSeconds: 0,
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			dir := t.TempDir()
+			gitDir := filepath.Join(dir, ".git")
+			assert.NoError(t, os.MkdirAll(gitDir, 0o755))
+
+			var rebaseErr error
+			if s.hangs {
+				rebaseErr = errors.New("signal: killed")
+			}
+			runner := oscommands.NewFakeRunner(t).
+				ExpectFunc("rebase", func(cmdObj oscommands.ICmdObj) bool {
+					if !lo.Contains(cmdObj.Args(), "--interactive") {
+						return false
+					}
+					ctx := cmdObj.GetContext()
+					if s.timeoutSeconds == 0 {
+						return ctx == nil
+					}
+					// git gets a context of its own, which is cancelled once
+					// the timeout is up
+					if ctx == nil || ctx.Done() == nil {
+						return false
+					}
+					if s.hangs {
+						assert.NoError(t, os.MkdirAll(filepath.Join(gitDir, "rebase-merge"), 0o755))
+						// git is killed
+						<-ctx.Done()
+					}
+					return true
+				}, "", rebaseErr)
+			if s.expectAbort {
+				runner.ExpectGitArgs([]string{"rebase", "--abort"}, "", nil)
+			}
+			userConfig := config.GetDefaultConfig()
+			userConfig.Git.Rebase.TimeoutSeconds = s.timeoutSeconds
+			instance := buildRebaseCommands(commonDeps{runner: runner, userConfig: userConfig, repoPaths: MockRepoPaths(dir)})
+
+			_, err := instance.RebaseUntilFirstFailure("master", "make test")
+			if s.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, s.expectedErr)
+			}
+			runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestRebaseInstallRebaseResultHook(t *testing.T) {
+	repoDir := t.TempDir()
+	userHooksDir := filepath.Join(repoDir, ".git", "hooks")
+	assert.NoError(t, os.MkdirAll(userHooksDir, 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(userHooksDir, "pre-commit"), []byte("#!/bin/sh\n"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(userHooksDir, "post-rewrite"), []byte("#!/bin/sh\n"), 0o755))
+	// git skips hooks that aren't executable, so we do too
+	assert.NoError(t, os.WriteFile(filepath.Join(userHooksDir, "commit-msg"), []byte("#!/bin/sh\n"), 0o644))
+	hooksDir := filepath.Join(repoDir, ".git", rebaseHooksDirName)
+	// left over from before the user removed the hook
+	assert.NoError(t, os.MkdirAll(hooksDir, 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(hooksDir, "pre-push"), []byte("#!/bin/sh\n"), 0o755))
+
+	instance := buildRebaseCommands(commonDeps{repoPaths: MockRepoPaths(repoDir)})
+	assert.Equal(t, hooksDir, instance.installRebaseResultHook())
+
+	entries, err := os.ReadDir(hooksDir)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{userPostRewriteHookName, "post-rewrite", "pre-commit"},
+		lo.Map(entries, func(entry os.DirEntry, _ int) string { return entry.Name() }))
+	readHook := func(name string) string {
+		content, err := os.ReadFile(filepath.Join(hooksDir, name))
+		assert.NoError(t, err)
+		return string(content)
+	}
+	assert.Equal(t, "#!/bin/sh\nexec '"+filepath.Join(userHooksDir, "pre-commit")+`' "$@"`+"\n", readHook("pre-commit"))
+	assert.Equal(t, "#!/bin/sh\nexec '"+filepath.Join(userHooksDir, "post-rewrite")+`' "$@"`+"\n", readHook(userPostRewriteHookName))
+	assert.Equal(t, rebaseResultPostRewriteHook, readHook("post-rewrite"))
+
+	t.Run("no git dir to set up the hooks in", func(t *testing.T) {
+		instance := buildRebaseCommands(commonDeps{repoPaths: MockRepoPaths(t.TempDir())})
+		assert.Equal(t, "", instance.installRebaseResultHook())
+	})
+}
+
+func TestRebaseRebasedCommitMapping(t *testing.T) {
+	// file2 reworded, file5 moved down to be fixed up into file3, and file4
+	// dropped; file1 was the base, and file6 is kept as it was
+	lastRebase := map[string]string{
+		"done": "pick 666666 add file6\nreword 222222 add file2\npick 333333 add file3\nfixup 555555 add file5\ndrop 444444 add file4\n",
+		"rewritten-list": "666666 666666\n" +
+			"222222 aaaaaa\n" +
+			"333333 bbbbbb\n" +
+			"555555 bbbbbb\n",
+	}
+	// stopped at a conflict after rewording file2
+	currentRebase := map[string]string{
+		"done":           "reword 222222 add file2\npick 333333 add file3\n",
+		"rewritten-list": "222222 cccccc\n",
+	}
+
+	type scenario struct {
+		testName string
+		// the files in .git/rebase-merge and .git/lazygit-last-rebase
+		currentRebase map[string]string
+		lastRebase    map[string]string
+		expected      map[string]string
+	}
+
+	scenarios := []scenario{
+		{
+			testName: "nothing to go by",
+			expected: nil,
+		},
+		{
+			testName:   "last rebase",
+			lastRebase: lastRebase,
+			expected: map[string]string{
+				"222222": "aaaaaa",
+				"333333": "bbbbbb",
+				"555555": "bbbbbb",
+				"444444": "",
+			},
+		},
+		{
+			testName:      "rebase in progress takes precedence",
+			currentRebase: currentRebase,
+			lastRebase:    lastRebase,
+			expected:      map[string]string{"222222": "cccccc"},
+		},
+		{
+			testName:      "rebase in progress that hasn't rewritten anything yet",
+			currentRebase: map[string]string{"done": "pick 333333 add file3\n"},
+			expected:      map[string]string{},
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			repoDir := t.TempDir()
+			writeFiles := func(dirName string, files map[string]string) {
+				if files == nil {
+					return
+				}
+				dir := filepath.Join(repoDir, ".git", dirName)
+				assert.NoError(t, os.MkdirAll(dir, 0o755))
+				for name, content := range files {
+					assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+				}
+			}
+			writeFiles("rebase-merge", s.currentRebase)
+			writeFiles(lastRebaseDirName, s.lastRebase)
+			instance := buildRebaseCommands(commonDeps{repoPaths: MockRepoPaths(repoDir)})
+
+			mapping, err := instance.RebasedCommitMapping()
+			assert.NoError(t, err)
+			assert.Equal(t, s.expected, mapping)
+		})
+	}
+}
+
+func TestRebaseRebaseBranchDroppingEmptied(t *testing.T) {
+	conflictErr := errors.New("error: could not apply 222222... fix")
+
+	type scenario struct {
+		testName   string
+		gitVersion *GitVersion
+		// gets the args that our rebase commands start with, and the
+		// rebase-merge dir, which the fake git removes when the rebase is done
+		runner      func(hooksArgs []string, rebaseMergeDir string) *oscommands.FakeCmdObjRunner
+		expectedErr string
+	}
+
+	// what git leaves behind when it stops at the pick of 222222 with nothing
+	// left to commit
+	stopAtPick := func(rebaseMergeDir string) {
+		assert.NoError(t, os.MkdirAll(rebaseMergeDir, 0o755))
+		assert.NoError(t, os.WriteFile(filepath.Join(rebaseMergeDir, "done"), []byte("pick 222222 fix\n"), 0o644))
+		assert.NoError(t, os.WriteFile(filepath.Join(rebaseMergeDir, "message"), []byte("fix\n"), 0o644))
+	}
+	// stagedFiles is what the index has that HEAD doesn't
+	rebaseStoppingAtPick := func(args []string, rebaseMergeDir string, stagedFiles string) *oscommands.FakeCmdObjRunner {
+		return oscommands.NewFakeRunner(t).
+			ExpectFunc("rebase that stops at a pick", func(cmdObj oscommands.ICmdObj) bool {
+				if !assert.ObjectsAreEqual(append([]string{"git"}, args...), cmdObj.Args()) {
+					return false
+				}
+				stopAtPick(rebaseMergeDir)
+				return true
+			}, "", conflictErr).
+			ExpectGitArgs([]string{"diff", "--cached", "--name-only", "HEAD"}, stagedFiles, nil)
+	}
+	olderRebaseArgs := func(hooksArgs []string) []string {
+		return append(hooksArgs, "rebase", "--interactive", "--autostash", "--keep-empty", "--no-autosquash", "--rebase-merges", "master")
+	}
+
+	scenarios := []scenario{
+		{
+			testName:   "git drops the emptied commits",
+			gitVersion: &GitVersion{2, 26, 0, ""},
+			runner: func(hooksArgs []string, rebaseMergeDir string) *oscommands.FakeCmdObjRunner {
+				return oscommands.NewFakeRunner(t).
+					ExpectGitArgs(append(hooksArgs, "rebase", "--interactive", "--autostash", "--keep-empty", "--empty=drop", "--no-autosquash", "--rebase-merges", "master"), "", nil)
+			},
+		},
+		{
+			testName:   "older git stops at an emptied commit, which we skip",
+			gitVersion: &GitVersion{2, 25, 0, ""},
+			runner: func(hooksArgs []string, rebaseMergeDir string) *oscommands.FakeCmdObjRunner {
+				return rebaseStoppingAtPick(olderRebaseArgs(hooksArgs), rebaseMergeDir, "").
+					// the commit itself changes something
+					ExpectGitArgs([]string{"diff-tree", "--quiet", "--root", "222222"}, "", errors.New("exit status 1")).
+					ExpectFunc("skip", func(cmdObj oscommands.ICmdObj) bool {
+						if !assert.ObjectsAreEqual(append(append([]string{"git"}, hooksArgs...), "rebase", "--skip"), cmdObj.Args()) {
+							return false
+						}
+						assert.NoError(t, os.RemoveAll(rebaseMergeDir))
+						return true
+					}, "", nil)
+			},
+		},
+		{
+			testName:   "commit that was empty to begin with is kept",
+			gitVersion: &GitVersion{2, 25, 0, ""},
+			runner: func(hooksArgs []string, rebaseMergeDir string) *oscommands.FakeCmdObjRunner {
+				return rebaseStoppingAtPick(olderRebaseArgs(hooksArgs), rebaseMergeDir, "").
+					ExpectGitArgs([]string{"diff-tree", "--quiet", "--root", "222222"}, "", nil)
+			},
+			expectedErr: conflictErr.Error(),
+		},
+		{
+			testName:   "conflicts are left to the user",
+			gitVersion: &GitVersion{2, 25, 0, ""},
+			runner: func(hooksArgs []string, rebaseMergeDir string) *oscommands.FakeCmdObjRunner {
+				return rebaseStoppingAtPick(olderRebaseArgs(hooksArgs), rebaseMergeDir, "fix\n")
+			},
+			expectedErr: conflictErr.Error(),
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			repoDir := t.TempDir()
+			assert.NoError(t, os.MkdirAll(filepath.Join(repoDir, ".git"), 0o755))
+			runner := s.runner(rebaseHooksArgs(repoDir), filepath.Join(repoDir, ".git", "rebase-merge"))
+			instance := buildRebaseCommands(commonDeps{runner: runner, gitVersion: s.gitVersion, repoPaths: MockRepoPaths(repoDir)})
+
+			err := instance.RebaseBranchDroppingEmptied("master")
+			if s.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, s.expectedErr)
+			}
+			runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestRebaseIsAncestor(t *testing.T) {
+	type scenario struct {
+		testName           string
+		runner             *oscommands.FakeCmdObjRunner
+		expectedIsAncestor bool
+		expectedErr        string
+	}
+
+	scenarios := []scenario{
+		{
+			testName: "ancestor",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"merge-base", "--is-ancestor", "abcdef", "HEAD"}, "", nil),
+			expectedIsAncestor: true,
+		},
+		{
+			testName: "not an ancestor",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"merge-base", "--is-ancestor", "abcdef", "HEAD"}, "", fakeExitError{exitCode: 1}),
+			expectedIsAncestor: false,
+		},
+		{
+			testName: "unknown ref",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"merge-base", "--is-ancestor", "abcdef", "HEAD"}, "", fakeExitError{exitCode: 128}),
+			expectedErr: "exit status",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			instance := buildRebaseCommands(commonDeps{runner: s.runner})
+
+			isAncestor, err := instance.IsAncestor("abcdef", "HEAD")
+			if s.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, s.expectedErr)
+			}
+			assert.Equal(t, s.expectedIsAncestor, isAncestor)
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestRebaseRebaseOntoBaseThatIsNotAnAncestor(t *testing.T) {
+	runner := oscommands.NewFakeRunner(t).
+		ExpectGitArgs([]string{"merge-base", "--is-ancestor", "unrelated", "HEAD"}, "", fakeExitError{exitCode: 1})
+	instance := buildRebaseCommands(commonDeps{runner: runner})
+
+	err := instance.RebaseOnto("master", "unrelated", EmptyCommitsKeep, nil)
+	assert.EqualError(t, err, "Commit unrelated isn't an ancestor of HEAD, so it can't be used as the base of a rebase")
+	runner.CheckForMissingCalls()
+}
+
+func TestRebaseCherryPickWithReword(t *testing.T) {
+	isCherryPick := func(cmdObj oscommands.ICmdObj) bool {
+		return cmdObj.Args()[len(cmdObj.Args())-1] == "HEAD" &&
+			lo.Contains(cmdObj.GetEnvVars(), daemon.DaemonKindEnvKey+"="+strconv.Itoa(int(daemon.DaemonKindCherryPick)))
+	}
+	rewordArgs := []string{"commit", "--allow-empty", "--amend", "--only", "--cleanup=whitespace", "-m", "change, reworded"}
+
+	type scenario struct {
+		testName string
+		// gets the rebase-merge dir, which the fake git creates when it stops
+		// and removes when the rebase is done
+		runner func(rebaseMergeDir string) *oscommands.FakeCmdObjRunner
+		// whether the cherry-pick stops at conflicts, which the user resolves
+		// before continuing
+		stops bool
+	}
+
+	lookUpCommit := func() *oscommands.FakeCmdObjRunner {
+		return oscommands.NewFakeRunner(t).
+			ExpectGitArgs([]string{"rev-parse", "--verify", "abc^{commit}"}, "abcdef\n", nil).
+			ExpectGitArgs([]string{"log", "--format=%s", "--max-count=1", "abcdef"}, "change\n", nil)
+	}
+
+	scenarios := []scenario{
+		{
+			testName: "clean cherry-pick",
+			runner: func(rebaseMergeDir string) *oscommands.FakeCmdObjRunner {
+				return lookUpCommit().
+					ExpectFunc("cherry-pick rebase", isCherryPick, "", nil).
+					ExpectGitArgs(rewordArgs, "", nil)
+			},
+		},
+		{
+			testName: "cherry-pick stops at conflicts",
+			stops:    true,
+			runner: func(rebaseMergeDir string) *oscommands.FakeCmdObjRunner {
+				return lookUpCommit().
+					ExpectFunc("cherry-pick rebase", func(cmdObj oscommands.ICmdObj) bool {
+						if !isCherryPick(cmdObj) {
+							return false
+						}
+						assert.NoError(t, os.MkdirAll(rebaseMergeDir, 0o755))
+						return true
+					}, "", errors.New("error: could not apply abcdef... change")).
+					ExpectFunc("continue", func(cmdObj oscommands.ICmdObj) bool {
+						if !lo.Contains(cmdObj.Args(), "--continue") {
+							return false
+						}
+						assert.NoError(t, os.RemoveAll(rebaseMergeDir))
+						return true
+					}, "", nil).
+					// the reword waits until the rebase has been continued
+					ExpectGitArgs(rewordArgs, "", nil)
+			},
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			repoDir := t.TempDir()
+			assert.NoError(t, os.MkdirAll(filepath.Join(repoDir, ".git"), 0o755))
+			runner := s.runner(filepath.Join(repoDir, ".git", "rebase-merge"))
+			instance := buildRebaseCommands(commonDeps{runner: runner, repoPaths: MockRepoPaths(repoDir)})
+
+			err := instance.CherryPickWithReword("abc", "change, reworded")
+			if s.stops {
+				assert.EqualError(t, err, "error: could not apply abcdef... change")
+				assert.NoError(t, instance.ContinueRebase())
+			} else {
+				assert.NoError(t, err)
+			}
+			runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestRebaseMoveCommitToTargetSha(t *testing.T) {
+	commits := []*models.Commit{
+		{Name: "commit4", Sha: "444444"},
+		{Name: "commit3", Sha: "333333"},
+		{Name: "commit2", Sha: "222222"},
+		{Name: "commit1", Sha: "111111"},
+	}
+
+	type scenario struct {
+		testName  string
+		movingSha string
+		targetSha string
+		placement string
+		// the todo changes that the rebase gets the daemon to make
+		expectedInstruction string
+		expectedBase        string
+		expectedErr         string
+	}
+
+	scenarios := []scenario{
+		{
+			testName:            "move an old commit after a newer one",
+			movingSha:           "111111",
+			targetSha:           "333333",
+			placement:           "after",
+			expectedInstruction: `{"Changes":[{"Sha":"111111","NewAction":1,"MoveAfterSha":"333333"}]}`,
+			expectedBase:        "--root",
+		},
+		{
+			testName:            "move a new commit before an older one",
+			movingSha:           "444444",
+			targetSha:           "222222",
+			placement:           "before",
+			expectedInstruction: `{"Changes":[{"Sha":"444444","NewAction":1,"MoveBeforeSha":"222222"}]}`,
+			expectedBase:        "111111",
+		},
+		{
+			testName:    "moving commit not in the slice",
+			movingSha:   "555555",
+			targetSha:   "111111",
+			placement:   "after",
+			expectedErr: "commit 555555 not found",
+		},
+		{
+			testName:    "target commit not in the slice",
+			movingSha:   "222222",
+			targetSha:   "555555",
+			placement:   "after",
+			expectedErr: "commit 555555 not found",
+		},
+		{
+			testName:    "same commit",
+			movingSha:   "222222",
+			targetSha:   "222222",
+			placement:   "before",
+			expectedErr: "a commit can't be moved relative to itself",
+		},
+		{
+			testName:    "invalid placement",
+			movingSha:   "222222",
+			targetSha:   "111111",
+			placement:   "above",
+			expectedErr: "invalid placement 'above'; must be 'before' or 'after'",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			runner := oscommands.NewFakeRunner(t)
+			if s.expectedInstruction != "" {
+				runner.ExpectFunc("rebase that moves the commit", func(cmdObj oscommands.ICmdObj) bool {
+					return cmdObj.Args()[len(cmdObj.Args())-1] == s.expectedBase &&
+						lo.Contains(cmdObj.GetEnvVars(), daemon.DaemonKindEnvKey+"="+strconv.Itoa(int(daemon.DaemonKindChangeTodoActions))) &&
+						lo.Contains(cmdObj.GetEnvVars(), daemon.DaemonInstructionEnvKey+"="+s.expectedInstruction)
+				}, "", nil)
+			}
+			instance := buildRebaseCommands(commonDeps{runner: runner})
+
+			err := instance.MoveCommitToTargetSha(commits, s.movingSha, s.targetSha, s.placement)
+			if s.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, s.expectedErr)
+			}
+			runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestRebaseRangeContainsMerges(t *testing.T) {
+	type scenario struct {
+		testName       string
+		runner         *oscommands.FakeCmdObjRunner
+		expectedMerges bool
+		expectedErr    string
+	}
+
+	scenarios := []scenario{
+		{
+			testName: "no merges",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"rev-list", "--merges", "--max-count=1", "abcdef..HEAD"}, "", nil),
+			expectedMerges: false,
+		},
+		{
+			testName: "merge in the range",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"rev-list", "--merges", "--max-count=1", "abcdef..HEAD"}, "123456\n", nil),
+			expectedMerges: true,
+		},
+		{
+			testName: "unknown base",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"rev-list", "--merges", "--max-count=1", "abcdef..HEAD"}, "", errors.New("fatal: bad revision 'abcdef..HEAD'")),
+			expectedErr: "fatal: bad revision 'abcdef..HEAD'",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			instance := buildRebaseCommands(commonDeps{runner: s.runner})
+
+			containsMerges, err := instance.RangeContainsMerges("abcdef")
+			if s.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, s.expectedErr)
+			}
+			assert.Equal(t, s.expectedMerges, containsMerges)
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestRebaseExportRebasePlan(t *testing.T) {
+	// commits whose changes upstream already has, and merges, are left out by
+	// git, like when it builds the todo of a rebase
+	runner := oscommands.NewFakeRunner(t).
+		ExpectGitArgs([]string{"log", "--reverse", "--topo-order", "--no-merges", "--right-only", "--cherry-pick", "--format=pick %H %s", "upstream...HEAD"},
+			"pick 111111 first\npick 222222 second\n", nil)
+	instance := buildRebaseCommands(commonDeps{runner: runner})
+
+	plan, err := instance.ExportRebasePlan("upstream")
+	assert.NoError(t, err)
+	assert.Equal(t, "pick 111111 first\npick 222222 second\n", plan)
+	runner.CheckForMissingCalls()
+}
+
+func TestRebaseContinueRebaseWithConflicts(t *testing.T) {
+	continueErr := errors.New("error: could not apply 222222... second\nhint: Resolve all conflicts manually")
+
+	type scenario struct {
+		testName         string
+		runner           *oscommands.FakeCmdObjRunner
+		expectedResult   RebaseResult
+		expectedConflict *RebaseConflict
+	}
+
+	scenarios := []scenario{
+		{
+			testName: "stopped at conflicts",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"rebase", "--continue"}, "", continueErr).
+				ExpectGitArgs([]string{"diff", "--name-only", "--diff-filter=U", "-z"}, "file\x00", nil).
+				ExpectGitArgs([]string{"diff", "--name-only", "--diff-filter=U", "-z"}, "file\x00", nil).
+				ExpectGitArgs([]string{"rev-parse", "--verify", "222222^{commit}"}, "2222222222\n", nil),
+			expectedResult:   RebaseResultConflicts,
+			expectedConflict: &RebaseConflict{Sha: "2222222222", Files: []string{"file"}},
+		},
+		{
+			testName: "stopped without conflicts",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"rebase", "--continue"}, "", continueErr).
+				ExpectGitArgs([]string{"diff", "--name-only", "--diff-filter=U", "-z"}, "", nil),
+			expectedResult:   RebaseResultStopped,
+			expectedConflict: nil,
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			instance := buildRebaseCommands(commonDeps{runner: s.runner})
+
+			result, conflict, err := instance.ContinueRebaseWithConflicts()
+			assert.Equal(t, continueErr, err)
+			assert.Equal(t, s.expectedResult, result)
+			assert.Equal(t, s.expectedConflict, conflict)
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestRebaseCouldNotApplySha(t *testing.T) {
+	type scenario struct {
+		testName    string
+		rebaseErr   error
+		done        string
+		runner      *oscommands.FakeCmdObjRunner
+		expectedSha string
+		expectedErr string
+	}
+
+	scenarios := []scenario{
+		{
+			testName:  "sha from git's output",
+			rebaseErr: errors.New("error: could not apply 123456... commit\nhint: Resolve all conflicts manually"),
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"rev-parse", "--verify", "123456^{commit}"}, "1234567890\n", nil),
+			expectedSha: "1234567890",
+		},
+		{
+			testName:  "translated output, so the sha comes from the done file",
+			rebaseErr: errors.New("erreur : impossible d'appliquer 123456... commit"),
+			done:      "pick abcdef commit\n",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"rev-parse", "--verify", "abcdef^{commit}"}, "abcdef1234\n", nil),
+			expectedSha: "abcdef1234",
+		},
+		{
+			testName:    "last done todo isn't for a commit",
+			rebaseErr:   errors.New("error: something else"),
+			done:        "pick abcdef commit\nexec make test\n",
+			runner:      oscommands.NewFakeRunner(t),
+			expectedErr: "the rebase isn't stopped at a commit",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			repoDir := t.TempDir()
+			assert.NoError(t, os.MkdirAll(filepath.Join(repoDir, ".git", "rebase-merge"), 0o755))
+			assert.NoError(t, os.WriteFile(filepath.Join(repoDir, ".git", "rebase-merge", "done"), []byte(s.done), 0o644))
+			instance := buildRebaseCommands(commonDeps{runner: s.runner, repoPaths: MockRepoPaths(repoDir)})
+
+			sha, err := instance.couldNotApplySha(s.rebaseErr)
+			if s.expectedErr == "" {
+				assert.NoError(t, err)
+				assert.Equal(t, s.expectedSha, sha)
+			} else {
+				assert.EqualError(t, err, s.expectedErr)
+			}
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestRebaseWithoutDaemonAbortsIfTodoCantBeEdited(t *testing.T) {
+	commits := []*models.Commit{
+		{Name: "commit3", Sha: "333333", Parents: []string{"222222"}},
+		{Name: "commit2", Sha: "222222", Parents: []string{"111111"}},
+		{Name: "commit1", Sha: "111111"},
+	}
+
+	// There's no todo file in the repo, because git doesn't really start a
+	// rebase here
+	runner := oscommands.NewFakeRunner(t).
+		ExpectGitArgs([]string{"rebase", "--interactive", "--autostash", "--keep-empty", "--no-autosquash", "111111"}, "", nil).
+		ExpectGitArgs([]string{"rebase", "--abort"}, "", nil)
+
+	userConfig := config.GetDefaultConfig()
+	userConfig.Git.Rebase.UseDaemon = false
+	instance := buildRebaseCommands(commonDeps{runner: runner, userConfig: userConfig, repoPaths: MockRepoPaths(t.TempDir())})
+
+	assert.Error(t, instance.InteractiveRebase(commits, 1, todo.Edit))
+	runner.CheckForMissingCalls()
+}
+
+func TestRebaseWithoutDaemonRefusesRewordWithMessage(t *testing.T) {
+	commits := []*models.Commit{
+		{Name: "second", Sha: "222222"},
+		{Name: "first", Sha: "111111"},
+	}
+
+	// Nothing is started, because git can't get the new message from lazygit
+	runner := oscommands.NewFakeRunner(t).
+		ExpectGitArgs([]string{"show", "-s", "--format=%H%x00%B", "222222", "111111"},
+			"222222\x00second\n\n111111\x00first\n", nil)
+
+	userConfig := config.GetDefaultConfig()
+	userConfig.Git.Rebase.UseDaemon = false
+	instance := buildRebaseCommands(commonDeps{runner: runner, userConfig: userConfig})
+
+	err := instance.RewordCommitsMatching(commits, regexp.MustCompile("second"), "2nd")
+	assert.EqualError(t, err, "Rewording a commit with this message needs lazygit to be git's editor, which git.rebase.useDaemon turns off")
+	runner.CheckForMissingCalls()
+}
+
+func TestRebaseRestoreOriginalMessage(t *testing.T) {
+	commits := []*models.Commit{
+		{Name: "regrettable", Sha: "aaaaaa", Parents: []string{"222222"}},
+		{Name: "second", Sha: "222222", Parents: []string{"111111"}},
+		{Name: "first", Sha: "111111"},
+	}
+	treeAndMessageArgs := func(ref string) []string {
+		return []string{"log", "-1", "--format=%T%n%B", ref}
+	}
+
+	type scenario struct {
+		testName string
+		index    int
+		// the files in .git/lazygit-last-rebase
+		lastRebase  map[string]string
+		runner      *oscommands.FakeCmdObjRunner
+		expectedErr string
+	}
+
+	scenarios := []scenario{
+		{
+			testName: "reworded head commit",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"reflog", "--format=%H"}, "aaaaaa\n333333\n222222\n", nil).
+				ExpectGitArgs(treeAndMessageArgs("aaaaaa"), "tree3\nregrettable\n\nnew body\n", nil).
+				ExpectGitArgs(treeAndMessageArgs("333333"), "tree3\nthird\n\nbody of third\n", nil).
+				ExpectGitArgs([]string{"log", "--format=%B", "--max-count=1", "333333"}, "third\n\nbody of third\n", nil).
+				ExpectGitArgs([]string{"commit", "--allow-empty", "--amend", "--only", "--cleanup=whitespace", "-m", "third", "-m", "body of third"}, "", nil),
+		},
+		{
+			testName: "commit reworded by the last rebase",
+			index:    1,
+			lastRebase: map[string]string{
+				"done":           "reword 444444 second\n",
+				"rewritten-list": "444444 222222\n",
+			},
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"reflog", "--format=%H"}, "aaaaaa\n222222\n", nil).
+				ExpectGitArgs(treeAndMessageArgs("222222"), "tree2\nregrettable\n", nil).
+				ExpectGitArgs(treeAndMessageArgs("444444"), "tree2\nsecond\n\nbody of second\n", nil).
+				ExpectGitArgs([]string{"log", "--format=%B", "--max-count=1", "444444"}, "second\n\nbody of second\n", nil).
+				ExpectFunc("rebase stopping at the commit", func(cmdObj oscommands.ICmdObj) bool {
+					return cmdObj.Args()[len(cmdObj.Args())-1] == "111111"
+				}, "", nil).
+				ExpectGitArgs([]string{"commit", "--allow-empty", "--amend", "--only", "--cleanup=whitespace", "-m", "second", "-m", "body of second"}, "", nil).
+				ExpectFunc("continue the rebase", func(cmdObj oscommands.ICmdObj) bool {
+					return strings.HasSuffix(cmdObj.ToString(), " rebase --continue")
+				}, "", nil),
+		},
+		{
+			testName: "commit below in the reflog has a different tree",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"reflog", "--format=%H"}, "aaaaaa\n222222\n", nil).
+				ExpectGitArgs(treeAndMessageArgs("aaaaaa"), "tree3\nregrettable\n", nil).
+				ExpectGitArgs(treeAndMessageArgs("222222"), "tree2\nsecond\n", nil),
+			expectedErr: "the original message of commit aaaaaa can't be found; the reflog may have expired",
+		},
+		{
+			testName: "reflog has expired",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"reflog", "--format=%H"}, "aaaaaa\n", nil).
+				ExpectGitArgs(treeAndMessageArgs("aaaaaa"), "tree3\nregrettable\n", nil),
+			expectedErr: "the original message of commit aaaaaa can't be found; the reflog may have expired",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			repoDir := t.TempDir()
+			if s.lastRebase != nil {
+				dir := filepath.Join(repoDir, ".git", lastRebaseDirName)
+				assert.NoError(t, os.MkdirAll(dir, 0o755))
+				for name, content := range s.lastRebase {
+					assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+				}
+			}
+			instance := buildRebaseCommands(commonDeps{runner: s.runner, repoPaths: MockRepoPaths(repoDir)})
+
+			err := instance.RestoreOriginalMessage(commits, s.index)
+			if s.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, s.expectedErr)
+			}
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestRebaseConflictStyle(t *testing.T) {
+	type scenario struct {
+		testName      string
+		conflictStyle string
+		gitVersion    *GitVersion
+		expectedArgs  []string
+	}
+
+	scenarios := []scenario{
+		{
+			testName:      "left to git's config",
+			conflictStyle: "",
+			gitVersion:    &GitVersion{2, 38, 0, ""},
+			expectedArgs:  []string{"rebase", "--interactive", "--autostash", "--keep-empty", "--no-autosquash", "--rebase-merges", "master"},
+		},
+		{
+			testName:      "zdiff3",
+			conflictStyle: "zdiff3",
+			gitVersion:    &GitVersion{2, 38, 0, ""},
+			expectedArgs:  []string{"-c", "merge.conflictStyle=zdiff3", "rebase", "--interactive", "--autostash", "--keep-empty", "--no-autosquash", "--rebase-merges", "master"},
+		},
+		{
+			testName:      "zdiff3 falls back to diff3 with older git",
+			conflictStyle: "zdiff3",
+			gitVersion:    &GitVersion{2, 34, 0, ""},
+			expectedArgs:  []string{"-c", "merge.conflictStyle=diff3", "rebase", "--interactive", "--autostash", "--keep-empty", "--no-autosquash", "--rebase-merges", "master"},
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			userConfig := config.GetDefaultConfig()
+			userConfig.Git.Rebase.ConflictStyle = s.conflictStyle
+			instance := buildRebaseCommands(commonDeps{gitVersion: s.gitVersion, userConfig: userConfig})
+
+			cmdObj := instance.PrepareInteractiveRebaseCommand(PrepareInteractiveRebaseCommandOpts{baseShaOrRoot: "master"})
+			assert.Equal(t, s.expectedArgs, cmdObj.Args()[1:])
+		})
+	}
+}
+
+func TestRebaseNoteRebuiltCommits(t *testing.T) {
+	// file2 reworded and file3 squashed into it; file1 was the base
+	lastRebase := map[string]string{
+		"done":           "reword 222222 add file2\nsquash 333333 add file3\npick 444444 add file4\n",
+		"rewritten-list": "222222 aaaaaa\n333333 aaaaaa\n444444 bbbbbb\n",
+	}
+	notesAddArgs := func(note string, sha string) []string {
+		return []string{"notes", "--ref=lazygit", "add", "--force", "--message", note, sha}
+	}
+
+	type scenario struct {
+		testName           string
+		noteRebuiltCommits bool
+		alreadyNoted       bool
+		runner             *oscommands.FakeCmdObjRunner
+		expectNoted        bool
+	}
+
+	scenarios := []scenario{
+		{
+			testName:           "rebuilt commits get a note",
+			noteRebuiltCommits: true,
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs(notesAddArgs("Rebuilt by lazygit in a rebase from:\nreword 222222\nsquash 333333", "aaaaaa"), "", nil).
+				ExpectGitArgs(notesAddArgs("Rebuilt by lazygit in a rebase from:\npick 444444", "bbbbbb"), "", nil),
+			expectNoted: true,
+		},
+		{
+			testName:           "turned off",
+			noteRebuiltCommits: false,
+			runner:             oscommands.NewFakeRunner(t),
+			expectNoted:        false,
+		},
+		{
+			testName:           "already noted",
+			noteRebuiltCommits: true,
+			alreadyNoted:       true,
+			runner:             oscommands.NewFakeRunner(t),
+			expectNoted:        true,
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			repoDir := t.TempDir()
+			dir := filepath.Join(repoDir, ".git", lastRebaseDirName)
+			assert.NoError(t, os.MkdirAll(dir, 0o755))
+			for name, content := range lastRebase {
+				assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+			}
+			if s.alreadyNoted {
+				assert.NoError(t, os.WriteFile(filepath.Join(dir, rebuiltCommitsNotedFile), nil, 0o644))
+			}
+
+			userConfig := config.GetDefaultConfig()
+			userConfig.Git.Rebase.NoteRebuiltCommits = s.noteRebuiltCommits
+			instance := buildRebaseCommands(commonDeps{runner: s.runner, userConfig: userConfig, repoPaths: MockRepoPaths(repoDir)})
+
+			instance.noteRebuiltCommits()
+			s.runner.CheckForMissingCalls()
+			if s.expectNoted {
+				assert.FileExists(t, filepath.Join(dir, rebuiltCommitsNotedFile))
+			} else {
+				assert.NoFileExists(t, filepath.Join(dir, rebuiltCommitsNotedFile))
+			}
+		})
+	}
+}
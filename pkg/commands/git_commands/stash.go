@@ -4,7 +4,10 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/jesseduffield/lazygit/pkg/commands/models"
 	"github.com/jesseduffield/lazygit/pkg/commands/oscommands"
+	"github.com/jesseduffield/lazygit/pkg/utils"
+	"github.com/samber/lo"
 )
 
 type StashCommands struct {
@@ -52,6 +55,29 @@ func (self *StashCommands) Apply(index int) error {
 	return self.cmd.New(cmdArgs).Run()
 }
 
+// AutostashEntries returns the stash entries that git created when it
+// couldn't reapply the changes it had stashed away with --autostash (e.g.
+// after a rebase), so that the user can be told to recover them. Returns an
+// empty slice if there are none.
+func (self *StashCommands) AutostashEntries() ([]*models.StashEntry, error) {
+	cmdArgs := NewGitCmd("stash").Arg("list", "-z", "--pretty=%ct|%gs").ToArgv()
+	rawString, err := self.cmd.New(cmdArgs).DontLog().RunWithOutput()
+	if err != nil {
+		return nil, err
+	}
+
+	loader := NewStashLoader(self.Common, self.cmd)
+	entries := lo.Map(utils.SplitNul(rawString), func(line string, index int) *models.StashEntry {
+		return loader.stashEntryFromLine(line, index)
+	})
+
+	// git gives these entries this exact message, no matter which command
+	// created them
+	return lo.Filter(entries, func(entry *models.StashEntry, _ int) bool {
+		return entry.Name == "autostash"
+	}), nil
+}
+
 // Push push stash
 func (self *StashCommands) Push(message string) error {
 	cmdArgs := NewGitCmd("stash").Arg("push", "-m", message).
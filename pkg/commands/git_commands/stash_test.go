@@ -3,8 +3,10 @@ package git_commands
 import (
 	"testing"
 
+	"github.com/jesseduffield/lazygit/pkg/commands/models"
 	"github.com/jesseduffield/lazygit/pkg/commands/oscommands"
 	"github.com/jesseduffield/lazygit/pkg/config"
+	"github.com/samber/lo"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -191,3 +193,46 @@ func TestStashRename(t *testing.T) {
 		})
 	}
 }
+
+func TestStashAutostashEntries(t *testing.T) {
+	type scenario struct {
+		testName        string
+		output          string
+		expectedIndices []int
+	}
+
+	scenarios := []scenario{
+		{
+			testName:        "no stash entries",
+			output:          "",
+			expectedIndices: []int{},
+		},
+		{
+			testName:        "no autostash entries",
+			output:          "1692154279|On master: my changes\x001692154000|WIP on master: 123456 commit\x00",
+			expectedIndices: []int{},
+		},
+		{
+			testName:        "autostash that couldn't be reapplied after a rebase",
+			output:          "1692154279|autostash\x001692154000|On master: autostash of mine\x001692153000|autostash\x00",
+			expectedIndices: []int{0, 2},
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			runner := oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"stash", "list", "-z", "--pretty=%ct|%gs"}, s.output, nil)
+			instance := buildStashCommands(commonDeps{runner: runner})
+
+			entries, err := instance.AutostashEntries()
+			assert.NoError(t, err)
+			assert.Equal(t, s.expectedIndices, lo.Map(entries, func(entry *models.StashEntry, _ int) int { return entry.Index }))
+			for _, entry := range entries {
+				assert.Equal(t, "autostash", entry.Name)
+			}
+			runner.CheckForMissingCalls()
+		})
+	}
+}
@@ -78,13 +78,44 @@ func (self *StatusCommands) IsInMergeState() (bool, error) {
 	return self.os.FileExists(filepath.Join(self.repoPaths.WorktreeGitDirPath(), "MERGE_HEAD"))
 }
 
-// Full ref (e.g. "refs/heads/mybranch") of the branch that is currently
-// being rebased, or empty string when we're not in a rebase
-func (self *StatusCommands) BranchBeingRebased() string {
-	for _, dir := range []string{"rebase-merge", "rebase-apply"} {
-		if bytesContent, err := os.ReadFile(filepath.Join(self.repoPaths.WorktreeGitDirPath(), dir, "head-name")); err == nil {
-			return strings.TrimSpace(string(bytesContent))
+// CurrentOperation returns the multi-step operation (rebase, merge,
+// cherry-pick or revert) that is currently in progress
+func (self *StatusCommands) CurrentOperation() (enums.Operation, error) {
+	gitDir := self.repoPaths.WorktreeGitDirPath()
+
+	markers := []struct {
+		path      string
+		operation enums.Operation
+	}{
+		{"rebase-merge", enums.OPERATION_REBASE},
+		{"rebase-apply", enums.OPERATION_REBASE},
+		{"MERGE_HEAD", enums.OPERATION_MERGE},
+		{"CHERRY_PICK_HEAD", enums.OPERATION_CHERRY_PICK},
+		{"REVERT_HEAD", enums.OPERATION_REVERT},
+	}
+
+	for _, marker := range markers {
+		exists, err := self.os.FileExists(filepath.Join(gitDir, marker.path))
+		if err != nil {
+			return enums.OPERATION_NONE, err
+		}
+		if exists {
+			return marker.operation, nil
 		}
 	}
-	return ""
+
+	// When a multi-commit cherry-pick or revert has stopped after the user
+	// committed a conflict resolution, the *_HEAD file is gone but the
+	// sequencer state remains, so we look at what the next step would be
+	if content, err := os.ReadFile(filepath.Join(gitDir, "sequencer", "todo")); err == nil {
+		firstWord, _, _ := strings.Cut(strings.TrimSpace(string(content)), " ")
+		switch firstWord {
+		case "pick", "p":
+			return enums.OPERATION_CHERRY_PICK, nil
+		case "revert":
+			return enums.OPERATION_REVERT, nil
+		}
+	}
+
+	return enums.OPERATION_NONE, nil
 }
@@ -104,6 +104,14 @@ func (self *SubmoduleCommands) UpdateAll() error {
 	return self.cmd.New(cmdArgs).Run()
 }
 
+// UpdateAllKeepingChanges is like UpdateAll, but without --force, so git
+// refuses to check out a submodule where that would lose local changes
+func (self *SubmoduleCommands) UpdateAllKeepingChanges() error {
+	cmdArgs := NewGitCmd("submodule").Arg("update").ToArgv()
+
+	return self.cmd.New(cmdArgs).Run()
+}
+
 func (self *SubmoduleCommands) Delete(submodule *models.SubmoduleConfig) error {
 	// based on https://gist.github.com/myusuf3/7f645819ded92bda6677
 
@@ -0,0 +1,169 @@
+package git_commands
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/go-errors/errors"
+	"github.com/jesseduffield/lazygit/pkg/commands/models"
+	"github.com/samber/lo"
+)
+
+// Like git interpret-trailers, we only treat a line as a trailer if its key is
+// a single token. Conventional commits add "BREAKING CHANGE", which git also
+// accepts as a key because it's so common.
+var trailerLineRegexp = regexp.MustCompile(`^([A-Za-z0-9-]+|BREAKING CHANGE): `)
+
+const coAuthorTrailerKey = "Co-authored-by"
+
+var trailerEmailRegexp = regexp.MustCompile(`<([^>]*)>\s*$`)
+
+// RewordCommitWithTrailers rewords the commit at the given index, adding the
+// given trailers (key to value, e.g. "Refs" to "#123") to the end of the body.
+// A trailer whose key is already among the body's trailers gets its value
+// replaced instead of being added again, so applying the same trailers twice
+// gives the same message.
+func (self *RebaseCommands) RewordCommitWithTrailers(
+	commits []*models.Commit, index int, subject string, body string, trailers map[string]string,
+) error {
+	return self.RewordCommit(commits, index, subject, withTrailers(body, trailers))
+}
+
+// Returns the body with the given trailers added to or updated in its trailer
+// block, i.e. its last paragraph if every line of that is a trailer. Keys are
+// compared case-insensitively, like git does. New trailers are added in order
+// of their keys, so that the result doesn't depend on map iteration order.
+func withTrailers(body string, trailers map[string]string) string {
+	body = strings.TrimRight(body, "\n")
+	if len(trailers) == 0 {
+		return body
+	}
+
+	paragraphs, trailerLines := splitTrailerBlock(body)
+
+	keys := lo.Keys(trailers)
+	sort.Strings(keys)
+	for _, key := range keys {
+		line := key + ": " + trailers[key]
+		_, existingIndex, found := lo.FindIndexOf(trailerLines, func(existing string) bool {
+			return strings.EqualFold(trailerLineRegexp.FindStringSubmatch(existing)[1], key)
+		})
+		if found {
+			trailerLines[existingIndex] = line
+		} else {
+			trailerLines = append(trailerLines, line)
+		}
+	}
+
+	return joinTrailerBlock(paragraphs, trailerLines)
+}
+
+// Splits a commit body into the paragraphs before its trailer block and the
+// lines of the trailer block, which is the last paragraph if every line of it
+// is a trailer.
+func splitTrailerBlock(body string) ([]string, []string) {
+	body = strings.TrimRight(body, "\n")
+	if body == "" {
+		return nil, nil
+	}
+
+	paragraphs := strings.Split(body, "\n\n")
+	lines := strings.Split(paragraphs[len(paragraphs)-1], "\n")
+	if !lo.EveryBy(lines, trailerLineRegexp.MatchString) {
+		return paragraphs, nil
+	}
+
+	return paragraphs[:len(paragraphs)-1], lines
+}
+
+// The reverse of splitTrailerBlock; there's no trailer block if trailerLines
+// is empty
+func joinTrailerBlock(paragraphs []string, trailerLines []string) string {
+	if len(trailerLines) > 0 {
+		paragraphs = append(paragraphs, strings.Join(trailerLines, "\n"))
+	}
+	return strings.Join(lo.Compact(paragraphs), "\n\n")
+}
+
+// AddCoAuthor rewords the commit at the given index to add a Co-authored-by
+// trailer for the given person, unless the commit already credits someone
+// with that email, in which case nothing happens.
+func (self *RebaseCommands) AddCoAuthor(commits []*models.Commit, index int, name string, email string) error {
+	return self.rewordTrailers(commits, index, func(body string) string {
+		return withCoAuthor(body, name, email)
+	})
+}
+
+// RemoveCoAuthor rewords the commit at the given index to drop the
+// Co-authored-by trailers with the given email. Nothing happens if there
+// aren't any.
+func (self *RebaseCommands) RemoveCoAuthor(commits []*models.Commit, index int, email string) error {
+	return self.rewordTrailers(commits, index, func(body string) string {
+		return withoutCoAuthor(body, email)
+	})
+}
+
+// Rewords the commit at the given index with its body edited by the given
+// function; the subject is never part of the trailer block, even if it
+// happens to look like a trailer
+func (self *RebaseCommands) rewordTrailers(commits []*models.Commit, index int, edit func(body string) string) error {
+	if index < 0 || index >= len(commits) {
+		return errors.New("index outside of range of commits")
+	}
+
+	message, err := self.commit.GetCommitMessage(commits[index].Sha)
+	if err != nil {
+		return err
+	}
+
+	summary, body, _ := strings.Cut(strings.TrimRight(message, "\n"), "\n")
+	body = strings.TrimSpace(body)
+	newBody := edit(body)
+	if newBody == body {
+		return nil
+	}
+
+	return self.RewordCommit(commits, index, summary, newBody)
+}
+
+func withCoAuthor(body string, name string, email string) string {
+	paragraphs, trailerLines := splitTrailerBlock(body)
+	if lo.SomeBy(trailerLines, func(line string) bool { return isCoAuthorLine(line, email) }) {
+		return body
+	}
+
+	trailerLines = append(trailerLines, fmt.Sprintf("%s: %s <%s>", coAuthorTrailerKey, name, email))
+	return joinTrailerBlock(paragraphs, trailerLines)
+}
+
+func withoutCoAuthor(body string, email string) string {
+	paragraphs, trailerLines := splitTrailerBlock(body)
+	trailerLines = lo.Reject(trailerLines, func(line string, _ int) bool { return isCoAuthorLine(line, email) })
+
+	return joinTrailerBlock(paragraphs, trailerLines)
+}
+
+func isCoAuthorLine(line string, email string) bool {
+	match := trailerLineRegexp.FindStringSubmatch(line)
+	if match == nil || !strings.EqualFold(match[1], coAuthorTrailerKey) {
+		return false
+	}
+
+	emailMatch := trailerEmailRegexp.FindStringSubmatch(line)
+	return emailMatch != nil && strings.EqualFold(emailMatch[1], email)
+}
+
+var nameAndEmailRegexp = regexp.MustCompile(`^\s*(.*?)\s*<([^<>]+)>\s*$`)
+
+// ParseNameAndEmail splits a person given as 'Name <Email>', e.g. the
+// co-author that the user typed in, into the name and the email
+func ParseNameAndEmail(value string) (string, string, error) {
+	match := nameAndEmailRegexp.FindStringSubmatch(value)
+	if match == nil || match[1] == "" {
+		return "", "", errors.Errorf("'%s' doesn't look like 'Name <Email>'", value)
+	}
+
+	return match[1], match[2], nil
+}
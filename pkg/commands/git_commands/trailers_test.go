@@ -0,0 +1,169 @@
+package git_commands
+
+import (
+	"testing"
+
+	"github.com/jesseduffield/lazygit/pkg/commands/models"
+	"github.com/jesseduffield/lazygit/pkg/commands/oscommands"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithTrailers(t *testing.T) {
+	scenarios := []struct {
+		testName string
+		body     string
+		trailers map[string]string
+		expected string
+	}{
+		{
+			testName: "no trailers",
+			body:     "Some details",
+			trailers: map[string]string{},
+			expected: "Some details",
+		},
+		{
+			testName: "empty body",
+			body:     "",
+			trailers: map[string]string{"Refs": "#123"},
+			expected: "Refs: #123",
+		},
+		{
+			testName: "trailers are separated from the body by a blank line",
+			body:     "Some details\nover two lines\n",
+			trailers: map[string]string{"Refs": "#123", "BREAKING CHANGE": "the config format changed"},
+			expected: "Some details\nover two lines\n\nBREAKING CHANGE: the config format changed\nRefs: #123",
+		},
+		{
+			testName: "existing trailer is updated rather than duplicated",
+			body:     "Some details\n\nRefs: #100\nReviewed-by: Jane Doe <jane@example.com>",
+			trailers: map[string]string{"refs": "#123"},
+			expected: "Some details\n\nrefs: #123\nReviewed-by: Jane Doe <jane@example.com>",
+		},
+		{
+			testName: "new trailer is added to the existing trailer block",
+			body:     "Some details\n\nReviewed-by: Jane Doe <jane@example.com>",
+			trailers: map[string]string{"Refs": "#123"},
+			expected: "Some details\n\nReviewed-by: Jane Doe <jane@example.com>\nRefs: #123",
+		},
+		{
+			testName: "last paragraph that isn't all trailers is left alone",
+			body:     "Some details\n\nNote: this is prose\nnot a trailer",
+			trailers: map[string]string{"Refs": "#123"},
+			expected: "Some details\n\nNote: this is prose\nnot a trailer\n\nRefs: #123",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			result := withTrailers(s.body, s.trailers)
+			assert.Equal(t, s.expected, result)
+
+			// applying the same trailers again doesn't change anything
+			assert.Equal(t, result, withTrailers(result, s.trailers))
+		})
+	}
+}
+
+func TestCoAuthorTrailers(t *testing.T) {
+	scenarios := []struct {
+		testName string
+		body     string
+		expected string
+	}{
+		{
+			testName: "empty body",
+			body:     "",
+			expected: "Co-authored-by: Jane Doe <jane@example.com>",
+		},
+		{
+			testName: "body without trailers",
+			body:     "Some details\nover two lines",
+			expected: "Some details\nover two lines\n\nCo-authored-by: Jane Doe <jane@example.com>",
+		},
+		{
+			testName: "added to the existing trailer block",
+			body:     "Some details\n\nSigned-off-by: John Smith <john@example.com>\nCo-authored-by: Joe Bloggs <joe@example.com>",
+			expected: "Some details\n\nSigned-off-by: John Smith <john@example.com>\nCo-authored-by: Joe Bloggs <joe@example.com>\nCo-authored-by: Jane Doe <jane@example.com>",
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			added := withCoAuthor(s.body, "Jane Doe", "jane@example.com")
+			assert.Equal(t, s.expected, added)
+
+			// adding the same co-author again doesn't duplicate it, even if the
+			// email is spelled differently
+			assert.Equal(t, added, withCoAuthor(added, "Jane", "Jane@Example.com"))
+
+			// removing the co-author gets us back to where we started
+			assert.Equal(t, s.body, withoutCoAuthor(added, "jane@example.com"))
+		})
+	}
+}
+
+func TestParseNameAndEmail(t *testing.T) {
+	name, email, err := ParseNameAndEmail(" Jane Doe <jane@example.com> ")
+	assert.NoError(t, err)
+	assert.Equal(t, "Jane Doe", name)
+	assert.Equal(t, "jane@example.com", email)
+
+	_, _, err = ParseNameAndEmail("jane@example.com")
+	assert.EqualError(t, err, "'jane@example.com' doesn't look like 'Name <Email>'")
+
+	_, _, err = ParseNameAndEmail("<jane@example.com>")
+	assert.Error(t, err)
+}
+
+func TestRebaseAddCoAuthor(t *testing.T) {
+	commits := []*models.Commit{{Name: "Subject", Sha: "123456"}}
+
+	type scenario struct {
+		testName string
+		runner   *oscommands.FakeCmdObjRunner
+	}
+
+	scenarios := []scenario{
+		{
+			testName: "co-author is added",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"log", "--format=%B", "--max-count=1", "123456"}, "Subject\n\nSome details\n", nil).
+				ExpectGitArgs([]string{"commit", "--allow-empty", "--amend", "--only", "--cleanup=whitespace", "-m", "Subject", "-m", "Some details\n\nCo-authored-by: Jane Doe <jane@example.com>"}, "", nil),
+		},
+		{
+			testName: "subject that looks like a trailer stays the subject",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"log", "--format=%B", "--max-count=1", "123456"}, "Fix: the thing\n", nil).
+				ExpectGitArgs([]string{"commit", "--allow-empty", "--amend", "--only", "--cleanup=whitespace", "-m", "Fix: the thing", "-m", "Co-authored-by: Jane Doe <jane@example.com>"}, "", nil),
+		},
+		{
+			testName: "co-author is already there",
+			runner: oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"log", "--format=%B", "--max-count=1", "123456"}, "Subject\n\nCo-authored-by: Jane Doe <jane@example.com>\n", nil),
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			instance := buildRebaseCommands(commonDeps{runner: s.runner})
+
+			assert.NoError(t, instance.AddCoAuthor(commits, 0, "Jane Doe", "jane@example.com"))
+			s.runner.CheckForMissingCalls()
+		})
+	}
+}
+
+func TestRebaseRemoveCoAuthor(t *testing.T) {
+	commits := []*models.Commit{{Name: "Subject", Sha: "123456"}}
+	runner := oscommands.NewFakeRunner(t).
+		ExpectGitArgs([]string{"log", "--format=%B", "--max-count=1", "123456"},
+			"Subject\n\nCo-authored-by: Jane Doe <jane@example.com>\nCo-authored-by: Joe Bloggs <joe@example.com>\n", nil).
+		ExpectGitArgs([]string{"commit", "--allow-empty", "--amend", "--only", "--cleanup=whitespace", "-m", "Subject", "-m", "Co-authored-by: Joe Bloggs <joe@example.com>"}, "", nil)
+	instance := buildRebaseCommands(commonDeps{runner: runner})
+
+	assert.NoError(t, instance.RemoveCoAuthor(commits, 0, "jane@example.com"))
+	runner.CheckForMissingCalls()
+}
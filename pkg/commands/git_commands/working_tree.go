@@ -3,10 +3,13 @@ package git_commands
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/go-errors/errors"
 	"github.com/jesseduffield/lazygit/pkg/commands/models"
 	"github.com/jesseduffield/lazygit/pkg/commands/oscommands"
+	"github.com/jesseduffield/lazygit/pkg/utils"
+	"github.com/samber/lo"
 )
 
 type WorkingTreeCommands struct {
@@ -368,3 +371,19 @@ func (self *WorkingTreeCommands) ResetMixed(ref string) error {
 
 	return self.cmd.New(cmdArgs).Run()
 }
+
+// WorkingTreeClean tells whether the working tree is clean enough to rebase
+// without stashing, i.e. whether no tracked file has staged or unstaged
+// changes. Untracked files don't count, since git rebases around them.
+func (self *WorkingTreeCommands) WorkingTreeClean() (bool, error) {
+	cmdArgs := NewGitCmd("status").Arg("--porcelain").ToArgv()
+
+	output, err := self.cmd.New(cmdArgs).DontLog().RunWithOutput()
+	if err != nil {
+		return false, err
+	}
+
+	return lo.EveryBy(utils.SplitLines(output), func(line string) bool {
+		return strings.HasPrefix(line, "??")
+	}), nil
+}
@@ -546,3 +546,48 @@ func TestWorkingTreeResetHard(t *testing.T) {
 		})
 	}
 }
+
+func TestWorkingTreeWorkingTreeClean(t *testing.T) {
+	type scenario struct {
+		testName      string
+		statusOutput  string
+		expectedClean bool
+	}
+
+	scenarios := []scenario{
+		{
+			testName:      "nothing changed",
+			statusOutput:  "",
+			expectedClean: true,
+		},
+		{
+			testName:      "only untracked files",
+			statusOutput:  "?? new.txt\n?? dir/other.txt\n",
+			expectedClean: true,
+		},
+		{
+			testName:      "modified tracked file",
+			statusOutput:  "?? new.txt\n M file.txt\n",
+			expectedClean: false,
+		},
+		{
+			testName:      "staged tracked file",
+			statusOutput:  "A  added.txt\n",
+			expectedClean: false,
+		},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.testName, func(t *testing.T) {
+			runner := oscommands.NewFakeRunner(t).
+				ExpectGitArgs([]string{"status", "--porcelain"}, s.statusOutput, nil)
+			instance := buildWorkingTreeCommands(commonDeps{runner: runner})
+
+			clean, err := instance.WorkingTreeClean()
+			assert.NoError(t, err)
+			assert.Equal(t, s.expectedClean, clean)
+			runner.CheckForMissingCalls()
+		})
+	}
+}
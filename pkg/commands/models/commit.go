@@ -56,6 +56,10 @@ type Commit struct {
 
 	// SHAs of parent commits (will be multiple if it's a merge commit)
 	Parents []string
+
+	// Set on the oldest loaded commit if the log was cut short by
+	// git.log.commitLimit, i.e. if there are older commits that weren't loaded
+	Truncated bool
 }
 
 func (c *Commit) ShortSha() string {
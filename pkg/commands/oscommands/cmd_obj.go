@@ -1,6 +1,8 @@
 package oscommands
 
 import (
+	"context"
+	"io"
 	"os/exec"
 	"strings"
 
@@ -53,6 +55,14 @@ type ICmdObj interface {
 	// returns true if StreamOutput() was called
 	ShouldStreamOutput() bool
 
+	// when you call this, then call Run(), the command's output is also written
+	// to the given writer as soon as the command writes it, e.g. for following
+	// its progress. Not yet supported for RunWithOutputs, RunAndProcessLines, or
+	// together with StreamOutput or a credential strategy.
+	WithOutputWriter(writer io.Writer) ICmdObj
+	// The writer given to WithOutputWriter, or nil if there was none
+	GetOutputWriter() io.Writer
+
 	// if you call this before ShouldStreamOutput we'll consider an error with no
 	// stderr content as a non-error. Not yet supported for Run or RunWithOutput (
 	// but adding support is trivial)
@@ -66,6 +76,12 @@ type ICmdObj interface {
 	WithMutex(mutex *deadlock.Mutex) ICmdObj
 	Mutex() *deadlock.Mutex
 
+	// Kills the command, along with any processes it has spawned, if the context
+	// is done before the command finishes
+	WithContext(ctx context.Context) ICmdObj
+	// The context given to WithContext, or nil if there was none
+	GetContext() context.Context
+
 	GetCredentialStrategy() CredentialStrategy
 	GetTask() gocui.Task
 
@@ -86,12 +102,18 @@ type CmdObj struct {
 	// see IgnoreEmptyError()
 	ignoreEmptyError bool
 
+	// see WithOutputWriter()
+	outputWriter io.Writer
+
 	// if set to true, it means we might be asked to enter a username/password by this command.
 	credentialStrategy CredentialStrategy
 	task               gocui.Task
 
 	// can be set so that we don't run certain commands simultaneously
 	mutex *deadlock.Mutex
+
+	// see WithContext()
+	ctx context.Context
 }
 
 type CredentialStrategy int
@@ -166,6 +188,16 @@ func (self *CmdObj) ShouldStreamOutput() bool {
 	return self.streamOutput
 }
 
+func (self *CmdObj) WithOutputWriter(writer io.Writer) ICmdObj {
+	self.outputWriter = writer
+
+	return self
+}
+
+func (self *CmdObj) GetOutputWriter() io.Writer {
+	return self.outputWriter
+}
+
 func (self *CmdObj) IgnoreEmptyError() ICmdObj {
 	self.ignoreEmptyError = true
 
@@ -202,6 +234,24 @@ func (self *CmdObj) RunAndProcessLines(onLine func(line string) (bool, error)) e
 	return self.runner.RunAndProcessLines(self, onLine)
 }
 
+func (self *CmdObj) WithContext(ctx context.Context) ICmdObj {
+	cmd := exec.CommandContext(ctx, self.cmd.Path)
+	cmd.Args = self.cmd.Args
+	cmd.Env = self.cmd.Env
+	cmd.Dir = self.cmd.Dir
+	// Git spawns hooks, editors and the like, which must not outlive it
+	PrepareForChildren(cmd)
+	cmd.Cancel = func() error { return Kill(cmd) }
+	self.cmd = cmd
+	self.ctx = ctx
+
+	return self
+}
+
+func (self *CmdObj) GetContext() context.Context {
+	return self.ctx
+}
+
 func (self *CmdObj) PromptOnCredentialRequest(task gocui.Task) ICmdObj {
 	self.credentialStrategy = PROMPT
 	self.task = task
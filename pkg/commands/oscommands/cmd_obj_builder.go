@@ -6,6 +6,7 @@ import (
 	"os/exec"
 	"strings"
 
+	"github.com/jesseduffield/lazygit/pkg/common"
 	"github.com/mgutz/str"
 )
 
@@ -26,6 +27,16 @@ type CmdObjBuilder struct {
 // poor man's version of explicitly saying that struct X implements interface Y
 var _ ICmdObjBuilder = &CmdObjBuilder{}
 
+// NewCmdObjBuilder returns a builder for commands on the given platform, for
+// code that runs outside of the gui, such as the daemon. The commands it
+// builds don't show up in the command log.
+func NewCmdObjBuilder(common *common.Common, platform *Platform) *CmdObjBuilder {
+	return &CmdObjBuilder{
+		runner:   &cmdObjRunner{log: common.Log, guiIO: NewNullGuiIO(common.Log)},
+		platform: platform,
+	}
+}
+
 func (self *CmdObjBuilder) New(args []string) ICmdObj {
 	cmd := exec.Command(args[0], args[1:]...)
 	cmd.Env = os.Environ()
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"bytes"
 	"io"
+	"os/exec"
 	"regexp"
 	"strings"
 	"time"
@@ -104,7 +105,7 @@ func (self *cmdObjRunner) RunWithOutputAux(cmdObj ICmdObj) (string, error) {
 	}
 
 	t := time.Now()
-	output, err := sanitisedCommandOutput(cmdObj.GetCmd().CombinedOutput())
+	output, err := sanitisedCommandOutput(combinedOutput(cmdObj.GetCmd(), cmdObj.GetOutputWriter()))
 	if err != nil {
 		self.log.WithField("command", cmdObj.ToString()).Error(output)
 	}
@@ -202,6 +203,23 @@ func sanitisedCommandOutput(output []byte, err error) (string, error) {
 	return outputString, nil
 }
 
+// Like cmd.CombinedOutput, except that the output also goes to outputWriter
+// if that isn't nil
+func combinedOutput(cmd *exec.Cmd, outputWriter io.Writer) ([]byte, error) {
+	if outputWriter == nil {
+		return cmd.CombinedOutput()
+	}
+
+	var output bytes.Buffer
+	// Giving stdout and stderr the very same writer makes them share a pipe, so
+	// that what they print stays in order
+	writer := io.MultiWriter(&output, outputWriter)
+	cmd.Stdout = writer
+	cmd.Stderr = writer
+	err := cmd.Run()
+	return output.Bytes(), err
+}
+
 type cmdHandler struct {
 	stdoutPipe io.Reader
 	stdinPipe  io.Writer
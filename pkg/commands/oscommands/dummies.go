@@ -20,6 +20,8 @@ type OSCommandDeps struct {
 	RemoveFileFn func(string) error
 	Cmd          *CmdObjBuilder
 	TempDir      string
+	// Gets what the commands log for the user (see OSCommand.LogCommand)
+	LogCommandFn func(str string, commandLine bool)
 }
 
 func NewDummyOSCommandWithDeps(deps OSCommandDeps) *OSCommand {
@@ -33,12 +35,17 @@ func NewDummyOSCommandWithDeps(deps OSCommandDeps) *OSCommand {
 		platform = dummyPlatform
 	}
 
+	guiIO := NewNullGuiIO(utils.NewDummyLog())
+	if deps.LogCommandFn != nil {
+		guiIO.logCommandFn = deps.LogCommandFn
+	}
+
 	return &OSCommand{
 		Common:       common,
 		Platform:     platform,
 		getenvFn:     deps.GetenvFn,
 		removeFileFn: deps.RemoveFileFn,
-		guiIO:        NewNullGuiIO(utils.NewDummyLog()),
+		guiIO:        guiIO,
 		tempDir:      deps.TempDir,
 	}
 }
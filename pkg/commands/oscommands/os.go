@@ -323,13 +323,27 @@ func (c *OSCommand) GetTempDir() string {
 	return c.tempDir
 }
 
-// GetLazygitPath returns the path of the currently executed file
+// GetLazygitPath returns the path of the currently executed file, quoted so
+// that git can use it as an editor. Git runs editors through the shell, even on
+// Windows (where it comes with its own sh), so the path needs quoting for sh
+// whatever the platform, e.g. for installs under "C:/Program Files".
 func GetLazygitPath() string {
 	ex, err := os.Executable() // get the executable path for git to use
 	if err != nil {
 		ex = os.Args[0] // fallback to the first call argument if needed
 	}
-	return `"` + filepath.ToSlash(ex) + `"`
+	return quoteEditorPath(filepath.ToSlash(ex))
+}
+
+// Inside double quotes, sh still gives these characters a special meaning, so
+// we need to escape them
+func quoteEditorPath(path string) string {
+	return `"` + strings.NewReplacer(
+		`\`, `\\`,
+		`"`, `\"`,
+		`$`, `\$`,
+		"`", "\\`",
+	).Replace(path) + `"`
 }
 
 func (c *OSCommand) UpdateWindowTitle() error {
@@ -4,7 +4,10 @@
 package oscommands
 
 import (
+	"bytes"
+	"context"
 	"testing"
+	"time"
 
 	"github.com/go-errors/errors"
 	"github.com/stretchr/testify/assert"
@@ -38,6 +41,16 @@ func TestOSCommandRunWithOutput(t *testing.T) {
 	}
 }
 
+func TestOSCommandRunWithOutputWriter(t *testing.T) {
+	c := NewDummyOSCommand()
+	var written bytes.Buffer
+	output, err := c.Cmd.New([]string{"sh", "-c", "echo out; echo err >&2; echo out"}).WithOutputWriter(&written).RunWithOutput()
+
+	assert.NoError(t, err)
+	assert.EqualValues(t, "out\nerr\nout\n", output)
+	assert.EqualValues(t, "out\nerr\nout\n", written.String())
+}
+
 func TestOSCommandOpenFileDarwin(t *testing.T) {
 	type scenario struct {
 		filename string
@@ -140,3 +153,17 @@ func TestOSCommandOpenFileLinux(t *testing.T) {
 		s.test(oSCmd.OpenFile(s.filename))
 	}
 }
+
+func TestCmdObjWithContext(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	// The sleep is a child of the shell; if it survived the shell being killed
+	// it would keep the output pipe open and we'd wait for it to finish
+	c := NewDummyOSCommand()
+	start := time.Now()
+	err := c.Cmd.New([]string{"sh", "-c", "sleep 10; echo done"}).WithContext(ctx).Run()
+
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
@@ -80,6 +80,25 @@ func TestOSCommandQuoteWindows(t *testing.T) {
 	assert.EqualValues(t, expected, actual)
 }
 
+func TestQuoteEditorPath(t *testing.T) {
+	scenarios := []struct {
+		path     string
+		expected string
+	}{
+		{"/usr/local/bin/lazygit", `"/usr/local/bin/lazygit"`},
+		{"C:/Program Files/lazygit/lazygit.exe", `"C:/Program Files/lazygit/lazygit.exe"`},
+		{"/home/me/my $dir/lazygit", `"/home/me/my \$dir/lazygit"`},
+		{"/home/me/\"quoted\" `dir`/lazygit", "\"/home/me/\\\"quoted\\\" \\`dir\\`/lazygit\""},
+	}
+
+	for _, s := range scenarios {
+		s := s
+		t.Run(s.path, func(t *testing.T) {
+			assert.Equal(t, s.expected, quoteEditorPath(s.path))
+		})
+	}
+}
+
 func TestOSCommandFileType(t *testing.T) {
 	type scenario struct {
 		path  string
@@ -12,3 +12,14 @@ const (
 	REBASE_MODE_REBASING
 	REBASE_MODE_MERGING
 )
+
+// The multi-step git operation that is currently in progress, if any
+type Operation int
+
+const (
+	OPERATION_NONE Operation = iota
+	OPERATION_REBASE
+	OPERATION_MERGE
+	OPERATION_CHERRY_PICK
+	OPERATION_REVERT
+)
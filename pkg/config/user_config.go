@@ -208,6 +208,8 @@ type GitConfig struct {
 	ParseEmoji bool `yaml:"parseEmoji"`
 	// Config for showing the log in the commits view
 	Log LogConfig `yaml:"log"`
+	// Config relating to rebasing
+	Rebase RebaseConfig `yaml:"rebase"`
 }
 
 type PagerType string
@@ -237,6 +239,12 @@ type PagingConfig struct {
 type CommitConfig struct {
 	// If true, pass '--signoff' flag when committing
 	SignOff bool `yaml:"signOff"`
+	// How git cleans up a message that lazygit rewords a commit with (git
+	// commit --cleanup). One of 'whitespace' (default) | 'strip' | 'verbatim'.
+	// 'strip' also removes lines starting with the comment character, such as
+	// those left over from an editor buffer, but would remove a subject like
+	// '#123 Fix crash' too
+	RewordCleanup string `yaml:"rewordCleanup" jsonschema:"enum=whitespace,enum=strip,enum=verbatim"`
 }
 
 type MergingConfig struct {
@@ -257,6 +265,77 @@ type LogConfig struct {
 	ShowGraph string `yaml:"showGraph" jsonschema:"enum=always,enum=never,enum=when-maximised"`
 	// displays the whole git graph by default in the commits view (equivalent to passing the `--all` argument to `git log`)
 	ShowWholeGraph bool `yaml:"showWholeGraph"`
+	// The number of commits to load at first in the commits panel; the rest are
+	// loaded once you scroll far enough down. Set to 0 to always load all commits
+	CommitLimit int `yaml:"commitLimit" jsonschema:"minimum=0"`
+}
+
+type RebaseConfig struct {
+	// If true, commits that are rebuilt by an interactive rebase (e.g. when moving
+	// or rewording commits) keep their original committer date instead of getting
+	// the current date. Rebases that run in the terminal so that you can edit
+	// commit messages there (e.g. rewording in your editor) don't keep them.
+	PreserveCommitterDates bool `yaml:"preserveCommitterDates"`
+	// If true, add a Signed-off-by trailer to every commit that is rebuilt by a
+	// rebase, for projects that require one on every commit. This includes
+	// commits that lazygit amends while a rebase is stopped at them, but not
+	// amends outside of a rebase
+	SignOff bool `yaml:"signOff"`
+	// If true, branches that point at commits in the range being rebased are
+	// moved along with those commits (git rebase --update-refs), which keeps
+	// stacked branches stacked. Requires git 2.38 or later
+	UpdateRefs bool `yaml:"updateRefs"`
+	// Which of git's rebase backends to use. One of: '' (let git decide) |
+	// 'merge' | 'apply'. Only a simple rebase onto a branch can use 'apply';
+	// interactive rebases always use the merge backend, and say so in the
+	// command log
+	Backend string `yaml:"backend" jsonschema:"enum=,enum=merge,enum=apply"`
+	// If true, uncommitted changes are stashed before a rebase and restored
+	// afterwards (git rebase --autostash). If false, rebasing a branch is
+	// refused while tracked files have uncommitted changes
+	AutoStash bool `yaml:"autoStash"`
+	// If true, run 'git submodule update' after a rebase of commits that change
+	// submodules finishes, so that the submodules are checked out at the commits
+	// that the rebased history points them at. Otherwise reordering or dropping
+	// such commits can leave a submodule showing up as modified, so lazygit
+	// warns before doing it
+	UpdateSubmodules bool `yaml:"updateSubmodules"`
+	// If true, rebasing the checked-out branch onto another branch happens in a
+	// temporary worktree, and the branch is only moved over once the rebase has
+	// succeeded, so that uncommitted changes can stay where they are without
+	// being stashed. If the rebase stops at conflicts it is given up, leaving
+	// the branch as it was
+	UseWorktree bool `yaml:"useWorktree"`
+	// If true, the rebase menu offers to rebase the checked-out branch and then
+	// force-push it (with lease) once the rebase has finished, even if it
+	// stops at conflicts on the way. If false, pushing is left to the user
+	PushAfterRebase bool `yaml:"pushAfterRebase"`
+	// If greater than 0, lazygit points a ref at the tip of the checked-out
+	// branch before each rebase (refs/lazygit/pre-rebase/<branch>/<timestamp>),
+	// so that the branch can be reset to it even after lazygit has been
+	// restarted. This many of them are kept per branch; older ones are deleted
+	PreRebaseRefs int `yaml:"preRebaseRefs" jsonschema:"minimum=0"`
+	// If greater than 0, a rebase that takes longer than this many seconds
+	// (e.g. because a hook or an editor hangs) is killed and aborted, which
+	// leaves the branch and any uncommitted changes the way they were before
+	TimeoutSeconds int `yaml:"timeoutSeconds" jsonschema:"minimum=0"`
+	// If false, lazygit doesn't have git run lazygit itself as the sequence
+	// editor, for when its binary can't be re-invoked (e.g. because it isn't
+	// on a stable path). Instead the rebase is started with a break, lazygit
+	// edits the todo file directly and then continues. Commits that lazygit
+	// would reword with a message of its own can't be reworded this way.
+	UseDaemon bool `yaml:"useDaemon"`
+	// The style of the conflict markers that lazygit's rebases leave in
+	// conflicted files (git's merge.conflictStyle). One of: '' (use git's
+	// config) | 'merge' | 'diff3' | 'zdiff3'. diff3 and zdiff3 also show what
+	// the conflicting lines looked like in the common ancestor. zdiff3 requires
+	// git 2.35; older versions get diff3
+	ConflictStyle string `yaml:"conflictStyle" jsonschema:"enum=,enum=merge,enum=diff3,enum=zdiff3"`
+	// If true, once a rebase that lazygit ran or continued has finished, each
+	// commit that it rebuilt gets a note under refs/notes/lazygit saying which
+	// commits it was made from and by which todos, so that it can be told
+	// apart from commits the user made themselves
+	NoteRebuiltCommits bool `yaml:"noteRebuiltCommits"`
 }
 
 type CommitPrefixConfig struct {
@@ -436,7 +515,8 @@ type KeybindingStashConfig struct {
 }
 
 type KeybindingCommitFilesConfig struct {
-	CheckoutCommitFile string `yaml:"checkoutCommitFile"`
+	CheckoutCommitFile    string `yaml:"checkoutCommitFile"`
+	RemoveFileFromHistory string `yaml:"removeFileFromHistory"`
 }
 
 type KeybindingMainConfig struct {
@@ -654,7 +734,8 @@ func GetDefaultConfig() *UserConfig {
 				ExternalDiffCommand: "",
 			},
 			Commit: CommitConfig{
-				SignOff: false,
+				SignOff:       false,
+				RewordCleanup: "whitespace",
 			},
 			Merging: MergingConfig{
 				ManualCommit: false,
@@ -664,6 +745,22 @@ func GetDefaultConfig() *UserConfig {
 				Order:          "topo-order",
 				ShowGraph:      "when-maximised",
 				ShowWholeGraph: false,
+				CommitLimit:    300,
+			},
+			Rebase: RebaseConfig{
+				PreserveCommitterDates: false,
+				SignOff:                false,
+				UpdateRefs:             false,
+				Backend:                "",
+				AutoStash:              true,
+				UpdateSubmodules:       false,
+				UseWorktree:            false,
+				PushAfterRebase:        true,
+				PreRebaseRefs:          0,
+				TimeoutSeconds:         0,
+				UseDaemon:              true,
+				ConflictStyle:          "",
+				NoteRebuiltCommits:     false,
 			},
 			SkipHookPrefix:      "WIP",
 			MainBranches:        []string{"master", "main"},
@@ -828,7 +925,8 @@ func GetDefaultConfig() *UserConfig {
 				RenameStash: "r",
 			},
 			CommitFiles: KeybindingCommitFilesConfig{
-				CheckoutCommitFile: "c",
+				CheckoutCommitFile:    "c",
+				RemoveFileFromHistory: "D",
 			},
 			Main: KeybindingMainConfig{
 				ToggleDragSelect:    "v",
@@ -8,6 +8,7 @@ import (
 	"github.com/jesseduffield/lazygit/pkg/gui/context"
 	"github.com/jesseduffield/lazygit/pkg/gui/filetree"
 	"github.com/jesseduffield/lazygit/pkg/gui/types"
+	"github.com/jesseduffield/lazygit/pkg/utils"
 )
 
 type CommitFilesController struct {
@@ -38,6 +39,11 @@ func (self *CommitFilesController) GetKeybindings(opts types.KeybindingsOpts) []
 			Handler:     self.checkSelected(self.discard),
 			Description: self.c.Tr.DiscardOldFileChange,
 		},
+		{
+			Key:         opts.GetKey(opts.Config.CommitFiles.RemoveFileFromHistory),
+			Handler:     self.checkSelected(self.removeFromHistory),
+			Description: self.c.Tr.RemoveFileFromHistory,
+		},
 		{
 			Key:         opts.GetKey(opts.Config.Universal.OpenFile),
 			Handler:     self.checkSelected(self.open),
@@ -195,6 +201,44 @@ func (self *CommitFilesController) discard(node *filetree.CommitFileNode) error
 	})
 }
 
+func (self *CommitFilesController) removeFromHistory(node *filetree.CommitFileNode) error {
+	parentContext, ok := self.c.CurrentContext().GetParentContext()
+	if !ok || parentContext.GetKey() != context.LOCAL_COMMITS_CONTEXT_KEY {
+		return self.c.ErrorMsg(self.c.Tr.CanOnlyRemoveFromLocalCommits)
+	}
+
+	if node.File == nil {
+		return self.c.ErrorMsg(self.c.Tr.RemoveFromHistoryNotSupportedForDir)
+	}
+
+	if ok, err := self.c.Helpers().PatchBuilding.ValidateNormalWorkingTreeState(); !ok {
+		return err
+	}
+
+	prompt := utils.ResolvePlaceholderString(
+		self.c.Tr.RemoveFileFromHistoryPrompt,
+		map[string]string{
+			"fileName":   node.GetPath(),
+			"branchName": self.c.Helpers().Refs.GetCheckedOutRef().Name,
+		},
+	)
+
+	return self.c.Confirm(types.ConfirmOpts{
+		Title:  self.c.Tr.RemoveFileFromHistoryTitle,
+		Prompt: prompt,
+		HandleConfirm: func() error {
+			return self.c.WithWaitingStatus(self.c.Tr.RemovingFileFromHistoryStatus, func(gocui.Task) error {
+				self.c.LogAction(self.c.Tr.Actions.RemoveFileFromHistory)
+				if err := self.c.Git().Rebase.RemoveFileFromHistory(node.GetPath()); err != nil {
+					return self.c.Error(err)
+				}
+
+				return self.c.Refresh(types.RefreshOptions{Mode: types.BLOCK_UI})
+			})
+		},
+	})
+}
+
 func (self *CommitFilesController) open(node *filetree.CommitFileNode) error {
 	return self.c.Helpers().Files.OpenFile(node.GetPath())
 }
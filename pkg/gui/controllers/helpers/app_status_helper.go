@@ -57,6 +57,15 @@ func (self appStatusHelperTask) Continue() {
 	self.waitingStatusHandle.Show()
 }
 
+// Changes the message of the waiting status that WithWaitingStatus shows while
+// the task runs, e.g. to say how far the work has got. Does nothing for tasks
+// that didn't come from WithWaitingStatus.
+func setWaitingStatusMessage(task gocui.Task, message string) {
+	if task, ok := task.(appStatusHelperTask); ok {
+		task.waitingStatusHandle.SetMessage(message)
+	}
+}
+
 // withWaitingStatus wraps a function and shows a waiting status while the function is still executing
 func (self *AppStatusHelper) WithWaitingStatus(message string, f func(gocui.Task) error) {
 	self.c.OnWorker(func(task gocui.Task) {
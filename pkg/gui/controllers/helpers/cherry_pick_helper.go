@@ -1,6 +1,10 @@
 package helpers
 
 import (
+	goContext "context"
+	"errors"
+	"sync"
+
 	"github.com/jesseduffield/gocui"
 	"github.com/jesseduffield/lazygit/pkg/commands/models"
 	"github.com/jesseduffield/lazygit/pkg/gui/modes/cherrypicking"
@@ -11,6 +15,10 @@ type CherryPickHelper struct {
 	c *HelperCommon
 
 	rebaseHelper *MergeAndRebaseHelper
+
+	// Set while a paste is running, so that pasting again can cancel it
+	cancelPaste goContext.CancelFunc
+	mutex       sync.Mutex
 }
 
 // I'm using the analogy of copy+paste in the terminology here because it's intuitively what's going on,
@@ -70,8 +78,20 @@ func (self *CherryPickHelper) CopyRange(selectedIndex int, commitsList []*models
 }
 
 // HandlePasteCommits begins a cherry-pick rebase with the commits the user has copied.
-// Only to be called from the branch commits controller
+// Only to be called from the branch commits controller. While the rebase is
+// running, pasting again offers to cancel it.
 func (self *CherryPickHelper) Paste() error {
+	if cancel := self.runningPasteCancel(); cancel != nil {
+		return self.c.Confirm(types.ConfirmOpts{
+			Title:  self.c.Tr.CancelCherryPick,
+			Prompt: self.c.Tr.SureCancelCherryPick,
+			HandleConfirm: func() error {
+				cancel()
+				return nil
+			},
+		})
+	}
+
 	return self.c.Confirm(types.ConfirmOpts{
 		Title:  self.c.Tr.CherryPick,
 		Prompt: self.c.Tr.SureCherryPick,
@@ -90,14 +110,39 @@ func (self *CherryPickHelper) Paste() error {
 			}
 
 			return self.c.WithWaitingStatus(self.c.Tr.CherryPickingStatus, func(gocui.Task) error {
+				ctx, cancel := goContext.WithCancel(goContext.Background())
+				self.setRunningPasteCancel(cancel)
+				defer func() {
+					self.setRunningPasteCancel(nil)
+					cancel()
+				}()
+
 				self.c.LogAction(self.c.Tr.Actions.CherryPick)
-				err := self.c.Git().Rebase.CherryPickCommits(self.getData().CherryPickedCommits)
+				err := self.c.Git().Rebase.CherryPickCommitsWithContext(ctx, self.getData().CherryPickedCommits)
+				if errors.Is(err, goContext.Canceled) {
+					// the rebase was aborted, so there's nothing to report
+					return self.c.Refresh(types.RefreshOptions{Mode: types.ASYNC})
+				}
 				return self.rebaseHelper.CheckMergeOrRebase(err)
 			})
 		},
 	})
 }
 
+func (self *CherryPickHelper) runningPasteCancel() goContext.CancelFunc {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	return self.cancelPaste
+}
+
+func (self *CherryPickHelper) setRunningPasteCancel(cancel goContext.CancelFunc) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	self.cancelPaste = cancel
+}
+
 func (self *CherryPickHelper) CanPaste() bool {
 	return self.getData().Active()
 }
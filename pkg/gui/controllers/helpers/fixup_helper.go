@@ -68,11 +68,11 @@ func (self *FixupHelper) HandleFindBaseCommitForFixupPress() error {
 		commits := self.c.Model().Commits
 		if commits[len(commits)-1].Status == models.StatusMerged {
 			// If the commit is not found, it's most likely because it's already
-			// merged, and more than 300 commits away. Check if the last known
+			// merged, and further away than git.log.commitLimit. Check if the last known
 			// commit is already merged; if so, show the "already merged" error.
 			return self.c.ErrorMsg(self.c.Tr.BaseCommitIsAlreadyOnMainBranch)
 		}
-		// If we get here, the current branch must have more then 300 commits. Unlikely...
+		// If we get here, the current branch must have more commits than the commit limit. Unlikely...
 		return self.c.ErrorMsg(self.c.Tr.BaseCommitIsNotInCurrentView)
 	}
 	if commit.Status == models.StatusMerged {
@@ -15,6 +15,11 @@ import (
 type MergeAndRebaseHelper struct {
 	c          *HelperCommon
 	refsHelper *RefsHelper
+
+	// Set by WithSubmoduleCheck when the rebase it starts changes submodules
+	// and git.rebase.updateSubmodules is on, so that we update them once the
+	// rebase is done
+	updateSubmodulesAfterRebase bool
 }
 
 func NewMergeAndRebaseHelper(
@@ -100,6 +105,17 @@ func (self *MergeAndRebaseHelper) genericMergeCommand(command string) error {
 			self.c.Git().Rebase.GenericMergeOrRebaseActionCmdObj(commandType, command),
 		)
 	}
+	if status == enums.REBASE_MODE_REBASING && command == REBASE_OPTION_CONTINUE {
+		needsEditor, err := self.c.Git().Rebase.ContinueNeedsMessageEditor()
+		if err != nil {
+			return self.c.Error(err)
+		}
+		if needsEditor {
+			result := self.c.Git().Rebase.ContinueRebaseWithEditorForRewords(self.c.RunSubprocessWithOutput)
+			return self.CheckMergeOrRebase(result)
+		}
+	}
+
 	result := self.c.Git().Rebase.GenericMergeOrRebaseAction(commandType, command)
 	if err := self.CheckMergeOrRebase(result); err != nil {
 		return err
@@ -126,6 +142,9 @@ func isMergeConflictErr(errStr string) bool {
 }
 
 func (self *MergeAndRebaseHelper) CheckMergeOrRebaseWithRefreshOptions(result error, refreshOptions types.RefreshOptions) error {
+	if err := self.updateSubmodulesIfDone(result); err != nil {
+		return err
+	}
 	if err := self.c.Refresh(refreshOptions); err != nil {
 		return err
 	}
@@ -143,6 +162,25 @@ func (self *MergeAndRebaseHelper) CheckMergeOrRebaseWithRefreshOptions(result er
 	}
 }
 
+// Once a rebase that WithSubmoduleCheck started is done, check the submodules
+// out at the commits that the new HEAD points them at
+func (self *MergeAndRebaseHelper) updateSubmodulesIfDone(result error) error {
+	if !self.updateSubmodulesAfterRebase || self.c.Git().Status.WorkingTreeState() != enums.REBASE_MODE_NONE {
+		return nil
+	}
+	self.updateSubmodulesAfterRebase = false
+
+	if result != nil || len(self.c.Model().Submodules) == 0 {
+		return nil
+	}
+
+	self.c.LogAction(self.c.Tr.Actions.BulkUpdateSubmodules)
+	if err := self.c.Git().Submodule.UpdateAllKeepingChanges(); err != nil {
+		return self.c.Error(err)
+	}
+	return nil
+}
+
 func (self *MergeAndRebaseHelper) CheckMergeOrRebase(result error) error {
 	return self.CheckMergeOrRebaseWithRefreshOptions(result, types.RefreshOptions{Mode: types.ASYNC})
 }
@@ -154,9 +192,19 @@ func (self *MergeAndRebaseHelper) CheckForConflicts(result error) error {
 
 	if isMergeConflictErr(result.Error()) {
 		return self.PromptForConflictHandling()
-	} else {
-		return self.c.ErrorMsg(result.Error())
 	}
+
+	// Git's own message buries what the command printed between its progress
+	// output, so show the failing commit and the command's output instead
+	if failedExec, err := self.c.Git().Rebase.FailedExec(); err == nil && failedExec != nil {
+		return self.c.ErrorMsg(utils.ResolvePlaceholderString(self.c.Tr.RebaseExecFailed, map[string]string{
+			"sha":     utils.ShortSha(failedExec.Sha),
+			"command": failedExec.Command,
+			"output":  failedExec.Output,
+		}))
+	}
+
+	return self.c.ErrorMsg(result.Error())
 }
 
 func (self *MergeAndRebaseHelper) PromptForConflictHandling() error {
@@ -195,6 +243,28 @@ func (self *MergeAndRebaseHelper) AbortMergeOrRebaseWithConfirm() error {
 	})
 }
 
+// When lazygit queues up a step to run after the user continues a rebase, it
+// persists a description of it in the worktree's git dir, but the step itself
+// is a closure that is lost when lazygit exits. So if an earlier instance was
+// closed before it got to run the step, all we can do is use the description to
+// let the user know that the operation wasn't finished
+func (self *MergeAndRebaseHelper) WarnAboutUnfinishedOperation() {
+	operation, found := self.c.Git().Rebase.DanglingContinuation()
+	if !found {
+		return
+	}
+	self.c.Git().Rebase.DiscardDanglingContinuation()
+
+	self.c.OnUIThread(func() error {
+		return self.c.Alert(
+			self.c.Tr.UnfinishedOperationTitle,
+			utils.ResolvePlaceholderString(self.c.Tr.UnfinishedOperationWarning, map[string]string{
+				"operation": operation,
+			}),
+		)
+	})
+}
+
 func (self *MergeAndRebaseHelper) workingTreeStateNoun() string {
 	workingTreeState := self.c.Git().Status.WorkingTreeState()
 	switch workingTreeState {
@@ -219,6 +289,64 @@ func (self *MergeAndRebaseHelper) PromptToContinueRebase() error {
 }
 
 func (self *MergeAndRebaseHelper) RebaseOntoRef(ref string) error {
+	isHeadDetached, err := self.c.Git().Branch.IsHeadDetached()
+	if err != nil {
+		return err
+	}
+	if isHeadDetached {
+		// There's no branch to move to the rebased commits, so without a
+		// warning it looks like the rebase threw the work away once HEAD moves on
+		return self.c.Confirm(types.ConfirmOpts{
+			Title:  self.c.Tr.RebaseDetachedHeadTitle,
+			Prompt: self.c.Tr.RebaseDetachedHeadWarning,
+			HandleConfirm: func() error {
+				return self.warnAboutSubmodulesAndRebaseOntoRef(ref)
+			},
+		})
+	}
+
+	return self.warnAboutSubmodulesAndRebaseOntoRef(ref)
+}
+
+func (self *MergeAndRebaseHelper) warnAboutSubmodulesAndRebaseOntoRef(ref string) error {
+	baseRef := ref
+	if baseCommit := self.c.Modes().MarkedBaseCommit.GetSha(); baseCommit != "" {
+		baseRef = baseCommit
+	}
+
+	return self.WithSubmoduleCheck(baseRef, func() error {
+		return self.showRebaseOntoRefMenu(ref)
+	})
+}
+
+// WithSubmoduleCheck calls f, which starts a rebase of the commits above
+// baseRef (or of all of HEAD's commits if baseRef is empty). Rebasing commits
+// that change submodules doesn't check the submodules out again, so if any of
+// them do, we either update the submodules once the rebase is done (with
+// git.rebase.updateSubmodules), or let the user know that they may show up as
+// modified before going ahead.
+func (self *MergeAndRebaseHelper) WithSubmoduleCheck(baseRef string, f func() error) error {
+	touchesSubmodules, err := self.c.Git().Rebase.RangeTouchesSubmodules(baseRef)
+	if err != nil {
+		return self.c.Error(err)
+	}
+	if !touchesSubmodules {
+		return f()
+	}
+
+	if self.c.UserConfig.Git.Rebase.UpdateSubmodules {
+		self.updateSubmodulesAfterRebase = true
+		return f()
+	}
+
+	return self.c.Confirm(types.ConfirmOpts{
+		Title:         self.c.Tr.RebaseChangesSubmodulesTitle,
+		Prompt:        self.c.Tr.RebaseChangesSubmodulesWarning,
+		HandleConfirm: f,
+	})
+}
+
+func (self *MergeAndRebaseHelper) showRebaseOntoRefMenu(ref string) error {
 	checkedOutBranch := self.refsHelper.GetCheckedOutRef().Name
 	menuItems := []*types.MenuItem{
 		{
@@ -227,12 +355,19 @@ func (self *MergeAndRebaseHelper) RebaseOntoRef(ref string) error {
 			OnPress: func() error {
 				self.c.LogAction(self.c.Tr.Actions.RebaseBranch)
 				return self.c.WithWaitingStatus(self.c.Tr.RebasingStatus, func(task gocui.Task) error {
+					// Say which step git is at, e.g. "Rebasing pick abc123 (3/8)"
+					onProgress := func(progress git_commands.RebaseProgress) {
+						setWaitingStatusMessage(task, self.c.Tr.RebasingStatus+" "+progress.String())
+					}
 					baseCommit := self.c.Modes().MarkedBaseCommit.GetSha()
 					var err error
 					if baseCommit != "" {
-						err = self.c.Git().Rebase.RebaseBranchFromBaseCommit(ref, baseCommit)
+						// Keep commits that become empty because the target already
+						// has their changes, so that nothing vanishes from the
+						// transplanted branch without the user seeing it
+						err = self.c.Git().Rebase.RebaseOnto(ref, baseCommit, git_commands.EmptyCommitsKeep, onProgress)
 					} else {
-						err = self.c.Git().Rebase.RebaseBranch(ref)
+						err = self.c.Git().Rebase.RebaseBranch(ref, onProgress)
 					}
 					err = self.CheckMergeOrRebase(err)
 					if err == nil {
@@ -266,6 +401,22 @@ func (self *MergeAndRebaseHelper) RebaseOntoRef(ref string) error {
 		},
 	}
 
+	if self.c.UserConfig.Git.Rebase.PushAfterRebase && self.c.Modes().MarkedBaseCommit.GetSha() == "" &&
+		self.refsHelper.GetCheckedOutRef().IsTrackingRemote() {
+		menuItems = append(menuItems, &types.MenuItem{
+			Label:   self.c.Tr.RebaseThenPush,
+			Key:     'p',
+			Tooltip: self.c.Tr.RebaseThenPushTooltip,
+			OnPress: func() error {
+				self.c.LogAction(self.c.Tr.Actions.RebaseBranchThenPush)
+				return self.c.WithWaitingStatus(self.c.Tr.RebasingStatus, func(task gocui.Task) error {
+					err := self.c.Git().Rebase.RebaseThenPushWithLease(task, ref)
+					return self.CheckMergeOrRebase(err)
+				})
+			},
+		})
+	}
+
 	title := utils.ResolvePlaceholderString(
 		lo.Ternary(self.c.Modes().MarkedBaseCommit.GetSha() != "",
 			self.c.Tr.RebasingFromBaseCommitTitle,
@@ -283,7 +434,11 @@ func (self *MergeAndRebaseHelper) RebaseOntoRef(ref string) error {
 }
 
 func (self *MergeAndRebaseHelper) MergeRefIntoCheckedOutBranch(refName string) error {
-	if self.c.Git().Branch.IsHeadDetached() {
+	isHeadDetached, err := self.c.Git().Branch.IsHeadDetached()
+	if err != nil {
+		return err
+	}
+	if isHeadDetached {
 		return self.c.ErrorMsg("Cannot merge branch in detached head state. You might have checked out a commit directly or a remote branch, in which case you should checkout the local branch you want to be on")
 	}
 	checkedOutBranchName := self.refsHelper.GetCheckedOutRef().Name
@@ -13,7 +13,6 @@ import (
 	"github.com/jesseduffield/lazygit/pkg/commands/types/enums"
 	"github.com/jesseduffield/lazygit/pkg/gui/context"
 	"github.com/jesseduffield/lazygit/pkg/gui/filetree"
-	"github.com/jesseduffield/lazygit/pkg/gui/mergeconflicts"
 	"github.com/jesseduffield/lazygit/pkg/gui/presentation"
 	"github.com/jesseduffield/lazygit/pkg/gui/types"
 	"github.com/jesseduffield/lazygit/pkg/utils"
@@ -282,12 +281,23 @@ func (self *RefreshHelper) refreshCommitsAndCommitFiles() {
 	}
 }
 
+// The branch that the current rebase is rebasing, or an empty string if we're
+// not rebasing a branch
+func (self *RefreshHelper) rebasingBranchName() string {
+	branchName, err := self.c.Git().Rebase.RebasingBranchName()
+	if err != nil {
+		self.c.Log.Warnf("Failed to get the name of the branch being rebased: %v", err)
+		return ""
+	}
+	return branchName
+}
+
 func (self *RefreshHelper) determineCheckedOutBranchName() string {
-	if rebasedBranch := self.c.Git().Status.BranchBeingRebased(); rebasedBranch != "" {
+	if rebasedBranch := self.rebasingBranchName(); rebasedBranch != "" {
 		// During a rebase we're on a detached head, so cannot determine the
 		// branch name in the usual way. We need to read it from the
 		// ".git/rebase-merge/head-name" file instead.
-		return strings.TrimPrefix(rebasedBranch, "refs/heads/")
+		return rebasedBranch
 	}
 
 	if bisectInfo := self.c.Git().Bisect.GetInfo(); bisectInfo.Bisecting() && bisectInfo.GetStartSha() != "" {
@@ -519,7 +529,7 @@ func (self *RefreshHelper) refreshStateFiles() error {
 			prevConflictFileCount++
 		}
 		if file.HasInlineMergeConflicts {
-			hasConflicts, err := mergeconflicts.FileHasConflictMarkers(file.Name)
+			hasConflicts, err := utils.FileHasConflictMarkers(file.Name)
 			if err != nil {
 				self.c.Log.Error(err)
 			} else if !hasConflicts {
@@ -694,6 +704,16 @@ func (self *RefreshHelper) refreshStatus() {
 
 	repoName := self.c.Git().RepoPaths.RepoName()
 
+	// HEAD is detached while rebasing, so show the branch being rebased rather
+	// than the sha that the rebase has got to
+	if currentBranch.DetachedHead && workingTreeState == enums.REBASE_MODE_REBASING {
+		if rebasedBranch := self.rebasingBranchName(); rebasedBranch != "" {
+			branch := *currentBranch
+			branch.Name = rebasedBranch
+			currentBranch = &branch
+		}
+	}
+
 	status := presentation.FormatStatus(repoName, currentBranch, types.ItemOperationNone, linkedWorktreeName, workingTreeState, self.c.Tr)
 
 	self.c.SetViewContent(self.c.Views().Status, status)
@@ -5,8 +5,10 @@ import (
 
 	"github.com/fsmiamoto/git-todo-parser/todo"
 	"github.com/jesseduffield/gocui"
+	"github.com/jesseduffield/lazygit/pkg/commands/git_commands"
 	"github.com/jesseduffield/lazygit/pkg/commands/models"
 	"github.com/jesseduffield/lazygit/pkg/commands/types/enums"
+	"github.com/jesseduffield/lazygit/pkg/config"
 	"github.com/jesseduffield/lazygit/pkg/gui/context"
 	"github.com/jesseduffield/lazygit/pkg/gui/controllers/helpers"
 	"github.com/jesseduffield/lazygit/pkg/gui/types"
@@ -14,8 +16,14 @@ import (
 	"github.com/samber/lo"
 )
 
-// after selecting the 200th commit, we'll load in all the rest
-const COMMIT_THRESHOLD = 200
+// after selecting a commit two thirds of the way down the initially loaded
+// commits (the 200th one with the default limit of 300), we'll load in all the
+// rest. Returns false if there's no limit, in which case everything is loaded
+// already.
+func commitThreshold(userConfig *config.UserConfig) (int, bool) {
+	limit := userConfig.Git.Log.CommitLimit
+	return limit * 2 / 3, limit > 0
+}
 
 type (
 	PullFilesFn func() error
@@ -397,14 +405,26 @@ func (self *LocalCommitsController) drop(commit *models.Commit) error {
 		Title:  self.c.Tr.DeleteCommitTitle,
 		Prompt: self.c.Tr.DeleteCommitPrompt,
 		HandleConfirm: func() error {
-			return self.c.WithWaitingStatus(self.c.Tr.DeletingStatus, func(gocui.Task) error {
-				self.c.LogAction(self.c.Tr.Actions.DropCommit)
-				return self.interactiveRebase(todo.Drop)
+			return self.c.Helpers().MergeAndRebase.WithSubmoduleCheck(parentSha(commit), func() error {
+				return self.c.WithWaitingStatus(self.c.Tr.DeletingStatus, func(gocui.Task) error {
+					self.c.LogAction(self.c.Tr.Actions.DropCommit)
+					return self.interactiveRebase(todo.Drop)
+				})
 			})
 		},
 	})
 }
 
+// The sha that a rebase of the commit and the ones above it starts from, or ""
+// for a root commit
+func parentSha(commit *models.Commit) string {
+	if len(commit.Parents) == 0 {
+		return ""
+	}
+
+	return commit.Parents[0]
+}
+
 func (self *LocalCommitsController) edit(commit *models.Commit) error {
 	applied, err := self.handleMidRebaseCommand(todo.Edit, commit)
 	if err != nil {
@@ -548,14 +568,16 @@ func (self *LocalCommitsController) moveDown(commit *models.Commit) error {
 		return self.c.ErrorMsg(self.c.Tr.AlreadyRebasing)
 	}
 
-	return self.c.WithWaitingStatusSync(self.c.Tr.MovingStatus, func() error {
-		self.c.LogAction(self.c.Tr.Actions.MoveCommitDown)
-		err := self.c.Git().Rebase.MoveCommitDown(self.c.Model().Commits, index)
-		if err == nil {
-			self.context().MoveSelectedLine(1)
-		}
-		return self.c.Helpers().MergeAndRebase.CheckMergeOrRebaseWithRefreshOptions(
-			err, types.RefreshOptions{Mode: types.SYNC})
+	return self.c.Helpers().MergeAndRebase.WithSubmoduleCheck(parentSha(commits[index+1]), func() error {
+		return self.c.WithWaitingStatusSync(self.c.Tr.MovingStatus, func() error {
+			self.c.LogAction(self.c.Tr.Actions.MoveCommitDown)
+			err := self.c.Git().Rebase.MoveCommitDown(self.c.Model().Commits, index)
+			if err == nil {
+				self.context().MoveSelectedLine(1)
+			}
+			return self.c.Helpers().MergeAndRebase.CheckMergeOrRebaseWithRefreshOptions(
+				err, types.RefreshOptions{Mode: types.SYNC})
+		})
 	})
 }
 
@@ -590,14 +612,16 @@ func (self *LocalCommitsController) moveUp(commit *models.Commit) error {
 		return self.c.ErrorMsg(self.c.Tr.AlreadyRebasing)
 	}
 
-	return self.c.WithWaitingStatusSync(self.c.Tr.MovingStatus, func() error {
-		self.c.LogAction(self.c.Tr.Actions.MoveCommitUp)
-		err := self.c.Git().Rebase.MoveCommitUp(self.c.Model().Commits, index)
-		if err == nil {
-			self.context().MoveSelectedLine(-1)
-		}
-		return self.c.Helpers().MergeAndRebase.CheckMergeOrRebaseWithRefreshOptions(
-			err, types.RefreshOptions{Mode: types.SYNC})
+	return self.c.Helpers().MergeAndRebase.WithSubmoduleCheck(parentSha(commit), func() error {
+		return self.c.WithWaitingStatusSync(self.c.Tr.MovingStatus, func() error {
+			self.c.LogAction(self.c.Tr.Actions.MoveCommitUp)
+			err := self.c.Git().Rebase.MoveCommitUp(self.c.Model().Commits, index)
+			if err == nil {
+				self.context().MoveSelectedLine(-1)
+			}
+			return self.c.Helpers().MergeAndRebase.CheckMergeOrRebaseWithRefreshOptions(
+				err, types.RefreshOptions{Mode: types.SYNC})
+		})
 	})
 }
 
@@ -707,9 +731,14 @@ func (self *LocalCommitsController) addCoAuthor() error {
 		Title:               self.c.Tr.AddCoAuthorPromptTitle,
 		FindSuggestionsFunc: self.c.Helpers().Suggestions.GetAuthorsSuggestionsFunc(),
 		HandleConfirm: func(value string) error {
+			name, email, err := git_commands.ParseNameAndEmail(value)
+			if err != nil {
+				return self.c.Error(err)
+			}
+
 			return self.c.WithWaitingStatus(self.c.Tr.AmendingStatus, func(gocui.Task) error {
 				self.c.LogAction(self.c.Tr.Actions.AddCommitCoAuthor)
-				if err := self.c.Git().Rebase.AddCommitCoAuthor(self.c.Model().Commits, self.context().GetSelectedLineIdx(), value); err != nil {
+				if err := self.c.Git().Rebase.AddCoAuthor(self.c.Model().Commits, self.context().GetSelectedLineIdx(), name, email); err != nil {
 					return self.c.Error(err)
 				}
 				return self.c.Refresh(types.RefreshOptions{Mode: types.ASYNC})
@@ -983,7 +1012,8 @@ func (self *LocalCommitsController) getDisabledReasonForRebaseCommandWithSelecte
 func (self *LocalCommitsController) GetOnFocus() func(types.OnFocusOpts) error {
 	return func(types.OnFocusOpts) error {
 		context := self.context()
-		if context.GetSelectedLineIdx() > COMMIT_THRESHOLD && context.GetLimitCommits() {
+		threshold, hasThreshold := commitThreshold(self.c.UserConfig)
+		if hasThreshold && context.GetSelectedLineIdx() > threshold && context.GetLimitCommits() {
 			context.SetLimitCommits(false)
 			self.c.OnWorker(func(_ gocui.Task) {
 				if err := self.c.Refresh(types.RefreshOptions{Scope: []types.RefreshableView{types.COMMITS}}); err != nil {
@@ -58,7 +58,8 @@ func (self *SubCommitsController) GetOnRenderToMain() func() error {
 func (self *SubCommitsController) GetOnFocus() func(types.OnFocusOpts) error {
 	return func(types.OnFocusOpts) error {
 		context := self.context()
-		if context.GetSelectedLineIdx() > COMMIT_THRESHOLD && context.GetLimitCommits() {
+		threshold, hasThreshold := commitThreshold(self.c.UserConfig)
+		if hasThreshold && context.GetSelectedLineIdx() > threshold && context.GetLimitCommits() {
 			context.SetLimitCommits(false)
 			self.c.OnWorker(func(_ gocui.Task) {
 				if err := self.c.Refresh(types.RefreshOptions{Scope: []types.RefreshableView{types.SUB_COMMITS}}); err != nil {
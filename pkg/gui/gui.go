@@ -1,6 +1,7 @@
 package gui
 
 import (
+	"bytes"
 	goContext "context"
 	"fmt"
 	"io"
@@ -792,7 +793,7 @@ func (gui *Gui) runSubprocessWithSuspense(subprocess oscommands.ICmdObj) (bool,
 	gui.BackgroundRoutineMgr.PauseBackgroundRefreshes(true)
 	defer gui.BackgroundRoutineMgr.PauseBackgroundRefreshes(false)
 
-	cmdErr := gui.runSubprocess(subprocess)
+	cmdErr := gui.runSubprocess(subprocess, os.Stdout)
 
 	if err := gui.g.Resume(); err != nil {
 		return false, err
@@ -805,12 +806,34 @@ func (gui *Gui) runSubprocessWithSuspense(subprocess oscommands.ICmdObj) (bool,
 	return true, nil
 }
 
-func (gui *Gui) runSubprocess(cmdObj oscommands.ICmdObj) error { //nolint:unparam
+// returns what the command printed, along with its error
+func (gui *Gui) runSubprocessWithSuspenseAndOutput(subprocess oscommands.ICmdObj) (string, error) {
+	gui.Mutexes.SubprocessMutex.Lock()
+	defer gui.Mutexes.SubprocessMutex.Unlock()
+
+	if err := gui.g.Suspend(); err != nil {
+		return "", err
+	}
+
+	gui.BackgroundRoutineMgr.PauseBackgroundRefreshes(true)
+	defer gui.BackgroundRoutineMgr.PauseBackgroundRefreshes(false)
+
+	var output bytes.Buffer
+	cmdErr := gui.runSubprocess(subprocess, io.MultiWriter(os.Stdout, &output))
+
+	if err := gui.g.Resume(); err != nil {
+		return "", err
+	}
+
+	return output.String(), cmdErr
+}
+
+func (gui *Gui) runSubprocess(cmdObj oscommands.ICmdObj, output io.Writer) error { //nolint:unparam
 	gui.LogCommand(cmdObj.ToString(), true)
 
 	subprocess := cmdObj.GetCmd()
-	subprocess.Stdout = os.Stdout
-	subprocess.Stderr = os.Stdout
+	subprocess.Stdout = output
+	subprocess.Stderr = output
 	subprocess.Stdin = os.Stdin
 
 	fmt.Fprintf(os.Stdout, "\n%s\n\n", style.FgBlue.Sprint("+ "+strings.Join(subprocess.Args, " ")))
@@ -841,6 +864,8 @@ func (gui *Gui) loadNewRepo() error {
 		return err
 	}
 
+	gui.helpers.MergeAndRebase.WarnAboutUnfinishedOperation()
+
 	if err := gui.os.UpdateWindowTitle(); err != nil {
 		return err
 	}
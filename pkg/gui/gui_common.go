@@ -41,6 +41,10 @@ func (self *guiCommon) RunSubprocess(cmdObj oscommands.ICmdObj) (bool, error) {
 	return self.gui.runSubprocessWithSuspense(cmdObj)
 }
 
+func (self *guiCommon) RunSubprocessWithOutput(cmdObj oscommands.ICmdObj) (string, error) {
+	return self.gui.runSubprocessWithSuspenseAndOutput(cmdObj)
+}
+
 func (self *guiCommon) PushContext(context types.Context, opts ...types.OnFocusOpts) error {
 	return self.gui.State.ContextMgr.Push(context, opts...)
 }
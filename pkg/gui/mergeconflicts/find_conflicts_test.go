@@ -1,7 +1,6 @@
 package mergeconflicts
 
 import (
-	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -60,42 +59,3 @@ func TestDetermineLineType(t *testing.T) {
 		assert.EqualValues(t, s.expected, determineLineType(s.line))
 	}
 }
-
-func TestFindConflictsAux(t *testing.T) {
-	type scenario struct {
-		content  string
-		expected bool
-	}
-
-	scenarios := []scenario{
-		{
-			content:  "",
-			expected: false,
-		},
-		{
-			content:  "blah",
-			expected: false,
-		},
-		{
-			content:  ">>>>>>> ",
-			expected: true,
-		},
-		{
-			content:  "<<<<<<< ",
-			expected: true,
-		},
-		{
-			content:  " <<<<<<< ",
-			expected: false,
-		},
-		{
-			content:  "a\nb\nc\n<<<<<<< ",
-			expected: true,
-		},
-	}
-
-	for _, s := range scenarios {
-		reader := strings.NewReader(s.content)
-		assert.EqualValues(t, s.expected, fileHasConflictMarkersAux(reader))
-	}
-}
@@ -34,6 +34,13 @@ func (self *WaitingStatusHandle) Hide() {
 	self.statusManager.removeStatus(self.id)
 }
 
+// Changes the message of the status, e.g. to say how far the work has got. It
+// shows up the next time that the status is rendered.
+func (self *WaitingStatusHandle) SetMessage(message string) {
+	self.message = message
+	self.statusManager.setStatusMessage(self.id, message)
+}
+
 type appStatus struct {
 	message    string
 	statusType string
@@ -97,6 +104,17 @@ func (self *StatusManager) addStatus(message string, statusType string) int {
 	return id
 }
 
+func (self *StatusManager) setStatusMessage(id int, message string) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	for i := range self.statuses {
+		if self.statuses[i].id == id {
+			self.statuses[i].message = message
+		}
+	}
+}
+
 func (self *StatusManager) removeStatus(id int) {
 	self.mutex.Lock()
 	defer self.mutex.Unlock()
@@ -52,6 +52,9 @@ type IGuiCommon interface {
 	// returns true if command completed successfully
 	RunSubprocess(cmdObj oscommands.ICmdObj) (bool, error)
 	RunSubprocessAndRefresh(oscommands.ICmdObj) error
+	// like RunSubprocess, but also returns what the command printed, and leaves
+	// reporting its error to the caller
+	RunSubprocessWithOutput(cmdObj oscommands.ICmdObj) (string, error)
 
 	PushContext(context Context, opts ...OnFocusOpts) error
 	PopContext() error
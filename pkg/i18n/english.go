@@ -237,9 +237,17 @@ type TranslationSet struct {
 	Continue                            string
 	RebasingTitle                       string
 	RebasingFromBaseCommitTitle         string
+	RebaseDetachedHeadTitle             string
+	RebaseDetachedHeadWarning           string
+	RebaseChangesSubmodulesTitle        string
+	RebaseChangesSubmodulesWarning      string
+	UnfinishedOperationTitle            string
+	UnfinishedOperationWarning          string
 	SimpleRebase                        string
 	InteractiveRebase                   string
 	InteractiveRebaseTooltip            string
+	RebaseThenPush                      string
+	RebaseThenPushTooltip               string
 	ConfirmMerge                        string
 	FwdNoUpstream                       string
 	FwdNoLocalUpstream                  string
@@ -255,6 +263,8 @@ type TranslationSet struct {
 	CherryPickCopyRange                 string
 	PasteCommits                        string
 	SureCherryPick                      string
+	CancelCherryPick                    string
+	SureCancelCherryPick                string
 	CherryPick                          string
 	Donate                              string
 	AskQuestion                         string
@@ -306,7 +316,27 @@ type TranslationSet struct {
 	DiscardAddedFileChangesPrompt       string
 	DiscardDeletedFileChangesPrompt     string
 	DiscardNotSupportedForDirectory     string
+	RemoveFileFromHistory               string
+	RemoveFileFromHistoryTitle          string
+	RemoveFileFromHistoryPrompt         string
+	CanOnlyRemoveFromLocalCommits       string
+	RemoveFromHistoryNotSupportedForDir string
+	RemovingFileFromHistoryStatus       string
 	DisabledForGPG                      string
+	RebaseNeedsCleanWorkingTree         string
+	RewordBelowMergeNeedsNewerGit       string
+	RewordFixupNeedsNewerGit            string
+	RebaseInWorktreeStopped             string
+	RebaseTodoShaNotFound               string
+	RebaseExecFailed                    string
+	RebaseTimedOut                      string
+	RebaseBaseNotAncestor               string
+	RewordWithMessageNeedsDaemon        string
+	NoPreRebaseRef                      string
+	UndoLastRebaseDuringRebase          string
+	UndoLastRebaseDetachedHead          string
+	RebaseThenPushNoUpstream            string
+	InteractiveRebaseNeedsMergeBackend  string
 	CreateRepo                          string
 	BareRepo                            string
 	InitialBranch                       string
@@ -688,6 +718,11 @@ type Log struct {
 	CreateFileWithContent    string
 	AppendingLineToFile      string
 	EditRebaseFromBaseCommit string
+	EditCommitWithCheck      string
+	RemoveFileFromHistory    string
+	ExecOnPaths              string
+	RebaseUntilFirstFailure  string
+	MergeBackendInstead      string
 }
 
 type Actions struct {
@@ -699,12 +734,15 @@ type Actions struct {
 	DeleteBranch                      string
 	Merge                             string
 	RebaseBranch                      string
+	RebaseBranchThenPush              string
 	RenameBranch                      string
 	CreateBranch                      string
 	FastForwardBranch                 string
 	CherryPick                        string
+	CherryPickWithReword              string
 	CheckoutFile                      string
 	DiscardOldFileChange              string
+	RemoveFileFromHistory             string
 	SquashCommitDown                  string
 	FixupCommit                       string
 	RewordCommit                      string
@@ -760,6 +798,7 @@ type Actions struct {
 	MovePatchToSelectedCommit         string
 	MovePatchIntoIndex                string
 	MovePatchIntoNewCommit            string
+	UncommitCommits                   string
 	DeleteRemoteBranch                string
 	SetBranchUpstream                 string
 	AddRemote                         string
@@ -1070,9 +1109,17 @@ func EnglishTranslationSet() TranslationSet {
 		KeybindingsMenuSectionNavigation:    "Navigation",
 		RebasingTitle:                       "Rebase '{{.checkedOutBranch}}' onto '{{.ref}}'",
 		RebasingFromBaseCommitTitle:         "Rebase '{{.checkedOutBranch}}' from marked base onto '{{.ref}}'",
+		RebaseDetachedHeadTitle:             "Rebase detached HEAD",
+		RebaseDetachedHeadWarning:           "HEAD is detached, so the rebase will rewrite commits without moving any branch. The rebased commits will only be reachable from HEAD; create a branch afterwards if you want to keep them. Continue?",
+		RebaseChangesSubmodulesTitle:        "Rebase changes submodules",
+		RebaseChangesSubmodulesWarning:      "Some of the commits being rebased change submodules. The rebase won't check the submodules out again, so they may show up as modified afterwards until you update them (or set git.rebase.updateSubmodules to have lazygit do it). Continue?",
+		UnfinishedOperationTitle:            "Unfinished operation",
+		UnfinishedOperationWarning:          "Lazygit was closed in the middle of '{{.operation}}', before it could do its last step after the rebase was continued. That step won't be done now, so please check that the commits look the way you expect.",
 		SimpleRebase:                        "Simple rebase",
 		InteractiveRebase:                   "Interactive rebase",
 		InteractiveRebaseTooltip:            "Begin an interactive rebase with a break at the start, so you can update the TODO commits before continuing",
+		RebaseThenPush:                      "Rebase and force-push",
+		RebaseThenPushTooltip:               "Rebase, then push the branch to its upstream with --force-with-lease. If the rebase stops at conflicts, the push happens once you've resolved them and continued; aborting the rebase cancels it.",
 		ConfirmMerge:                        "Are you sure you want to merge '{{.selectedBranch}}' into '{{.checkedOutBranch}}'?",
 		FwdNoUpstream:                       "Cannot fast-forward a branch with no upstream",
 		FwdNoLocalUpstream:                  "Cannot fast-forward a branch whose remote is not registered locally",
@@ -1088,6 +1135,8 @@ func EnglishTranslationSet() TranslationSet {
 		CherryPickCopyRange:                 "Copy commit range (cherry-pick)",
 		PasteCommits:                        "Paste commits (cherry-pick)",
 		SureCherryPick:                      "Are you sure you want to cherry-pick the copied commits onto this branch?",
+		CancelCherryPick:                    "Cancel cherry-pick",
+		SureCancelCherryPick:                "The copied commits are still being cherry-picked. Do you want to stop and undo the commits that were already picked?",
 		CherryPick:                          "Cherry-pick",
 		Donate:                              "Donate",
 		AskQuestion:                         "Ask Question",
@@ -1139,7 +1188,27 @@ func EnglishTranslationSet() TranslationSet {
 		DiscardAddedFileChangesPrompt:       "Are you sure you want to discard this commit's changes to this file? The file was added in this commit, so it will be deleted again.",
 		DiscardDeletedFileChangesPrompt:     "Are you sure you want to discard this commit's changes to this file? The file was deleted in this commit, so it will reappear.",
 		DiscardNotSupportedForDirectory:     "Discarding changes is not supported for entire directories. Please use a custom patch for this.",
+		RemoveFileFromHistory:               "Remove this file from every commit that is only on the checked-out branch",
+		RemoveFileFromHistoryTitle:          "Remove file from history",
+		RemoveFileFromHistoryPrompt:         "Are you sure you want to remove '{{.fileName}}' from every commit that is only on branch '{{.branchName}}'? Commits that are also on other branches are left unchanged, so the file stays in those. This rewrites the branch's history: if it has been shared (e.g. pushed), everyone who has it will need to reset onto the new version, and anything secret in the file should be considered leaked anyway. The file will also be removed from your working tree.",
+		CanOnlyRemoveFromLocalCommits:       "Files can only be removed from the history of the checked-out branch",
+		RemoveFromHistoryNotSupportedForDir: "Removing entire directories from history is not supported. Please remove the files one by one.",
+		RemovingFileFromHistoryStatus:       "Removing file from history",
 		DisabledForGPG:                      "Feature not available for users using GPG",
+		RebaseNeedsCleanWorkingTree:         "You have uncommitted changes to tracked files. Commit or stash them before rebasing, or set git.rebase.autoStash to true",
+		RewordBelowMergeNeedsNewerGit:       "Rewording a commit below a merge commit requires git 2.22 or later, as older versions would flatten the merge",
+		RewordFixupNeedsNewerGit:            "Creating an amend! commit requires git 2.32 or later, as older versions don't squash it when autosquashing",
+		RebaseInWorktreeStopped:             "The rebase stopped before it was done, so it was given up and your branch is unchanged. To resolve conflicts, rebase with git.rebase.useWorktree turned off",
+		RebaseTodoShaNotFound:               "Commit %s isn't part of the rebase, probably because the commits have changed since they were loaded. Refresh and try again",
+		RebaseExecFailed:                    "The rebase is paused at commit {{.sha}} because '{{.command}}' failed. Fix the problem and continue the rebase. It printed:\n\n{{.output}}",
+		RebaseTimedOut:                      "The rebase didn't finish within git.rebase.timeoutSeconds (%d), so it was given up and your branch is unchanged",
+		RebaseBaseNotAncestor:               "Commit %s isn't an ancestor of HEAD, so it can't be used as the base of a rebase",
+		RewordWithMessageNeedsDaemon:        "Rewording a commit with this message needs lazygit to be git's editor, which git.rebase.useDaemon turns off",
+		NoPreRebaseRef:                      "There's no ref marking where %s was before a rebase, so the rebase can't be undone (see git.rebase.preRebaseRefs)",
+		UndoLastRebaseDuringRebase:          "A rebase can't be undone while another one is in progress",
+		UndoLastRebaseDetachedHead:          "A rebase can only be undone with a branch checked out",
+		RebaseThenPushNoUpstream:            "The checked-out branch doesn't track a branch on a remote, so there's nowhere to push it to after the rebase",
+		InteractiveRebaseNeedsMergeBackend:  "Interactive rebases can only use the merge backend, not the apply backend",
 		CreateRepo:                          "Not in a git repository. Create a new git repository? (y/n): ",
 		BareRepo:                            "You've attempted to open Lazygit in a bare repo but Lazygit does not yet support bare repos. Open most recent repo? (y/n) ",
 		InitialBranch:                       "Branch name? (leave empty for git's default): ",
@@ -1487,11 +1556,14 @@ func EnglishTranslationSet() TranslationSet {
 			DeleteBranch:                      "Delete branch",
 			Merge:                             "Merge",
 			RebaseBranch:                      "Rebase branch",
+			RebaseBranchThenPush:              "Rebase branch and push",
 			RenameBranch:                      "Rename branch",
 			CreateBranch:                      "Create branch",
 			CherryPick:                        "(Cherry-pick) paste commits",
+			CherryPickWithReword:              "Cherry-pick and reword commit",
 			CheckoutFile:                      "Checkout file",
 			DiscardOldFileChange:              "Discard old file change",
+			RemoveFileFromHistory:             "Remove file from history",
 			SquashCommitDown:                  "Squash commit down",
 			FixupCommit:                       "Fixup commit",
 			RewordCommit:                      "Reword commit",
@@ -1548,6 +1620,7 @@ func EnglishTranslationSet() TranslationSet {
 			MovePatchToSelectedCommit:         "Move patch to selected commit",
 			MovePatchIntoIndex:                "Move patch into index",
 			MovePatchIntoNewCommit:            "Move patch into new commit",
+			UncommitCommits:                   "Uncommit commits",
 			DeleteRemoteBranch:                "Delete remote branch",
 			SetBranchUpstream:                 "Set branch upstream",
 			AddRemote:                         "Add remote",
@@ -1622,6 +1695,11 @@ func EnglishTranslationSet() TranslationSet {
 			CreateFileWithContent:    "Creating file '{{.path}}'",
 			AppendingLineToFile:      "Appending '{{.line}}' to file '{{.filename}}'",
 			EditRebaseFromBaseCommit: "Beginning interactive rebase from '{{.baseCommit}}' onto '{{.targetBranchName}}",
+			EditCommitWithCheck:      "Editing commit {{.shortSha}}, then running '{{.checkCmd}}'",
+			RemoveFileFromHistory:    "Removing '{{.fileName}}' from the commits that are only on branch '{{.branchName}}'",
+			ExecOnPaths:              "Rebasing onto {{.baseSha}}, running '{{.execCmd}}' after each commit touching {{.paths}}",
+			RebaseUntilFirstFailure:  "Rebasing onto '{{.ref}}', running '{{.checkCmd}}' after each commit",
+			MergeBackendInstead:      "git.rebase.backend is 'apply', but interactive rebases can only use the merge backend, so this rebase uses that",
 		},
 	}
 }
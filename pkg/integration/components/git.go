@@ -24,6 +24,61 @@ func (self *Git) RemoteTagDeleted(ref string, tagName string) *Git {
 	})
 }
 
+func (self *Git) CommitMessage(ref string, expectedMessage string) *Git {
+	return self.expect([]string{"git", "log", "-1", "--format=%B", ref}, func(output string) (bool, string) {
+		return output == expectedMessage, fmt.Sprintf("Expected message of commit %s to be '%s', but got '%s'", ref, expectedMessage, output)
+	})
+}
+
+// CommitSignatureStatus checks the signature status that git reports for the
+// commit (the %G? placeholder of git log), e.g. "G" for a good signature or "N"
+// for no signature
+func (self *Git) CommitSignatureStatus(ref string, expectedStatus string) *Git {
+	return self.expect([]string{"git", "log", "-1", "--format=%G?", ref}, func(output string) (bool, string) {
+		return output == expectedStatus, fmt.Sprintf("Expected signature status of commit %s to be '%s', but got '%s'", ref, expectedStatus, output)
+	})
+}
+
+// CommitterDate checks the committer date of the commit in git's raw format,
+// e.g. "1600000000 +0000"
+func (self *Git) CommitterDate(ref string, expectedDate string) *Git {
+	return self.expect([]string{"git", "log", "-1", "--format=%cd", "--date=raw", ref}, func(output string) (bool, string) {
+		return output == expectedDate, fmt.Sprintf("Expected committer date of commit %s to be '%s', but got '%s'", ref, expectedDate, output)
+	})
+}
+
+// RefPresent checks whether the given fully qualified ref exists
+func (self *Git) RefPresent(ref string, expected bool) *Git {
+	return self.expect([]string{"git", "for-each-ref", ref}, func(output string) (bool, string) {
+		return (output != "") == expected, fmt.Sprintf("Expected ref %s to exist: %t, but got '%s'", ref, expected, output)
+	})
+}
+
+// Note checks the note that the given notes ref (e.g. "lazygit" for
+// refs/notes/lazygit) has for the commit. A commit without a note counts as
+// having an empty one.
+func (self *Git) Note(notesRef string, ref string, matcher *TextMatcher) *Git {
+	self.matchString(matcher, fmt.Sprintf("Unexpected note of commit %s in %s.", ref, notesRef), func() string {
+		output, err := self.shell.runCommandWithOutput([]string{"git", "notes", "--ref=" + notesRef, "show", ref})
+		if err != nil {
+			return ""
+		}
+		return strings.TrimSpace(output)
+	})
+
+	return self
+}
+
+// IsAncestor checks that the first commit is an ancestor of the second one
+func (self *Git) IsAncestor(ancestor string, descendant string) *Git {
+	self.assertWithRetries(func() (bool, string) {
+		_, err := self.shell.runCommandWithOutput([]string{"git", "merge-base", "--is-ancestor", ancestor, descendant})
+		return err == nil, fmt.Sprintf("Expected %s to be an ancestor of %s", ancestor, descendant)
+	})
+
+	return self
+}
+
 func (self *Git) assert(cmdArgs []string, expected string) *Git {
 	self.expect(cmdArgs, func(output string) (bool, string) {
 		return output == expected, fmt.Sprintf("Expected current branch name to be '%s', but got '%s'", expected, output)
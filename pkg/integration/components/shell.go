@@ -469,6 +469,56 @@ func (self *Shell) Chdir(path string) *Shell {
 	return self
 }
 
+// A stand-in for gpg that signs anything without needing a key, and reports
+// every signature it is asked to verify as good
+const fakeGpgScript = `#!/bin/sh
+for arg in "$@"; do
+	if [ "$arg" = "--verify" ]; then
+		echo "[GNUPG:] NEWSIG"
+		echo "[GNUPG:] GOODSIG 0123456789ABCDEF Fake Signer <fake@example.com>"
+		echo "[GNUPG:] VALIDSIG 0123456789ABCDEF0123456789ABCDEF01234567 2024-01-01 1704067200 0 4 0 1 10 00 0123456789ABCDEF0123456789ABCDEF01234567"
+		echo "[GNUPG:] TRUST_ULTIMATE 0 pgp"
+		exit 0
+	fi
+done
+cat > /dev/null
+echo "[GNUPG:] SIG_CREATED D 1 10 00 1704067200 0123456789ABCDEF0123456789ABCDEF01234567" >&2
+printf -- '-----BEGIN PGP SIGNATURE-----\n\nZmFrZQ==\n-----END PGP SIGNATURE-----\n'
+`
+
+// A stand-in for gpg that fails to sign, like gpg does when the key is missing
+const failingGpgScript = `#!/bin/sh
+cat > /dev/null
+echo "gpg: signing failed: No secret key" >&2
+exit 2
+`
+
+// UseFakeGpg turns on commit signing, with gpg.program pointing at a script
+// that signs without a real key. Commits made from then on (including the
+// ones that lazygit rebuilds in a rebase) are signed, so lazygit takes the
+// code paths it takes for gpg users. Use Git().CommitSignatureStatus to check
+// that a commit was signed.
+func (self *Shell) UseFakeGpg() *Shell {
+	return self.useGpgProgram(fakeGpgScript)
+}
+
+// UseFailingFakeGpg is like UseFakeGpg, except that signing always fails, so
+// that tests can check how lazygit reports the failure
+func (self *Shell) UseFailingFakeGpg() *Shell {
+	return self.useGpgProgram(failingGpgScript)
+}
+
+func (self *Shell) useGpgProgram(script string) *Shell {
+	// Keeping the script in the git dir means it doesn't show up as a file
+	self.CreateFile(".git/fake-gpg", script)
+	self.MakeExecutable(".git/fake-gpg")
+
+	return self.
+		SetConfig("commit.gpgsign", "true").
+		SetConfig("user.signingkey", "0123456789ABCDEF").
+		SetConfig("gpg.program", filepath.Join(self.dir, ".git", "fake-gpg"))
+}
+
 func (self *Shell) SetAuthor(authorName string, authorEmail string) *Shell {
 	self.RunCommand([]string{"git", "config", "--local", "user.name", authorName})
 	self.RunCommand([]string{"git", "config", "--local", "user.email", authorEmail})
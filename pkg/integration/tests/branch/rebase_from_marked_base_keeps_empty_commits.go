@@ -0,0 +1,74 @@
+package branch
+
+import (
+	"github.com/jesseduffield/lazygit/pkg/config"
+	. "github.com/jesseduffield/lazygit/pkg/integration/components"
+)
+
+var RebaseFromMarkedBaseKeepsEmptyCommits = NewIntegrationTest(NewIntegrationTestArgs{
+	Description:  "Rebase onto another branch from a marked base commit, keeping both a deliberately empty commit and one that becomes empty",
+	ExtraCmdArgs: []string{},
+	Skip:         false,
+	SetupConfig:  func(config *config.AppConfig) {},
+	SetupRepo: func(shell *Shell) {
+		shell.
+			NewBranch("base-branch").
+			CreateFileAndAdd("file", "one").
+			Commit("one").
+			NewBranch("active-branch").
+			CreateFileAndAdd("active-file", "active one").
+			Commit("active one").
+			EmptyCommit("marker").
+			CreateFileAndAdd("shared-file", "shared").
+			Commit("shared change").
+			CreateFileAndAdd("active-file", "active two").
+			Commit("active two").
+			Checkout("base-branch").
+			NewBranch("target-branch").
+			CreateFileAndAdd("target-file", "target one").
+			Commit("target one").
+			// not the same patch as the active branch's commit, so git doesn't
+			// skip that as already applied; it just becomes empty
+			CreateFileAndAdd("shared-file", "shared").
+			CreateFileAndAdd("other-file", "other").
+			Commit("shared change on target").
+			Checkout("active-branch")
+	},
+	Run: func(t *TestDriver, keys config.KeybindingConfig) {
+		t.Views().Commits().
+			Focus().
+			Lines(
+				Contains("active two"),
+				Contains("shared change"),
+				Contains("marker"),
+				Contains("active one"),
+				Contains("one"),
+			).
+			NavigateToLine(Contains("active one")).
+			Press(keys.Commits.MarkCommitAsBaseForRebase)
+
+		t.Views().Branches().
+			Focus().
+			Lines(
+				Contains("active-branch"),
+				Contains("target-branch"),
+				Contains("base-branch"),
+			).
+			SelectNextItem().
+			Press(keys.Branches.RebaseBranch)
+
+		t.ExpectPopup().Menu().
+			Title(Equals("Rebase 'active-branch' from marked base onto 'target-branch'")).
+			Select(Contains("Simple rebase")).
+			Confirm()
+
+		t.Views().Commits().Lines(
+			Contains("active two"),
+			Contains("shared change"),
+			Contains("marker"),
+			Contains("shared change on target"),
+			Contains("target one"),
+			Contains("one"),
+		)
+	},
+})
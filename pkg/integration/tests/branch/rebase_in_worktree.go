@@ -0,0 +1,72 @@
+package branch
+
+import (
+	"github.com/jesseduffield/lazygit/pkg/config"
+	. "github.com/jesseduffield/lazygit/pkg/integration/components"
+)
+
+var RebaseInWorktree = NewIntegrationTest(NewIntegrationTestArgs{
+	Description:  "Rebase onto another branch in a temporary worktree, leaving uncommitted changes in the main worktree alone without stashing them",
+	ExtraCmdArgs: []string{},
+	Skip:         false,
+	SetupConfig: func(config *config.AppConfig) {
+		config.UserConfig.Git.Rebase.UseWorktree = true
+		config.UserConfig.Git.Rebase.AutoStash = false
+	},
+	SetupRepo: func(shell *Shell) {
+		shell.
+			CreateFileAndAdd("staged-file", "original\n").
+			CreateFileAndAdd("unstaged-file", "original\n").
+			Commit("base").
+			NewBranch("my-branch").
+			CreateFileAndAdd("branch-file", "branch\n").
+			Commit("branch commit").
+			Checkout("master").
+			CreateFileAndAdd("master-file", "master\n").
+			Commit("master commit").
+			Checkout("my-branch").
+			UpdateFileAndAdd("staged-file", "staged change\n").
+			UpdateFile("unstaged-file", "unstaged change\n").
+			CreateFile("untracked-file", "untracked\n")
+	},
+	Run: func(t *TestDriver, keys config.KeybindingConfig) {
+		t.Views().Branches().
+			Focus().
+			Lines(
+				Contains("my-branch").IsSelected(),
+				Contains("master"),
+			).
+			SelectNextItem().
+			Press(keys.Branches.RebaseBranch)
+
+		t.ExpectPopup().Menu().
+			Title(Equals("Rebase 'my-branch' onto 'master'")).
+			Select(Contains("Simple rebase")).
+			Confirm()
+
+		t.Views().Commits().Lines(
+			Contains("branch commit"),
+			Contains("master commit"),
+			Contains("base"),
+		)
+
+		t.Views().Files().
+			Lines(
+				Contains("M  staged-file"),
+				Contains(" M unstaged-file"),
+				Contains("?? untracked-file"),
+			)
+
+		t.Views().Stash().IsEmpty()
+
+		t.Views().Worktrees().
+			Focus().
+			Lines(
+				Contains("repo (main)"),
+			)
+
+		t.FileSystem().FileContent("master-file", Equals("master\n"))
+		t.FileSystem().FileContent("staged-file", Equals("staged change\n"))
+		t.FileSystem().FileContent("unstaged-file", Equals("unstaged change\n"))
+	},
+})
@@ -0,0 +1,73 @@
+package branch
+
+import (
+	"github.com/jesseduffield/lazygit/pkg/config"
+	. "github.com/jesseduffield/lazygit/pkg/integration/components"
+	"github.com/jesseduffield/lazygit/pkg/integration/tests/shared"
+)
+
+var RebaseThenPush = NewIntegrationTest(NewIntegrationTestArgs{
+	Description:  "Rebase onto another branch and force-push, where the push waits until the conflicts have been resolved and the rebase continued",
+	ExtraCmdArgs: []string{},
+	Skip:         false,
+	SetupConfig:  func(config *config.AppConfig) {},
+	SetupRepo: func(shell *Shell) {
+		shared.MergeConflictsSetup(shell)
+		shell.CloneIntoRemote("origin")
+		shell.SetBranchUpstream("first-change-branch", "origin/first-change-branch")
+	},
+	Run: func(t *TestDriver, keys config.KeybindingConfig) {
+		t.Views().Branches().
+			Focus().
+			Lines(
+				Contains("first-change-branch").Contains("✓").IsSelected(),
+				Contains("second-change-branch"),
+				Contains("original-branch"),
+			).
+			SelectNextItem().
+			Press(keys.Branches.RebaseBranch)
+
+		t.ExpectPopup().Menu().
+			Title(Equals("Rebase 'first-change-branch' onto 'second-change-branch'")).
+			Select(Contains("Rebase and force-push")).
+			Confirm()
+
+		t.Common().AcknowledgeConflicts()
+
+		t.Views().Files().IsFocused().
+			SelectedLine(MatchesRegexp("UU.*file")).
+			PressEnter()
+
+		t.Views().MergeConflicts().
+			IsFocused().
+			PressPrimaryAction()
+
+		t.Common().ContinueOnConflictsResolved()
+
+		t.Views().Information().Content(DoesNotContain("Rebasing"))
+
+		t.Views().Branches().
+			Lines(
+				Contains("first-change-branch").Contains("✓"),
+				Contains("second-change-branch"),
+				Contains("original-branch"),
+			)
+
+		t.Views().Remotes().Focus().
+			Lines(Contains("origin")).
+			PressEnter()
+
+		t.Views().RemoteBranches().IsFocused().
+			NavigateToLine(Contains("first-change-branch")).
+			PressEnter()
+
+		// resolving the conflict in favour of the second change made "first
+		// change" empty, so the rebase dropped it
+		t.Views().SubCommits().IsFocused().
+			TopLines(
+				Contains("second-change-branch unrelated change"),
+				Contains("second change"),
+				Contains("original"),
+			)
+	},
+})
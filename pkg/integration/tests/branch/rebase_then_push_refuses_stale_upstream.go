@@ -0,0 +1,69 @@
+package branch
+
+import (
+	"github.com/jesseduffield/lazygit/pkg/config"
+	. "github.com/jesseduffield/lazygit/pkg/integration/components"
+)
+
+var RebaseThenPushRefusesStaleUpstream = NewIntegrationTest(NewIntegrationTestArgs{
+	Description:  "Rebase and force-push a branch whose upstream has moved on since it was last fetched, where the push is refused rather than overwriting the new commits",
+	ExtraCmdArgs: []string{},
+	Skip:         false,
+	SetupConfig:  func(config *config.AppConfig) {},
+	SetupRepo: func(shell *Shell) {
+		shell.EmptyCommit("base")
+		shell.CloneIntoRemote("origin")
+		shell.NewBranch("feature")
+		shell.EmptyCommit("on feature")
+		shell.PushBranch("origin", "feature")
+		shell.Checkout("master")
+		shell.EmptyCommit("on master")
+		shell.Checkout("feature")
+
+		// someone else pushes to the branch after we last fetched
+		shell.RunShellCommand(`git -C ../origin update-ref refs/heads/feature $(git -C ../origin commit-tree -p feature -m "pushed by someone else" "feature^{tree}")`)
+	},
+	Run: func(t *TestDriver, keys config.KeybindingConfig) {
+		t.Views().Branches().
+			Focus().
+			Lines(
+				Contains("feature").Contains("✓").IsSelected(),
+				Contains("master"),
+			).
+			SelectNextItem().
+			Press(keys.Branches.RebaseBranch)
+
+		t.ExpectPopup().Menu().
+			Title(Equals("Rebase 'feature' onto 'master'")).
+			Select(Contains("Rebase and force-push")).
+			Confirm()
+
+		t.ExpectPopup().Alert().
+			Title(Equals("Error")).
+			Content(Contains("stale info")).
+			Confirm()
+
+		// the rebase itself went ahead
+		t.Views().Commits().
+			Lines(
+				Contains("on feature"),
+				Contains("on master"),
+				Contains("base"),
+			)
+
+		t.Views().Remotes().Focus().
+			Lines(Contains("origin")).
+			PressEnter()
+
+		t.Views().RemoteBranches().IsFocused().
+			NavigateToLine(Contains("feature")).
+			PressEnter()
+
+		// nothing was pushed, so the remote branch is where we last saw it
+		t.Views().SubCommits().IsFocused().
+			Lines(
+				Contains("on feature"),
+				Contains("base"),
+			)
+	},
+})
@@ -0,0 +1,67 @@
+package branch
+
+import (
+	"github.com/jesseduffield/lazygit/pkg/config"
+	. "github.com/jesseduffield/lazygit/pkg/integration/components"
+)
+
+var RebaseThenPushToDifferentlyNamedUpstream = NewIntegrationTest(NewIntegrationTestArgs{
+	Description:  "Rebase a branch whose upstream has a different name and force-push it, which pushes to the upstream rather than to a remote branch named after the local one",
+	ExtraCmdArgs: []string{},
+	Skip:         false,
+	SetupConfig:  func(config *config.AppConfig) {},
+	SetupRepo: func(shell *Shell) {
+		shell.EmptyCommit("base")
+		shell.CloneIntoRemote("origin")
+		shell.NewBranch("feature")
+		shell.EmptyCommit("on feature")
+		shell.RunCommand([]string{"git", "push", "origin", "feature:other-name"})
+		shell.SetBranchUpstream("feature", "origin/other-name")
+		shell.Checkout("master")
+		shell.EmptyCommit("on master")
+		shell.Checkout("feature")
+	},
+	Run: func(t *TestDriver, keys config.KeybindingConfig) {
+		t.Views().Branches().
+			Focus().
+			Lines(
+				Contains("feature").Contains("✓").IsSelected(),
+				Contains("master"),
+			).
+			SelectNextItem().
+			Press(keys.Branches.RebaseBranch)
+
+		t.ExpectPopup().Menu().
+			Title(Equals("Rebase 'feature' onto 'master'")).
+			Select(Contains("Rebase and force-push")).
+			Confirm()
+
+		t.Views().Information().Content(DoesNotContain("Rebasing"))
+
+		t.Views().Branches().
+			Lines(
+				Contains("feature").Contains("✓"),
+				Contains("master"),
+			)
+
+		t.Views().Remotes().Focus().
+			Lines(Contains("origin")).
+			PressEnter()
+
+		// no branch named 'feature' was created on the remote
+		t.Views().RemoteBranches().IsFocused().
+			Lines(
+				Contains("master"),
+				Contains("other-name"),
+			).
+			NavigateToLine(Contains("other-name")).
+			PressEnter()
+
+		t.Views().SubCommits().IsFocused().
+			Lines(
+				Contains("on feature"),
+				Contains("on master"),
+				Contains("base"),
+			)
+	},
+})
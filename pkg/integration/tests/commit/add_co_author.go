@@ -34,7 +34,27 @@ var AddCoAuthor = NewIntegrationTest(NewIntegrationTestArgs{
 
 		t.Views().Main().ContainsLines(
 			Contains("initial commit"),
+			Equals("    "),
 			Contains("Co-authored-by: John Smith <jsmith@gmail.com>"),
 		)
+
+		t.Git().CommitMessage("HEAD", "initial commit\n\nCo-authored-by: John Smith <jsmith@gmail.com>")
+
+		// adding the same co-author again leaves the commit alone
+		t.Views().Commits().
+			Press(keys.Commits.ResetCommitAuthor).
+			Tap(func() {
+				t.ExpectPopup().Menu().
+					Title(Equals("Amend commit attribute")).
+					Select(Contains("Add co-author")).
+					Confirm()
+
+				t.ExpectPopup().Prompt().
+					Title(Contains("Add co-author")).
+					Type("John Smith <JSmith@gmail.com>").
+					Confirm()
+			})
+
+		t.Git().CommitMessage("HEAD", "initial commit\n\nCo-authored-by: John Smith <jsmith@gmail.com>")
 	},
 })
@@ -0,0 +1,111 @@
+package commit
+
+import (
+	"github.com/jesseduffield/lazygit/pkg/config"
+	. "github.com/jesseduffield/lazygit/pkg/integration/components"
+)
+
+var RemoveFileFromHistory = NewIntegrationTest(NewIntegrationTestArgs{
+	Description:  "Remove a file from the commits that are only on the branch, keeping commits that end up empty and leaving shared commits alone",
+	ExtraCmdArgs: []string{},
+	Skip:         false,
+	SetupConfig:  func(config *config.AppConfig) {},
+	SetupRepo: func(shell *Shell) {
+		shell.CreateFileAndAdd("file0", "file0")
+		shell.CreateFileAndAdd("secret", "password")
+		shell.Commit("first commit")
+		shell.NewBranch("other")
+		shell.Checkout("master")
+
+		shell.CreateFileAndAdd("file1", "file1")
+		shell.UpdateFileAndAdd("secret", "another password")
+		shell.Commit("update secret")
+
+		shell.UpdateFileAndAdd("secret", "yet another password")
+		shell.Commit("change secret again")
+
+		shell.EmptyCommit("empty on purpose")
+
+		shell.CreateFileAndAdd("file2", "file2")
+		shell.Commit("last commit")
+	},
+	Run: func(t *TestDriver, keys config.KeybindingConfig) {
+		t.Views().Commits().
+			Focus().
+			Lines(
+				Contains("last commit").IsSelected(),
+				Contains("empty on purpose"),
+				Contains("change secret again"),
+				Contains("update secret"),
+				Contains("first commit"),
+			).
+			NavigateToLine(Contains("change secret again")).
+			PressEnter()
+
+		t.Views().CommitFiles().
+			IsFocused().
+			Lines(
+				Contains("secret").IsSelected(),
+			).
+			Press(keys.CommitFiles.RemoveFileFromHistory)
+
+		t.ExpectPopup().Confirmation().
+			Title(Equals("Remove file from history")).
+			Content(Contains("Are you sure you want to remove 'secret' from every commit that is only on branch 'master'? Commits that are also on other branches are left unchanged")).
+			Confirm()
+
+		t.Views().Commits().
+			Focus().
+			Lines(
+				Contains("last commit"),
+				Contains("empty on purpose"),
+				Contains("change secret again").IsSelected(),
+				Contains("update secret"),
+				Contains("first commit"),
+			).
+			PressEnter()
+
+		// the commit only touched the file, so it's empty now
+		t.Views().CommitFiles().
+			IsFocused().
+			Lines(
+				Contains("(none)"),
+			).
+			PressEscape()
+
+		// the first commit to drop the file is the first one that isn't
+		// shared with the other branch
+		t.Views().Commits().
+			IsFocused().
+			NavigateToLine(Contains("update secret")).
+			PressEnter()
+
+		t.Views().CommitFiles().
+			IsFocused().
+			Lines(
+				Contains("file1").IsSelected(),
+				Contains("D").Contains("secret"),
+			).
+			PressEscape()
+
+		t.Views().Commits().
+			IsFocused().
+			NavigateToLine(Contains("first commit")).
+			PressEnter()
+
+		t.Views().CommitFiles().
+			IsFocused().
+			Lines(
+				Contains("file0").IsSelected(),
+				Contains("secret"),
+			)
+
+		t.FileSystem().PathNotPresent("secret")
+
+		// the shared commit wasn't rewritten, and no backup of the old
+		// history is kept around
+		t.Git().
+			IsAncestor("other", "master").
+			RefPresent("refs/original/refs/heads/master", false)
+	},
+})
@@ -0,0 +1,45 @@
+package commit
+
+import (
+	"github.com/jesseduffield/lazygit/pkg/config"
+	. "github.com/jesseduffield/lazygit/pkg/integration/components"
+)
+
+var RewordKeepsCommentLines = NewIntegrationTest(NewIntegrationTestArgs{
+	Description:  "Reword a commit with the verbatim cleanup mode, which keeps lines that look like comments",
+	ExtraCmdArgs: []string{},
+	Skip:         false,
+	SetupConfig: func(config *config.AppConfig) {
+		config.UserConfig.Git.Commit.RewordCleanup = "verbatim"
+	},
+	SetupRepo: func(shell *Shell) {
+		shell.CreateNCommits(2)
+	},
+	Run: func(t *TestDriver, keys config.KeybindingConfig) {
+		t.Views().Commits().
+			Focus().
+			Lines(
+				Contains("commit 02").IsSelected(),
+				Contains("commit 01"),
+			).
+			Press(keys.Commits.RenameCommit)
+
+		t.ExpectPopup().CommitMessagePanel().
+			Clear().
+			Type("#123 new subject").
+			SwitchToDescription().
+			Type("# not a comment").
+			AddNewline().
+			Type("the body").
+			SwitchToSummary().
+			Confirm()
+
+		t.Views().Commits().
+			Lines(
+				Contains("#123 new subject").IsSelected(),
+				Contains("commit 01"),
+			)
+
+		t.Git().CommitMessage("HEAD", "#123 new subject\n\n# not a comment\nthe body")
+	},
+})
@@ -0,0 +1,48 @@
+package commit
+
+import (
+	"github.com/jesseduffield/lazygit/pkg/config"
+	. "github.com/jesseduffield/lazygit/pkg/integration/components"
+)
+
+var RewordStripsCommentLines = NewIntegrationTest(NewIntegrationTestArgs{
+	Description:  "Reword a commit below the head with the strip cleanup mode, which removes comment lines from the message",
+	ExtraCmdArgs: []string{},
+	Skip:         false,
+	SetupConfig: func(config *config.AppConfig) {
+		config.UserConfig.Git.Commit.RewordCleanup = "strip"
+	},
+	SetupRepo: func(shell *Shell) {
+		shell.CreateNCommits(3)
+	},
+	Run: func(t *TestDriver, keys config.KeybindingConfig) {
+		t.Views().Commits().
+			Focus().
+			Lines(
+				Contains("commit 03").IsSelected(),
+				Contains("commit 02"),
+				Contains("commit 01"),
+			).
+			NavigateToLine(Contains("commit 02")).
+			Press(keys.Commits.RenameCommit)
+
+		t.ExpectPopup().CommitMessagePanel().
+			Clear().
+			Type("new subject").
+			SwitchToDescription().
+			Type("# On branch master").
+			AddNewline().
+			Type("the body").
+			SwitchToSummary().
+			Confirm()
+
+		t.Views().Commits().
+			Lines(
+				Contains("commit 03"),
+				Contains("new subject").IsSelected(),
+				Contains("commit 01"),
+			)
+
+		t.Git().CommitMessage("HEAD~1", "new subject\n\nthe body")
+	},
+})
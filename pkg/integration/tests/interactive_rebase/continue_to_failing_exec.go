@@ -0,0 +1,45 @@
+package interactive_rebase
+
+import (
+	"github.com/jesseduffield/lazygit/pkg/config"
+	. "github.com/jesseduffield/lazygit/pkg/integration/components"
+)
+
+var ContinueToFailingExec = NewIntegrationTest(NewIntegrationTestArgs{
+	Description:  "Continue a rebase that then stops because an exec fails, and see what the exec printed",
+	ExtraCmdArgs: []string{},
+	Skip:         false,
+	SetupConfig:  func(config *config.AppConfig) {},
+	SetupRepo: func(shell *Shell) {
+		shell.CreateFileAndAdd("a", "a")
+		shell.Commit("base")
+		shell.CreateFileAndAdd("b", "b")
+		shell.Commit("add b")
+		shell.CreateFileAndAdd("c", "c")
+		shell.Commit("add c")
+
+		// pause the rebase before anything has been done, with a check after
+		// each commit that only the last one fails
+		shell.RunShellCommand(`GIT_SEQUENCE_EDITOR="sed -i.bak '1i break'" git rebase -i -x "echo checking; if test -f c; then echo 'c is not allowed' >&2; exit 1; fi" HEAD~2`)
+	},
+	Run: func(t *TestDriver, keys config.KeybindingConfig) {
+		t.Views().Commits().Focus()
+
+		t.Common().ContinueRebase()
+
+		t.ExpectPopup().Alert().
+			Title(Equals("Error")).
+			Content(
+				Contains("because 'echo checking; if test -f c; then echo 'c is not allowed' >&2; exit 1; fi' failed").
+					Contains("It printed:\n\nchecking\nc is not allowed"),
+			).
+			Confirm()
+
+		t.Views().Commits().
+			TopLines(
+				Contains("add c"),
+			)
+
+		t.Views().Information().Content(Contains("Rebasing"))
+	},
+})
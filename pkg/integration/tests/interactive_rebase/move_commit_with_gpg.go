@@ -0,0 +1,37 @@
+package interactive_rebase
+
+import (
+	"github.com/jesseduffield/lazygit/pkg/config"
+	. "github.com/jesseduffield/lazygit/pkg/integration/components"
+)
+
+var MoveCommitWithGpg = NewIntegrationTest(NewIntegrationTestArgs{
+	Description:  "Move a commit down with commit signing turned on, and check that the rebuilt commits are signed",
+	ExtraCmdArgs: []string{},
+	Skip:         false,
+	SetupConfig:  func(config *config.AppConfig) {},
+	SetupRepo: func(shell *Shell) {
+		shell.
+			UseFakeGpg().
+			CreateNCommits(3)
+	},
+	Run: func(t *TestDriver, keys config.KeybindingConfig) {
+		t.Views().Commits().
+			Focus().
+			Lines(
+				Contains("commit 03").IsSelected(),
+				Contains("commit 02"),
+				Contains("commit 01"),
+			).
+			Press(keys.Commits.MoveDownCommit).
+			Lines(
+				Contains("commit 02"),
+				Contains("commit 03").IsSelected(),
+				Contains("commit 01"),
+			)
+
+		t.Git().
+			CommitSignatureStatus("HEAD", "G").
+			CommitSignatureStatus("HEAD~1", "G")
+	},
+})
@@ -0,0 +1,53 @@
+package interactive_rebase
+
+import (
+	"fmt"
+
+	"github.com/jesseduffield/lazygit/pkg/config"
+	. "github.com/jesseduffield/lazygit/pkg/integration/components"
+)
+
+var MovePreservingCommitterDates = NewIntegrationTest(NewIntegrationTestArgs{
+	Description:  "Move a commit down with preserveCommitterDates enabled and check that every rebuilt commit keeps its own committer date",
+	ExtraCmdArgs: []string{},
+	Skip:         false,
+	SetupConfig: func(config *config.AppConfig) {
+		config.UserConfig.Git.Rebase.PreserveCommitterDates = true
+	},
+	SetupRepo: func(shell *Shell) {
+		for i := 1; i <= 4; i++ {
+			shell.CreateFileAndAdd(fmt.Sprintf("file%02d.txt", i), "content")
+			shell.RunCommandWithEnv(
+				[]string{"git", "commit", "-m", fmt.Sprintf("commit %02d", i)},
+				[]string{fmt.Sprintf("GIT_COMMITTER_DATE=@160000000%d +0000", i)},
+			)
+		}
+	},
+	Run: func(t *TestDriver, keys config.KeybindingConfig) {
+		t.Views().Commits().
+			Focus().
+			Lines(
+				Contains("commit 04").IsSelected(),
+				Contains("commit 03"),
+				Contains("commit 02"),
+				Contains("commit 01"),
+			).
+			NavigateToLine(Contains("commit 03")).
+			Press(keys.Commits.MoveDownCommit).
+			Lines(
+				Contains("commit 04"),
+				Contains("commit 02"),
+				Contains("commit 03").IsSelected(),
+				Contains("commit 01"),
+			)
+
+		t.Git().
+			CommitMessage("HEAD", "commit 04").
+			CommitterDate("HEAD", "1600000004 +0000").
+			CommitMessage("HEAD~1", "commit 02").
+			CommitterDate("HEAD~1", "1600000002 +0000").
+			CommitMessage("HEAD~2", "commit 03").
+			CommitterDate("HEAD~2", "1600000003 +0000").
+			CommitterDate("HEAD~3", "1600000001 +0000")
+	},
+})
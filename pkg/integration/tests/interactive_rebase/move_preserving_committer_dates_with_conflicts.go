@@ -0,0 +1,51 @@
+package interactive_rebase
+
+import (
+	"fmt"
+
+	"github.com/jesseduffield/lazygit/pkg/config"
+	. "github.com/jesseduffield/lazygit/pkg/integration/components"
+)
+
+var MovePreservingCommitterDatesWithConflicts = NewIntegrationTest(NewIntegrationTestArgs{
+	Description:  "Swap two commits with preserveCommitterDates enabled, causing conflicts, and check that the commits keep their committer dates once the conflicts are resolved",
+	ExtraCmdArgs: []string{},
+	Skip:         false,
+	SetupConfig: func(config *config.AppConfig) {
+		config.UserConfig.Git.Rebase.PreserveCommitterDates = true
+	},
+	SetupRepo: func(shell *Shell) {
+		for i, content := range []string{"one", "two", "three"} {
+			if i == 0 {
+				shell.CreateFileAndAdd("myfile", content)
+			} else {
+				shell.UpdateFileAndAdd("myfile", content)
+			}
+			shell.RunCommandWithEnv(
+				[]string{"git", "commit", "-m", "commit " + content},
+				[]string{fmt.Sprintf("GIT_COMMITTER_DATE=@160000000%d +0000", i+1)},
+			)
+		}
+	},
+	Run: func(t *TestDriver, keys config.KeybindingConfig) {
+		t.Views().Commits().
+			Focus().
+			Lines(
+				Contains("commit three").IsSelected(),
+				Contains("commit two"),
+				Contains("commit one"),
+			).
+			Press(keys.Commits.MoveDownCommit)
+
+		// The pending todos don't include the breaks that the rebase stops at
+		// to set the dates
+		handleConflictsFromSwap(t)
+
+		t.Git().
+			CommitMessage("HEAD", "commit two").
+			CommitterDate("HEAD", "1600000002 +0000").
+			CommitMessage("HEAD~1", "commit three").
+			CommitterDate("HEAD~1", "1600000003 +0000").
+			CommitterDate("HEAD~2", "1600000001 +0000")
+	},
+})
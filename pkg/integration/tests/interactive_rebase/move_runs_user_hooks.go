@@ -0,0 +1,43 @@
+package interactive_rebase
+
+import (
+	"github.com/jesseduffield/lazygit/pkg/config"
+	. "github.com/jesseduffield/lazygit/pkg/integration/components"
+)
+
+// Logs what git ran the hook for, and how many commits it was told about
+var postRewriteHook = `#!/bin/sh
+
+echo "$1 $(wc -l | tr -d ' ')" >> .git/post-rewrite-log
+`
+
+var MoveRunsUserHooks = NewIntegrationTest(NewIntegrationTestArgs{
+	Description:  "Move a commit and check that the user's post-rewrite hook still gets run by the rebase, alongside the one that lazygit uses to keep the result of the rebase",
+	ExtraCmdArgs: []string{},
+	Skip:         false,
+	SetupConfig:  func(config *config.AppConfig) {},
+	SetupRepo: func(shell *Shell) {
+		shell.CreateFile(".git/hooks/post-rewrite", postRewriteHook)
+		shell.MakeExecutable(".git/hooks/post-rewrite")
+
+		shell.CreateNCommits(3)
+	},
+	Run: func(t *TestDriver, keys config.KeybindingConfig) {
+		t.Views().Commits().
+			Focus().
+			Lines(
+				Contains("commit 03").IsSelected(),
+				Contains("commit 02"),
+				Contains("commit 01"),
+			).
+			Press(keys.Commits.MoveDownCommit).
+			Lines(
+				Contains("commit 02"),
+				Contains("commit 03").IsSelected(),
+				Contains("commit 01"),
+			)
+
+		t.FileSystem().FileContent(".git/post-rewrite-log", Equals("rebase 2\n"))
+		t.FileSystem().PathPresent(".git/lazygit-last-rebase/rewritten-list")
+	},
+})
@@ -0,0 +1,49 @@
+package interactive_rebase
+
+import (
+	"github.com/jesseduffield/lazygit/pkg/config"
+	. "github.com/jesseduffield/lazygit/pkg/integration/components"
+)
+
+var MoveWithUpdateRefs = NewIntegrationTest(NewIntegrationTestArgs{
+	Description:  "Reorders the commits of the bottom branch of a stack with git.rebase.updateRefs enabled, and checks that the bottom branch moves along with them",
+	ExtraCmdArgs: []string{},
+	Skip:         false,
+	GitVersion:   AtLeast("2.38.0"),
+	SetupConfig: func(config *config.AppConfig) {
+		config.UserConfig.Git.MainBranches = []string{"master"}
+		config.UserConfig.Git.Rebase.UpdateRefs = true
+	},
+	SetupRepo: func(shell *Shell) {
+		shell.
+			CreateNCommits(1).
+			NewBranch("branch1").
+			CreateNCommitsStartingAt(2, 2).
+			NewBranch("branch2").
+			CreateNCommitsStartingAt(1, 4)
+	},
+	Run: func(t *TestDriver, keys config.KeybindingConfig) {
+		t.Views().Commits().
+			Focus().
+			Lines(
+				Contains("CI commit 04").IsSelected(),
+				Contains("CI * commit 03"),
+				Contains("CI commit 02"),
+				Contains("CI commit 01"),
+			).
+			NavigateToLine(Contains("commit 02")).
+			Press(keys.Commits.MoveUpCommit).
+			Lines(
+				Contains("CI commit 04"),
+				Contains("CI * commit 02").IsSelected(),
+				Contains("CI commit 03"),
+				Contains("CI commit 01"),
+			)
+
+		t.Git().
+			CommitMessage("branch1", "commit 02").
+			CommitMessage("branch1^", "commit 03").
+			CommitMessage("branch2", "commit 04").
+			CommitMessage("branch2^", "commit 02")
+	},
+})
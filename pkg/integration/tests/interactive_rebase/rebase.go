@@ -19,6 +19,10 @@ var Rebase = NewIntegrationTest(NewIntegrationTestArgs{
 
 		shell.CreateFileAndAdd("fixup-commit-file", "fixup-commit-file")
 		shell.Commit("commit to fixup")
+
+		// Continuing with a squash in the todo opens the editor for the combined
+		// message; keep git's proposed message as it is
+		shell.SetConfig("core.editor", "true")
 	},
 	Run: func(t *TestDriver, keys config.KeybindingConfig) {
 		t.Views().Commits().
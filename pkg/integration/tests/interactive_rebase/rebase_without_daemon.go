@@ -0,0 +1,69 @@
+package interactive_rebase
+
+import (
+	"github.com/jesseduffield/lazygit/pkg/config"
+	. "github.com/jesseduffield/lazygit/pkg/integration/components"
+)
+
+var RebaseWithoutDaemon = NewIntegrationTest(NewIntegrationTestArgs{
+	Description:  "Move, squash and edit commits with git.rebase.useDaemon off, so that lazygit edits the todo file itself instead of being git's editor",
+	ExtraCmdArgs: []string{},
+	Skip:         false,
+	SetupConfig: func(config *config.AppConfig) {
+		config.UserConfig.Git.Rebase.UseDaemon = false
+	},
+	SetupRepo: func(shell *Shell) {
+		shell.CreateNCommits(4)
+	},
+	Run: func(t *TestDriver, keys config.KeybindingConfig) {
+		t.Views().Commits().
+			Focus().
+			Lines(
+				Contains("commit 04").IsSelected(),
+				Contains("commit 03"),
+				Contains("commit 02"),
+				Contains("commit 01"),
+			).
+			Press(keys.Commits.MoveDownCommit).
+			Lines(
+				Contains("commit 03"),
+				Contains("commit 04").IsSelected(),
+				Contains("commit 02"),
+				Contains("commit 01"),
+			).
+			NavigateToLine(Contains("commit 02")).
+			Press(keys.Commits.SquashDown).
+			Tap(func() {
+				t.ExpectPopup().Confirmation().
+					Title(Equals("Squash")).
+					Content(Equals("Are you sure you want to squash this commit into the commit below?")).
+					Confirm()
+			}).
+			Lines(
+				Contains("commit 03"),
+				Contains("commit 04"),
+				Contains("commit 01").IsSelected(),
+			).
+			NavigateToLine(Contains("commit 04")).
+			Press(keys.Universal.Edit).
+			Lines(
+				Contains("commit 03"),
+				MatchesRegexp("YOU ARE HERE.*commit 04").IsSelected(),
+				Contains("commit 01"),
+			).
+			Tap(func() {
+				t.Common().ContinueRebase()
+			}).
+			Lines(
+				Contains("commit 03"),
+				Contains("commit 04"),
+				Contains("commit 01"),
+			).
+			NavigateToLine(Contains("commit 01"))
+
+		t.Views().Main().
+			Content(Contains("    commit 01\n    \n    commit 02")).
+			Content(Contains("+file01 content")).
+			Content(Contains("+file02 content"))
+	},
+})
@@ -0,0 +1,52 @@
+package interactive_rebase
+
+import (
+	"github.com/jesseduffield/lazygit/pkg/config"
+	. "github.com/jesseduffield/lazygit/pkg/integration/components"
+)
+
+var RewordAfterConflictInEditor = NewIntegrationTest(NewIntegrationTestArgs{
+	Description:  "Continue a rebase that stopped at a conflicting pick, with a reword still to come that gets its message from the editor",
+	ExtraCmdArgs: []string{},
+	Skip:         false,
+	SetupConfig:  func(config *config.AppConfig) {},
+	SetupRepo: func(shell *Shell) {
+		shell.CreateFileAndAdd("file", "original")
+		shell.Commit("base")
+		shell.NewBranch("feature")
+		shell.UpdateFileAndAdd("file", "feature")
+		shell.Commit("feature change")
+		shell.CreateFileAndAdd("other", "other")
+		shell.Commit("to reword")
+		shell.Checkout("master")
+		shell.UpdateFileAndAdd("file", "master")
+		shell.Commit("master change")
+		shell.Checkout("feature")
+
+		shell.RunShellCommand(`GIT_SEQUENCE_EDITOR="sed -i.bak 's/^pick \(.* to reword\)$/reword \1/'" git rebase -i master || true`)
+		shell.SetConfig("core.editor", `sh -c 'echo "reworded in editor" >"$1"' -`)
+	},
+	Run: func(t *TestDriver, keys config.KeybindingConfig) {
+		t.Views().Commits().
+			Lines(
+				Contains("reword").Contains("to reword"),
+				Contains("<-- YOU ARE HERE --- feature change"),
+				Contains("master change"),
+				Contains("base"),
+			)
+
+		t.Shell().UpdateFileAndAdd("file", "resolved")
+
+		t.Common().ContinueRebase()
+
+		t.Views().Commits().
+			Lines(
+				Contains("reworded in editor"),
+				Contains("feature change"),
+				Contains("master change"),
+				Contains("base"),
+			)
+
+		t.Views().Information().Content(DoesNotContain("Rebasing"))
+	},
+})
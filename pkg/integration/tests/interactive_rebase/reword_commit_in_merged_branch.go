@@ -0,0 +1,57 @@
+package interactive_rebase
+
+import (
+	"github.com/jesseduffield/lazygit/pkg/config"
+	. "github.com/jesseduffield/lazygit/pkg/integration/components"
+)
+
+var RewordCommitInMergedBranch = NewIntegrationTest(NewIntegrationTestArgs{
+	Description:  "Rewords a commit of a branch that was merged in, and checks that the merge commit and the shape of the history are kept",
+	ExtraCmdArgs: []string{},
+	Skip:         false,
+	SetupConfig:  func(config *config.AppConfig) {},
+	SetupRepo: func(shell *Shell) {
+		shell.
+			EmptyCommit("initial commit").
+			NewBranch("feature").
+			EmptyCommit("feature 01").
+			EmptyCommit("feature 02").
+			Checkout("master").
+			EmptyCommit("master 01").
+			Merge("feature")
+	},
+	Run: func(t *TestDriver, keys config.KeybindingConfig) {
+		t.Views().Commits().
+			Focus().
+			Lines(
+				Contains("Merge branch 'feature'").IsSelected(),
+				Contains("feature 02"),
+				Contains("feature 01"),
+				Contains("master 01"),
+				Contains("initial commit"),
+			).
+			NavigateToLine(Contains("feature 01")).
+			Press(keys.Commits.RenameCommit).
+			Tap(func() {
+				t.ExpectPopup().CommitMessagePanel().
+					Title(Equals("Reword commit")).
+					InitialText(Equals("feature 01")).
+					Clear().
+					Type("renamed feature 01").
+					Confirm()
+			}).
+			Lines(
+				Contains("Merge branch 'feature'"),
+				Contains("feature 02"),
+				Contains("renamed feature 01").IsSelected(),
+				Contains("master 01"),
+				Contains("initial commit"),
+			)
+
+		t.Git().
+			CommitMessage("HEAD^1", "master 01").
+			CommitMessage("HEAD^2", "feature 02").
+			CommitMessage("HEAD^2^", "renamed feature 01").
+			CommitMessage("HEAD^2^^", "initial commit")
+	},
+})
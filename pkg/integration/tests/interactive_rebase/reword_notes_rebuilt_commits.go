@@ -0,0 +1,48 @@
+package interactive_rebase
+
+import (
+	"github.com/jesseduffield/lazygit/pkg/config"
+	. "github.com/jesseduffield/lazygit/pkg/integration/components"
+)
+
+var RewordNotesRebuiltCommits = NewIntegrationTest(NewIntegrationTestArgs{
+	Description:  "Reword a commit with git.rebase.noteRebuiltCommits on, and check that the commits the rebase rebuilt get a note saying what they were made from",
+	ExtraCmdArgs: []string{},
+	Skip:         false,
+	SetupConfig: func(config *config.AppConfig) {
+		config.UserConfig.Git.Rebase.NoteRebuiltCommits = true
+	},
+	SetupRepo: func(shell *Shell) {
+		shell.CreateNCommits(3)
+	},
+	Run: func(t *TestDriver, keys config.KeybindingConfig) {
+		t.Views().Commits().
+			Focus().
+			Lines(
+				Contains("commit 03"),
+				Contains("commit 02"),
+				Contains("commit 01"),
+			).
+			NavigateToLine(Contains("commit 02")).
+			Press(keys.Commits.RenameCommit).
+			Tap(func() {
+				t.ExpectPopup().CommitMessagePanel().
+					Title(Equals("Reword commit")).
+					InitialText(Equals("commit 02")).
+					Clear().
+					Type("renamed 02").
+					Confirm()
+			}).
+			Lines(
+				Contains("commit 03"),
+				Contains("renamed 02"),
+				Contains("commit 01"),
+			)
+
+		t.Git().
+			Note("lazygit", "HEAD", MatchesRegexp(`^Rebuilt by lazygit in a rebase from:\npick [0-9a-f]{40}$`)).
+			Note("lazygit", "HEAD~1", MatchesRegexp(`^Rebuilt by lazygit in a rebase from:\nedit [0-9a-f]{40}$`)).
+			// kept as it was, so it isn't noted
+			Note("lazygit", "HEAD~2", Equals(""))
+	},
+})
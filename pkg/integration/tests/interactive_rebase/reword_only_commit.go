@@ -0,0 +1,36 @@
+package interactive_rebase
+
+import (
+	"github.com/jesseduffield/lazygit/pkg/config"
+	. "github.com/jesseduffield/lazygit/pkg/integration/components"
+)
+
+var RewordOnlyCommit = NewIntegrationTest(NewIntegrationTestArgs{
+	Description:  "Rewords the only commit of a repo, which is both the first commit and the head commit",
+	ExtraCmdArgs: []string{},
+	Skip:         false,
+	SetupConfig:  func(config *config.AppConfig) {},
+	SetupRepo: func(shell *Shell) {
+		shell.
+			CreateNCommits(1)
+	},
+	Run: func(t *TestDriver, keys config.KeybindingConfig) {
+		t.Views().Commits().
+			Focus().
+			Lines(
+				Contains("commit 01").IsSelected(),
+			).
+			Press(keys.Commits.RenameCommit).
+			Tap(func() {
+				t.ExpectPopup().CommitMessagePanel().
+					Title(Equals("Reword commit")).
+					InitialText(Equals("commit 01")).
+					Clear().
+					Type("renamed 01").
+					Confirm()
+			}).
+			Lines(
+				Contains("renamed 01"),
+			)
+	},
+})
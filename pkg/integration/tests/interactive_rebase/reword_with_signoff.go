@@ -0,0 +1,48 @@
+package interactive_rebase
+
+import (
+	"github.com/jesseduffield/lazygit/pkg/config"
+	. "github.com/jesseduffield/lazygit/pkg/integration/components"
+)
+
+var RewordWithSignoff = NewIntegrationTest(NewIntegrationTestArgs{
+	Description:  "Rewords a commit with git.rebase.signOff enabled, and checks that both the reworded commit and the already signed-off commit rebuilt on top of it end up signed off exactly once",
+	ExtraCmdArgs: []string{},
+	Skip:         false,
+	SetupConfig: func(config *config.AppConfig) {
+		config.UserConfig.Git.Rebase.SignOff = true
+	},
+	SetupRepo: func(shell *Shell) {
+		shell.
+			RunCommand([]string{"git", "commit", "--allow-empty", "--signoff", "-m", "commit 01"}).
+			EmptyCommit("commit 02").
+			RunCommand([]string{"git", "commit", "--allow-empty", "--signoff", "-m", "commit 03"})
+	},
+	Run: func(t *TestDriver, keys config.KeybindingConfig) {
+		t.Views().Commits().
+			Focus().
+			Lines(
+				Contains("commit 03"),
+				Contains("commit 02"),
+				Contains("commit 01"),
+			).
+			NavigateToLine(Contains("commit 02")).
+			Press(keys.Commits.RenameCommit).
+			Tap(func() {
+				t.ExpectPopup().CommitMessagePanel().
+					Title(Equals("Reword commit")).
+					Clear().
+					Type("renamed 02").
+					Confirm()
+			}).
+			Lines(
+				Contains("commit 03"),
+				Contains("renamed 02"),
+				Contains("commit 01"),
+			)
+
+		t.Git().
+			CommitMessage("HEAD", "commit 03\n\nSigned-off-by: CI <CI@example.com>").
+			CommitMessage("HEAD^", "renamed 02\n\nSigned-off-by: CI <CI@example.com>")
+	},
+})
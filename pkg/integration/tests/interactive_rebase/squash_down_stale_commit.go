@@ -0,0 +1,47 @@
+package interactive_rebase
+
+import (
+	"github.com/jesseduffield/lazygit/pkg/config"
+	. "github.com/jesseduffield/lazygit/pkg/integration/components"
+)
+
+var SquashDownStaleCommit = NewIntegrationTest(NewIntegrationTestArgs{
+	Description:  "Squash down a commit that is no longer on the branch because the branch changed behind lazygit's back, and check that the rebase fails instead of doing nothing",
+	ExtraCmdArgs: []string{},
+	Skip:         false,
+	SetupConfig:  func(config *config.AppConfig) {},
+	SetupRepo: func(shell *Shell) {
+		shell.
+			CreateNCommits(3)
+	},
+	Run: func(t *TestDriver, keys config.KeybindingConfig) {
+		t.Views().Commits().
+			Focus().
+			Lines(
+				Contains("commit 03").IsSelected(),
+				Contains("commit 02"),
+				Contains("commit 01"),
+			)
+
+		// the commits view isn't refreshed, so it still shows commit 03
+		t.Shell().RunCommand([]string{"git", "reset", "--hard", "HEAD~1"})
+
+		t.Views().Commits().
+			Press(keys.Commits.SquashDown).
+			Tap(func() {
+				t.ExpectPopup().Confirmation().
+					Title(Equals("Squash")).
+					Content(Equals("Are you sure you want to squash this commit into the commit below?")).
+					Confirm()
+
+				t.ExpectPopup().Alert().
+					Title(Equals("Error")).
+					Content(Contains("isn't part of the rebase, probably because the commits have changed since they were loaded")).
+					Confirm()
+			}).
+			Lines(
+				Contains("commit 02"),
+				Contains("commit 01"),
+			)
+	},
+})
@@ -0,0 +1,36 @@
+package interactive_rebase
+
+import (
+	"github.com/jesseduffield/lazygit/pkg/config"
+	. "github.com/jesseduffield/lazygit/pkg/integration/components"
+)
+
+var StatusShowsRebasingBranch = NewIntegrationTest(NewIntegrationTestArgs{
+	Description:  "While a branch is being rebased, the status panel shows the branch's name rather than the detached head",
+	ExtraCmdArgs: []string{},
+	Skip:         false,
+	SetupConfig:  func(config *config.AppConfig) {},
+	SetupRepo: func(shell *Shell) {
+		shell.NewBranch("feature")
+		shell.CreateNCommits(3)
+	},
+	Run: func(t *TestDriver, keys config.KeybindingConfig) {
+		t.Views().Status().Content(Contains("repo → feature"))
+
+		t.Views().Commits().
+			Focus().
+			NavigateToLine(Contains("commit 02")).
+			Press(keys.Universal.Edit).
+			Lines(
+				Contains("pick").Contains("commit 03"),
+				Contains("<-- YOU ARE HERE --- commit 02"),
+				Contains("commit 01"),
+			)
+
+		t.Views().Status().Content(Contains("(rebasing) repo → feature"))
+
+		t.Common().ContinueRebase()
+
+		t.Views().Status().Content(Contains("repo → feature").DoesNotContain("rebasing"))
+	},
+})
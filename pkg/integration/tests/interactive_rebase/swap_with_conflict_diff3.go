@@ -0,0 +1,93 @@
+package interactive_rebase
+
+import (
+	"github.com/jesseduffield/lazygit/pkg/config"
+	. "github.com/jesseduffield/lazygit/pkg/integration/components"
+)
+
+var SwapWithConflictDiff3 = NewIntegrationTest(NewIntegrationTestArgs{
+	Description:  "Swap two commits with git.rebase.conflictStyle set to diff3, and check that both the conflict from the swap and the one from continuing show the base version",
+	ExtraCmdArgs: []string{},
+	Skip:         false,
+	SetupConfig: func(config *config.AppConfig) {
+		config.UserConfig.Git.Rebase.ConflictStyle = "diff3"
+	},
+	SetupRepo: func(shell *Shell) {
+		shell.CreateFileAndAdd("myfile", "one")
+		shell.Commit("commit one")
+		shell.UpdateFileAndAdd("myfile", "two")
+		shell.Commit("commit two")
+		shell.UpdateFileAndAdd("myfile", "three")
+		shell.Commit("commit three")
+	},
+	Run: func(t *TestDriver, keys config.KeybindingConfig) {
+		t.Views().Commits().
+			Focus().
+			Lines(
+				Contains("commit three").IsSelected(),
+				Contains("commit two"),
+				Contains("commit one"),
+			).
+			Press(keys.Commits.MoveDownCommit)
+
+		t.Common().AcknowledgeConflicts()
+
+		t.Views().Files().
+			IsFocused().
+			Lines(
+				Contains("UU myfile"),
+			).
+			PressEnter()
+
+		t.Views().MergeConflicts().
+			IsFocused().
+			TopLines(
+				Contains("<<<<<<< HEAD"),
+				Contains("one"),
+				Contains("|||||||"),
+				Contains("two"),
+				Contains("======="),
+				Contains("three"),
+				Contains(">>>>>>>"),
+			).
+			SelectNextItem().
+			SelectNextItem().
+			PressPrimaryAction() // pick "three"
+
+		t.Common().ContinueOnConflictsResolved()
+
+		t.Common().AcknowledgeConflicts()
+
+		t.Views().Files().
+			IsFocused().
+			Lines(
+				Contains("UU myfile"),
+			).
+			PressEnter()
+
+		t.Views().MergeConflicts().
+			IsFocused().
+			TopLines(
+				Contains("<<<<<<< HEAD"),
+				Contains("three"),
+				Contains("|||||||"),
+				Contains("one"),
+				Contains("======="),
+				Contains("two"),
+				Contains(">>>>>>>"),
+			).
+			SelectNextItem().
+			SelectNextItem().
+			PressPrimaryAction() // pick "two"
+
+		t.Common().ContinueOnConflictsResolved()
+
+		t.Views().Commits().
+			Focus().
+			Lines(
+				Contains("commit two").IsSelected(),
+				Contains("commit three"),
+				Contains("commit one"),
+			)
+	},
+})
@@ -0,0 +1,56 @@
+package patch_building
+
+import (
+	"github.com/jesseduffield/lazygit/pkg/config"
+	. "github.com/jesseduffield/lazygit/pkg/integration/components"
+)
+
+var MoveToEarlierCommitWithGpg = NewIntegrationTest(NewIntegrationTestArgs{
+	Description:  "Moving a patch to an earlier commit is refused when commit signing is turned on",
+	ExtraCmdArgs: []string{},
+	Skip:         false,
+	SetupConfig:  func(config *config.AppConfig) {},
+	SetupRepo: func(shell *Shell) {
+		shell.UseFakeGpg()
+
+		shell.CreateFileAndAdd("unrelated-file", "")
+		shell.Commit("destination commit")
+
+		shell.CreateFileAndAdd("file", "file content")
+		shell.Commit("commit to move from")
+	},
+	Run: func(t *TestDriver, keys config.KeybindingConfig) {
+		t.Views().Commits().
+			Focus().
+			Lines(
+				Contains("commit to move from").IsSelected(),
+				Contains("destination commit"),
+			).
+			PressEnter()
+
+		t.Views().CommitFiles().
+			IsFocused().
+			Lines(
+				Contains("file").IsSelected(),
+			).
+			PressPrimaryAction().
+			PressEscape()
+
+		t.Views().Information().Content(Contains("Building patch"))
+
+		t.Views().Commits().
+			IsFocused().
+			SelectNextItem()
+
+		t.Common().SelectPatchOption(Contains("Move patch to selected commit"))
+
+		t.ExpectPopup().Alert().
+			Title(Equals("Error")).
+			Content(Equals("Feature not available for users using GPG")).
+			Confirm()
+
+		t.Git().
+			CommitSignatureStatus("HEAD", "G").
+			CommitSignatureStatus("HEAD~1", "G")
+	},
+})
@@ -0,0 +1,52 @@
+package submodule
+
+import (
+	"github.com/jesseduffield/lazygit/pkg/config"
+	. "github.com/jesseduffield/lazygit/pkg/integration/components"
+)
+
+var DropCommitUpdatesSubmodule = NewIntegrationTest(NewIntegrationTestArgs{
+	Description:  "Drop a commit that moved a submodule's pointer, with git.rebase.updateSubmodules on, and see the submodule checked out at the old commit again",
+	ExtraCmdArgs: []string{},
+	Skip:         false,
+	SetupConfig: func(cfg *config.AppConfig) {
+		cfg.UserConfig.Git.Rebase.UpdateSubmodules = true
+	},
+	SetupRepo: func(shell *Shell) {
+		shell.EmptyCommit("first commit")
+		shell.CloneIntoSubmodule("my_submodule")
+		shell.GitAddAll()
+		shell.Commit("add submodule")
+		shell.RunCommand([]string{"git", "-C", "my_submodule", "commit", "--allow-empty", "-m", "submodule commit"})
+		shell.GitAdd("my_submodule")
+		shell.Commit("bump submodule")
+		shell.CreateFileAndAdd("file", "content")
+		shell.Commit("other commit")
+	},
+	Run: func(t *TestDriver, keys config.KeybindingConfig) {
+		t.Views().Commits().Focus().
+			Lines(
+				Contains("other commit").IsSelected(),
+				Contains("bump submodule"),
+				Contains("add submodule"),
+				Contains("first commit"),
+			).
+			NavigateToLine(Contains("bump submodule")).
+			Press(keys.Universal.Remove).
+			Tap(func() {
+				t.ExpectPopup().Confirmation().
+					Title(Equals("Delete commit")).
+					Content(Equals("Are you sure you want to delete this commit?")).
+					Confirm()
+			}).
+			Lines(
+				Contains("other commit"),
+				Contains("add submodule").IsSelected(),
+				Contains("first commit"),
+			)
+
+		// Without the update the submodule would still be at "submodule commit"
+		// and show up as modified
+		t.Views().Files().IsEmpty()
+	},
+})
@@ -0,0 +1,47 @@
+package submodule
+
+import (
+	"github.com/jesseduffield/lazygit/pkg/config"
+	. "github.com/jesseduffield/lazygit/pkg/integration/components"
+)
+
+var MoveCommitWarnsAboutSubmodule = NewIntegrationTest(NewIntegrationTestArgs{
+	Description:  "Move a commit that moved a submodule's pointer, with git.rebase.updateSubmodules off, and get warned that the submodule may show up as modified",
+	ExtraCmdArgs: []string{},
+	Skip:         false,
+	SetupConfig:  func(cfg *config.AppConfig) {},
+	SetupRepo: func(shell *Shell) {
+		shell.EmptyCommit("first commit")
+		shell.CloneIntoSubmodule("my_submodule")
+		shell.GitAddAll()
+		shell.Commit("add submodule")
+		shell.RunCommand([]string{"git", "-C", "my_submodule", "commit", "--allow-empty", "-m", "submodule commit"})
+		shell.GitAdd("my_submodule")
+		shell.Commit("bump submodule")
+		shell.CreateFileAndAdd("file", "content")
+		shell.Commit("other commit")
+	},
+	Run: func(t *TestDriver, keys config.KeybindingConfig) {
+		t.Views().Commits().Focus().
+			Lines(
+				Contains("other commit").IsSelected(),
+				Contains("bump submodule"),
+				Contains("add submodule"),
+				Contains("first commit"),
+			).
+			NavigateToLine(Contains("bump submodule")).
+			Press(keys.Commits.MoveUpCommit).
+			Tap(func() {
+				t.ExpectPopup().Confirmation().
+					Title(Equals("Rebase changes submodules")).
+					Content(Contains("may show up as modified")).
+					Confirm()
+			}).
+			Lines(
+				Contains("bump submodule").IsSelected(),
+				Contains("other commit"),
+				Contains("add submodule"),
+				Contains("first commit"),
+			)
+	},
+})
@@ -48,6 +48,11 @@ var tests = []*components.IntegrationTest{
 	branch.RebaseCancelOnConflict,
 	branch.RebaseDoesNotAutosquash,
 	branch.RebaseFromMarkedBase,
+	branch.RebaseFromMarkedBaseKeepsEmptyCommits,
+	branch.RebaseInWorktree,
+	branch.RebaseThenPush,
+	branch.RebaseThenPushRefusesStaleUpstream,
+	branch.RebaseThenPushToDifferentlyNamedUpstream,
 	branch.RebaseToUpstream,
 	branch.Rename,
 	branch.Reset,
@@ -77,10 +82,13 @@ var tests = []*components.IntegrationTest{
 	commit.HistoryComplex,
 	commit.NewBranch,
 	commit.PreserveCommitMessage,
+	commit.RemoveFileFromHistory,
 	commit.ResetAuthor,
 	commit.Revert,
 	commit.RevertMerge,
 	commit.Reword,
+	commit.RewordKeepsCommentLines,
+	commit.RewordStripsCommentLines,
 	commit.Search,
 	commit.SetAuthor,
 	commit.StageRangeOfLines,
@@ -153,6 +161,7 @@ var tests = []*components.IntegrationTest{
 	interactive_rebase.AmendHeadCommitDuringRebase,
 	interactive_rebase.AmendMerge,
 	interactive_rebase.AmendNonHeadCommitDuringRebase,
+	interactive_rebase.ContinueToFailingExec,
 	interactive_rebase.DropTodoCommitWithUpdateRef,
 	interactive_rebase.DropWithCustomCommentChar,
 	interactive_rebase.EditFirstCommit,
@@ -161,21 +170,36 @@ var tests = []*components.IntegrationTest{
 	interactive_rebase.FixupFirstCommit,
 	interactive_rebase.FixupSecondCommit,
 	interactive_rebase.Move,
+	interactive_rebase.MoveCommitWithFailingGpg,
+	interactive_rebase.MoveCommitWithGpg,
 	interactive_rebase.MoveInRebase,
+	interactive_rebase.MovePreservingCommitterDates,
+	interactive_rebase.MovePreservingCommitterDatesWithConflicts,
+	interactive_rebase.MoveRunsUserHooks,
 	interactive_rebase.MoveWithCustomCommentChar,
+	interactive_rebase.MoveWithUpdateRefs,
 	interactive_rebase.PickRescheduled,
 	interactive_rebase.Rebase,
+	interactive_rebase.RebaseWithoutDaemon,
+	interactive_rebase.RewordAfterConflictInEditor,
+	interactive_rebase.RewordCommitInMergedBranch,
 	interactive_rebase.RewordCommitWithEditorAndFail,
 	interactive_rebase.RewordFirstCommit,
 	interactive_rebase.RewordLastCommit,
+	interactive_rebase.RewordNotesRebuiltCommits,
+	interactive_rebase.RewordOnlyCommit,
+	interactive_rebase.RewordWithSignoff,
 	interactive_rebase.RewordYouAreHereCommit,
 	interactive_rebase.RewordYouAreHereCommitWithEditor,
 	interactive_rebase.SquashDownFirstCommit,
 	interactive_rebase.SquashDownSecondCommit,
+	interactive_rebase.SquashDownStaleCommit,
 	interactive_rebase.SquashFixupsAboveFirstCommit,
+	interactive_rebase.StatusShowsRebasingBranch,
 	interactive_rebase.SwapInRebaseWithConflict,
 	interactive_rebase.SwapInRebaseWithConflictAndEdit,
 	interactive_rebase.SwapWithConflict,
+	interactive_rebase.SwapWithConflictDiff3,
 	misc.ConfirmOnQuit,
 	misc.CopyToClipboard,
 	misc.DisabledKeybindings,
@@ -186,6 +210,7 @@ var tests = []*components.IntegrationTest{
 	patch_building.ApplyInReverseWithConflict,
 	patch_building.MoveToEarlierCommit,
 	patch_building.MoveToEarlierCommitNoKeepEmpty,
+	patch_building.MoveToEarlierCommitWithGpg,
 	patch_building.MoveToIndex,
 	patch_building.MoveToIndexPartOfAdjacentAddedLines,
 	patch_building.MoveToIndexPartial,
@@ -224,7 +249,9 @@ var tests = []*components.IntegrationTest{
 	stash.StashStaged,
 	stash.StashUnstaged,
 	submodule.Add,
+	submodule.DropCommitUpdatesSubmodule,
 	submodule.Enter,
+	submodule.MoveCommitWarnsAboutSubmodule,
 	submodule.Remove,
 	submodule.Reset,
 	sync.FetchPrune,
@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+)
+
+var (
+	conflictStartBytes = []byte("<<<<<<< ")
+	conflictEndBytes   = []byte(">>>>>>> ")
+)
+
+// tells us whether a file actually has inline merge conflicts. We need to run this
+// because git will continue showing a status of 'UU' even after the conflicts have
+// been resolved in the user's editor
+func FileHasConflictMarkers(path string) (bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+
+	defer file.Close()
+
+	return fileHasConflictMarkersAux(file), nil
+}
+
+// Efficiently scans through a file looking for merge conflict markers. Returns true if it does
+func fileHasConflictMarkersAux(file io.Reader) bool {
+	scanner := bufio.NewScanner(file)
+	scanner.Split(bufio.ScanLines)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+
+		// only searching for start/end markers because the others are more ambiguous
+		if bytes.HasPrefix(line, conflictStartBytes) {
+			return true
+		}
+
+		if bytes.HasPrefix(line, conflictEndBytes) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -3,10 +3,12 @@ package utils
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/fsmiamoto/git-todo-parser/todo"
 	"github.com/samber/lo"
+	"golang.org/x/exp/slices"
 )
 
 // Read a git-rebase-todo file, change the action for the given sha to
@@ -32,6 +34,18 @@ func EditRebaseTodo(filePath string, sha string, oldAction todo.TodoCommand, new
 	return fmt.Errorf("Todo %s not found in git-rebase-todo", sha)
 }
 
+// MissingPickTodos returns those of the given shas that no pick todo in the
+// list is for. Lazygit works out which commits to change from the commits it
+// last loaded, so a sha that's missing from the todo git generated usually
+// means that the history has changed since.
+func MissingPickTodos(todos []todo.Todo, shas []string) []string {
+	return lo.Filter(shas, func(sha string, _ int) bool {
+		return sha == "" || !lo.ContainsBy(todos, func(t todo.Todo) bool {
+			return t.Command == todo.Pick && equalShas(t.Commit, sha)
+		})
+	})
+}
+
 func equalShas(a, b string) bool {
 	return strings.HasPrefix(a, b) || strings.HasPrefix(b, a)
 }
@@ -63,6 +77,131 @@ func WriteRebaseTodoFile(fileName string, todos []todo.Todo, commentChar byte) e
 	return err
 }
 
+// ToggleTodoLineEnabled comments out the todo for the given sha, or comments
+// it in again if it is already commented out. Git skips commented-out todos,
+// so this drops the commit like a drop todo would, but keeps the original
+// line around so that it's easy to bring back.
+func ToggleTodoLineEnabled(fileName string, sha string, commentChar byte) error {
+	todos, err := ReadRebaseTodoFile(fileName, commentChar)
+	if err != nil {
+		return err
+	}
+	toggledTodos, err := toggleTodoLineEnabled(todos, sha, commentChar)
+	if err != nil {
+		return err
+	}
+	return WriteRebaseTodoFile(fileName, toggledTodos, commentChar)
+}
+
+func toggleTodoLineEnabled(todos []todo.Todo, sha string, commentChar byte) ([]todo.Todo, error) {
+	result := slices.Clone(todos)
+
+	_, idx, ok := lo.FindIndexOf(result, func(t todo.Todo) bool {
+		return t.Command != todo.Comment && t.Commit != "" && equalShas(t.Commit, sha)
+	})
+	if ok {
+		var sb strings.Builder
+		if err := todo.Write(&sb, result[idx:idx+1], commentChar); err != nil {
+			return nil, err
+		}
+		result[idx] = todo.Todo{Command: todo.Comment, Comment: " " + strings.TrimSuffix(sb.String(), "\n")}
+		return result, nil
+	}
+
+	for i, t := range result {
+		if t.Command != todo.Comment {
+			continue
+		}
+		// Most comments aren't todos (e.g. the help text that git puts at the
+		// end of the file), so failing to parse one just means it's not ours
+		uncommented, err := todo.Parse(strings.NewReader(strings.TrimPrefix(t.Comment, " ")), commentChar)
+		if err != nil || len(uncommented) != 1 {
+			continue
+		}
+		if uncommented[0].Commit != "" && equalShas(uncommented[0].Commit, sha) {
+			result[i] = uncommented[0]
+			return result, nil
+		}
+	}
+
+	return nil, fmt.Errorf("Todo %s not found in git-rebase-todo", sha)
+}
+
+// ConvertTodoLine replaces the todo for the given sha with a todo of a
+// different kind. For the actions that act on a commit (pick, reword, edit,
+// squash, fixup and drop) arg must be empty. For label, arg is the name of the
+// new label; for reset and merge it's the label to reset to or merge in, which
+// must have been defined further up the todo, by a todo that the rebase has
+// already carried out (doneTodos), or be git's built-in "onto" label.
+// Converting to reset or label means the commit is no longer picked.
+func ConvertTodoLine(fileName string, doneTodos []todo.Todo, sha string, action string, arg string, commentChar byte) error {
+	todos, err := ReadRebaseTodoFile(fileName, commentChar)
+	if err != nil {
+		return err
+	}
+	convertedTodos, err := convertTodoLine(todos, doneTodos, sha, action, arg)
+	if err != nil {
+		return err
+	}
+	return WriteRebaseTodoFile(fileName, convertedTodos, commentChar)
+}
+
+func convertTodoLine(todos []todo.Todo, doneTodos []todo.Todo, sha string, action string, arg string) ([]todo.Todo, error) {
+	command, ok := lo.Find(convertibleTodoCommands, func(c todo.TodoCommand) bool {
+		return c.String() == action
+	})
+	if !ok {
+		return nil, fmt.Errorf("Unknown todo action %q", action)
+	}
+
+	idx := slices.IndexFunc(todos, func(t todo.Todo) bool {
+		return t.Command != todo.Comment && t.Commit != "" && equalShas(t.Commit, sha)
+	})
+	if idx == -1 {
+		return nil, fmt.Errorf("Todo %s not found in git-rebase-todo", sha)
+	}
+	commit := todos[idx].Commit
+
+	var converted todo.Todo
+	switch command {
+	case todo.Label, todo.Reset, todo.Merge:
+		if arg == "" || strings.ContainsAny(arg, " \t") {
+			return nil, fmt.Errorf("Todo action %s needs a label without spaces", action)
+		}
+		if command != todo.Label && !labelDefined(append(slices.Clone(doneTodos), todos[:idx]...), arg) {
+			return nil, fmt.Errorf("Label %s not found in git-rebase-todo", arg)
+		}
+		converted = todo.Todo{Command: command, Label: arg}
+		if command == todo.Merge {
+			// -C reuses the commit's message for the merge commit
+			converted.Flag = "-C"
+			converted.Commit = commit
+		}
+	default:
+		if arg != "" {
+			return nil, fmt.Errorf("Todo action %s doesn't take an argument", action)
+		}
+		converted = todo.Todo{Command: command, Commit: commit}
+	}
+
+	result := slices.Clone(todos)
+	result[idx] = converted
+	return result, nil
+}
+
+// The actions that ConvertTodoLine can convert a todo to
+var convertibleTodoCommands = []todo.TodoCommand{
+	todo.Pick, todo.Reword, todo.Edit, todo.Squash, todo.Fixup, todo.Drop,
+	todo.Label, todo.Reset, todo.Merge,
+}
+
+func labelDefined(todos []todo.Todo, label string) bool {
+	// git defines the onto label itself for rebases that use --rebase-merges
+	return label == "onto" || lo.ContainsBy(todos, func(t todo.Todo) bool {
+		return t.Command == todo.Label && t.Label == label
+	})
+}
+
 func PrependStrToTodoFile(filePath string, linesToPrepend []byte) error {
 	existingContent, err := os.ReadFile(filePath)
 	if err != nil {
@@ -134,13 +273,13 @@ func moveTodoUp(todos []todo.Todo, sha string, action todo.TodoCommand) ([]todo.
 	return rearrangedTodos, nil
 }
 
-func MoveFixupCommitDown(fileName string, originalSha string, fixupSha string, commentChar byte) error {
+func MoveFixupCommitDown(fileName string, originalSha string, fixupSha string, useFixupMessage bool, commentChar byte) error {
 	todos, err := ReadRebaseTodoFile(fileName, commentChar)
 	if err != nil {
 		return err
 	}
 
-	newTodos, err := moveFixupCommitDown(todos, originalSha, fixupSha)
+	newTodos, err := moveFixupCommitDown(todos, originalSha, fixupSha, useFixupMessage)
 	if err != nil {
 		return err
 	}
@@ -148,7 +287,9 @@ func MoveFixupCommitDown(fileName string, originalSha string, fixupSha string, c
 	return WriteRebaseTodoFile(fileName, newTodos, commentChar)
 }
 
-func moveFixupCommitDown(todos []todo.Todo, originalSha string, fixupSha string) ([]todo.Todo, error) {
+// If useFixupMessage is set, the fixup commit becomes a "fixup -C", so that its
+// message replaces the original's (this is what git does with amend! commits)
+func moveFixupCommitDown(todos []todo.Todo, originalSha string, fixupSha string, useFixupMessage bool) ([]todo.Todo, error) {
 	isOriginal := func(t todo.Todo) bool {
 		return t.Command == todo.Pick && equalShas(t.Commit, originalSha)
 	}
@@ -173,12 +314,336 @@ func moveFixupCommitDown(todos []todo.Todo, originalSha string, fixupSha string)
 	newTodos := MoveElement(todos, fixupIndex, originalIndex+1)
 
 	newTodos[originalIndex+1].Command = todo.Fixup
+	if useFixupMessage {
+		newTodos[originalIndex+1].Flag = "-C"
+	}
+
+	return newTodos, nil
+}
+
+// Read a git-rebase-todo file and move the todo for the given sha so that it
+// comes directly after the commit afterSha (and after any fixup or squash
+// todos that already follow that commit)
+func MoveTodoAfter(fileName string, sha string, action todo.TodoCommand, afterSha string, commentChar byte) error {
+	todos, err := ReadRebaseTodoFile(fileName, commentChar)
+	if err != nil {
+		return err
+	}
+
+	newTodos, err := moveTodoAfter(todos, sha, action, afterSha)
+	if err != nil {
+		return err
+	}
+
+	return WriteRebaseTodoFile(fileName, newTodos, commentChar)
+}
+
+func moveTodoAfter(todos []todo.Todo, sha string, action todo.TodoCommand, afterSha string) ([]todo.Todo, error) {
+	_, sourceIdx, ok := lo.FindIndexOf(todos, func(t todo.Todo) bool {
+		return t.Command == action && equalShas(t.Commit, sha)
+	})
+	if !ok {
+		return nil, fmt.Errorf("Todo %s not found in git-rebase-todo", sha)
+	}
+
+	newTodos := Remove(slices.Clone(todos), sourceIdx)
+
+	_, targetIdx, ok := lo.FindIndexOf(newTodos, func(t todo.Todo) bool {
+		return (t.Command == todo.Pick || t.Command == todo.Reword || t.Command == todo.Edit) &&
+			equalShas(t.Commit, afterSha)
+	})
+	if !ok {
+		return nil, fmt.Errorf("Todo %s not found in git-rebase-todo", afterSha)
+	}
+
+	destinationIdx := targetIdx + 1
+	for destinationIdx < len(newTodos) && isFixupOrSquash(newTodos[destinationIdx]) {
+		destinationIdx++
+	}
+
+	return slices.Insert(newTodos, destinationIdx, todos[sourceIdx]), nil
+}
+
+// Read a git-rebase-todo file and move the todo for the given sha so that it
+// comes directly before the commit beforeSha
+func MoveTodoBefore(fileName string, sha string, action todo.TodoCommand, beforeSha string, commentChar byte) error {
+	todos, err := ReadRebaseTodoFile(fileName, commentChar)
+	if err != nil {
+		return err
+	}
+
+	newTodos, err := moveTodoBefore(todos, sha, action, beforeSha)
+	if err != nil {
+		return err
+	}
+
+	return WriteRebaseTodoFile(fileName, newTodos, commentChar)
+}
+
+func moveTodoBefore(todos []todo.Todo, sha string, action todo.TodoCommand, beforeSha string) ([]todo.Todo, error) {
+	_, sourceIdx, ok := lo.FindIndexOf(todos, func(t todo.Todo) bool {
+		return t.Command == action && equalShas(t.Commit, sha)
+	})
+	if !ok {
+		return nil, fmt.Errorf("Todo %s not found in git-rebase-todo", sha)
+	}
+
+	newTodos := Remove(slices.Clone(todos), sourceIdx)
+
+	_, targetIdx, ok := lo.FindIndexOf(newTodos, func(t todo.Todo) bool {
+		return (t.Command == todo.Pick || t.Command == todo.Reword || t.Command == todo.Edit) &&
+			equalShas(t.Commit, beforeSha)
+	})
+	if !ok {
+		return nil, fmt.Errorf("Todo %s not found in git-rebase-todo", beforeSha)
+	}
+
+	return slices.Insert(newTodos, targetIdx, todos[sourceIdx]), nil
+}
+
+// Read a git-rebase-todo file and rearrange the picks of the given commits so
+// that they come in the given order. The picks take the places in the file
+// that the picks of these commits had before, so any other todos (e.g.
+// update-ref lines) stay where they are.
+func ReorderTodos(fileName string, shas []string, commentChar byte) error {
+	todos, err := ReadRebaseTodoFile(fileName, commentChar)
+	if err != nil {
+		return err
+	}
+
+	newTodos, err := reorderTodos(todos, shas)
+	if err != nil {
+		return err
+	}
+
+	return WriteRebaseTodoFile(fileName, newTodos, commentChar)
+}
+
+func reorderTodos(todos []todo.Todo, shas []string) ([]todo.Todo, error) {
+	isPickOf := func(t todo.Todo, sha string) bool {
+		return t.Command == todo.Pick && equalShas(t.Commit, sha)
+	}
+
+	slots := []int{}
+	for i, t := range todos {
+		if lo.SomeBy(shas, func(sha string) bool { return isPickOf(t, sha) }) {
+			slots = append(slots, i)
+		}
+	}
+
+	if len(slots) != len(shas) {
+		return nil, fmt.Errorf("Expected %d todos to reorder, found %d", len(shas), len(slots))
+	}
+
+	newTodos := slices.Clone(todos)
+	for i, sha := range shas {
+		_, index, ok := lo.FindIndexOf(todos, func(t todo.Todo) bool { return isPickOf(t, sha) })
+		if !ok {
+			return nil, fmt.Errorf("Todo %s not found in git-rebase-todo", sha)
+		}
+		newTodos[slots[i]] = todos[index]
+	}
+
+	return newTodos, nil
+}
+
+func isFixupOrSquash(t todo.Todo) bool {
+	return t.Command == todo.Fixup || t.Command == todo.Squash
+}
+
+// Read a git-rebase-todo file, change the pick of the given sha to an edit,
+// and add an exec line with the given command directly after it, so that the
+// command runs as soon as the user continues from the edit stop
+func EditTodoWithCheck(fileName string, sha string, checkCmd string, commentChar byte) error {
+	todos, err := ReadRebaseTodoFile(fileName, commentChar)
+	if err != nil {
+		return err
+	}
+
+	newTodos, err := editTodoWithCheck(todos, sha, checkCmd)
+	if err != nil {
+		return err
+	}
+
+	return WriteRebaseTodoFile(fileName, newTodos, commentChar)
+}
+
+func editTodoWithCheck(todos []todo.Todo, sha string, checkCmd string) ([]todo.Todo, error) {
+	_, idx, ok := lo.FindIndexOf(todos, func(t todo.Todo) bool {
+		return t.Command == todo.Pick && equalShas(t.Commit, sha)
+	})
+	if !ok {
+		return nil, fmt.Errorf("Todo %s not found in git-rebase-todo", sha)
+	}
+
+	newTodos := make([]todo.Todo, 0, len(todos)+1)
+	newTodos = append(newTodos, todos[:idx+1]...)
+	newTodos[idx].Command = todo.Edit
+	newTodos = append(newTodos, todo.Todo{Command: todo.Exec, ExecCommand: checkCmd})
+	newTodos = append(newTodos, todos[idx+1:]...)
+
+	return newTodos, nil
+}
+
+// Read a git-rebase-todo file and add an exec line running execCmd after each
+// commit for which shouldExec returns true. A commit followed by fixups or
+// squashes only exists once the whole group has been applied, so in that case
+// the exec goes after the group, and runs if any commit in it matches.
+func AddExecAfterTodos(fileName string, shouldExec func(sha string) (bool, error), execCmd string, commentChar byte) error {
+	todos, err := ReadRebaseTodoFile(fileName, commentChar)
+	if err != nil {
+		return err
+	}
+
+	newTodos, err := addExecAfterTodos(todos, shouldExec, execCmd)
+	if err != nil {
+		return err
+	}
+
+	return WriteRebaseTodoFile(fileName, newTodos, commentChar)
+}
+
+func addExecAfterTodos(todos []todo.Todo, shouldExec func(sha string) (bool, error), execCmd string) ([]todo.Todo, error) {
+	newTodos := make([]todo.Todo, 0, len(todos))
+	pendingExec := false
+	for i, t := range todos {
+		newTodos = append(newTodos, t)
+
+		switch t.Command {
+		case todo.Pick, todo.Reword, todo.Edit, todo.Fixup, todo.Squash:
+			matches, err := shouldExec(t.Commit)
+			if err != nil {
+				return nil, err
+			}
+			pendingExec = pendingExec || matches
+		default:
+			continue
+		}
+
+		if pendingExec && (i == len(todos)-1 || !isFixupOrSquash(todos[i+1])) {
+			newTodos = append(newTodos, todo.Todo{Command: todo.Exec, ExecCommand: execCmd})
+			pendingExec = false
+		}
+	}
 
 	return newTodos, nil
 }
 
+// The file in the rebase-merge directory in which PreserveCommitterDates keeps
+// the committer dates of the rebase's commits, as "<sha> <date>" lines. Git
+// deletes it with the rest of the rebase state.
+const CommitterDatesFile = "lazygit-committer-dates"
+
+// Read a git-rebase-todo file and put a break before each commit that has an
+// entry in committerDates, and keep the dates in CommitterDatesFile next to
+// it. Git can't be told a committer date per todo, so the rebase stops at each
+// of the breaks, and whoever runs it continues it with GIT_COMMITTER_DATE set
+// to the date of the commit that git makes next (see
+// RemoveCommitterDateBreaks).
+func PreserveCommitterDates(fileName string, committerDates map[string]string, commentChar byte) error {
+	todos, err := ReadRebaseTodoFile(fileName, commentChar)
+	if err != nil {
+		return err
+	}
+
+	content := lo.Map(lo.Keys(committerDates), func(sha string, _ int) string { return sha + " " + committerDates[sha] + "\n" })
+	slices.Sort(content)
+	if err := os.WriteFile(filepath.Join(filepath.Dir(fileName), CommitterDatesFile), []byte(strings.Join(content, "")), 0o644); err != nil {
+		return err
+	}
+
+	return WriteRebaseTodoFile(fileName, AddCommitterDateBreaks(todos, committerDates), commentChar)
+}
+
+// ReadCommitterDates reads the dates that PreserveCommitterDates kept in the
+// given file
+func ReadCommitterDates(fileName string) (map[string]string, error) {
+	content, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	committerDates := map[string]string{}
+	for _, line := range SplitLines(string(content)) {
+		if sha, date, found := strings.Cut(line, " "); found {
+			committerDates[sha] = date
+		}
+	}
+
+	return committerDates, nil
+}
+
+// CommitterDateOf returns the date in committerDates of the commit that the
+// todo makes, or "" if it doesn't make one or there's no date for it. Fixups
+// and squashes are folded into the commit above them, which keeps its date.
+func CommitterDateOf(t todo.Todo, committerDates map[string]string) string {
+	switch t.Command {
+	case todo.Pick, todo.Reword, todo.Edit:
+		for sha, date := range committerDates {
+			if equalShas(t.Commit, sha) {
+				return date
+			}
+		}
+	}
+
+	return ""
+}
+
+// AddCommitterDateBreaks puts a break before each todo that makes a commit
+// with an entry in committerDates
+func AddCommitterDateBreaks(todos []todo.Todo, committerDates map[string]string) []todo.Todo {
+	newTodos := make([]todo.Todo, 0, len(todos))
+	for _, t := range todos {
+		if CommitterDateOf(t, committerDates) != "" {
+			newTodos = append(newTodos, todo.Todo{Command: todo.Break})
+		}
+		newTodos = append(newTodos, t)
+	}
+
+	return newTodos
+}
+
+// RemoveCommitterDateBreaks takes out the breaks that AddCommitterDateBreaks
+// put in, so that they don't show up as pending todos while the rebase is
+// stopped for some other reason. Breaks of the user's own that come before
+// them are kept.
+func RemoveCommitterDateBreaks(todos []todo.Todo, committerDates map[string]string) []todo.Todo {
+	return lo.Reject(todos, func(t todo.Todo, i int) bool {
+		return t.Command == todo.Break && i+1 < len(todos) && CommitterDateOf(todos[i+1], committerDates) != ""
+	})
+}
+
 // We render a todo in the commits view if it's a commit or if it's an
 // update-ref. We don't render label, reset, or comment lines.
 func isRenderedTodo(t todo.Todo) bool {
 	return t.Commit != "" || t.Command == todo.UpdateRef
 }
+
+// Tells us whether the user should get to edit the message that git is asking
+// for, given the todos that the rebase has done so far. Git has just applied
+// the last of these, so we want the editor if that's a reword, or if it ends a
+// chain of fixups and squashes that contains a squash (git only asks for the
+// combined message at the end of the chain). For anything else (e.g. a pick
+// that stopped at conflicts) the message is the original one and we can leave
+// it as is.
+func DoneTodosNeedMessageEditor(doneTodos []todo.Todo) bool {
+	doneTodos = lo.Filter(doneTodos, func(t todo.Todo, _ int) bool { return t.Commit != "" })
+	if len(doneTodos) == 0 {
+		return false
+	}
+
+	for i := len(doneTodos) - 1; i >= 0; i-- {
+		switch doneTodos[i].Command {
+		case todo.Reword:
+			return i == len(doneTodos)-1
+		case todo.Squash:
+			return true
+		case todo.Fixup:
+			continue
+		default:
+			return false
+		}
+	}
+
+	return false
+}
@@ -2,9 +2,12 @@ package utils
 
 import (
 	"errors"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/fsmiamoto/git-todo-parser/todo"
+	"github.com/samber/lo"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -232,12 +235,13 @@ func TestRebaseCommands_moveTodoUp(t *testing.T) {
 
 func TestRebaseCommands_moveFixupCommitDown(t *testing.T) {
 	scenarios := []struct {
-		name          string
-		todos         []todo.Todo
-		originalSha   string
-		fixupSha      string
-		expectedTodos []todo.Todo
-		expectedErr   error
+		name            string
+		todos           []todo.Todo
+		originalSha     string
+		fixupSha        string
+		useFixupMessage bool
+		expectedTodos   []todo.Todo
+		expectedErr     error
 	}{
 		{
 			name: "fixup commit is the last commit",
@@ -253,6 +257,23 @@ func TestRebaseCommands_moveFixupCommitDown(t *testing.T) {
 			},
 			expectedErr: nil,
 		},
+		{
+			name: "amend commit uses its own message",
+			todos: []todo.Todo{
+				{Command: todo.Pick, Commit: "original"},
+				{Command: todo.Pick, Commit: "other"},
+				{Command: todo.Pick, Commit: "fixup"},
+			},
+			originalSha:     "original",
+			fixupSha:        "fixup",
+			useFixupMessage: true,
+			expectedTodos: []todo.Todo{
+				{Command: todo.Pick, Commit: "original"},
+				{Command: todo.Fixup, Commit: "fixup", Flag: "-C"},
+				{Command: todo.Pick, Commit: "other"},
+			},
+			expectedErr: nil,
+		},
 		{
 			// TODO: is this something we actually want to support?
 			name: "fixup commit is separated from original commit",
@@ -318,7 +339,348 @@ func TestRebaseCommands_moveFixupCommitDown(t *testing.T) {
 
 	for _, scenario := range scenarios {
 		t.Run(scenario.name, func(t *testing.T) {
-			actualTodos, actualErr := moveFixupCommitDown(scenario.todos, scenario.originalSha, scenario.fixupSha)
+			actualTodos, actualErr := moveFixupCommitDown(scenario.todos, scenario.originalSha, scenario.fixupSha, scenario.useFixupMessage)
+
+			if scenario.expectedErr == nil {
+				assert.NoError(t, actualErr)
+			} else {
+				assert.EqualError(t, actualErr, scenario.expectedErr.Error())
+			}
+
+			assert.EqualValues(t, scenario.expectedTodos, actualTodos)
+		})
+	}
+}
+
+func TestRebaseCommands_editTodoWithCheck(t *testing.T) {
+	scenarios := []struct {
+		name          string
+		todos         []todo.Todo
+		sha           string
+		checkCmd      string
+		expectedTodos []todo.Todo
+		expectedErr   error
+	}{
+		{
+			name: "edit commit in the middle",
+			todos: []todo.Todo{
+				{Command: todo.Pick, Commit: "1234"},
+				{Command: todo.Pick, Commit: "5678"},
+				{Command: todo.Pick, Commit: "abcd"},
+			},
+			sha:      "5678",
+			checkCmd: "make test",
+			expectedTodos: []todo.Todo{
+				{Command: todo.Pick, Commit: "1234"},
+				{Command: todo.Edit, Commit: "5678"},
+				{Command: todo.Exec, ExecCommand: "make test"},
+				{Command: todo.Pick, Commit: "abcd"},
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "edit last commit",
+			todos: []todo.Todo{
+				{Command: todo.Pick, Commit: "1234"},
+				{Command: todo.Pick, Commit: "5678"},
+			},
+			sha:      "5678",
+			checkCmd: "make test",
+			expectedTodos: []todo.Todo{
+				{Command: todo.Pick, Commit: "1234"},
+				{Command: todo.Edit, Commit: "5678"},
+				{Command: todo.Exec, ExecCommand: "make test"},
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "commit not found",
+			todos: []todo.Todo{
+				{Command: todo.Pick, Commit: "1234"},
+			},
+			sha:           "5678",
+			checkCmd:      "make test",
+			expectedTodos: nil,
+			expectedErr:   errors.New("Todo 5678 not found in git-rebase-todo"),
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			actualTodos, actualErr := editTodoWithCheck(scenario.todos, scenario.sha, scenario.checkCmd)
+
+			if scenario.expectedErr == nil {
+				assert.NoError(t, actualErr)
+			} else {
+				assert.EqualError(t, actualErr, scenario.expectedErr.Error())
+			}
+
+			assert.EqualValues(t, scenario.expectedTodos, actualTodos)
+		})
+	}
+}
+
+func TestRebaseCommands_AddCommitterDateBreaks(t *testing.T) {
+	breakTodo := todo.Todo{Command: todo.Break}
+
+	scenarios := []struct {
+		name           string
+		todos          []todo.Todo
+		committerDates map[string]string
+		expectedTodos  []todo.Todo
+	}{
+		{
+			name: "reordered picks",
+			todos: []todo.Todo{
+				{Command: todo.Pick, Commit: "5678"},
+				{Command: todo.Pick, Commit: "1234"},
+			},
+			committerDates: map[string]string{
+				"1234567890": "1700000000 +0100",
+				"5678901234": "1700000100 +0100",
+			},
+			expectedTodos: []todo.Todo{
+				breakTodo,
+				{Command: todo.Pick, Commit: "5678"},
+				breakTodo,
+				{Command: todo.Pick, Commit: "1234"},
+			},
+		},
+		{
+			name: "fixup keeps the date of the commit it's folded into",
+			todos: []todo.Todo{
+				{Command: todo.Pick, Commit: "1234"},
+				{Command: todo.Fixup, Commit: "5678"},
+				{Command: todo.Label, Label: "mylabel"},
+				{Command: todo.Pick, Commit: "abcd"},
+			},
+			committerDates: map[string]string{
+				"1234": "1700000000 +0000",
+				"5678": "1700000100 +0000",
+			},
+			expectedTodos: []todo.Todo{
+				breakTodo,
+				{Command: todo.Pick, Commit: "1234"},
+				{Command: todo.Fixup, Commit: "5678"},
+				{Command: todo.Label, Label: "mylabel"},
+				{Command: todo.Pick, Commit: "abcd"},
+			},
+		},
+		{
+			name: "dropped commits are left alone",
+			todos: []todo.Todo{
+				{Command: todo.Drop, Commit: "1234"},
+				{Command: todo.Reword, Commit: "5678"},
+			},
+			committerDates: map[string]string{
+				"1234": "1700000000 +0000",
+				"5678": "1700000100 +0000",
+			},
+			expectedTodos: []todo.Todo{
+				{Command: todo.Drop, Commit: "1234"},
+				breakTodo,
+				{Command: todo.Reword, Commit: "5678"},
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			actualTodos := AddCommitterDateBreaks(scenario.todos, scenario.committerDates)
+			assert.EqualValues(t, scenario.expectedTodos, actualTodos)
+
+			assert.EqualValues(t, scenario.todos, RemoveCommitterDateBreaks(actualTodos, scenario.committerDates))
+		})
+	}
+}
+
+func TestRebaseCommands_RemoveCommitterDateBreaksKeepsOtherBreaks(t *testing.T) {
+	committerDates := map[string]string{"1234": "1700000000 +0000"}
+	todos := []todo.Todo{
+		{Command: todo.Break},
+		{Command: todo.Break},
+		{Command: todo.Pick, Commit: "1234"},
+		{Command: todo.Break},
+		{Command: todo.Pick, Commit: "5678"},
+	}
+
+	assert.EqualValues(t, []todo.Todo{
+		{Command: todo.Break},
+		{Command: todo.Pick, Commit: "1234"},
+		{Command: todo.Break},
+		{Command: todo.Pick, Commit: "5678"},
+	}, RemoveCommitterDateBreaks(todos, committerDates))
+}
+
+func TestRebaseCommands_PreserveCommitterDates(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "git-rebase-todo")
+	assert.NoError(t, os.WriteFile(path, []byte("pick 1234 first\npick 5678 second\n"), 0o644))
+	committerDates := map[string]string{"1234": "1700000000 +0000", "5678": "1700000100 +0000"}
+
+	assert.NoError(t, PreserveCommitterDates(path, committerDates, '#'))
+
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "break\npick 1234 first\nbreak\npick 5678 second\n", string(content))
+	readDates, err := ReadCommitterDates(filepath.Join(dir, CommitterDatesFile))
+	assert.NoError(t, err)
+	assert.Equal(t, committerDates, readDates)
+}
+
+func TestRebaseCommands_moveTodoAfter(t *testing.T) {
+	scenarios := []struct {
+		name          string
+		todos         []todo.Todo
+		sha           string
+		action        todo.TodoCommand
+		afterSha      string
+		expectedTodos []todo.Todo
+		expectedErr   error
+	}{
+		{
+			name: "move newer commit down to directly after target",
+			todos: []todo.Todo{
+				{Command: todo.Pick, Commit: "target"},
+				{Command: todo.Pick, Commit: "1234"},
+				{Command: todo.Fixup, Commit: "fixup"},
+			},
+			sha:      "fixup",
+			action:   todo.Fixup,
+			afterSha: "target",
+			expectedTodos: []todo.Todo{
+				{Command: todo.Pick, Commit: "target"},
+				{Command: todo.Fixup, Commit: "fixup"},
+				{Command: todo.Pick, Commit: "1234"},
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "move older commit up to directly after target",
+			todos: []todo.Todo{
+				{Command: todo.Fixup, Commit: "fixup"},
+				{Command: todo.Pick, Commit: "1234"},
+				{Command: todo.Reword, Commit: "target"},
+			},
+			sha:      "fixup",
+			action:   todo.Fixup,
+			afterSha: "target",
+			expectedTodos: []todo.Todo{
+				{Command: todo.Pick, Commit: "1234"},
+				{Command: todo.Reword, Commit: "target"},
+				{Command: todo.Fixup, Commit: "fixup"},
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "goes after existing fixups of the target",
+			todos: []todo.Todo{
+				{Command: todo.Pick, Commit: "target"},
+				{Command: todo.Fixup, Commit: "fixup1"},
+				{Command: todo.Pick, Commit: "1234"},
+				{Command: todo.Fixup, Commit: "fixup2"},
+			},
+			sha:      "fixup2",
+			action:   todo.Fixup,
+			afterSha: "target",
+			expectedTodos: []todo.Todo{
+				{Command: todo.Pick, Commit: "target"},
+				{Command: todo.Fixup, Commit: "fixup1"},
+				{Command: todo.Fixup, Commit: "fixup2"},
+				{Command: todo.Pick, Commit: "1234"},
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "target not found",
+			todos: []todo.Todo{
+				{Command: todo.Pick, Commit: "1234"},
+				{Command: todo.Fixup, Commit: "fixup"},
+			},
+			sha:           "fixup",
+			action:        todo.Fixup,
+			afterSha:      "target",
+			expectedTodos: nil,
+			expectedErr:   errors.New("Todo target not found in git-rebase-todo"),
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			actualTodos, actualErr := moveTodoAfter(scenario.todos, scenario.sha, scenario.action, scenario.afterSha)
+
+			if scenario.expectedErr == nil {
+				assert.NoError(t, actualErr)
+			} else {
+				assert.EqualError(t, actualErr, scenario.expectedErr.Error())
+			}
+
+			assert.EqualValues(t, scenario.expectedTodos, actualTodos)
+		})
+	}
+}
+
+func TestRebaseCommands_moveTodoBefore(t *testing.T) {
+	scenarios := []struct {
+		name          string
+		todos         []todo.Todo
+		sha           string
+		action        todo.TodoCommand
+		beforeSha     string
+		expectedTodos []todo.Todo
+		expectedErr   error
+	}{
+		{
+			name: "move newest commit down to directly before target",
+			todos: []todo.Todo{
+				{Command: todo.Pick, Commit: "1234"},
+				{Command: todo.Pick, Commit: "target"},
+				{Command: todo.Pick, Commit: "5678"},
+				{Command: todo.Pick, Commit: "new"},
+			},
+			sha:       "new",
+			action:    todo.Pick,
+			beforeSha: "target",
+			expectedTodos: []todo.Todo{
+				{Command: todo.Pick, Commit: "1234"},
+				{Command: todo.Pick, Commit: "new"},
+				{Command: todo.Pick, Commit: "target"},
+				{Command: todo.Pick, Commit: "5678"},
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "move before the very first todo",
+			todos: []todo.Todo{
+				{Command: todo.Edit, Commit: "target"},
+				{Command: todo.Pick, Commit: "new"},
+			},
+			sha:       "new",
+			action:    todo.Pick,
+			beforeSha: "target",
+			expectedTodos: []todo.Todo{
+				{Command: todo.Pick, Commit: "new"},
+				{Command: todo.Edit, Commit: "target"},
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "target not found",
+			todos: []todo.Todo{
+				{Command: todo.Pick, Commit: "1234"},
+				{Command: todo.Pick, Commit: "new"},
+			},
+			sha:           "new",
+			action:        todo.Pick,
+			beforeSha:     "target",
+			expectedTodos: nil,
+			expectedErr:   errors.New("Todo target not found in git-rebase-todo"),
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			actualTodos, actualErr := moveTodoBefore(scenario.todos, scenario.sha, scenario.action, scenario.beforeSha)
 
 			if scenario.expectedErr == nil {
 				assert.NoError(t, actualErr)
@@ -330,3 +692,414 @@ func TestRebaseCommands_moveFixupCommitDown(t *testing.T) {
 		})
 	}
 }
+
+func TestRebaseCommands_reorderTodos(t *testing.T) {
+	scenarios := []struct {
+		name          string
+		todos         []todo.Todo
+		shas          []string
+		expectedTodos []todo.Todo
+		expectedErr   string
+	}{
+		{
+			name: "full reversal",
+			todos: []todo.Todo{
+				{Command: todo.Pick, Commit: "1111"},
+				{Command: todo.Pick, Commit: "2222"},
+				{Command: todo.Pick, Commit: "3333"},
+				{Command: todo.Pick, Commit: "4444"},
+				{Command: todo.Pick, Commit: "5555"},
+			},
+			shas: []string{"5555", "4444", "3333", "2222", "1111"},
+			expectedTodos: []todo.Todo{
+				{Command: todo.Pick, Commit: "5555"},
+				{Command: todo.Pick, Commit: "4444"},
+				{Command: todo.Pick, Commit: "3333"},
+				{Command: todo.Pick, Commit: "2222"},
+				{Command: todo.Pick, Commit: "1111"},
+			},
+		},
+		{
+			name: "other todos stay in place",
+			todos: []todo.Todo{
+				{Command: todo.Pick, Commit: "1111"},
+				{Command: todo.UpdateRef, Ref: "refs/heads/branch"},
+				{Command: todo.Pick, Commit: "2222"},
+				{Command: todo.Pick, Commit: "3333"},
+			},
+			shas: []string{"2222", "3333", "1111"},
+			expectedTodos: []todo.Todo{
+				{Command: todo.Pick, Commit: "2222"},
+				{Command: todo.UpdateRef, Ref: "refs/heads/branch"},
+				{Command: todo.Pick, Commit: "3333"},
+				{Command: todo.Pick, Commit: "1111"},
+			},
+		},
+		{
+			name: "commit missing from the todo",
+			todos: []todo.Todo{
+				{Command: todo.Pick, Commit: "1111"},
+				{Command: todo.Pick, Commit: "2222"},
+			},
+			shas:          []string{"2222", "3333", "1111"},
+			expectedTodos: nil,
+			expectedErr:   "Expected 3 todos to reorder, found 2",
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			actualTodos, actualErr := reorderTodos(scenario.todos, scenario.shas)
+
+			if scenario.expectedErr == "" {
+				assert.NoError(t, actualErr)
+			} else {
+				assert.EqualError(t, actualErr, scenario.expectedErr)
+			}
+
+			assert.EqualValues(t, scenario.expectedTodos, actualTodos)
+		})
+	}
+}
+
+func TestRebaseCommands_customCommentChar(t *testing.T) {
+	content := "pick 1234 #42 fix the thing\n" +
+		"pick 5678 other change\n" +
+		"; Rebase abcd..5678 onto abcd\n"
+
+	fileName := filepath.Join(t.TempDir(), "git-rebase-todo")
+	assert.NoError(t, os.WriteFile(fileName, []byte(content), 0o644))
+
+	todos, err := ReadRebaseTodoFile(fileName, ';')
+	assert.NoError(t, err)
+	assert.Equal(t, []todo.Todo{
+		{Command: todo.Pick, Commit: "1234", Msg: "#42 fix the thing"},
+		{Command: todo.Pick, Commit: "5678", Msg: "other change"},
+		{Command: todo.Comment, Comment: " Rebase abcd..5678 onto abcd"},
+	}, todos)
+
+	assert.NoError(t, MoveTodoDown(fileName, "5678", todo.Pick, ';'))
+
+	newContent, err := os.ReadFile(fileName)
+	assert.NoError(t, err)
+	assert.Equal(t,
+		"pick 5678 other change\n"+
+			"pick 1234 #42 fix the thing\n"+
+			"; Rebase abcd..5678 onto abcd\n",
+		string(newContent))
+}
+
+func TestRebaseCommands_doneTodosNeedMessageEditor(t *testing.T) {
+	scenarios := []struct {
+		name      string
+		doneTodos []todo.Todo
+		expected  bool
+	}{
+		{
+			name:      "nothing done yet",
+			doneTodos: []todo.Todo{},
+			expected:  false,
+		},
+		{
+			name: "continuing a pick after a reword",
+			doneTodos: []todo.Todo{
+				{Command: todo.Reword, Commit: "1234"},
+				{Command: todo.Pick, Commit: "5678"},
+			},
+			expected: false,
+		},
+		{
+			name: "continuing into a reword after a pick",
+			doneTodos: []todo.Todo{
+				{Command: todo.Pick, Commit: "1234"},
+				{Command: todo.Exec, ExecCommand: "make test"},
+				{Command: todo.Reword, Commit: "5678"},
+			},
+			expected: true,
+		},
+		{
+			name: "end of a squash chain",
+			doneTodos: []todo.Todo{
+				{Command: todo.Pick, Commit: "1234"},
+				{Command: todo.Squash, Commit: "5678"},
+				{Command: todo.Fixup, Commit: "abcd"},
+			},
+			expected: true,
+		},
+		{
+			name: "fixups only",
+			doneTodos: []todo.Todo{
+				{Command: todo.Pick, Commit: "1234"},
+				{Command: todo.Fixup, Commit: "5678"},
+			},
+			expected: false,
+		},
+		{
+			name: "fixup of a reworded commit",
+			doneTodos: []todo.Todo{
+				{Command: todo.Reword, Commit: "1234"},
+				{Command: todo.Fixup, Commit: "5678"},
+			},
+			expected: false,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			assert.Equal(t, scenario.expected, DoneTodosNeedMessageEditor(scenario.doneTodos))
+		})
+	}
+}
+
+func TestRebaseCommands_addExecAfterTodos(t *testing.T) {
+	touchesPaths := func(sha string) (bool, error) {
+		return sha == "1234" || sha == "efgh", nil
+	}
+
+	scenarios := []struct {
+		name          string
+		todos         []todo.Todo
+		expectedTodos []todo.Todo
+	}{
+		{
+			name: "exec only after matching commits",
+			todos: []todo.Todo{
+				{Command: todo.Pick, Commit: "1234"},
+				{Command: todo.Pick, Commit: "5678"},
+				{Command: todo.Reword, Commit: "efgh"},
+			},
+			expectedTodos: []todo.Todo{
+				{Command: todo.Pick, Commit: "1234"},
+				{Command: todo.Exec, ExecCommand: "make lint"},
+				{Command: todo.Pick, Commit: "5678"},
+				{Command: todo.Reword, Commit: "efgh"},
+				{Command: todo.Exec, ExecCommand: "make lint"},
+			},
+		},
+		{
+			name: "matching fixup runs the exec after its group",
+			todos: []todo.Todo{
+				{Command: todo.Pick, Commit: "5678"},
+				{Command: todo.Fixup, Commit: "efgh"},
+				{Command: todo.Pick, Commit: "abcd"},
+			},
+			expectedTodos: []todo.Todo{
+				{Command: todo.Pick, Commit: "5678"},
+				{Command: todo.Fixup, Commit: "efgh"},
+				{Command: todo.Exec, ExecCommand: "make lint"},
+				{Command: todo.Pick, Commit: "abcd"},
+			},
+		},
+		{
+			name: "no matching commits",
+			todos: []todo.Todo{
+				{Command: todo.Pick, Commit: "5678"},
+				{Command: todo.Pick, Commit: "abcd"},
+			},
+			expectedTodos: []todo.Todo{
+				{Command: todo.Pick, Commit: "5678"},
+				{Command: todo.Pick, Commit: "abcd"},
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			actualTodos, err := addExecAfterTodos(scenario.todos, touchesPaths, "make lint")
+			assert.NoError(t, err)
+			assert.EqualValues(t, scenario.expectedTodos, actualTodos)
+		})
+	}
+}
+
+func TestRebaseCommands_toggleTodoLineEnabled(t *testing.T) {
+	content := "pick 1234 first change\n" +
+		"edit 5678 second change\n" +
+		"pick 9abc third change\n" +
+		"\n" +
+		"# Rebase abcd..9abc onto abcd (3 commands)\n" +
+		"#\n" +
+		"# p, pick <commit> = use commit\n"
+
+	fileName := filepath.Join(t.TempDir(), "git-rebase-todo")
+	assert.NoError(t, os.WriteFile(fileName, []byte(content), 0o644))
+
+	assert.NoError(t, ToggleTodoLineEnabled(fileName, "1234", '#'))
+	assert.NoError(t, ToggleTodoLineEnabled(fileName, "5678", '#'))
+
+	disabledContent, err := os.ReadFile(fileName)
+	assert.NoError(t, err)
+	assert.Equal(t,
+		"# pick 1234 first change\n"+
+			"# edit 5678 second change\n"+
+			"pick 9abc third change\n"+
+			"# Rebase abcd..9abc onto abcd (3 commands)\n"+
+			"#\n"+
+			"# p, pick <commit> = use commit\n",
+		string(disabledContent))
+
+	// git skips the commented-out lines
+	todos, err := ReadRebaseTodoFile(fileName, '#')
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(lo.Filter(todos, func(t todo.Todo, _ int) bool { return t.Command != todo.Comment })))
+
+	assert.NoError(t, ToggleTodoLineEnabled(fileName, "1234", '#'))
+	assert.NoError(t, ToggleTodoLineEnabled(fileName, "5678", '#'))
+
+	enabledContent, err := os.ReadFile(fileName)
+	assert.NoError(t, err)
+	assert.Equal(t,
+		"pick 1234 first change\n"+
+			"edit 5678 second change\n"+
+			"pick 9abc third change\n"+
+			"# Rebase abcd..9abc onto abcd (3 commands)\n"+
+			"#\n"+
+			"# p, pick <commit> = use commit\n",
+		string(enabledContent))
+
+	assert.EqualError(t, ToggleTodoLineEnabled(fileName, "def0", '#'), "Todo def0 not found in git-rebase-todo")
+}
+
+func TestRebaseCommands_convertTodoLine(t *testing.T) {
+	todos := []todo.Todo{
+		{Command: todo.Label, Label: "onto"},
+		{Command: todo.Pick, Commit: "1234"},
+		{Command: todo.Label, Label: "feature"},
+		{Command: todo.Reset, Label: "onto"},
+		{Command: todo.Pick, Commit: "5678"},
+		{Command: todo.Pick, Commit: "abcd"},
+	}
+
+	scenarios := []struct {
+		name          string
+		doneTodos     []todo.Todo
+		sha           string
+		action        string
+		arg           string
+		expectedTodos []todo.Todo
+		expectedErr   error
+	}{
+		{
+			name:   "pick to reset",
+			sha:    "abcd",
+			action: "reset",
+			arg:    "feature",
+			expectedTodos: []todo.Todo{
+				{Command: todo.Label, Label: "onto"},
+				{Command: todo.Pick, Commit: "1234"},
+				{Command: todo.Label, Label: "feature"},
+				{Command: todo.Reset, Label: "onto"},
+				{Command: todo.Pick, Commit: "5678"},
+				{Command: todo.Reset, Label: "feature"},
+			},
+		},
+		{
+			name:   "pick to merge",
+			sha:    "abcd",
+			action: "merge",
+			arg:    "feature",
+			expectedTodos: []todo.Todo{
+				{Command: todo.Label, Label: "onto"},
+				{Command: todo.Pick, Commit: "1234"},
+				{Command: todo.Label, Label: "feature"},
+				{Command: todo.Reset, Label: "onto"},
+				{Command: todo.Pick, Commit: "5678"},
+				{Command: todo.Merge, Flag: "-C", Commit: "abcd", Label: "feature"},
+			},
+		},
+		{
+			name:   "pick to edit",
+			sha:    "5678",
+			action: "edit",
+			expectedTodos: []todo.Todo{
+				{Command: todo.Label, Label: "onto"},
+				{Command: todo.Pick, Commit: "1234"},
+				{Command: todo.Label, Label: "feature"},
+				{Command: todo.Reset, Label: "onto"},
+				{Command: todo.Edit, Commit: "5678"},
+				{Command: todo.Pick, Commit: "abcd"},
+			},
+		},
+		{
+			name:      "label that the rebase already created",
+			doneTodos: []todo.Todo{{Command: todo.Label, Label: "earlier"}},
+			sha:       "1234",
+			action:    "reset",
+			arg:       "earlier",
+			expectedTodos: []todo.Todo{
+				{Command: todo.Label, Label: "onto"},
+				{Command: todo.Reset, Label: "earlier"},
+				{Command: todo.Label, Label: "feature"},
+				{Command: todo.Reset, Label: "onto"},
+				{Command: todo.Pick, Commit: "5678"},
+				{Command: todo.Pick, Commit: "abcd"},
+			},
+		},
+		{
+			name:        "label is only defined further down",
+			sha:         "1234",
+			action:      "reset",
+			arg:         "feature",
+			expectedErr: errors.New("Label feature not found in git-rebase-todo"),
+		},
+		{
+			name:        "unknown label",
+			sha:         "abcd",
+			action:      "reset",
+			arg:         "nonexistent",
+			expectedErr: errors.New("Label nonexistent not found in git-rebase-todo"),
+		},
+		{
+			name:        "reset without a label",
+			sha:         "abcd",
+			action:      "reset",
+			expectedErr: errors.New("Todo action reset needs a label without spaces"),
+		},
+		{
+			name:        "pick with an argument",
+			sha:         "abcd",
+			action:      "pick",
+			arg:         "feature",
+			expectedErr: errors.New("Todo action pick doesn't take an argument"),
+		},
+		{
+			name:        "unknown action",
+			sha:         "abcd",
+			action:      "exec",
+			expectedErr: errors.New("Unknown todo action \"exec\""),
+		},
+		{
+			name:        "commit not found",
+			sha:         "ef01",
+			action:      "drop",
+			expectedErr: errors.New("Todo ef01 not found in git-rebase-todo"),
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			actualTodos, actualErr := convertTodoLine(todos, scenario.doneTodos, scenario.sha, scenario.action, scenario.arg)
+
+			if scenario.expectedErr == nil {
+				assert.NoError(t, actualErr)
+			} else {
+				assert.EqualError(t, actualErr, scenario.expectedErr.Error())
+			}
+
+			assert.EqualValues(t, scenario.expectedTodos, actualTodos)
+		})
+	}
+}
+
+func TestRebaseCommands_missingPickTodos(t *testing.T) {
+	todos := []todo.Todo{
+		{Command: todo.Pick, Commit: "1234"},
+		{Command: todo.Edit, Commit: "5678"},
+		{Command: todo.Pick, Commit: "abcd"},
+	}
+
+	assert.Empty(t, MissingPickTodos(todos, []string{"1234", "abcdef"}))
+	// the commit is there, but not as a pick
+	assert.Equal(t, []string{"5678"}, MissingPickTodos(todos, []string{"5678"}))
+	assert.Equal(t, []string{"ef01", ""}, MissingPickTodos(todos, []string{"1234", "ef01", ""}))
+}